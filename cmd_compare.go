@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runCompare implements the "compare" subcommand, which reports how the
+// services of compose files for several environments differ from a base file
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	base := fs.String("base", "", "Base compose file (required)")
+	overlay := fs.Bool("overlay", false, "The files are overrides merged onto the base file, as with docker compose -f base -f file")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from the base file)")
+	format := fs.String("format", "markdown", "Output format: markdown or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter compare -base file [-overlay] [-config file] [-format markdown|json] file...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "markdown" && *format != "json" {
+		printError("Error: -format must be markdown or json")
+		return 1
+	}
+	if *base == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	r := &runner{configFile: *configFile, envSettings: envSettings}
+	settings, err := r.settings(*base)
+	if err != nil {
+		return 1
+	}
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+
+	baseData, err := os.ReadFile(*base)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	var variants []dockercompose.Variant
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+		variants = append(variants, dockercompose.Variant{Name: environmentName(name), Data: data})
+	}
+
+	comparison, err := dockercompose.CompareVariants(baseData, variants, *overlay, opts)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(comparison.Services) == 0 {
+		fmt.Println("No differences between environments")
+		return 0
+	}
+	fmt.Print(markdownComparison(comparison))
+	return 0
+}
+
+// environmentName names the environment of a compose file after the last
+// part of its name: compose.prod.yml is "prod"
+func environmentName(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".yml"), ".yaml")
+	if i := strings.LastIndex(name, "."); i >= 0 && i+1 < len(name) {
+		return name[i+1:]
+	}
+	return name
+}
+
+// markdownComparison renders a comparison as one table per service, with a
+// column for the base file and one for each environment
+func markdownComparison(comparison *dockercompose.Comparison) string {
+	header := append([]string{"Field", "Base"}, comparison.Environments...)
+	var sections []string
+	for _, service := range comparison.Services {
+		var rows [][]string
+		for _, field := range service.Fields {
+			name := "`" + field.Field + "`"
+			if field.Field == "" {
+				name = "(service)"
+			}
+			row := []string{name, field.Base}
+			for _, environment := range comparison.Environments {
+				row = append(row, field.Values[environment])
+			}
+			rows = append(rows, row)
+		}
+		sections = append(sections, formatter.MarkdownSection(service.Service, header, rows))
+	}
+	return formatter.JoinSections(sections...)
+}
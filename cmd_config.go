@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/awsqed/config-formatter/internal/config"
+)
+
+// runConfig implements the "config" subcommand
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		printConfigUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "check":
+		return runConfigCheck(args[1:])
+	case "print-effective":
+		return runConfigPrintEffective(args[1:])
+	case "schema":
+		return runConfigSchema()
+	default:
+		printError("Error: unknown config command '%s'", args[0])
+		printConfigUsage()
+		return 1
+	}
+}
+
+func printConfigUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  config-formatter config check [-config file] [path]")
+	fmt.Fprintln(os.Stderr, "  config-formatter config print-effective [-config file] <file>")
+	fmt.Fprintln(os.Stderr, "  config-formatter config schema")
+}
+
+// runConfigCheck validates the config file that applies to a path
+func runConfigCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	configFile := fs.String("config", "", "Config file to check (default: discovered from path)")
+	fs.Parse(args)
+
+	target := "."
+	if fs.NArg() > 0 {
+		target = fs.Arg(0)
+	}
+
+	// A config file given as the path is checked directly
+	if *configFile == "" && slices.Contains(config.FileNames, filepath.Base(target)) {
+		*configFile = target
+	}
+
+	cfg, err := findConfig(target, *configFile)
+	if err != nil {
+		printConfigError(os.Stderr, err)
+		return 1
+	}
+	if cfg == nil {
+		printError("Error: no config file found for %s", target)
+		return 1
+	}
+
+	fmt.Println(stdoutColor.green("Config file is valid: " + cfg.Path))
+	return 0
+}
+
+// runConfigPrintEffective shows the merged settings for a file and where each comes from
+func runConfigPrintEffective(args []string) int {
+	fs := flag.NewFlagSet("config print-effective", flag.ExitOnError)
+	configFile := fs.String("config", "", "Config file to use (default: discovered from file)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		printConfigUsage()
+		return 1
+	}
+	target := fs.Arg(0)
+
+	cfg, err := findConfig(target, *configFile)
+	if err != nil {
+		printConfigError(os.Stderr, err)
+		return 1
+	}
+
+	layers := []config.Layer{{Source: "default", Settings: config.Defaults()}}
+	if cfg != nil {
+		layers = append(layers, cfg.Layers(target)...)
+	}
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	layers = append(layers, config.Layer{Source: config.EnvSource, Settings: envSettings})
+	layers, err = config.WithPreset(layers)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	for _, value := range config.Effective(layers) {
+		fmt.Printf("%s: %s  # %s\n", value.Name, value.Value, value.Source)
+	}
+	return 0
+}
+
+// runConfigSchema prints the JSON Schema for the config file
+func runConfigSchema() int {
+	schema, err := config.Schema()
+	if err != nil {
+		printError("Error generating schema: %v", err)
+		return 1
+	}
+	fmt.Println(string(schema))
+	return 0
+}
+
+// printConfigError reports a config loading error to w, one line per
+// validation problem
+func printConfigError(w io.Writer, err error) {
+	var validation *config.ValidationError
+	if !errors.As(err, &validation) {
+		fmt.Fprintln(w, stderrColor.red("Error: "+err.Error()))
+		return
+	}
+	for _, p := range validation.Problems {
+		fmt.Fprintf(w, "%s %s\n", location(stderrColor, validation.Path, p.Line, p.Column), stderrColor.red(p.Message))
+	}
+}
+
+// findConfig loads the explicit config file, or the one discovered from the
+// directory of target. It returns nil when no config file exists.
+func findConfig(target, explicit string) (*config.Config, error) {
+	path := explicit
+	if path == "" {
+		dir := target
+		if info, err := os.Stat(target); err != nil || !info.IsDir() {
+			dir = filepath.Dir(target)
+		}
+
+		found, err := config.Find(dir)
+		if err != nil {
+			return nil, err
+		}
+		if found == "" {
+			return nil, nil
+		}
+		path = found
+	}
+
+	return config.Load(path)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runDiff implements the "diff" subcommand, which compares two config files
+// by value after formatting both
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	formatterType := fs.String("type", "", "Formatter type to use for both files (auto-detected from the first file if not specified)")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from the first file)")
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter diff [-type type] [-config file] [-format text|json] fileA fileB")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		printError("Error: -format must be text or json")
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	nameA, nameB := fs.Arg(0), fs.Arg(1)
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 2
+	}
+	r := &runner{configFile: *configFile, envSettings: envSettings}
+	if *formatterType != "" {
+		r.flagSettings.Type = formatterType
+	}
+	settings, err := r.settings(nameA)
+	if err != nil {
+		return 2
+	}
+
+	changes, err := semanticDiff(nameA, nameB, settings)
+	if err != nil {
+		printError("Error: %v", err)
+		return 2
+	}
+
+	if *format == "json" {
+		if changes == nil {
+			changes = []formatter.Change{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			printError("Error: %v", err)
+			return 2
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, change := range changes {
+			switch change.Kind {
+			case formatter.ChangeAdded:
+				fmt.Printf("%s %s: %s\n", stdoutColor.green("+"), change.Path, change.New)
+			case formatter.ChangeRemoved:
+				fmt.Printf("%s %s: %s\n", stdoutColor.red("-"), change.Path, change.Old)
+			default:
+				fmt.Printf("%s %s: %s %s %s\n", stdoutColor.yellow("~"), change.Path, change.Old, stdoutColor.dim("->"), change.New)
+			}
+		}
+		if len(changes) == 0 {
+			fmt.Println(stdoutColor.green("No semantic differences"))
+		}
+	}
+
+	// As with diff(1): 1 when the files differ, 2 on trouble
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// semanticDiff formats both files with the formatter and settings chosen for
+// the first, so that normalized values compare equal, and compares the results
+func semanticDiff(nameA, nameB string, settings config.Settings) ([]formatter.Change, error) {
+	dataA, err := os.ReadFile(nameA)
+	if err != nil {
+		return nil, err
+	}
+	dataB, err := os.ReadFile(nameB)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected formatter.Formatter
+	if settings.Type != nil && *settings.Type != "" {
+		selected, err = formatters.Lookup(*settings.Type)
+	} else {
+		selected, err = formatters.Detect(nameA, dataA)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+	formattedA, err := selected.Format(dataA, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nameA, err)
+	}
+	formattedB, err := selected.Format(dataB, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", nameB, err)
+	}
+
+	changes, err := formatter.SemanticDiff(formattedA, formattedB)
+	var parseErr *formatter.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("semantic diff supports YAML and JSON files; %s output could not be read as YAML: %v", selected.Name(), parseErr)
+	}
+	return changes, err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runDocs implements the "docs" subcommand, which prints a markdown summary
+// of config files for runbooks
+func runDocs(args []string) int {
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+	formatterType := fs.String("type", "", "Formatter type to use (auto-detected if not specified)")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from each file)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter docs [-type type] [-config file] file...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	r := &runner{configFile: *configFile, envSettings: envSettings}
+	if *formatterType != "" {
+		r.flagSettings.Type = formatterType
+	}
+
+	exitCode := 0
+	for i, name := range fs.Args() {
+		settings, err := r.settings(name)
+		if err != nil {
+			exitCode = 1
+			continue
+		}
+		text, err := document(name, settings)
+		if err != nil {
+			printError("%s: %v", name, err)
+			exitCode = 1
+			continue
+		}
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s\n\n", name)
+		}
+		if text == "" {
+			text = "Nothing to summarize\n"
+		}
+		fmt.Print(text)
+	}
+	return exitCode
+}
+
+// document formats a file with its effective settings and summarizes the
+// result with the formatter's Document method
+func document(name string, settings config.Settings) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	var selected formatter.Formatter
+	if settings.Type != nil && *settings.Type != "" {
+		selected, err = formatters.Lookup(*settings.Type)
+	} else {
+		selected, err = formatters.Detect(name, data)
+	}
+	if err != nil {
+		return "", err
+	}
+	documenter, ok := selected.(formatter.Documenter)
+	if !ok {
+		return "", fmt.Errorf("no summary for %s files", selected.Name())
+	}
+
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+	formatted, err := selected.Format(data, opts)
+	if err != nil {
+		return "", err
+	}
+	return documenter.Document(formatted)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+)
+
+// runEnvReport implements the "env-report" subcommand, which lists the
+// environment variables a compose project references
+func runEnvReport(args []string) int {
+	fs := flag.NewFlagSet("env-report", flag.ExitOnError)
+	envFile := fs.String("env-file", "", "The .env file to look variables up in (default: .env in the project directory)")
+	format := fs.String("format", "table", "Output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter env-report [-env-file file] [-format table|json] [dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "table" && *format != "json" {
+		printError("Error: -format must be table or json")
+		return 1
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if *envFile == "" {
+		*envFile = filepath.Join(dir, ".env")
+	} else if _, err := os.Stat(*envFile); err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	variables, err := dockercompose.EnvReport(dir, *envFile)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(variables, "", "  ")
+		if err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(variables) == 0 {
+		fmt.Println("No variables referenced in " + dir)
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VARIABLE\tSERVICES\tDEFAULT\tIN .env")
+	for _, variable := range variables {
+		services := strings.Join(variable.Services, ",")
+		if services == "" {
+			services = "-"
+		}
+		inEnvFile := "no"
+		if variable.InEnvFile {
+			inEnvFile = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", variable.Name, services, describeDefault(variable), inEnvFile)
+	}
+	w.Flush()
+	return 0
+}
+
+// describeDefault summarizes the default values of a variable for the table
+func describeDefault(variable *dockercompose.Variable) string {
+	var quoted []string
+	for _, value := range variable.Defaults {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+	switch {
+	case variable.HasDefault:
+		return strings.Join(quoted, ", ")
+	case len(quoted) > 0:
+		return strings.Join(quoted, ", ") + " (not on every reference)"
+	case variable.Required:
+		return "required"
+	}
+	return "-"
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/internal/modules/traefik"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runGenerate implements the "generate" subcommand
+func runGenerate(args []string) int {
+	if len(args) == 0 {
+		printGenerateUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "traefik-labels":
+		return runGenerateTraefikLabels(args[1:])
+	default:
+		printError("Error: unknown generate command '%s'", args[0])
+		printGenerateUsage()
+		return 1
+	}
+}
+
+func printGenerateUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  config-formatter generate traefik-labels -service name -rule host|rule -port port [-entrypoint name] [-tls] [-cert-resolver name] [-w] file")
+}
+
+// runGenerateTraefikLabels adds the Traefik labels routing to a compose
+// service and formats the file, so the labels are written the way the
+// formatter would write them
+func runGenerateTraefikLabels(args []string) int {
+	fs := flag.NewFlagSet("generate traefik-labels", flag.ExitOnError)
+	service := fs.String("service", "", "Compose service to route to; also the router and service name (required)")
+	rule := fs.String("rule", "", "Router rule, or a host name for Host(`name`) (required)")
+	port := fs.Int("port", 0, "Port the container listens on (required)")
+	entryPoint := fs.String("entrypoint", "web", "Entry point the router listens on")
+	tls := fs.Bool("tls", false, "Enable TLS on the router")
+	certResolver := fs.String("cert-resolver", "", "Certificate resolver for the router's certificates (implies -tls)")
+	inPlace := fs.Bool("w", false, "Write the result to the file instead of stdout")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from the file's directory)")
+	fs.Usage = printGenerateUsage
+	fs.Parse(args)
+
+	if *service == "" || *rule == "" || *port == 0 || fs.NArg() != 1 {
+		printGenerateUsage()
+		fs.PrintDefaults()
+		return 1
+	}
+	if !traefik.ValidName(*service) {
+		printError("Error: -service %q cannot be used as a Traefik router name", *service)
+		return 1
+	}
+	if *port < 1 || *port > 65535 {
+		printError("Error: -port must be between 1 and 65535")
+		return 1
+	}
+	name := fs.Arg(0)
+
+	labels := traefik.ServiceLabels{
+		Name:         *service,
+		Rule:         traefik.HostRule(*rule),
+		Port:         *port,
+		EntryPoint:   *entryPoint,
+		TLS:          *tls || *certResolver != "",
+		CertResolver: *certResolver,
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		printError("Error reading file: %v", err)
+		return 1
+	}
+	updated, err := dockercompose.SetLabels(data, *service, labels.Labels(), labels.Owns)
+	if err != nil {
+		printError("%s: Error: %v", name, err)
+		return 1
+	}
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	r := &runner{configFile: *configFile, envSettings: envSettings}
+	settings, err := r.settings(name)
+	if err != nil {
+		return 1
+	}
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+	formatted, err := dockercompose.New().Format(updated, opts)
+	if err != nil {
+		printError("%s: Error formatting file: %v", name, err)
+		return 1
+	}
+
+	if !*inPlace {
+		os.Stdout.Write(formatted)
+		return 0
+	}
+	if err := os.WriteFile(name, formatted, 0644); err != nil {
+		printError("Error writing file: %v", err)
+		return 1
+	}
+	fmt.Println(stdoutColor.green(fmt.Sprintf("Traefik labels for %s written to: %s", *service, name)))
+	return 0
+}
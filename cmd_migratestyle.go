@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/lockedfile"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// styleChange is a file whose key order changes with this version
+type styleChange struct {
+	path      string
+	formatter string
+	original  []byte
+	formatted []byte
+}
+
+// runMigrateStyle implements the "migrate-style" subcommand, which shows how
+// the key order of the files of a repository formatted with an older release
+// changes with this one, and can apply the changes in a commit of their own
+// Only the paths orderChanges records as changed since that release are
+// re-ordered; files that are not formatted otherwise are left out, as
+// formatting them would change more than key order.
+func runMigrateStyle(args []string) int {
+	fs := flag.NewFlagSet("migrate-style", flag.ExitOnError)
+	fromVersion := fs.String("from-version", "", "Release the files were formatted with, e.g. v1.4.0 (required)")
+	apply := fs.Bool("apply", false, "Rewrite the files and commit them with git in one dedicated commit")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from each file's directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter migrate-style -from-version vX.Y.Z [-apply] [-config file] [dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *fromVersion == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 1
+	}
+	if _, ok := parseVersion(*fromVersion); !ok {
+		printError("Error: -from-version must be a release version such as v1.4.0")
+		return 1
+	}
+	if Version != "dev" && compareVersions(*fromVersion, Version) >= 0 {
+		printError("Error: -from-version %s is not older than this release (%s)", *fromVersion, Version)
+		return 1
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	r := &runner{configFile: *configFile, envSettings: envSettings}
+	changes, unformatted, failed := r.styleChanges(dir, *fromVersion)
+	if len(unformatted) > 0 {
+		fmt.Fprintf(os.Stderr, "%d files were left out because they are not formatted, so formatting them changes more than key order:\n", len(unformatted))
+		for _, path := range unformatted {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
+		}
+	}
+
+	if !*apply {
+		for _, change := range changes {
+			fmt.Print(unifiedDiff(change.path, change.original, change.formatted, stdoutColor))
+		}
+		if len(changes) == 0 {
+			fmt.Println(stdoutColor.green(fmt.Sprintf("No files change key order between %s and %s", *fromVersion, Version)))
+		} else {
+			fmt.Printf("%d files change key order between %s and %s; run with -apply to commit them\n", len(changes), *fromVersion, Version)
+		}
+		if failed > 0 {
+			printError("%d files could not be formatted", failed)
+			return 1
+		}
+		return 0
+	}
+
+	if failed > 0 {
+		printError("Error: %d files could not be formatted, nothing was changed", failed)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Println(stdoutColor.green(fmt.Sprintf("No files change key order between %s and %s", *fromVersion, Version)))
+		return 0
+	}
+	if err := commitStyleChanges(dir, changes, *fromVersion); err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	fmt.Println(stdoutColor.green(fmt.Sprintf("Committed the key order changes of %d files", len(changes))))
+	return 0
+}
+
+// styleChanges returns the files under dir whose key order changes with the
+// ordering changes made since fromVersion, the files left out because they
+// are not formatted, and the number of files that failed
+func (r *runner) styleChanges(dir, fromVersion string) ([]styleChange, []string, int) {
+	files, err := findConfigFiles(dir)
+	if err != nil {
+		printError("Error reading directory: %v", err)
+		return nil, nil, 1
+	}
+
+	var changes []styleChange
+	var unformatted []string
+	failed := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			printError("%s: Error reading file: %v", path, err)
+			failed++
+			continue
+		}
+		settings, err := r.settings(path)
+		if err != nil {
+			failed++
+			continue
+		}
+
+		var selected formatter.Formatter
+		if settings.Type != nil && *settings.Type != "" {
+			selected, err = formatters.Lookup(*settings.Type)
+		} else {
+			selected, err = formatters.Detect(path, data)
+		}
+		if err != nil {
+			// Files no formatter recognizes are not ours
+			continue
+		}
+
+		var changed []string
+		for _, change := range orderChangesSince(fromVersion, selected.Name()) {
+			changed = append(changed, change.paths...)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		// Formatting with the changed paths kept in order gives the file
+		// back when it was formatted with the older release
+		opts := formatter.DefaultOptions()
+		settings.Apply(&opts)
+		kept := opts
+		kept.KeepOrder = slices.Concat(opts.KeepOrder, changed)
+		previous, err := selected.Format(data, kept)
+		if err != nil {
+			printError("%s: Error formatting file: %v", path, err)
+			failed++
+			continue
+		}
+		if !bytes.Equal(data, previous) {
+			unformatted = append(unformatted, path)
+			continue
+		}
+
+		formatted, err := selected.Format(data, opts)
+		if err != nil {
+			printError("%s: Error formatting file: %v", path, err)
+			failed++
+			continue
+		}
+		if !bytes.Equal(data, formatted) {
+			changes = append(changes, styleChange{path: path, formatter: selected.Name(), original: data, formatted: formatted})
+		}
+	}
+	return changes, unformatted, failed
+}
+
+// commitStyleChanges writes the re-ordered files and commits them, and only
+// them, with git. Files with uncommitted changes are refused, so the commit
+// holds nothing but the style migration.
+func commitStyleChanges(dir string, changes []styleChange, fromVersion string) error {
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		path, err := filepath.Abs(change.path)
+		if err != nil {
+			return err
+		}
+		paths[i] = path
+	}
+
+	status, err := git(dir, append([]string{"status", "--porcelain", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return fmt.Errorf("files to reformat have uncommitted changes, commit or stash them first:\n%s", status)
+	}
+	staged, err := git(dir, "diff", "--cached", "--name-only")
+	if err != nil {
+		return err
+	}
+	if staged != "" {
+		return fmt.Errorf("other changes are staged, commit or unstage them first:\n%s", staged)
+	}
+
+	for i, change := range changes {
+		if err := lockedfile.Replace(paths[i], change.original, change.formatted); err != nil {
+			return err
+		}
+	}
+	if _, err := git(dir, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	var message strings.Builder
+	fmt.Fprintf(&message, "Re-order config file keys for config-formatter %s\n\n", Version)
+	fmt.Fprintf(&message, "The files were formatted with %s. This commit applies the key ordering changes made to their formatters since then:\n\n", fromVersion)
+	for _, change := range orderChangesSince(fromVersion, "") {
+		if slices.ContainsFunc(changes, func(c styleChange) bool { return c.formatter == change.formatter }) {
+			fmt.Fprintf(&message, "- %s: %s\n", change.formatter, change.description)
+		}
+	}
+	_, err = git(dir, "commit", "-q", "-m", message.String())
+	return err
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
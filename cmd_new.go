@@ -0,0 +1,305 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/internal/modules/traefik"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// serviceTemplate is the skeleton of a well-known compose service
+type serviceTemplate struct {
+	image       string
+	environment []string
+	volume      string
+
+	// port is the HTTP port the service listens on, routed to by Traefik;
+	// 0 for services that do not speak HTTP
+	port int
+}
+
+// serviceTemplates are the skeletons of services recognized by name; other
+// services are built from a directory of their name
+var serviceTemplates = map[string]serviceTemplate{
+	"web":      {image: "nginx:alpine", port: 80},
+	"nginx":    {image: "nginx:alpine", port: 80},
+	"db":       {image: "postgres:16-alpine", environment: []string{"POSTGRES_PASSWORD: ${POSTGRES_PASSWORD:?}"}, volume: "/var/lib/postgresql/data"},
+	"postgres": {image: "postgres:16-alpine", environment: []string{"POSTGRES_PASSWORD: ${POSTGRES_PASSWORD:?}"}, volume: "/var/lib/postgresql/data"},
+	"mysql":    {image: "mysql:8", environment: []string{"MYSQL_ROOT_PASSWORD: ${MYSQL_ROOT_PASSWORD:?}"}, volume: "/var/lib/mysql"},
+	"redis":    {image: "redis:7-alpine", volume: "/data"},
+	"cache":    {image: "redis:7-alpine"},
+}
+
+// serviceName matches the service names compose accepts
+var serviceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// runNew implements the "new" subcommand
+func runNew(args []string) int {
+	if len(args) == 0 {
+		printNewUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "compose":
+		return runNewCompose(args[1:])
+	case "traefik-static":
+		return runNewTraefikStatic(args[1:])
+	default:
+		printError("Error: unknown new command '%s'", args[0])
+		printNewUsage()
+		return 1
+	}
+}
+
+func printNewUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  config-formatter new compose -services name,... [-with-traefik] [-output file]")
+	fmt.Fprintln(os.Stderr, "  config-formatter new traefik-static [-entrypoints name:port,...] [-acme -acme-email address] [-output file]")
+}
+
+// writeNew formats a generated file with the settings that apply where it is
+// written, so a new project starts out formatted, and writes it to output,
+// or stdout when output is empty; an existing file is not overwritten
+func writeNew(skeleton string, f formatter.Formatter, output, defaultName, configFile string) int {
+	if output != "" {
+		if _, err := os.Stat(output); err == nil {
+			printError("Error: %s already exists", output)
+			return 1
+		}
+	}
+
+	envSettings, err := config.FromEnv()
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+	r := &runner{configFile: configFile, envSettings: envSettings}
+	target := output
+	if target == "" {
+		target = defaultName
+	}
+	settings, err := r.settings(target)
+	if err != nil {
+		return 1
+	}
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+	formatted, err := f.Format([]byte(skeleton), opts)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	if output == "" {
+		os.Stdout.Write(formatted)
+		return 0
+	}
+	if err := os.WriteFile(output, formatted, 0644); err != nil {
+		printError("Error writing file: %v", err)
+		return 1
+	}
+	fmt.Println(stdoutColor.green("File written to: " + filepath.Clean(output)))
+	return 0
+}
+
+// runNewCompose writes a skeleton compose file
+func runNewCompose(args []string) int {
+	fs := flag.NewFlagSet("new compose", flag.ExitOnError)
+	services := fs.String("services", "", "Comma-separated service names, e.g. web,api,db (required)")
+	withTraefik := fs.Bool("with-traefik", false, "Add a Traefik service and route to the HTTP services by host name")
+	output := fs.String("output", "", "File to write (default: stdout); an existing file is not overwritten")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from the output's directory)")
+	fs.Usage = func() {
+		printNewUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var names []string
+	for _, name := range strings.Split(*services, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 || fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+	for _, name := range names {
+		if !serviceName.MatchString(name) || *withTraefik && !traefik.ValidName(name) {
+			printError("Error: %q is not a valid service name", name)
+			return 1
+		}
+		if *withTraefik && name == "traefik" {
+			printError("Error: -with-traefik adds the traefik service itself")
+			return 1
+		}
+	}
+	return writeNew(composeSkeleton(names, *withTraefik), dockercompose.New(), *output, "docker-compose.yml", *configFile)
+}
+
+// composeSkeleton returns a compose file for the services, unformatted
+// Services without a template are built from a directory of their name and
+// are assumed to serve HTTP on port 8080.
+func composeSkeleton(names []string, withTraefik bool) string {
+	var b strings.Builder
+	var volumes []string
+	b.WriteString("services:\n")
+	if withTraefik {
+		b.WriteString("  traefik:\n")
+		b.WriteString("    image: traefik:v3.1\n")
+		b.WriteString("    command:\n")
+		b.WriteString("      - --providers.docker=true\n")
+		b.WriteString("      - --providers.docker.exposedbydefault=false\n")
+		b.WriteString("      - --entrypoints.web.address=:80\n")
+		b.WriteString("    ports:\n")
+		b.WriteString("      - \"80:80\"\n")
+		b.WriteString("    volumes:\n")
+		b.WriteString("      - /var/run/docker.sock:/var/run/docker.sock:ro\n")
+		b.WriteString("    restart: unless-stopped\n")
+	}
+
+	for _, name := range names {
+		template, ok := serviceTemplates[name]
+		if !ok {
+			template = serviceTemplate{port: 8080}
+		}
+		fmt.Fprintf(&b, "  %s:\n", name)
+		if template.image != "" {
+			fmt.Fprintf(&b, "    image: %s\n", template.image)
+		} else {
+			fmt.Fprintf(&b, "    build: ./%s\n", name)
+		}
+		if len(template.environment) > 0 {
+			b.WriteString("    environment:\n")
+			for _, variable := range template.environment {
+				fmt.Fprintf(&b, "      %s\n", variable)
+			}
+		}
+		if template.volume != "" {
+			volumes = append(volumes, name+"-data")
+			fmt.Fprintf(&b, "    volumes:\n      - %s-data:%s\n", name, template.volume)
+		}
+		if withTraefik && template.port != 0 {
+			labels := traefik.ServiceLabels{Name: name, Rule: traefik.HostRule(name + ".localhost"), Port: template.port, EntryPoint: "web"}
+			b.WriteString("    labels:\n")
+			for _, label := range labels.Labels() {
+				value, _ := yaml.Marshal(label[1])
+				fmt.Fprintf(&b, "      %s: %s", label[0], value)
+			}
+		}
+		b.WriteString("    restart: unless-stopped\n")
+	}
+
+	if len(volumes) > 0 {
+		b.WriteString("volumes:\n")
+		for _, volume := range volumes {
+			fmt.Fprintf(&b, "  %s:\n", volume)
+		}
+	}
+	return b.String()
+}
+
+// entryPoint is an entry point of a Traefik static config
+type entryPoint struct {
+	name string
+	port int
+}
+
+// runNewTraefikStatic writes a skeleton Traefik static config
+func runNewTraefikStatic(args []string) int {
+	fs := flag.NewFlagSet("new traefik-static", flag.ExitOnError)
+	entryPoints := fs.String("entrypoints", "web:80,websecure:443", "Comma-separated entry points as name:port")
+	acme := fs.Bool("acme", false, "Add a Let's Encrypt certificate resolver and use it on the port 443 entry point")
+	acmeEmail := fs.String("acme-email", "", "Email address of the ACME account (required with -acme)")
+	output := fs.String("output", "", "File to write (default: stdout); an existing file is not overwritten")
+	configFile := fs.String("config", "", "Config file to use (default: discovered from the output's directory)")
+	fs.Usage = func() {
+		printNewUsage()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+	var points []entryPoint
+	for _, field := range strings.Split(*entryPoints, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		name, portText, _ := strings.Cut(field, ":")
+		port, err := strconv.Atoi(portText)
+		if !traefik.ValidName(name) || err != nil || port < 1 || port > 65535 {
+			printError("Error: entry point %q must be written name:port", field)
+			return 1
+		}
+		points = append(points, entryPoint{name, port})
+	}
+	if len(points) == 0 {
+		printError("Error: -entrypoints needs at least one entry point")
+		return 1
+	}
+	if *acme && *acmeEmail == "" {
+		printError("Error: -acme needs -acme-email")
+		return 1
+	}
+
+	return writeNew(traefikStaticSkeleton(points, *acme, *acmeEmail), traefik.New(), *output, "traefik.yml", *configFile)
+}
+
+// traefikStaticSkeleton returns a Traefik static config with the docker
+// provider, unformatted
+// With acme, the certificates of the port 443 entry point come from Let's
+// Encrypt, with the HTTP challenge on the port 80 entry point when there is
+// one, which then redirects to HTTPS, and the TLS challenge otherwise.
+func traefikStaticSkeleton(points []entryPoint, acme bool, email string) string {
+	var http, https string
+	for _, point := range points {
+		switch point.port {
+		case 80:
+			http = point.name
+		case 443:
+			https = point.name
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("entryPoints:\n")
+	for _, point := range points {
+		fmt.Fprintf(&b, "  %s:\n    address: \":%d\"\n", point.name, point.port)
+		switch {
+		case acme && point.name == http && https != "":
+			fmt.Fprintf(&b, "    http:\n      redirections:\n        entryPoint:\n          to: %s\n          scheme: https\n", https)
+		case acme && point.name == https:
+			b.WriteString("    http:\n      tls:\n        certResolver: letsencrypt\n")
+		}
+	}
+	b.WriteString("providers:\n  docker:\n    exposedByDefault: false\n")
+	b.WriteString("api:\n  dashboard: true\n")
+	b.WriteString("log:\n  level: INFO\n")
+
+	if acme {
+		value, _ := yaml.Marshal(email)
+		b.WriteString("certificatesResolvers:\n  letsencrypt:\n    acme:\n")
+		fmt.Fprintf(&b, "      email: %s", value)
+		b.WriteString("      storage: /letsencrypt/acme.json\n")
+		if http != "" {
+			fmt.Fprintf(&b, "      httpChallenge:\n        entryPoint: %s\n", http)
+		} else {
+			b.WriteString("      tlsChallenge: {}\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awsqed/config-formatter/internal/config"
+)
+
+// releasesURL is the GitHub API endpoint for the latest release
+const releasesURL = "https://api.github.com/repos/awsqed/config-formatter/releases/latest"
+
+// checksumsAsset lists the SHA-256 of every release archive, in sha256sum
+// format; checksumsAsset + ".sig" is its base64 ed25519 signature
+const checksumsAsset = "checksums.txt"
+
+// releasePublicKey is the base64 ed25519 key release checksums are signed
+// with, set at build time with -ldflags "-X main.releasePublicKey=..." (make
+// release sets it). A build without it cannot tell a genuine release from one
+// whose archive and checksums were replaced together, so it only updates
+// with -insecure.
+var releasePublicKey = ""
+
+// release is the part of a GitHub release the updater reads
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the named asset
+func (r *release) assetURL(name string) (string, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset.URL, true
+		}
+	}
+	return "", false
+}
+
+// runSelfUpdate implements the "self-update" subcommand, which replaces the
+// running binary with the latest release for this platform
+func runSelfUpdate(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release is available (exit code 1 if there is)")
+	force := fs.Bool("force", false, "Install the latest release even if it is not newer, or over a development build")
+	insecure := fs.Bool("insecure", false, "Install without a signature check when this build has no release key, trusting the release's checksums alone")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter self-update [-check] [-force] [-insecure]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if networkDisabled() {
+		printError("Error: self-update needs network access, which %s disables", config.EnvName("offline"))
+		return 1
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	latest, err := fetchRelease(client)
+	if err != nil {
+		printError("Error: checking for updates: %v", err)
+		return 1
+	}
+
+	newer := compareVersions(latest.TagName, Version) > 0
+	if *check {
+		if !newer {
+			fmt.Println(stdoutColor.green(fmt.Sprintf("config-formatter %s is up to date", Version)))
+			return 0
+		}
+		fmt.Printf("config-formatter %s is available (current: %s)\n", latest.TagName, Version)
+		return 1
+	}
+	if !newer && !*force {
+		if Version == "dev" {
+			printError("Error: this is a development build; use -force to replace it with %s", latest.TagName)
+			return 1
+		}
+		fmt.Println(stdoutColor.green(fmt.Sprintf("config-formatter %s is up to date", Version)))
+		return 0
+	}
+
+	// The checksums come from the same release as the archive, so without
+	// the signature they only show that the download is intact
+	if releasePublicKey == "" {
+		if !*insecure {
+			printError("Error: this build has no release key to verify the signature of %s with; install a release build, or use -insecure to trust the checksums alone", latest.TagName)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, stderrColor.yellow(fmt.Sprintf("warning: installing %s without a signature check; the checksums only show that the download is intact, not that it is genuine", latest.TagName)))
+	}
+
+	binary, err := downloadRelease(client, latest)
+	if err != nil {
+		printError("Error: downloading %s: %v", latest.TagName, err)
+		return 1
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		executable, err = filepath.EvalSymlinks(executable)
+	}
+	if err == nil {
+		err = replaceExecutable(executable, binary)
+	}
+	if err != nil {
+		printError("Error: installing %s: %v", latest.TagName, err)
+		return 1
+	}
+
+	fmt.Println(stdoutColor.green(fmt.Sprintf("Updated config-formatter %s to %s", Version, latest.TagName)))
+	return 0
+}
+
+// fetchRelease reads the latest release from the GitHub API
+func fetchRelease(client *http.Client) (*release, error) {
+	data, err := download(client, releasesURL)
+	if err != nil {
+		return nil, err
+	}
+	var latest release
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return nil, fmt.Errorf("reading release: %v", err)
+	}
+	if latest.TagName == "" {
+		return nil, errors.New("no release found")
+	}
+	return &latest, nil
+}
+
+// download fetches a URL
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// downloadRelease downloads the archive for this platform, verifies it
+// against the release checksums, and their signature when the build has a
+// release key, and returns the binary inside it
+func downloadRelease(client *http.Client, latest *release) ([]byte, error) {
+	name := "config-formatter-" + runtime.GOOS + "-" + runtime.GOARCH
+	archive := name + ".tar.gz"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+		archive = "config-formatter-" + runtime.GOOS + "-" + runtime.GOARCH + ".zip"
+	}
+
+	archiveURL, ok := latest.assetURL(archive)
+	if !ok {
+		return nil, fmt.Errorf("no release archive for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	checksumsURL, ok := latest.assetURL(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("the release has no %s to verify the download against", checksumsAsset)
+	}
+
+	checksums, err := download(client, checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+	if releasePublicKey != "" {
+		signatureURL, ok := latest.assetURL(checksumsAsset + ".sig")
+		if !ok {
+			return nil, fmt.Errorf("the release has no %s.sig", checksumsAsset)
+		}
+		signature, err := download(client, signatureURL)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(checksums, signature); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := download(client, archiveURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(checksums, archive, data); err != nil {
+		return nil, err
+	}
+
+	if runtime.GOOS == "windows" {
+		return extractZip(data, name)
+	}
+	return extractTarGz(data, name)
+}
+
+// verifySignature checks the base64 ed25519 signature of the checksums file
+func verifySignature(checksums, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(releasePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("the built-in release key is invalid")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), checksums, sig) {
+		return fmt.Errorf("the signature of %s does not match", checksumsAsset)
+	}
+	return nil
+}
+
+// verifyChecksum checks data against its line in a sha256sum file
+func verifyChecksum(checksums []byte, name string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		sum, file, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok || strings.TrimLeft(file, " *") != name {
+			continue
+		}
+		actual := sha256.Sum256(data)
+		if !strings.EqualFold(sum, hex.EncodeToString(actual[:])) {
+			return fmt.Errorf("checksum mismatch for %s", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is not listed in %s", name, checksumsAsset)
+}
+
+// extractTarGz returns the named file from a .tar.gz archive
+func extractTarGz(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s is not in the archive", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(header.Name) == name && header.Typeflag == tar.TypeReg {
+			return io.ReadAll(archive)
+		}
+	}
+}
+
+// extractZip returns the named file from a .zip archive
+func extractZip(data []byte, name string) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range archive.File {
+		if filepath.Base(file.Name) != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s is not in the archive", name)
+}
+
+// replaceExecutable swaps the binary at path for binary
+// The new binary is written next to the old one and renamed over it, so a
+// failed update leaves the old binary in place; Windows does not allow
+// replacing a running executable, so there the old one is moved aside first
+func replaceExecutable(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-formatter-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// compareVersions compares two vMAJOR.MINOR.PATCH versions numerically
+// Anything that is not such a version, like "dev", sorts before every release
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return pa[i] - pb[i]
+		}
+	}
+	return 0
+}
+
+// parseVersion splits "v1.2.3" into its numbers; a pre-release suffix is
+// ignored
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	fields := strings.Split(version, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/modules/traefik"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runTraefikReport implements the "traefik-report" subcommand, which reports
+// the middleware and TLS usage of Traefik dynamic configuration files
+func runTraefikReport(args []string) int {
+	fs := flag.NewFlagSet("traefik-report", flag.ExitOnError)
+	format := fs.String("format", "markdown", "Output format: markdown or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter traefik-report [-format markdown|json] file|dir...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *format != "markdown" && *format != "json" {
+		printError("Error: -format must be markdown or json")
+		return 1
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	report, err := traefik.UsageReport(fs.Args()...)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Print(markdownReport(report))
+	return 0
+}
+
+// markdownReport renders a report as markdown tables, one section per table;
+// empty sections are left out
+func markdownReport(report *traefik.Report) string {
+	var rows [][]string
+	for _, m := range report.Middlewares {
+		kind := m.Type
+		if !m.Defined {
+			kind = "not defined"
+		}
+		routers := strings.Join(m.Routers, ", ")
+		if m.Unused {
+			routers = "**unused**"
+		}
+		rows = append(rows, []string{m.Protocol, m.Name, kind, routers, strings.Join(m.Chains, ", ")})
+	}
+	middlewares := formatter.MarkdownSection("Middlewares", []string{"Protocol", "Middleware", "Type", "Routers", "Chains"}, rows)
+
+	rows = nil
+	for _, chain := range report.SharedChains {
+		rows = append(rows, []string{chain.Protocol, strings.Join(chain.Middlewares, ", "), strings.Join(chain.Routers, ", ")})
+	}
+	chains := formatter.MarkdownSection("Shared Middleware Chains", []string{"Protocol", "Middlewares", "Routers"}, rows)
+
+	rows = nil
+	for _, chain := range report.RouterChains {
+		var problems []string
+		for _, cycle := range chain.Cycles {
+			problems = append(problems, "**cycle** "+strings.Join(cycle, " → "))
+		}
+		for _, name := range chain.Duplicates {
+			problems = append(problems, "**duplicate** "+name)
+		}
+		rows = append(rows, []string{chain.Protocol, chain.Router, strings.Join(chain.Middlewares, ", "), strings.Join(problems, ", ")})
+	}
+	flattened := formatter.MarkdownSection("Router Middleware Chains", []string{"Protocol", "Router", "Middlewares", "Problems"}, rows)
+
+	rows = nil
+	for _, option := range report.TLSOptions {
+		defined := "yes"
+		if !option.Defined {
+			defined = "no"
+		}
+		rows = append(rows, []string{option.Name, defined, strings.Join(option.Routers, ", ")})
+	}
+	options := formatter.MarkdownSection("TLS Options", []string{"Options", "Defined", "Routers"}, rows)
+
+	rows = nil
+	for _, resolver := range report.CertResolvers {
+		rows = append(rows, []string{resolver.Name, strings.Join(resolver.Routers, ", ")})
+	}
+	resolvers := formatter.MarkdownSection("Certificate Resolvers", []string{"Resolver", "Routers"}, rows)
+
+	text := formatter.JoinSections(middlewares, chains, flattened, options, resolvers)
+	if text == "" {
+		return "No routers or middlewares found\n"
+	}
+	return text
+}
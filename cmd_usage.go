@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+)
+
+// runUsage implements the "usage" subcommand, which reports the services
+// using each volume, network, secret and config of a compose project
+func runUsage(args []string) int {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "Remove the definitions no service uses from the compose files")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter usage [-fix] [dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	usage, err := dockercompose.ProjectUsage(dir)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	if *fix {
+		changed, err := dockercompose.RemoveUnused(dir, usage)
+		if err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+		for _, file := range changed {
+			fmt.Fprintln(os.Stderr, stderrColor.green("Removed unused definitions from "+filepath.Join(dir, file)))
+		}
+		if usage, err = dockercompose.ProjectUsage(dir); err != nil {
+			printError("Error: %v", err)
+			return 1
+		}
+	}
+
+	if len(usage.Resources) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tNAME\tSERVICES")
+		for _, resource := range usage.Resources {
+			services := strings.Join(resource.Services, ", ")
+			if resource.Unused() {
+				services = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", resource.Kind, resource.Name, services)
+		}
+		w.Flush()
+	}
+
+	for _, issue := range usage.Issues {
+		name := filepath.Join(dir, issue.File)
+		fmt.Fprintf(os.Stderr, "%s %s %s\n", location(stderrColor, name, issue.Line, issue.Column), issue.Message, stderrColor.dim("["+issue.Rule+"]"))
+	}
+	if len(usage.Issues) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// runValidate implements the "validate" subcommand, which checks a compose
+// project for conflicts between its files, services and profiles
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	envFile := fs.String("env-file", "", "The .env file to read COMPOSE_PROJECT_NAME from (default: .env in the project directory)")
+	offline := fs.Bool("offline", false, "Refuse all network access")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  config-formatter validate [-env-file file] [-offline] [dir]")
+	}
+	fs.Parse(args)
+	if *offline {
+		disableNetwork()
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if *envFile == "" {
+		*envFile = filepath.Join(dir, ".env")
+	} else if _, err := os.Stat(*envFile); err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	issues, err := dockercompose.ValidateProject(dir, *envFile)
+	if err != nil {
+		printError("Error: %v", err)
+		return 1
+	}
+
+	// Warnings are reported without failing the project
+	errorCount := 0
+	for _, issue := range issues {
+		name := filepath.Join(dir, issue.File)
+		message := issue.Message
+		if issue.Severity == formatter.SeverityWarning {
+			message = stderrColor.yellow("warning:") + " " + message
+		} else {
+			errorCount++
+		}
+		fmt.Fprintf(os.Stderr, "%s %s %s\n", location(stderrColor, name, issue.Line, issue.Column), message, stderrColor.dim("["+issue.Rule+"]"))
+	}
+	if errorCount > 0 {
+		return 1
+	}
+	if len(issues) > 0 {
+		fmt.Println(stdoutColor.green(fmt.Sprintf("No conflicts found in %s, %d warnings", dir, len(issues))))
+		return 0
+	}
+
+	fmt.Println(stdoutColor.green("No conflicts found in " + dir))
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Color modes accepted by -color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// palette wraps text in ANSI color codes when enabled
+type palette struct {
+	enabled bool
+}
+
+// Palettes for each output stream, decided separately since only one of them
+// may be a terminal (e.g. stdout piped to a file, stderr on screen)
+var (
+	stdoutColor palette
+	stderrColor palette
+)
+
+// setupColor enables color on stdout and stderr according to mode
+func setupColor(mode string) {
+	stdoutColor = palette{enabled: useColor(mode, os.Stdout)}
+	stderrColor = palette{enabled: useColor(mode, os.Stderr)}
+}
+
+// useColor decides whether to color output written to f
+// In auto mode color is used only on a terminal, and never when NO_COLOR is
+// set (https://no-color.org) or TERM is "dumb"
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p palette) wrap(code, text string) string {
+	if !p.enabled {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+func (p palette) bold(text string) string   { return p.wrap("1", text) }
+func (p palette) dim(text string) string    { return p.wrap("2", text) }
+func (p palette) red(text string) string    { return p.wrap("31", text) }
+func (p palette) green(text string) string  { return p.wrap("32", text) }
+func (p palette) yellow(text string) string { return p.wrap("33", text) }
+func (p palette) cyan(text string) string   { return p.wrap("36", text) }
+
+// printError writes an error message to stderr
+func printError(format string, args ...any) {
+	fmt.Fprintln(os.Stderr, stderrColor.red(fmt.Sprintf(format, args...)))
+}
+
+// location renders a file:line:col: prefix for messages
+func location(p palette, name string, line, column int) string {
+	return p.bold(fmt.Sprintf("%s:%d:%d:", name, line, column))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/linediff"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is a single line of a line-based diff
+type diffOp struct {
+	kind byte // ' ' unchanged, '-' removed, '+' added
+	line string
+}
+
+// diffLines returns the edit script turning a into b line by line
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	x := 0
+	for _, hunk := range linediff.Diff(a, b) {
+		for _, line := range a[x:hunk.AStart] {
+			ops = append(ops, diffOp{kind: ' ', line: line})
+		}
+		for _, line := range a[hunk.AStart:hunk.AEnd] {
+			ops = append(ops, diffOp{kind: '-', line: line})
+		}
+		for _, line := range b[hunk.BStart:hunk.BEnd] {
+			ops = append(ops, diffOp{kind: '+', line: line})
+		}
+		x = hunk.AEnd
+	}
+	for _, line := range a[x:] {
+		ops = append(ops, diffOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// unifiedDiff renders the changes from original to formatted as a unified diff,
+// or returns "" when they are identical
+func unifiedDiff(name string, original, formatted []byte, colors palette) string {
+	if string(original) == string(formatted) {
+		return ""
+	}
+
+	ops := diffLines(splitLines(string(original)), splitLines(string(formatted)))
+
+	var b strings.Builder
+	b.WriteString(colors.bold("--- "+name+" (original)") + "\n")
+	b.WriteString(colors.bold("+++ "+name+" (formatted)") + "\n")
+
+	// Walk the script, emitting a hunk for each run of changes plus context
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		end := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(end+diffContext, len(ops))
+
+		// Line numbers are 1-based positions of the hunk in each file
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		b.WriteString(colors.cyan(fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldLine, oldCount, newLine, newCount)) + "\n")
+		for _, op := range ops[hunkStart:hunkEnd] {
+			line := string(op.kind) + op.line
+			switch op.kind {
+			case '-':
+				line = colors.red(line)
+			case '+':
+				line = colors.green(line)
+			}
+			b.WriteString(line + "\n")
+		}
+
+		start = hunkEnd
+	}
+
+	return b.String()
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,481 @@
+// Package config loads and validates .config-formatter.yaml project files
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the config file names looked up, in order of preference
+var FileNames = []string{".config-formatter.yaml", ".config-formatter.yml"}
+
+// Settings holds configurable option values; nil fields are unset
+type Settings struct {
+	Preset            *string
+	Type              *string
+	Indent            *int
+	QuoteStyle        *string
+	CollapseLists     *bool
+	BuildForm         *string
+	SortScrapeConfigs *bool
+	SortSections      *bool
+	KeepOrder         *[]string
+	SortKeys          *bool
+	Normalize         *bool
+	BlankLines        *string
+	DocumentMarkers   *string
+	ExpandTabs        *bool
+	AlignComments     *bool
+	BindMountAllow    *[]string
+	LintSeverity      *map[string]string
+	HealthcheckPolicy *[]formatter.HealthcheckPolicy
+	RequireLimits     *bool
+	Jobs              *int
+	Color             *string
+	Offline           *bool
+}
+
+// Merge overlays the options set in other onto s
+func (s *Settings) Merge(other Settings) {
+	for _, opt := range options {
+		opt.merge(s, other)
+	}
+}
+
+// Apply copies the set options into formatter options
+// Jobs, Color and Offline only affect the command line and are not formatter
+// options
+func (s Settings) Apply(opts *formatter.Options) {
+	if s.Indent != nil {
+		opts.Indent = *s.Indent
+	}
+	if s.QuoteStyle != nil {
+		opts.QuoteStyle = formatter.QuoteStyle(*s.QuoteStyle)
+	}
+	if s.CollapseLists != nil {
+		opts.CollapseSingleItemLists = *s.CollapseLists
+	}
+	if s.BuildForm != nil {
+		opts.BuildForm = formatter.BuildForm(*s.BuildForm)
+	}
+	if s.SortScrapeConfigs != nil {
+		opts.SortScrapeConfigs = *s.SortScrapeConfigs
+	}
+	if s.SortSections != nil {
+		opts.SortSections = *s.SortSections
+	}
+	if s.KeepOrder != nil {
+		opts.KeepOrder = *s.KeepOrder
+	}
+	if s.SortKeys != nil {
+		opts.PreserveKeyOrder = !*s.SortKeys
+	}
+	if s.Normalize != nil {
+		opts.PreserveValues = !*s.Normalize
+	}
+	if s.BlankLines != nil {
+		opts.BlankLines = formatter.BlankLinePolicy(*s.BlankLines)
+	}
+	if s.DocumentMarkers != nil {
+		opts.DocumentMarkers = formatter.DocumentMarkerPolicy(*s.DocumentMarkers)
+	}
+	if s.ExpandTabs != nil {
+		opts.ExpandTabs = *s.ExpandTabs
+	}
+	if s.AlignComments != nil {
+		opts.AlignComments = *s.AlignComments
+	}
+	if s.BindMountAllow != nil {
+		opts.BindMountAllowlist = *s.BindMountAllow
+	}
+	if s.RequireLimits != nil {
+		opts.RequireResourceLimits = *s.RequireLimits
+	}
+	if s.HealthcheckPolicy != nil {
+		opts.HealthcheckPolicy = *s.HealthcheckPolicy
+	}
+	if s.LintSeverity != nil {
+		opts.LintSeverity = make(map[string]formatter.Severity, len(*s.LintSeverity))
+		for rule, severity := range *s.LintSeverity {
+			opts.LintSeverity[rule] = formatter.Severity(severity)
+		}
+	}
+}
+
+// Defaults returns the built-in settings, mirroring formatter.DefaultOptions
+func Defaults() Settings {
+	opts := formatter.DefaultOptions()
+	quoteStyle := string(opts.QuoteStyle)
+	buildForm := string(opts.BuildForm)
+	sortKeys := !opts.PreserveKeyOrder
+	normalize := !opts.PreserveValues
+	blankLines := string(opts.BlankLines)
+	documentMarkers := string(opts.DocumentMarkers)
+	keepOrder := []string{}
+	bindMountAllow := []string{}
+	lintSeverity := map[string]string{}
+	healthcheckPolicy := []formatter.HealthcheckPolicy{}
+	jobs := 0
+	color := "auto"
+	offline := false
+	return Settings{
+		Indent:            &opts.Indent,
+		QuoteStyle:        &quoteStyle,
+		CollapseLists:     &opts.CollapseSingleItemLists,
+		BuildForm:         &buildForm,
+		SortScrapeConfigs: &opts.SortScrapeConfigs,
+		SortSections:      &opts.SortSections,
+		KeepOrder:         &keepOrder,
+		SortKeys:          &sortKeys,
+		Normalize:         &normalize,
+		BlankLines:        &blankLines,
+		DocumentMarkers:   &documentMarkers,
+		ExpandTabs:        &opts.ExpandTabs,
+		AlignComments:     &opts.AlignComments,
+		BindMountAllow:    &bindMountAllow,
+		LintSeverity:      &lintSeverity,
+		HealthcheckPolicy: &healthcheckPolicy,
+		RequireLimits:     &opts.RequireResourceLimits,
+		Jobs:              &jobs,
+		Color:             &color,
+		Offline:           &offline,
+	}
+}
+
+// Override applies settings to files matching any of its glob patterns
+type Override struct {
+	Files    []string
+	Settings Settings
+
+	// Line is where the override starts in the config file
+	Line int
+}
+
+// Config is a parsed config file
+type Config struct {
+	// Path is the config file location and Dir its directory, which override
+	// patterns are relative to
+	Path string
+	Dir  string
+
+	Settings  Settings
+	Overrides []Override
+}
+
+// Problem is a single validation failure in a config file
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", e.Path, p.Line, p.Column, p.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Find looks for a config file in dir and its parents, returning "" if none exists
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		for _, name := range FileNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and validates a config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse validates config data read from path
+// Every problem is reported at once in a *ValidationError
+func Parse(path string, data []byte) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Path: path, Dir: filepath.Dir(abs)}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// An empty file is a valid config with nothing set
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return cfg, nil
+	}
+
+	var problems []Problem
+	report := func(node *yaml.Node, format string, args ...any) {
+		problems = append(problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		report(doc, "config must be a mapping of option names to values")
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		if key.Value == "overrides" {
+			cfg.Overrides = parseOverrides(value, report)
+			continue
+		}
+		decodeOption(&cfg.Settings, key, value, report)
+	}
+
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool {
+			if problems[i].Line != problems[j].Line {
+				return problems[i].Line < problems[j].Line
+			}
+			return problems[i].Column < problems[j].Column
+		})
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+	return cfg, nil
+}
+
+// decodeOption decodes a single "name: value" pair into settings
+func decodeOption(s *Settings, key, value *yaml.Node, report func(*yaml.Node, string, ...any)) {
+	opt, ok := lookupOption(key.Value)
+	if !ok {
+		report(key, "unknown option %q", key.Value)
+		return
+	}
+	if err := opt.decode(s, value); err != nil {
+		report(value, "option %q %v", key.Value, err)
+	}
+}
+
+// parseOverrides decodes the overrides list
+func parseOverrides(node *yaml.Node, report func(*yaml.Node, string, ...any)) []Override {
+	if node.Kind != yaml.SequenceNode {
+		report(node, "overrides must be a list")
+		return nil
+	}
+
+	var overrides []Override
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			report(item, "override must be a mapping with a files key")
+			continue
+		}
+
+		override := Override{Line: item.Line}
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key, value := item.Content[i], item.Content[i+1]
+			if key.Value == "files" {
+				override.Files = parseGlobs(value, report)
+				continue
+			}
+			decodeOption(&override.Settings, key, value, report)
+		}
+
+		if override.Files == nil && formatter.MappingValue(item, "files") == nil {
+			report(item, "override is missing the files key")
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides
+}
+
+// parseGlobs decodes a glob pattern or list of patterns
+func parseGlobs(node *yaml.Node, report func(*yaml.Node, string, ...any)) []string {
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+
+	var globs []string
+	for _, item := range items {
+		if item.Kind != yaml.ScalarNode || item.Tag != "!!str" {
+			report(item, "files must be a glob pattern or a list of glob patterns")
+			continue
+		}
+		if err := validateGlob(item.Value); err != nil {
+			report(item, "bad glob pattern %q: %v", item.Value, err)
+			continue
+		}
+		globs = append(globs, item.Value)
+	}
+	return globs
+}
+
+// Matches reports whether file is matched by the override
+func (o Override) Matches(dir, file string) bool {
+	rel, ok := relativeTo(dir, file)
+	if !ok {
+		return false
+	}
+	for _, pattern := range o.Files {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// relativeTo returns file relative to dir with forward slashes, ok is false
+// when file lies outside dir
+func relativeTo(dir, file string) (string, bool) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Resolve returns the settings that apply to file: the top-level settings with
+// every matching override applied in order
+func (c *Config) Resolve(file string) Settings {
+	settings := c.Settings
+	for _, override := range c.Overrides {
+		if override.Matches(c.Dir, file) {
+			settings.Merge(override.Settings)
+		}
+	}
+	return settings
+}
+
+// EffectiveValue is one option in the effective settings report
+type EffectiveValue struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Layer is a named set of settings used to build the effective settings
+type Layer struct {
+	Source   string
+	Settings Settings
+}
+
+// Layers returns the config layers that apply to file, lowest precedence first:
+// the top-level settings followed by each matching override
+func (c *Config) Layers(file string) []Layer {
+	layers := []Layer{{Source: c.Path, Settings: c.Settings}}
+	for _, override := range c.Overrides {
+		if override.Matches(c.Dir, file) {
+			layers = append(layers, Layer{
+				Source:   fmt.Sprintf("%s:%d (override %s)", c.Path, override.Line, strings.Join(override.Files, ", ")),
+				Settings: override.Settings,
+			})
+		}
+	}
+	return layers
+}
+
+// Effective merges layers, lowest precedence first, and reports every option
+// with its final value and the layer it came from
+func Effective(layers []Layer) []EffectiveValue {
+	var values []EffectiveValue
+	for _, opt := range options {
+		value := EffectiveValue{Name: opt.name, Value: "(unset)", Source: "default"}
+		for _, layer := range layers {
+			if v, ok := opt.format(layer.Settings); ok {
+				value.Value = v
+				value.Source = layer.Source
+			}
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// Values returns the options set in s by name, written as in the effective
+// settings report
+func (s Settings) Values() map[string]string {
+	values := make(map[string]string)
+	for _, opt := range options {
+		if v, ok := opt.format(s); ok {
+			values[opt.name] = v
+		}
+	}
+	return values
+}
+
+// Schema returns the JSON Schema describing the config file
+func Schema() ([]byte, error) {
+	properties := make(map[string]any)
+	for _, opt := range options {
+		property := map[string]any{"description": opt.description}
+		for k, v := range opt.schema {
+			property[k] = v
+		}
+		properties[opt.name] = property
+	}
+
+	overrideProperties := make(map[string]any, len(properties)+1)
+	for k, v := range properties {
+		overrideProperties[k] = v
+	}
+	overrideProperties["files"] = map[string]any{
+		"description": "Glob pattern(s) relative to the config file; ** matches any number of directories",
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 1},
+		},
+	}
+
+	properties["overrides"] = map[string]any{
+		"description": "Settings applied to files matching glob patterns, in order",
+		"type":        "array",
+		"items": map[string]any{
+			"type":                 "object",
+			"required":             []string{"files"},
+			"additionalProperties": false,
+			"properties":           overrideProperties,
+		},
+	}
+
+	schema := map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "config-formatter configuration",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
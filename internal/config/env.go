@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variable of every option
+// The variable name is the option name in upper case, e.g. CONFIG_FORMATTER_INDENT
+const EnvPrefix = "CONFIG_FORMATTER_"
+
+// EnvSource names the environment layer in the effective settings report
+const EnvSource = "environment"
+
+// EnvName returns the environment variable for an option name
+func EnvName(option string) string {
+	return EnvPrefix + strings.ToUpper(option)
+}
+
+// FromEnv reads settings from CONFIG_FORMATTER_* environment variables
+// Values are parsed like YAML scalars, so "4", "true" and "single" all work
+func FromEnv() (Settings, error) {
+	return fromLookup(os.LookupEnv)
+}
+
+func fromLookup(lookup func(string) (string, bool)) (Settings, error) {
+	var settings Settings
+	var problems []string
+
+	for _, opt := range options {
+		name := EnvName(opt.name)
+		raw, ok := lookup(name)
+		if !ok || raw == "" {
+			continue
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(raw), &doc); err != nil || len(doc.Content) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: cannot parse value %q", name, raw))
+			continue
+		}
+		if err := opt.decode(&settings, doc.Content[0]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %v", name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return Settings{}, fmt.Errorf("invalid environment configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return settings, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// validateGlob checks that a glob pattern is well-formed
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return path.ErrBadPattern
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether name matches pattern
+// Both use forward slashes. Segments are matched with path.Match, and a "**"
+// segment matches any number of directories, including none. A pattern without
+// a slash matches the base name at any depth, like .gitignore.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of directories for "**"
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// option describes one configurable setting
+// The options table is the single source for decoding, validation, the JSON
+// schema and the effective settings report, so adding a setting means adding
+// a field to Settings and an entry to options
+type option struct {
+	name        string
+	description string
+
+	// schema is the JSON Schema fragment describing the value
+	schema map[string]any
+
+	// decode parses a YAML value into the settings
+	decode func(s *Settings, node *yaml.Node) error
+
+	// format renders the value, ok is false when it is unset
+	format func(s Settings) (value string, ok bool)
+
+	// merge copies the value from src to dst if it is set in src
+	merge func(dst *Settings, src Settings)
+}
+
+// options lists every setting accepted in the config file and in overrides
+var options = []option{
+	stringOption("preset", "Named bundle of settings to start from; other settings override its values", PresetNames(),
+		func(s *Settings) **string { return &s.Preset }),
+	stringOption("type", "Formatter type to use instead of auto-detection", nil,
+		func(s *Settings) **string { return &s.Type }),
+	intOption("indent", "Number of spaces for indentation", 1,
+		func(s *Settings) **int { return &s.Indent }),
+	stringOption("quote_style", "Quotes used when a normalizer has to quote a value", []string{"double", "single"},
+		func(s *Settings) **string { return &s.QuoteStyle }),
+	boolOption("collapse_lists", "Write single-item lists as a plain value where the field allows either form",
+		func(s *Settings) **bool { return &s.CollapseLists }),
+	stringOption("build_form", "How compose build sections are written: as they are, always as a mapping with a context, or as the context path when it is the only key", []string{"keep", "long", "short"},
+		func(s *Settings) **string { return &s.BuildForm }),
+	boolOption("sort_scrape_configs", "Order the Prometheus scrape_configs list by job_name",
+		func(s *Settings) **bool { return &s.SortScrapeConfigs }),
+	boolOption("sort_sections", "Order the sections of INI files, the tables of TOML files, SSH Host blocks and WireGuard peers by name",
+		func(s *Settings) **bool { return &s.SortSections }),
+	listOption("keep_order", "Key paths whose children are never reordered, e.g. services.*.command; * matches one key, ** any number",
+		func(s *Settings) **[]string { return &s.KeepOrder }),
+	boolOption("sort_keys", "Order mapping keys by the formatter's conventions; false keeps the original order",
+		func(s *Settings) **bool { return &s.SortKeys }),
+	boolOption("normalize", "Rewrite values into canonical form (environment lists, ports, durations, ...)",
+		func(s *Settings) **bool { return &s.Normalize }),
+	stringOption("blank_lines", "Where blank lines go: between top-level sections, nowhere, or where the input had them", []string{"sections", "none", "preserve"},
+		func(s *Settings) **string { return &s.BlankLines }),
+	stringOption("document_markers", "Where YAML documents get \"---\" and \"...\" markers: only between documents, always, or where the input had them", []string{"never", "always", "preserve"},
+		func(s *Settings) **string { return &s.DocumentMarkers }),
+	boolOption("expand_tabs", "Convert the tabs indenting YAML lines to spaces before parsing, instead of reporting them",
+		func(s *Settings) **bool { return &s.ExpandTabs }),
+	boolOption("align_comments", "Line up inline comments of consecutive lines in a block on a common column",
+		func(s *Settings) **bool { return &s.AlignComments }),
+	listOption("bind_mount_allowlist", "Host paths, with everything below them, that lint accepts as writable compose bind mounts",
+		func(s *Settings) **[]string { return &s.BindMountAllow }),
+	healthcheckPolicyOption("healthcheck_policy", "Compose images whose services need a healthcheck, each with the healthcheck formatting inserts into services without one",
+		func(s *Settings) **[]formatter.HealthcheckPolicy { return &s.HealthcheckPolicy }),
+	boolOption("require_resource_limits", "Make lint require memory and CPU limits of every compose service; usually set in an override for production files",
+		func(s *Settings) **bool { return &s.RequireLimits }),
+	mapOption("lint_severity", "Severity of lint rules by rule ID, e.g. compose/privileged: error", []string{"error", "warning", "off"},
+		func(s *Settings) **map[string]string { return &s.LintSeverity }),
+	intOption("jobs", "Number of files formatted at once when formatting a directory; 0 uses one per CPU", 0,
+		func(s *Settings) **int { return &s.Jobs }),
+	stringOption("color", "When to color diffs, summaries and error locations; auto colors terminals unless NO_COLOR is set", []string{"auto", "always", "never"},
+		func(s *Settings) **string { return &s.Color }),
+	boolOption("offline", "Refuse all network access; formatting, linting and validation never need it",
+		func(s *Settings) **bool { return &s.Offline }),
+}
+
+// lookupOption returns the option with the given name
+func lookupOption(name string) (option, bool) {
+	for _, opt := range options {
+		if opt.name == name {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+// intOption defines an integer setting with a lower bound
+func intOption(name, description string, minimum int, field func(*Settings) **int) option {
+	return option{
+		name:        name,
+		description: description,
+		schema:      map[string]any{"type": "integer", "minimum": minimum},
+		decode: func(s *Settings, node *yaml.Node) error {
+			if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+				return fmt.Errorf("must be an integer")
+			}
+			value, err := strconv.Atoi(node.Value)
+			if err != nil {
+				return fmt.Errorf("must be an integer")
+			}
+			if value < minimum {
+				return fmt.Errorf("must be at least %d", minimum)
+			}
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			return strconv.Itoa(*value), true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
+
+// boolOption defines a boolean setting
+func boolOption(name, description string, field func(*Settings) **bool) option {
+	return option{
+		name:        name,
+		description: description,
+		schema:      map[string]any{"type": "boolean"},
+		decode: func(s *Settings, node *yaml.Node) error {
+			if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+				return fmt.Errorf("must be true or false")
+			}
+			value := strings.EqualFold(node.Value, "true")
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			return strconv.FormatBool(*value), true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
+
+// stringOption defines a string setting, optionally restricted to enum values
+func stringOption(name, description string, enum []string, field func(*Settings) **string) option {
+	schema := map[string]any{"type": "string"}
+	if len(enum) > 0 {
+		schema["enum"] = enum
+	}
+
+	return option{
+		name:        name,
+		description: description,
+		schema:      schema,
+		decode: func(s *Settings, node *yaml.Node) error {
+			if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+				return fmt.Errorf("must be a string")
+			}
+			if len(enum) > 0 && !slices.Contains(enum, node.Value) {
+				return fmt.Errorf("must be one of %s", strings.Join(enum, ", "))
+			}
+			value := node.Value
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			return *value, true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
+
+// listOption defines a setting holding a list of strings; a single string is
+// taken as a list of one
+func listOption(name, description string, field func(*Settings) **[]string) option {
+	return option{
+		name:        name,
+		description: description,
+		schema: map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}},
+		decode: func(s *Settings, node *yaml.Node) error {
+			items := []*yaml.Node{node}
+			if node.Kind == yaml.SequenceNode {
+				items = node.Content
+			}
+			value := []string{}
+			for _, item := range items {
+				if item.Kind != yaml.ScalarNode || item.Tag != "!!str" || item.Value == "" {
+					return fmt.Errorf("must be a string or a list of strings")
+				}
+				value = append(value, item.Value)
+			}
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			return strings.Join(*value, ", "), true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
+
+// mapOption defines a setting mapping names to strings, restricted to enum
+// values when given
+func mapOption(name, description string, enum []string, field func(*Settings) **map[string]string) option {
+	values := map[string]any{"type": "string"}
+	if len(enum) > 0 {
+		values["enum"] = enum
+	}
+
+	return option{
+		name:        name,
+		description: description,
+		schema:      map[string]any{"type": "object", "additionalProperties": values},
+		decode: func(s *Settings, node *yaml.Node) error {
+			if node.Kind != yaml.MappingNode {
+				return fmt.Errorf("must be a mapping")
+			}
+			value := map[string]string{}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, item := node.Content[i], node.Content[i+1]
+				if item.Kind != yaml.ScalarNode || item.Tag != "!!str" {
+					return fmt.Errorf("%s must be a string", key.Value)
+				}
+				if len(enum) > 0 && !slices.Contains(enum, item.Value) {
+					return fmt.Errorf("%s must be one of %s", key.Value, strings.Join(enum, ", "))
+				}
+				value[key.Value] = item.Value
+			}
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			entries := make([]string, 0, len(*value))
+			for key, item := range *value {
+				entries = append(entries, key+"="+item)
+			}
+			slices.Sort(entries)
+			return strings.Join(entries, ", "), true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
+
+// healthcheckPolicyOption defines a setting holding a list of healthcheck
+// policies, each a mapping with an image pattern and an optional healthcheck
+func healthcheckPolicyOption(name, description string, field func(*Settings) **[]formatter.HealthcheckPolicy) option {
+	return option{
+		name:        name,
+		description: description,
+		schema: map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"required":             []string{"image"},
+				"additionalProperties": false,
+				"properties": map[string]any{
+					"image":       map[string]any{"type": "string", "description": "Image pattern, e.g. postgres or ghcr.io/acme/*"},
+					"healthcheck": map[string]any{"type": "object", "description": "Healthcheck inserted into matching services without one"},
+				},
+			},
+		},
+		decode: func(s *Settings, node *yaml.Node) error {
+			if node.Kind != yaml.SequenceNode {
+				return fmt.Errorf("must be a list of mappings with an image")
+			}
+			value := []formatter.HealthcheckPolicy{}
+			for _, item := range node.Content {
+				image := formatter.MappingValue(item, "image")
+				if item.Kind != yaml.MappingNode || image == nil || image.Kind != yaml.ScalarNode || image.Value == "" {
+					return fmt.Errorf("must be a list of mappings with an image")
+				}
+				if _, err := path.Match(image.Value, ""); err != nil {
+					return fmt.Errorf("has a bad image pattern %q: %v", image.Value, err)
+				}
+				policy := formatter.HealthcheckPolicy{Image: image.Value}
+				for i := 0; i+1 < len(item.Content); i += 2 {
+					switch key, value := item.Content[i].Value, item.Content[i+1]; key {
+					case "image":
+					case "healthcheck":
+						if value.Kind != yaml.MappingNode {
+							return fmt.Errorf("healthcheck of %s must be a mapping", image.Value)
+						}
+						policy.Healthcheck = value
+					default:
+						return fmt.Errorf("has an unknown key %q", key)
+					}
+				}
+				value = append(value, policy)
+			}
+			*field(s) = &value
+			return nil
+		},
+		format: func(s Settings) (string, bool) {
+			value := *field(&s)
+			if value == nil {
+				return "", false
+			}
+			images := make([]string, len(*value))
+			for i, policy := range *value {
+				images[i] = policy.Image
+				if policy.Healthcheck != nil {
+					images[i] += " (with healthcheck)"
+				}
+			}
+			return strings.Join(images, ", "), true
+		},
+		merge: func(dst *Settings, src Settings) {
+			if value := *field(&src); value != nil {
+				*field(dst) = value
+			}
+		},
+	}
+}
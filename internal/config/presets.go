@@ -0,0 +1,79 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// presetFiles holds the presets, one config file per preset named after it
+// They are embedded so the binary needs nothing beside it
+//
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// presets are named bundles of settings selected with the preset option
+// A preset sits just above the defaults, so the config file, environment and
+// flags can still override any of its values
+var presets = map[string]Settings{}
+
+func init() {
+	for _, name := range PresetNames() {
+		path := "presets/" + name + ".yaml"
+		data, err := presetFiles.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		cfg, err := Parse(path, data)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in preset: %v", err))
+		}
+		if cfg.Settings.Preset != nil || len(cfg.Overrides) > 0 {
+			panic(fmt.Sprintf("invalid built-in preset %s: presets only set options", name))
+		}
+		presets[name] = cfg.Settings
+	}
+}
+
+// PresetNames returns the names of the available presets, sorted
+func PresetNames() []string {
+	entries, err := presetFiles.ReadDir("presets")
+	if err != nil {
+		panic(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merged merges layers, lowest precedence first, into a single set of settings
+func Merged(layers []Layer) Settings {
+	var settings Settings
+	for _, layer := range layers {
+		settings.Merge(layer.Settings)
+	}
+	return settings
+}
+
+// WithPreset inserts the preset selected by the layers, if any, directly above
+// the first layer, which holds the defaults. The preset is chosen by the
+// highest layer that sets one.
+func WithPreset(layers []Layer) ([]Layer, error) {
+	name := Merged(layers).Preset
+	if name == nil || *name == "" || len(layers) == 0 {
+		return layers, nil
+	}
+
+	preset, ok := presets[*name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (available: %v)", *name, PresetNames())
+	}
+
+	result := make([]Layer, 0, len(layers)+1)
+	result = append(result, layers[0], Layer{Source: "preset " + *name, Settings: preset})
+	return append(result, layers[1:]...), nil
+}
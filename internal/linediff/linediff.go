@@ -0,0 +1,95 @@
+// Package linediff finds the lines that differ between two texts, for the
+// unified diffs of the command line and the text edits of the library
+package linediff
+
+import "slices"
+
+// MaxDistance bounds the work of Diff; texts differing in more lines are
+// replaced in one hunk
+const MaxDistance = 2000
+
+// Hunk replaces the lines AStart to AEnd of one text with the lines BStart to
+// BEnd of another
+type Hunk struct {
+	AStart, AEnd, BStart, BEnd int
+}
+
+// Diff returns the hunks turning the lines of a into those of b, by the Myers
+// shortest edit script; past MaxDistance a single hunk replaces everything.
+// It keeps O((n+m)+D²) memory for D changed lines.
+func Diff[Line ~string | ~[]byte](a, b []Line) []Hunk {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	offset := n + m
+	v := make([]int, 2*offset+2)
+
+	// trace holds, for each d, v before round d, from k = -d to d
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && d <= MaxDistance && !found; d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && string(a[x]) == string(b[y]) {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return []Hunk{{0, n, 0, m}}
+	}
+
+	// Walk the trace back, collecting the lines that are kept
+	type match struct{ x, y int }
+	var matches []match
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v(k-1) < v(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			matches = append(matches, match{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		matches = append(matches, match{x, y})
+	}
+
+	// Between consecutive kept lines is a hunk
+	var hunks []Hunk
+	ax, by := 0, 0
+	for i := len(matches) - 1; i >= -1; i-- {
+		next := match{n, m}
+		if i >= 0 {
+			next = matches[i]
+		}
+		if next.x > ax || next.y > by {
+			hunks = append(hunks, Hunk{ax, next.x, by, next.y})
+		}
+		ax, by = next.x+1, next.y+1
+	}
+	return hunks
+}
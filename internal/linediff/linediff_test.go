@@ -0,0 +1,73 @@
+package linediff
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// apply replaces the hunks of a with the lines of b they stand for
+func apply(a, b []string, hunks []Hunk) []string {
+	var out []string
+	x := 0
+	for _, hunk := range hunks {
+		out = append(out, a[x:hunk.AStart]...)
+		out = append(out, b[hunk.BStart:hunk.BEnd]...)
+		x = hunk.AEnd
+	}
+	return append(out, a[x:]...)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		// changed is the number of lines the hunks remove and add
+		changed int
+	}{
+		{"equal", "a b c", "a b c", 0},
+		{"empty", "", "", 0},
+		{"insert", "a c", "a b c", 1},
+		{"delete", "a b c", "a c", 1},
+		{"replace", "a b c", "a x c", 2},
+		{"from empty", "", "a b", 2},
+		{"to empty", "a b", "", 2},
+		{"reorder", "a b c d", "b a d c", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			hunks := Diff(a, b)
+			if got := apply(a, b, hunks); !slices.Equal(got, b) {
+				t.Fatalf("applying %v to %q gives %q, want %q", hunks, a, got, b)
+			}
+			changed := 0
+			for _, hunk := range hunks {
+				changed += hunk.AEnd - hunk.AStart + hunk.BEnd - hunk.BStart
+			}
+			if changed != tt.changed {
+				t.Errorf("hunks %v change %d lines, want %d", hunks, changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestDiffBytes(t *testing.T) {
+	a := [][]byte{[]byte("a\n"), []byte("b\n")}
+	b := [][]byte{[]byte("a\n"), []byte("c\n")}
+	if got, want := Diff(a, b), []Hunk{{1, 2, 1, 2}}; !slices.Equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffMaxDistance(t *testing.T) {
+	var a, b []string
+	for i := range MaxDistance {
+		a = append(a, "a"+strconv.Itoa(i))
+		b = append(b, "b"+strconv.Itoa(i))
+	}
+	if got, want := Diff(a, b), []Hunk{{0, len(a), 0, len(b)}}; !slices.Equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package lockedfile
+
+import "os"
+
+// lock does nothing on systems without flock or LockFileEx; writes are not
+// serialized there
+func lock(f *os.File) error {
+	return nil
+}
+
+// unlock does nothing
+func unlock(f *os.File) error {
+	return nil
+}
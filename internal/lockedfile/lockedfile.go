@@ -0,0 +1,122 @@
+// Package lockedfile replaces files under an advisory lock, so that
+// config-formatter processes writing the same file at once, such as an
+// editor's format on save, a pre-commit hook and a file watcher, take turns
+// instead of interleaving their writes
+//
+// The lock is held on a sidecar file next to the one written, named after it
+// with a leading dot and a .lock extension, which is left in place: a lock
+// file that was removed and created again could be held by two processes at
+// once. The new contents are written to a temporary file in the same
+// directory, synced and renamed over the file, so a crash leaves either the
+// old file or the new one, never part of either.
+package lockedfile
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrChanged reports that a file no longer holds what was read from it, so
+// writing over it would lose the change
+var ErrChanged = errors.New("file changed while it was being formatted")
+
+// WriteFile writes data to the file name, creating it with perm if needed,
+// while holding an exclusive lock on it
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	return write(name, perm, func([]byte) (bool, error) { return true, nil }, data)
+}
+
+// Replace writes data over the file name, read as old, while holding an
+// exclusive lock on it. A file that holds data already is left alone, and one
+// that holds anything but old, because another process wrote it since, is
+// left alone with ErrChanged.
+func Replace(name string, old, data []byte) error {
+	return write(name, 0644, func(current []byte) (bool, error) {
+		switch {
+		case bytes.Equal(current, data):
+			return false, nil
+		case !bytes.Equal(current, old):
+			return false, ErrChanged
+		}
+		return true, nil
+	}, data)
+}
+
+// lockName returns the name of the sidecar file locked while name is written
+func lockName(name string) string {
+	return filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".lock")
+}
+
+// write locks the file name, asks check whether to write over what it holds,
+// and replaces it with data; the lock is released once the file is in place
+func write(name string, perm os.FileMode, check func(current []byte) (bool, error), data []byte) (err error) {
+	// A symlink is replaced through, not by a regular file
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+
+	l, err := os.OpenFile(lockName(name), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := l.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if err := lock(l); err != nil {
+		return err
+	}
+	defer unlock(l)
+
+	current, err := os.ReadFile(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		current = nil
+	case err != nil:
+		return err
+	default:
+		// The file keeps its permissions
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		perm = info.Mode().Perm()
+	}
+	if ok, err := check(current); !ok || err != nil {
+		return err
+	}
+	return replace(name, data, perm)
+}
+
+// replace writes data to a temporary file next to name, syncs it to disk and
+// renames it over name
+func replace(name string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,106 @@
+package lockedfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestReplaceConcurrent races several Replace calls from the same old
+// contents: one writes its data whole and the others find the file changed
+func TestReplaceConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "compose.yaml")
+	old := []byte("services: {}\n")
+	if err := os.WriteFile(name, old, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 16
+	datas := make([][]byte, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		datas[i] = bytes.Repeat([]byte(fmt.Sprintf("writer%d: x\n", i)), 1000)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = Replace(name, old, datas[i])
+		}()
+	}
+	wg.Wait()
+
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := -1
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if written >= 0 {
+				t.Errorf("writers %d and %d both replaced the file", written, i)
+			}
+			written = i
+		case !errors.Is(err, ErrChanged):
+			t.Errorf("writer %d: %v, want ErrChanged", i, err)
+		}
+	}
+	if written < 0 {
+		t.Fatal("no writer replaced the file")
+	}
+	if !bytes.Equal(got, datas[written]) {
+		t.Errorf("file holds %d bytes, want the %d bytes of writer %d", len(got), len(datas[written]), written)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode = %v, want it kept at 0600", perm)
+	}
+
+	// Only the file and its lock are left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 2 {
+		t.Errorf("directory holds %q, want the file and its lock", names)
+	}
+}
+
+func TestReplaceChanged(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "traefik.yml")
+	old := []byte("entryPoints: {}\n")
+	edited := []byte("entryPoints: {}\nproviders: {}\n")
+	if err := os.WriteFile(name, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Replace(name, old, []byte("entryPoints: {}\n# formatted\n"))
+	if !errors.Is(err, ErrChanged) {
+		t.Fatalf("Replace() = %v, want ErrChanged", err)
+	}
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, edited) {
+		t.Errorf("file = %q, want the edit %q kept", got, edited)
+	}
+
+	// A file that holds the data already is no change
+	if err := Replace(name, old, edited); err != nil {
+		t.Errorf("Replace() with the current contents = %v, want nil", err)
+	}
+}
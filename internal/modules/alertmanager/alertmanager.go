@@ -0,0 +1,295 @@
+package alertmanager
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// AlertmanagerFormatter formats Prometheus Alertmanager configuration files
+type AlertmanagerFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new AlertmanagerFormatter
+// Top-level sections are separated by blank lines
+func New() *AlertmanagerFormatter {
+	return &AlertmanagerFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+			// Child routes are matched in order, the first match wins
+			OrderSensitive: []string{"routes"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *AlertmanagerFormatter) Name() string {
+	return "alertmanager"
+}
+
+// CanHandle checks if this file is an Alertmanager configuration file
+func (f *AlertmanagerFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := filepath.Base(filename)
+	if base == "alertmanager.yml" || base == "alertmanager.yaml" {
+		return true
+	}
+
+	// Check for Alertmanager specific keys
+	if !formatter.MayHaveTopLevelKey(data, "route") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// A routing tree and the receivers it sends to are both required
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		return formatter.MappingValue(content, "route") != nil && formatter.MappingValue(content, "receivers") != nil
+	}
+
+	return false
+}
+
+// Format formats an Alertmanager YAML file with consistent indentation and ordering
+func (f *AlertmanagerFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *AlertmanagerFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports repeated items in the lists whose order is significant
+func (f *AlertmanagerFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *AlertmanagerFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *AlertmanagerFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Alertmanager conventions
+// Label matchers and other user-defined mappings keep their order
+func (f *AlertmanagerFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	order := keyOrderFor(path, isTopLevel)
+	if order == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// keyOrderFor returns the ranking function for the mapping at path, or nil if
+// the mapping keeps its original order
+func keyOrderFor(path []string, isTopLevel bool) func(key string) int {
+	if isTopLevel {
+		return rank(topLevelOrder)
+	}
+
+	var table map[string]int
+	switch {
+	case len(path) == 1 && path[0] == "global":
+		return func(key string) int {
+			if order, ok := globalOrder[key]; ok {
+				return order
+			}
+			return 10
+		}
+	case isRoute(path):
+		table = routeOrder
+	case len(path) == 2 && path[0] == "inhibit_rules":
+		table = inhibitRuleOrder
+	case len(path) == 2 && path[0] == "receivers":
+		// Integrations come after the name, grouped by kind
+		return func(key string) int {
+			if key == "name" {
+				return 1
+			}
+			return 10
+		}
+	case len(path) == 4 && path[0] == "receivers" && strings.HasSuffix(path[2], "_configs"):
+		table = integrationOrder
+	default:
+		return nil
+	}
+	return rank(table)
+}
+
+// isRoute reports whether path is the routing tree root or one of its child
+// routes (route.routes.0.routes.1 ...)
+func isRoute(path []string) bool {
+	if len(path) == 0 || path[0] != "route" {
+		return false
+	}
+	rest := path[1:]
+	for len(rest) >= 2 && rest[0] == "routes" {
+		rest = rest[2:]
+	}
+	return len(rest) == 0
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// topLevelOrder ranks the top-level sections: defaults, then how alerts are
+// routed and silenced, then where they are sent
+var topLevelOrder = map[string]int{
+	"global":              1,
+	"route":               2,
+	"inhibit_rules":       3,
+	"receivers":           4,
+	"templates":           5,
+	"time_intervals":      6,
+	"mute_time_intervals": 7,
+}
+
+// globalOrder ranks the global defaults; integration defaults (smtp_*,
+// slack_api_url, ...) share a rank and are sorted by name
+var globalOrder = map[string]int{
+	"resolve_timeout": 1,
+	"http_config":     100,
+}
+
+// routeOrder ranks the keys of a route: where alerts go, which alerts match,
+// how they are grouped and timed, then the child routes
+var routeOrder = map[string]int{
+	// Destination
+	"receiver": 1,
+	"continue": 2,
+
+	// Matching
+	"matchers": 10,
+	"match":    11,
+	"match_re": 12,
+
+	// Grouping and timing
+	"group_by":              20,
+	"group_wait":            21,
+	"group_interval":        22,
+	"repeat_interval":       23,
+	"mute_time_intervals":   24,
+	"active_time_intervals": 25,
+
+	// Child routes are last, since they can be long
+	"routes": 100,
+}
+
+// inhibitRuleOrder ranks the keys of an inhibit rule: the alerts that mute,
+// the alerts that are muted, then the labels they must share
+var inhibitRuleOrder = map[string]int{
+	"source_matchers": 1,
+	"source_match":    2,
+	"source_match_re": 3,
+	"target_matchers": 10,
+	"target_match":    11,
+	"target_match_re": 12,
+	"equal":           20,
+}
+
+// integrationOrder ranks the keys shared by receiver integrations (email,
+// slack, webhook, pagerduty, ...): whether resolved alerts are sent, the
+// endpoint and credentials, the recipient, then the message
+var integrationOrder = map[string]int{
+	"send_resolved": 1,
+
+	// Endpoint and credentials
+	"url":            10,
+	"url_file":       10,
+	"api_url":        10,
+	"api_url_file":   10,
+	"webhook_url":    10,
+	"smarthost":      10,
+	"api_key":        11,
+	"api_key_file":   11,
+	"routing_key":    11,
+	"service_key":    11,
+	"auth_username":  11,
+	"auth_password":  12,
+	"auth_identity":  12,
+	"auth_secret":    12,
+	"bot_token":      11,
+	"bot_token_file": 11,
+	"token":          11,
+	"token_file":     11,
+
+	// Recipient
+	"to":       20,
+	"from":     21,
+	"channel":  20,
+	"chat_id":  20,
+	"room_id":  20,
+	"user_key": 20,
+
+	// Message
+	"title":       30,
+	"subject":     30,
+	"text":        31,
+	"message":     31,
+	"description": 31,
+	"html":        32,
+	"headers":     33,
+
+	// Transport
+	"http_config": 100,
+	"tls_config":  101,
+}
@@ -0,0 +1,341 @@
+package argocd
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// ArgoCDFormatter formats Argo CD Application and ApplicationSet manifests
+type ArgoCDFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new ArgoCDFormatter
+func New() *ArgoCDFormatter {
+	return &ArgoCDFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			// Later value files override earlier ones, and generators and
+			// multiple sources are merged in order
+			OrderSensitive: []string{"valueFiles", "spec.generators", "spec.sources"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *ArgoCDFormatter) Name() string {
+	return "argocd"
+}
+
+// kinds are the resources the formatter knows
+var kinds = map[string]bool{
+	"Application":    true,
+	"ApplicationSet": true,
+}
+
+// CanHandle checks if this file holds Argo CD applications
+// Manifests have no telling file name, so only the apiVersion and kind are
+// used; one Argo CD document in a manifest is enough
+func (f *ArgoCDFormatter) CanHandle(filename string, data []byte) bool {
+	// Most manifests are not ours; the API group has to appear somewhere
+	if !bytes.Contains(data, []byte("argoproj.io/")) {
+		return false
+	}
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return false
+	}
+	for _, doc := range docs {
+		if resourceKind(doc) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKind returns the kind of an Argo CD resource the formatter knows,
+// or "" for any other document
+func resourceKind(doc *yaml.Node) string {
+	if len(doc.Content) == 0 {
+		return ""
+	}
+	apiVersion := formatter.MappingValue(doc.Content[0], "apiVersion")
+	kind := formatter.MappingValue(doc.Content[0], "kind")
+	if apiVersion == nil || kind == nil || !strings.HasPrefix(apiVersion.Value, "argoproj.io/") || !kinds[kind.Value] {
+		return ""
+	}
+	return kind.Value
+}
+
+// Format formats an Argo CD manifest with consistent indentation and ordering
+func (f *ArgoCDFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *ArgoCDFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in Argo CD manifests
+func (f *ArgoCDFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode formats one document, choosing the order tables by its kind
+// Other Kubernetes resources in the same file are left as they are
+func (f *ArgoCDFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	if !isRoot || node.Kind != yaml.DocumentNode {
+		return
+	}
+	if kind := resourceKind(node); kind != "" {
+		f.formatNodeWithContext(node.Content[0], kind, nil, opts)
+	}
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *ArgoCDFormatter) formatNodeWithContext(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		f.sortMappingNode(node, kind, path, opts)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNodeWithContext(node.Content[i+1], kind, append(path, node.Content[i].Value), opts)
+		}
+	case yaml.SequenceNode:
+		if !opts.PreserveValues {
+			sortList(node, kind, path)
+		}
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, kind, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// applicationPath reports whether path matches pattern, a MatchKeyPath
+// pattern relative to the Application spec: the spec of an Application, or the
+// spec of the template of an ApplicationSet. An empty pattern is the spec.
+func applicationPath(kind, pattern string, path []string) bool {
+	prefix := "spec"
+	if kind == "ApplicationSet" {
+		prefix = "spec.template.spec"
+	}
+	if pattern == "" {
+		return strings.Join(path, ".") == prefix
+	}
+	return formatter.MatchKeyPath(prefix+"."+pattern, path)
+}
+
+// sortList sorts syncOptions by value and Helm parameters by name
+func sortList(node *yaml.Node, kind string, path []string) {
+	switch {
+	case applicationPath(kind, "syncPolicy.syncOptions", path):
+		formatter.SortSequence(node, formatter.SequenceOptions{})
+	case applicationPath(kind, "source.helm.parameters", path),
+		applicationPath(kind, "sources.*.helm.parameters", path),
+		applicationPath(kind, "source.helm.fileParameters", path),
+		applicationPath(kind, "sources.*.helm.fileParameters", path):
+		formatter.SortSequence(node, formatter.SequenceOptions{Field: "name"})
+	}
+}
+
+// orderTable returns the order table for the mapping at path in a resource of
+// the given kind, or nil to leave it alone
+func orderTable(kind string, path []string) map[string]int {
+	switch {
+	case len(path) == 0:
+		return topLevelOrder
+	case len(path) == 1 && path[0] == "metadata":
+		return metadataOrder
+	case kind == "ApplicationSet" && len(path) == 1 && path[0] == "spec":
+		return applicationSetOrder
+	case kind == "ApplicationSet" && formatter.MatchKeyPath("spec.template", path):
+		return templateOrder
+	case kind == "ApplicationSet" && formatter.MatchKeyPath("spec.template.metadata", path):
+		return metadataOrder
+	}
+
+	for _, t := range applicationTables {
+		if applicationPath(kind, t.pattern, path) {
+			return t.table
+		}
+	}
+	return nil
+}
+
+// pathTable maps a key path, as a MatchKeyPath pattern, to an order table
+type pathTable struct {
+	pattern string
+	table   map[string]int
+}
+
+// applicationTables lists the order tables of an Application spec, with
+// patterns relative to the spec; the first match wins
+var applicationTables = []pathTable{
+	{"", applicationOrder},
+	{"source", sourceOrder},
+	{"sources.*", sourceOrder},
+	{"source.helm", helmOrder},
+	{"sources.*.helm", helmOrder},
+	{"source.helm.parameters.*", parameterOrder},
+	{"sources.*.helm.parameters.*", parameterOrder},
+	{"destination", destinationOrder},
+	{"syncPolicy", syncPolicyOrder},
+	{"syncPolicy.automated", automatedOrder},
+	{"syncPolicy.retry", retryOrder},
+	{"syncPolicy.retry.backoff", backoffOrder},
+}
+
+// sortMappingNode sorts keys in a mapping node according to Argo CD conventions
+// Keys missing from the order table follow the known ones, by name; mappings
+// without a table, such as labels and Helm values, keep their order
+func (f *ArgoCDFormatter) sortMappingNode(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	table := orderTable(kind, path)
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || table == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder is the usual order of a Kubernetes resource
+var topLevelOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"metadata":   3,
+	"spec":       4,
+	"status":     5,
+	"operation":  6,
+}
+
+// metadataOrder ranks the fields of metadata
+var metadataOrder = map[string]int{
+	"name":        1,
+	"namespace":   2,
+	"labels":      3,
+	"annotations": 4,
+	"finalizers":  5,
+}
+
+// applicationOrder ranks the fields of an Application spec: the project, what
+// is deployed where, then how it is synced
+var applicationOrder = map[string]int{
+	"project":              1,
+	"source":               2,
+	"sources":              3,
+	"sourceHydrator":       4,
+	"destination":          5,
+	"syncPolicy":           6,
+	"ignoreDifferences":    7,
+	"info":                 8,
+	"revisionHistoryLimit": 9,
+}
+
+// sourceOrder ranks the fields of a source: where the manifests are, then the
+// tool rendering them
+var sourceOrder = map[string]int{
+	"repoURL":        1,
+	"targetRevision": 2,
+	"path":           3,
+	"chart":          4,
+	"ref":            5,
+	"name":           6,
+	"helm":           10,
+	"kustomize":      11,
+	"directory":      12,
+	"plugin":         13,
+}
+
+// helmOrder ranks the fields of the Helm settings of a source
+var helmOrder = map[string]int{
+	"releaseName":    1,
+	"version":        2,
+	"namespace":      3,
+	"valueFiles":     4,
+	"values":         5,
+	"valuesObject":   6,
+	"parameters":     7,
+	"fileParameters": 8,
+}
+
+// parameterOrder ranks the fields of a Helm parameter
+var parameterOrder = map[string]int{
+	"name":        1,
+	"value":       2,
+	"forceString": 3,
+}
+
+// destinationOrder ranks the fields of a destination
+var destinationOrder = map[string]int{
+	"server":    1,
+	"name":      2,
+	"namespace": 3,
+}
+
+// syncPolicyOrder ranks the fields of a sync policy
+var syncPolicyOrder = map[string]int{
+	"automated":                1,
+	"syncOptions":              2,
+	"retry":                    3,
+	"managedNamespaceMetadata": 4,
+}
+
+// automatedOrder ranks the fields of automated sync
+var automatedOrder = map[string]int{
+	"enabled":    1,
+	"prune":      2,
+	"selfHeal":   3,
+	"allowEmpty": 4,
+}
+
+// retryOrder ranks the fields of a retry policy
+var retryOrder = map[string]int{
+	"limit":   1,
+	"backoff": 2,
+}
+
+// backoffOrder ranks the fields of a retry backoff
+var backoffOrder = map[string]int{
+	"duration":    1,
+	"factor":      2,
+	"maxDuration": 3,
+}
+
+// applicationSetOrder ranks the fields of an ApplicationSet spec: how
+// templates are rendered, the generators, then the template
+var applicationSetOrder = map[string]int{
+	"goTemplate":                   1,
+	"goTemplateOptions":            2,
+	"generators":                   3,
+	"strategy":                     4,
+	"syncPolicy":                   5,
+	"preservedFields":              6,
+	"ignoreApplicationDifferences": 7,
+	"template":                     10,
+	"templatePatch":                11,
+}
+
+// templateOrder ranks the fields of an ApplicationSet template
+var templateOrder = map[string]int{
+	"metadata": 1,
+	"spec":     2,
+}
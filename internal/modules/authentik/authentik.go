@@ -0,0 +1,153 @@
+package authentik
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// AuthentikFormatter formats authentik blueprints
+type AuthentikFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new AuthentikFormatter
+// Top-level sections and entries are separated by blank lines
+func New() *AuthentikFormatter {
+	return &AuthentikFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}, {"entries"}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *AuthentikFormatter) Name() string {
+	return "authentik"
+}
+
+// CanHandle checks if this file is an authentik blueprint: a YAML file with
+// a top-level version and a list of entries, each naming a model
+func (f *AuthentikFormatter) CanHandle(filename string, data []byte) bool {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+	default:
+		return false
+	}
+	if !formatter.MayHaveTopLevelKey(data, "entries") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return false
+	}
+	entries := formatter.MappingValue(root.Content[0], "entries")
+	if formatter.MappingValue(root.Content[0], "version") == nil || entries == nil || entries.Kind != yaml.SequenceNode || len(entries.Content) == 0 {
+		return false
+	}
+	return formatter.MappingValue(entries.Content[0], "model") != nil
+}
+
+// Format formats an authentik blueprint
+func (f *AuthentikFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Entries keep their order, as later entries may refer to earlier ones; the
+// keys of each are ordered by entryOrder. Custom tags such as !KeyOf, !Find
+// and !Env are kept as written, with their arguments.
+func (f *AuthentikFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		if !opts.PreserveKeyOrder {
+			f.formatNode(node, nil)
+		}
+	})
+}
+
+// formatNode sorts the mappings of the blueprint with an order table, from
+// node down
+func (f *AuthentikFormatter) formatNode(node *yaml.Node, path []string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			f.formatNode(child, path)
+		}
+	case yaml.MappingNode:
+		if table := orderFor(path); table != nil {
+			sortMapping(node, rank(table))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNode(node.Content[i+1], append(path, node.Content[i].Value))
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			f.formatNode(child, append(path, strconv.Itoa(i)))
+		}
+	}
+}
+
+// orderFor returns the order table of the mapping at path, or nil for
+// mappings that keep their order, such as the attributes of a model
+func orderFor(path []string) map[string]int {
+	switch {
+	case len(path) == 0:
+		return topLevelOrder
+	case len(path) == 1 && path[0] == "metadata":
+		return metadataOrder
+	case len(path) == 2 && path[0] == "entries":
+		return entryOrder
+	}
+	return nil
+}
+
+// rank returns a ranking function for an order table; unknown keys go last,
+// in their order
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// sortMapping orders the entries of a mapping by rank; entries of the same
+// rank keep their order, and comments move with the entries they describe
+func sortMapping(node *yaml.Node, rank func(key string) int) {
+	formatter.SortMapping(node, func(a, b string) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+}
+
+// topLevelOrder ranks the sections of a blueprint: its schema version, what
+// it is, the context its entries can read, then the entries
+var topLevelOrder = map[string]int{
+	"version":  1,
+	"metadata": 2,
+	"context":  3,
+	"entries":  4,
+}
+
+// metadataOrder ranks the keys of the metadata section
+var metadataOrder = map[string]int{
+	"name":   1,
+	"labels": 2,
+}
+
+// entryOrder ranks the keys of an entry: the model it manages and whether it
+// should exist, how it is found and referred to, when it applies, then the
+// attributes it is given
+var entryOrder = map[string]int{
+	"model":       1,
+	"state":       2,
+	"id":          3,
+	"identifiers": 4,
+	"conditions":  5,
+	"permissions": 6,
+	"attrs":       7,
+}
@@ -0,0 +1,209 @@
+package bitbucket
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// BitbucketFormatter formats Bitbucket Pipelines files (bitbucket-pipelines.yml)
+type BitbucketFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new BitbucketFormatter
+// Top-level sections are separated by blank lines
+func New() *BitbucketFormatter {
+	return &BitbucketFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *BitbucketFormatter) Name() string {
+	return "bitbucket"
+}
+
+// CanHandle checks if this file is a Bitbucket Pipelines file
+func (f *BitbucketFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := filepath.Base(filename)
+	if base == "bitbucket-pipelines.yml" || base == "bitbucket-pipelines.yaml" {
+		return true
+	}
+
+	// Check for Bitbucket specific keys
+	if !formatter.MayHaveTopLevelKey(data, "pipelines") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// The pipelines mapping holds the triggers (default, branches, ...)
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		pipelines := formatter.MappingValue(root.Content[0], "pipelines")
+		return pipelines != nil && pipelines.Kind == yaml.MappingNode
+	}
+
+	return false
+}
+
+// Format formats a Bitbucket Pipelines YAML file with consistent indentation and ordering
+func (f *BitbucketFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *BitbucketFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *BitbucketFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *BitbucketFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Bitbucket conventions
+// Branch and tag patterns, variables and custom pipelines keep their order
+func (f *BitbucketFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var table map[string]int
+	switch {
+	case isTopLevel:
+		table = topLevelOrder
+	case len(path) == 1 && path[0] == "pipelines":
+		table = pipelinesOrder
+	case len(path) == 1 && path[0] == "definitions":
+		table = definitionsOrder
+	case len(path) > 0 && path[len(path)-1] == "step":
+		table = stepOrder
+	case len(path) > 0 && path[len(path)-1] == "stage":
+		table = stageOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder ranks the top-level keys: defaults, then reusable
+// definitions, then the pipelines using them
+// definitions has to stay above pipelines, since steps defined there with
+// anchors are referenced by aliases further down
+var topLevelOrder = map[string]int{
+	"image":       1,
+	"clone":       2,
+	"options":     3,
+	"labels":      4,
+	"definitions": 10,
+	"pipelines":   20,
+}
+
+// pipelinesOrder ranks the pipeline triggers
+var pipelinesOrder = map[string]int{
+	"default":       1,
+	"branches":      2,
+	"pull-requests": 3,
+	"tags":          4,
+	"bookmarks":     5,
+	"custom":        6,
+}
+
+// definitionsOrder ranks the definitions
+var definitionsOrder = map[string]int{
+	"caches":   1,
+	"services": 2,
+	"steps":    3,
+}
+
+// stepOrder ranks the keys of a step: what it is called and where it runs,
+// then its environment and conditions, then the script and its results
+var stepOrder = map[string]int{
+	// Identity and runner
+	"name":    1,
+	"image":   2,
+	"runs-on": 3,
+	"size":    4,
+	"clone":   5,
+	"oidc":    6,
+
+	// Environment and conditions
+	"caches":     10,
+	"services":   11,
+	"deployment": 12,
+	"trigger":    13,
+	"condition":  14,
+	"max-time":   15,
+	"fail-fast":  16,
+
+	// Commands
+	"script":       20,
+	"after-script": 21,
+
+	// Results
+	"artifacts": 30,
+}
+
+// stageOrder ranks the keys of a deployment stage
+var stageOrder = map[string]int{
+	"name":       1,
+	"deployment": 2,
+	"trigger":    3,
+	"condition":  4,
+	"steps":      10,
+}
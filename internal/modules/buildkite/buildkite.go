@@ -0,0 +1,235 @@
+package buildkite
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// BuildkiteFormatter formats Buildkite pipeline files (.buildkite/pipeline.yml)
+type BuildkiteFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new BuildkiteFormatter
+// Top-level steps are separated by blank lines
+func New() *BuildkiteFormatter {
+	return &BuildkiteFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{"steps"}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *BuildkiteFormatter) Name() string {
+	return "buildkite"
+}
+
+// stepTypeKeys are keys only Buildkite steps have; Drone and Woodpecker steps
+// also live under a top-level "steps" key but use name and image instead
+var stepTypeKeys = []string{"label", "command", "plugins", "wait", "block", "input", "trigger", "group", "artifact_paths", "soft_fail", "timeout_in_minutes"}
+
+// CanHandle checks if this file is a Buildkite pipeline
+// The pipeline is usually named pipeline.yml, which says little, so the steps
+// are looked at as well
+func (f *BuildkiteFormatter) CanHandle(filename string, data []byte) bool {
+	if !formatter.MayHaveTopLevelKey(data, "steps") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return false
+	}
+
+	steps := formatter.MappingValue(root.Content[0], "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return false
+	}
+	if strings.Contains(filename, "buildkite") {
+		return true
+	}
+
+	// Look for a step only Buildkite would write
+	for _, step := range steps.Content {
+		switch step.Kind {
+		case yaml.ScalarNode:
+			if step.Value == "wait" || step.Value == "block" || step.Value == "input" {
+				return true
+			}
+		case yaml.MappingNode:
+			if formatter.MappingValue(step, "image") != nil {
+				return false
+			}
+			for _, key := range stepTypeKeys {
+				if formatter.MappingValue(step, key) != nil {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Format formats a Buildkite YAML file with consistent indentation and ordering
+func (f *BuildkiteFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *BuildkiteFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *BuildkiteFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *BuildkiteFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Buildkite conventions
+// Only the pipeline and its steps are sorted; env, agents and plugin settings
+// keep their order
+func (f *BuildkiteFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var order func(key string) int
+	switch {
+	case isTopLevel:
+		order = getPipelineKeyOrder
+	case isStep(path):
+		order = getStepKeyOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// isStep reports whether path is a step: an item of the top-level steps list
+// or of a group step's steps (steps.0.steps.1)
+func isStep(path []string) bool {
+	if len(path) < 2 || len(path)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(path); i += 2 {
+		if path[i] != "steps" {
+			return false
+		}
+	}
+	return true
+}
+
+// getPipelineKeyOrder returns the sort order for a top-level key
+// Defaults for every step come before the steps, notifications after them
+func getPipelineKeyOrder(key string) int {
+	pipelineOrder := map[string]int{
+		"agents": 1,
+		"env":    2,
+		"steps":  10,
+		"notify": 20,
+	}
+
+	if order, ok := pipelineOrder[key]; ok {
+		return order
+	}
+	return 999
+}
+
+// getStepKeyOrder returns the sort order for a key of a step
+// What the step is called, then when it runs, then what it runs, then where
+// and how it runs
+func getStepKeyOrder(key string) int {
+	stepOrder := map[string]int{
+		// Step type keys name the step like label does
+		"label":   1,
+		"group":   1,
+		"block":   1,
+		"input":   1,
+		"wait":    1,
+		"trigger": 1,
+		"key":     2,
+
+		// When it runs
+		"depends_on":               10,
+		"allow_dependency_failure": 11,
+		"if":                       12,
+		"branches":                 13,
+
+		// What it runs
+		"command":  20,
+		"commands": 20,
+		"env":      21,
+		"plugins":  22,
+		"build":    23,
+		"async":    24,
+		"prompt":   25,
+		"fields":   26,
+		"steps":    27,
+
+		// Where and how it runs
+		"agents":             30,
+		"artifact_paths":     31,
+		"parallelism":        32,
+		"concurrency":        33,
+		"concurrency_group":  34,
+		"priority":           35,
+		"retry":              36,
+		"soft_fail":          37,
+		"skip":               38,
+		"timeout_in_minutes": 39,
+		"notify":             40,
+	}
+
+	if order, ok := stepOrder[key]; ok {
+		return order
+	}
+	return 999
+}
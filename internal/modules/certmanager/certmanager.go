@@ -0,0 +1,368 @@
+package certmanager
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// CertManagerFormatter formats cert-manager resources: Issuer, ClusterIssuer
+// and Certificate
+type CertManagerFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new CertManagerFormatter
+func New() *CertManagerFormatter {
+	return &CertManagerFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			// Among solvers matching a name equally well, the first one wins
+			OrderSensitive: []string{"spec.acme.solvers"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *CertManagerFormatter) Name() string {
+	return "cert-manager"
+}
+
+// kinds are the resources the formatter knows
+var kinds = map[string]bool{
+	"Issuer":        true,
+	"ClusterIssuer": true,
+	"Certificate":   true,
+}
+
+// CanHandle checks if this file holds cert-manager resources
+// Manifests have no telling file name, so only the apiVersion and kind are
+// used; one cert-manager document in a manifest is enough
+func (f *CertManagerFormatter) CanHandle(filename string, data []byte) bool {
+	// Most manifests are not ours; the API group has to appear somewhere
+	if !bytes.Contains(data, []byte("cert-manager.io/")) {
+		return false
+	}
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return false
+	}
+	for _, doc := range docs {
+		if resourceKind(doc) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKind returns the kind of a cert-manager resource the formatter
+// knows, or "" for any other document
+func resourceKind(doc *yaml.Node) string {
+	if len(doc.Content) == 0 {
+		return ""
+	}
+	apiVersion := formatter.MappingValue(doc.Content[0], "apiVersion")
+	kind := formatter.MappingValue(doc.Content[0], "kind")
+	if apiVersion == nil || kind == nil || !strings.HasPrefix(apiVersion.Value, "cert-manager.io/") || !kinds[kind.Value] {
+		return ""
+	}
+	return kind.Value
+}
+
+// Format formats a cert-manager resource file with consistent indentation and ordering
+func (f *CertManagerFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *CertManagerFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in cert-manager resources
+func (f *CertManagerFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode formats one document, choosing the order tables by its kind
+// Other Kubernetes resources in the same file are left as they are
+func (f *CertManagerFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	if !isRoot || node.Kind != yaml.DocumentNode {
+		return
+	}
+	if kind := resourceKind(node); kind != "" {
+		f.formatNodeWithContext(node.Content[0], kind, nil, opts)
+	}
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *CertManagerFormatter) formatNodeWithContext(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		f.sortMappingNode(node, kind, path, opts)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNodeWithContext(node.Content[i+1], kind, append(path, node.Content[i].Value), opts)
+		}
+	case yaml.SequenceNode:
+		// DNS names are a set; sorted they diff cleanly
+		if !opts.PreserveValues && isNameList(path) {
+			formatter.SortSequence(node, formatter.SequenceOptions{})
+		}
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, kind, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// nameLists are the key paths of the DNS name lists sorted by name
+var nameLists = []string{
+	"spec.dnsNames",
+	"spec.acme.solvers.*.selector.dnsNames",
+	"spec.acme.solvers.*.selector.dnsZones",
+}
+
+// isNameList reports whether path is one of the nameLists
+func isNameList(path []string) bool {
+	for _, pattern := range nameLists {
+		if formatter.MatchKeyPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderTable returns the order table for the mapping at path in a resource of
+// the given kind, or nil to leave it alone
+func orderTable(kind string, path []string) map[string]int {
+	if len(path) == 0 {
+		return topLevelOrder
+	}
+	if len(path) == 1 && path[0] == "metadata" {
+		return metadataOrder
+	}
+	if len(path) == 1 && path[0] == "spec" {
+		if kind == "Certificate" {
+			return certificateOrder
+		}
+		return issuerOrder
+	}
+	// privateKeySecretRef, apiTokenSecretRef, keySecretRef, ...
+	if strings.HasSuffix(path[len(path)-1], "SecretRef") {
+		return secretRefOrder
+	}
+	tables := issuerTables
+	if kind == "Certificate" {
+		tables = certificateTables
+	}
+	for _, t := range tables {
+		if formatter.MatchKeyPath(t.pattern, path) {
+			return t.table
+		}
+	}
+	return nil
+}
+
+// pathTable maps a key path, as a MatchKeyPath pattern, to an order table
+type pathTable struct {
+	pattern string
+	table   map[string]int
+}
+
+// certificateTables lists the order tables below the spec of a Certificate;
+// the first match wins
+var certificateTables = []pathTable{
+	{"spec.issuerRef", issuerRefOrder},
+	{"spec.privateKey", privateKeyOrder},
+	{"spec.secretTemplate", secretTemplateOrder},
+}
+
+// issuerTables lists the order tables below the spec of an Issuer or
+// ClusterIssuer; the first match wins
+var issuerTables = []pathTable{
+	{"spec.acme", acmeOrder},
+	{"spec.acme.solvers.*", solverOrder},
+	{"spec.acme.solvers.*.selector", selectorOrder},
+	{"spec.acme.solvers.*.http01.ingress", ingressSolverOrder},
+	{"spec.acme.solvers.*.http01.gatewayHTTPRoute", gatewaySolverOrder},
+	{"spec.acme.solvers.*.dns01", dns01Order},
+	{"spec.acme.externalAccountBinding", externalAccountBindingOrder},
+}
+
+// sortMappingNode sorts keys in a mapping node according to cert-manager conventions
+// Keys missing from the order table follow the known ones, by name; mappings
+// without a table, such as labels and DNS provider settings, keep their order
+func (f *CertManagerFormatter) sortMappingNode(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	table := orderTable(kind, path)
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || table == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder is the usual order of a Kubernetes resource
+var topLevelOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"metadata":   3,
+	"spec":       4,
+	"status":     5,
+}
+
+// metadataOrder ranks the fields of metadata
+var metadataOrder = map[string]int{
+	"name":        1,
+	"namespace":   2,
+	"labels":      3,
+	"annotations": 4,
+}
+
+// certificateOrder ranks the fields of a Certificate spec: the secret it
+// writes and who signs it, what it is for, then its lifetime and key
+var certificateOrder = map[string]int{
+	"secretName":     1,
+	"secretTemplate": 2,
+	"issuerRef":      3,
+
+	// Identity
+	"commonName":     10,
+	"dnsNames":       11,
+	"ipAddresses":    12,
+	"uris":           13,
+	"emailAddresses": 14,
+	"otherNames":     15,
+	"subject":        16,
+	"literalSubject": 17,
+
+	// Lifetime
+	"duration":              20,
+	"renewBefore":           21,
+	"renewBeforePercentage": 22,
+
+	// Key and usage
+	"privateKey":              30,
+	"usages":                  31,
+	"isCA":                    32,
+	"nameConstraints":         33,
+	"encodeUsagesInRequest":   34,
+	"keystores":               35,
+	"additionalOutputFormats": 36,
+	"revisionHistoryLimit":    37,
+}
+
+// issuerRefOrder ranks the fields of an issuer reference
+var issuerRefOrder = map[string]int{
+	"name":  1,
+	"kind":  2,
+	"group": 3,
+}
+
+// privateKeyOrder ranks the fields of a private key
+var privateKeyOrder = map[string]int{
+	"algorithm":      1,
+	"size":           2,
+	"encoding":       3,
+	"rotationPolicy": 4,
+}
+
+// secretTemplateOrder ranks the fields of a secret template
+var secretTemplateOrder = map[string]int{
+	"labels":      1,
+	"annotations": 2,
+}
+
+// issuerOrder ranks the fields of an Issuer or ClusterIssuer spec; an issuer
+// has one of the issuer types
+var issuerOrder = map[string]int{
+	"acme":       1,
+	"ca":         2,
+	"selfSigned": 3,
+	"vault":      4,
+	"venafi":     5,
+}
+
+// acmeOrder ranks the fields of an ACME issuer: the server and the account,
+// then the solvers
+var acmeOrder = map[string]int{
+	"server":                      1,
+	"email":                       2,
+	"privateKeySecretRef":         3,
+	"externalAccountBinding":      4,
+	"disableAccountKeyGeneration": 5,
+	"profile":                     6,
+	"preferredChain":              7,
+	"skipTLSVerify":               8,
+	"caBundle":                    9,
+	"enableDurationFeature":       10,
+	"solvers":                     20,
+}
+
+// solverOrder ranks the fields of an ACME solver: which names it solves,
+// then how
+var solverOrder = map[string]int{
+	"selector": 1,
+	"http01":   2,
+	"dns01":    3,
+}
+
+// selectorOrder ranks the fields of a solver selector
+var selectorOrder = map[string]int{
+	"dnsNames":    1,
+	"dnsZones":    2,
+	"matchLabels": 3,
+}
+
+// ingressSolverOrder ranks the fields of an HTTP-01 ingress solver
+var ingressSolverOrder = map[string]int{
+	"ingressClassName": 1,
+	"class":            2,
+	"name":             3,
+	"serviceType":      4,
+	"podTemplate":      5,
+	"ingressTemplate":  6,
+}
+
+// gatewaySolverOrder ranks the fields of an HTTP-01 Gateway API solver
+var gatewaySolverOrder = map[string]int{
+	"parentRefs":  1,
+	"serviceType": 2,
+	"labels":      3,
+	"podTemplate": 4,
+}
+
+// dns01Order puts the CNAME strategy before the DNS provider
+var dns01Order = map[string]int{
+	"cnameStrategy": 1,
+}
+
+// externalAccountBindingOrder ranks the fields of an ACME external account binding
+var externalAccountBindingOrder = map[string]int{
+	"keyID":        1,
+	"keySecretRef": 2,
+	"keyAlgorithm": 3,
+}
+
+// secretRefOrder ranks the fields of a secret key reference
+var secretRefOrder = map[string]int{
+	"name": 1,
+	"key":  2,
+}
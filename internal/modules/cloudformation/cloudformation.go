@@ -0,0 +1,183 @@
+package cloudformation
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// CloudFormationFormatter formats AWS CloudFormation and SAM templates,
+// written in YAML or JSON
+type CloudFormationFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new CloudFormationFormatter
+// Top-level sections, resources, parameters and outputs are separated by
+// blank lines
+func New() *CloudFormationFormatter {
+	return &CloudFormationFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}, {"Resources"}, {"Parameters"}, {"Outputs"}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *CloudFormationFormatter) Name() string {
+	return "cloudformation"
+}
+
+// CanHandle checks if this file is a CloudFormation template: a YAML, JSON
+// or .template file with an AWSTemplateFormatVersion, or Resources of an
+// AWS:: type
+func (f *CloudFormationFormatter) CanHandle(filename string, data []byte) bool {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml", ".json", ".template":
+	default:
+		return false
+	}
+	if bytes.Contains(data, []byte("AWSTemplateFormatVersion")) {
+		return true
+	}
+	return bytes.Contains(data, []byte("Resources")) && bytes.Contains(data, []byte("AWS::"))
+}
+
+// Format formats a CloudFormation template
+func (f *CloudFormationFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// JSON templates stay JSON. In YAML templates the short forms of intrinsic
+// functions (!Ref, !GetAtt, !Sub, ...) are kept as written.
+func (f *CloudFormationFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return formatter.FormatJSONContext(ctx, data, opts, func(path []string, node *formatter.JSONNode) {
+			if table := orderFor(path); table != nil && node.Kind == formatter.JSONObject && !opts.PreserveKeyOrder {
+				formatter.SortJSONEntries(node.Entries, rank(table))
+			}
+		})
+	}
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, nil, opts)
+	})
+}
+
+// formatNode sorts the mappings of the template with an order table, from
+// node down
+func (f *CloudFormationFormatter) formatNode(node *yaml.Node, path []string, opts formatter.Options) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			f.formatNode(child, path, opts)
+		}
+	case yaml.MappingNode:
+		if table := orderFor(path); table != nil && !opts.PreserveKeyOrder {
+			sortMapping(node, rank(table))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNode(node.Content[i+1], append(path, node.Content[i].Value), opts)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			f.formatNode(child, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// orderFor returns the order table of the mapping at path, or nil for
+// mappings that keep their order, such as resource properties
+func orderFor(path []string) map[string]int {
+	switch {
+	case len(path) == 0:
+		return topLevelOrder
+	case len(path) != 2:
+		return nil
+	case path[0] == "Resources":
+		return resourceOrder
+	case path[0] == "Parameters":
+		return parameterOrder
+	case path[0] == "Outputs":
+		return outputOrder
+	}
+	return nil
+}
+
+// rank returns a ranking function for an order table; unknown keys go last,
+// in their order
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// sortMapping orders the entries of a mapping by rank; entries of the same
+// rank keep their order, and comments move with the entries they describe
+func sortMapping(node *yaml.Node, rank func(key string) int) {
+	formatter.SortMapping(node, func(a, b string) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+}
+
+// topLevelOrder ranks the sections of a template in the order the
+// CloudFormation documentation lists them
+var topLevelOrder = map[string]int{
+	"AWSTemplateFormatVersion": 1,
+	"Description":              2,
+	"Metadata":                 3,
+	"Transform":                4,
+	"Parameters":               5,
+	"Rules":                    6,
+	"Mappings":                 7,
+	"Conditions":               8,
+	"Globals":                  9,
+	"Resources":                10,
+	"Outputs":                  11,
+}
+
+// resourceOrder ranks the attributes of a resource: what it is and whether
+// and after what it is created, its properties, then its policies
+var resourceOrder = map[string]int{
+	"Type":                1,
+	"Condition":           2,
+	"DependsOn":           3,
+	"Metadata":            4,
+	"Properties":          5,
+	"CreationPolicy":      6,
+	"UpdatePolicy":        7,
+	"UpdateReplacePolicy": 8,
+	"DeletionPolicy":      9,
+}
+
+// parameterOrder ranks the keys of a parameter: its type and description,
+// its default, then the constraints on its value
+var parameterOrder = map[string]int{
+	"Type":                  1,
+	"Description":           2,
+	"Default":               3,
+	"AllowedValues":         4,
+	"AllowedPattern":        5,
+	"ConstraintDescription": 6,
+	"MinLength":             7,
+	"MaxLength":             8,
+	"MinValue":              9,
+	"MaxValue":              10,
+	"NoEcho":                11,
+}
+
+// outputOrder ranks the keys of an output
+var outputOrder = map[string]int{
+	"Description": 1,
+	"Condition":   2,
+	"Value":       3,
+	"Export":      4,
+}
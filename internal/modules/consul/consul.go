@@ -0,0 +1,309 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// agentKeyHCL and agentKeyJSON match a top-level setting only Consul agent
+// configs have, which marks an .hcl or .json file as one; Nomad agents share
+// datacenter and server, so those do not count
+var (
+	agentKeyHCL  = regexp.MustCompile(`(?m)^(node_name|bootstrap_expect|retry_join|retry_join_wan|primary_datacenter|ui_config)\s*[={]`)
+	agentKeyJSON = regexp.MustCompile(`"(node_name|bootstrap_expect|retry_join|retry_join_wan|primary_datacenter|ui_config)"\s*:`)
+)
+
+// ConsulFormatter formats HashiCorp Consul agent configs, written in HCL or
+// JSON
+type ConsulFormatter struct{}
+
+// New creates a new ConsulFormatter
+func New() *ConsulFormatter {
+	return &ConsulFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *ConsulFormatter) Name() string {
+	return "consul"
+}
+
+// CanHandle checks if this file is a Consul agent config: consul.hcl or
+// consul.json, an .hcl or .json file in a consul.d directory, or one with a
+// top-level setting only Consul has
+func (f *ConsulFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	ext := filepath.Ext(base)
+	switch {
+	case base == "consul.hcl" || base == "consul.json":
+		return true
+	case ext != ".hcl" && ext != ".json":
+		return false
+	case filepath.Base(filepath.Dir(filename)) == "consul.d":
+		return true
+	case ext == ".hcl":
+		return agentKeyHCL.Match(data)
+	}
+	return agentKeyJSON.Match(data)
+}
+
+// Format formats a Consul agent config
+func (f *ConsulFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// JSON configs stay JSON. The top level is ordered by topLevelOrder: the
+// datacenter, the node, the server settings, then how the agent joins the
+// cluster; services go last, sorted by name, with their keys ordered by
+// serviceOrder. The retry_join lists lose duplicate addresses, and a single
+// address becomes a list.
+func (f *ConsulFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return formatter.FormatJSONContext(ctx, data, opts, func(path []string, node *formatter.JSONNode) {
+			formatJSON(path, node, opts)
+		})
+	}
+	return formatter.FormatHCLContext(ctx, data, opts, func(file *formatter.HCLFile) {
+		formatHCL(file, opts)
+	})
+}
+
+// formatHCL orders an HCL config and normalizes its retry_join lists
+func formatHCL(file *formatter.HCLFile, opts formatter.Options) {
+	for _, item := range file.Body.Items {
+		if !item.IsBlock() && isJoinList(item.Name) && !opts.PreserveValues {
+			if values, ok := item.Value.Strings(); ok {
+				item.Value.SetStrings(uniqueAddresses(values))
+			}
+		}
+	}
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortHCLItems(file.Body.Items, func(item *formatter.HCLItem) int {
+		return rank(item.Name, item.IsBlock())
+	})
+	var services []*formatter.HCLItem
+	for _, item := range file.Body.Items {
+		if item.IsBlock() && isService(item.Name) {
+			services = append(services, item)
+			formatter.SortHCLItems(item.Body.Items, func(item *formatter.HCLItem) int {
+				return serviceKeyRank(item.Name)
+			})
+		}
+	}
+	sorted := slices.Clone(services)
+	slices.SortStableFunc(sorted, func(a, b *formatter.HCLItem) int {
+		return strings.Compare(hclServiceName(a), hclServiceName(b))
+	})
+	// The services take the places of the service blocks, in name order
+	next := 0
+	for i, item := range file.Body.Items {
+		if item.IsBlock() && isService(item.Name) {
+			file.Body.Items[i] = sorted[next]
+			next++
+		}
+	}
+}
+
+// hclServiceName returns the name of a service block, or its id
+func hclServiceName(block *formatter.HCLItem) string {
+	for _, key := range []string{"name", "id"} {
+		if attribute := block.Body.Get(key); attribute != nil {
+			if name, err := strconv.Unquote(strings.TrimSpace(attribute.Value.Raw)); err == nil {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// formatJSON orders a JSON config and normalizes its retry_join lists
+func formatJSON(path []string, node *formatter.JSONNode, opts formatter.Options) {
+	if len(path) == 0 && node.Kind == formatter.JSONObject {
+		for _, entry := range node.Entries {
+			if isJoinList(entry.Name()) && !opts.PreserveValues {
+				normalizeJSONJoinList(entry)
+			}
+		}
+	}
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	switch {
+	case len(path) == 0 && node.Kind == formatter.JSONObject:
+		formatter.SortJSONEntries(node.Entries, func(name string) int {
+			entry := slices.IndexFunc(node.Entries, func(entry *formatter.JSONEntry) bool { return entry.Name() == name })
+			return rank(name, node.Entries[entry].Value.Kind == formatter.JSONObject)
+		})
+	case len(path) == 1 && isService(path[0]) && node.Kind == formatter.JSONObject:
+		formatter.SortJSONEntries(node.Entries, serviceKeyRank)
+	case len(path) == 2 && isService(path[0]) && node.Kind == formatter.JSONObject:
+		if _, err := strconv.Atoi(path[1]); err == nil {
+			formatter.SortJSONEntries(node.Entries, serviceKeyRank)
+		}
+	case len(path) == 1 && isService(path[0]) && node.Kind == formatter.JSONArray:
+		if slices.ContainsFunc(node.Entries, (*formatter.JSONEntry).HasComments) {
+			return
+		}
+		slices.SortStableFunc(node.Entries, func(a, b *formatter.JSONEntry) int {
+			return strings.Compare(jsonServiceName(a.Value), jsonServiceName(b.Value))
+		})
+	}
+}
+
+// jsonServiceName returns the name of a service object, or its id
+func jsonServiceName(node *formatter.JSONNode) string {
+	for _, key := range []string{"name", "id"} {
+		for _, entry := range node.Entries {
+			if entry.Name() == key && entry.Value.Kind == formatter.JSONScalar {
+				if name, err := strconv.Unquote(entry.Value.Raw); err == nil {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeJSONJoinList drops the duplicate addresses of a retry_join list
+// and turns a single address into a list
+func normalizeJSONJoinList(entry *formatter.JSONEntry) {
+	value := entry.Value
+	switch {
+	case value.Kind == formatter.JSONScalar && strings.HasPrefix(value.Raw, `"`):
+		entry.Value = &formatter.JSONNode{Kind: formatter.JSONArray, Entries: []*formatter.JSONEntry{{Value: value}}, Inline: true}
+	case value.Kind == formatter.JSONArray:
+		seen := make(map[string]bool, len(value.Entries))
+		kept := value.Entries[:0:0]
+		for _, item := range value.Entries {
+			if item.Value.Kind == formatter.JSONScalar && !item.HasComments() {
+				if seen[item.Value.Raw] {
+					continue
+				}
+				seen[item.Value.Raw] = true
+			}
+			kept = append(kept, item)
+		}
+		value.Entries = kept
+	}
+}
+
+// uniqueAddresses returns the string literals of a retry_join list without
+// duplicates, in their order
+func uniqueAddresses(values []string) []string {
+	var unique []string
+	for _, value := range values {
+		if !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// isJoinList reports whether a setting lists the addresses an agent joins
+func isJoinList(name string) bool {
+	return name == "retry_join" || name == "retry_join_wan" || name == "start_join" || name == "start_join_wan"
+}
+
+// isService reports whether a setting defines services
+func isService(name string) bool {
+	return name == "service" || name == "services"
+}
+
+// Ranks of the settings missing from topLevelOrder: other attributes, other
+// blocks, and services, which go last
+const (
+	otherAttributeRank = 50
+	otherBlockRank     = 100
+	servicesRank       = 200
+	checksRank         = 210
+)
+
+// rank returns the position of a top-level setting
+func rank(name string, block bool) int {
+	switch {
+	case isService(name):
+		return servicesRank
+	case name == "check" || name == "checks":
+		return checksRank
+	}
+	if r, ok := topLevelOrder[name]; ok {
+		return r
+	}
+	if block {
+		return otherBlockRank
+	}
+	return otherAttributeRank
+}
+
+// topLevelOrder ranks the top-level settings of an agent config: which
+// datacenter and node it is, whether it is a server, where it keeps its data,
+// then its addresses and how it joins the cluster
+var topLevelOrder = map[string]int{
+	"datacenter":         1,
+	"primary_datacenter": 2,
+	"domain":             3,
+	"node_name":          10,
+	"node_id":            11,
+	"node_meta":          12,
+	"server":             20,
+	"bootstrap_expect":   21,
+	"bootstrap":          22,
+	"ui":                 23,
+	"data_dir":           30,
+	"log_level":          31,
+	"client_addr":        40,
+	"bind_addr":          41,
+	"advertise_addr":     42,
+	"advertise_addr_wan": 43,
+	"start_join":         44,
+	"start_join_wan":     45,
+	"retry_join":         46,
+	"retry_join_wan":     47,
+	"encrypt":            48,
+	"ports":              60,
+	"addresses":          61,
+	"ui_config":          62,
+	"acl":                70,
+	"tls":                71,
+	"connect":            72,
+	"telemetry":          73,
+}
+
+// serviceKeyRank returns the position of a key in a service definition: what
+// the service is, where it listens, then its checks and Connect settings
+func serviceKeyRank(name string) int {
+	if r, ok := serviceOrder[name]; ok {
+		return r
+	}
+	return otherAttributeRank
+}
+
+// serviceOrder ranks the keys of a service definition
+var serviceOrder = map[string]int{
+	"id":                  1,
+	"name":                2,
+	"kind":                3,
+	"tags":                4,
+	"address":             5,
+	"port":                6,
+	"socket_path":         7,
+	"meta":                8,
+	"enable_tag_override": 9,
+	"check":               60,
+	"checks":              61,
+	"connect":             70,
+	"proxy":               71,
+	"weights":             80,
+	"token":               90,
+}
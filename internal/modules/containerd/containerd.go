@@ -0,0 +1,182 @@
+package containerd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// ContainerdFormatter formats containerd's config.toml
+type ContainerdFormatter struct{}
+
+// New creates a new ContainerdFormatter
+func New() *ContainerdFormatter {
+	return &ContainerdFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *ContainerdFormatter) Name() string {
+	return "containerd"
+}
+
+// CanHandle checks if this file is a containerd config: a TOML file in a
+// containerd directory, or one configuring containerd's plugins
+func (f *ContainerdFormatter) CanHandle(filename string, data []byte) bool {
+	if filepath.Ext(filename) != ".toml" {
+		return false
+	}
+	if filepath.Base(filepath.Dir(filename)) == "containerd" {
+		return true
+	}
+	return bytes.Contains(data, []byte(`"io.containerd.`))
+}
+
+// rootOrder ranks the keys before the first table; version comes first as
+// it decides how the rest of the file is read
+var rootOrder = map[string]int{
+	"version":          0,
+	"root":             1,
+	"state":            2,
+	"temp":             3,
+	"plugin_dir":       4,
+	"disabled_plugins": 5,
+	"required_plugins": 6,
+	"oom_score":        7,
+	"imports":          8,
+}
+
+// tableOrder ranks the top-level tables; the plugins come last, as they are
+// the bulk of the file
+var tableOrder = map[string]int{
+	"grpc":              0,
+	"ttrpc":             1,
+	"debug":             2,
+	"metrics":           3,
+	"cgroup":            4,
+	"timeouts":          5,
+	"stream_processors": 6,
+	"proxy_plugins":     7,
+	"plugins":           8,
+}
+
+// grpcOrder ranks the keys of the grpc and ttrpc tables
+var grpcOrder = map[string]int{
+	"address":               0,
+	"tcp_address":           1,
+	"tcp_tls_ca":            2,
+	"tcp_tls_cert":          3,
+	"tcp_tls_key":           4,
+	"uid":                   5,
+	"gid":                   6,
+	"max_recv_message_size": 7,
+	"max_send_message_size": 8,
+}
+
+// debugOrder ranks the keys of the debug table
+var debugOrder = map[string]int{
+	"address": 0,
+	"uid":     1,
+	"gid":     2,
+	"level":   3,
+	"format":  4,
+}
+
+// metricsOrder ranks the keys of the metrics table
+var metricsOrder = map[string]int{
+	"address":        0,
+	"grpc_histogram": 1,
+}
+
+// proxyPluginOrder ranks the keys of a proxy plugin
+var proxyPluginOrder = map[string]int{
+	"type":         0,
+	"address":      1,
+	"platform":     2,
+	"exports":      3,
+	"capabilities": 4,
+}
+
+// mirrorOrder ranks the keys of a registry mirror
+var mirrorOrder = map[string]int{
+	"endpoint": 0,
+	"rewrite":  1,
+}
+
+// tableKeyOrder returns the order table of the keys of a table, or nil to
+// keep them as written
+func tableKeyOrder(path []string) map[string]int {
+	switch {
+	case len(path) == 1 && (path[0] == "grpc" || path[0] == "ttrpc"):
+		return grpcOrder
+	case len(path) == 1 && path[0] == "debug":
+		return debugOrder
+	case len(path) == 1 && path[0] == "metrics":
+		return metricsOrder
+	case len(path) == 2 && path[0] == "proxy_plugins":
+		return proxyPluginOrder
+	case len(path) >= 3 && path[0] == "plugins" && path[len(path)-3] == "registry" && path[len(path)-2] == "mirrors":
+		return mirrorOrder
+	}
+	return nil
+}
+
+// compareTables orders tables by tableOrder, then by key, so the plugins
+// are sorted by ID and their option tables, such as the registry mirrors,
+// follow the table they belong to
+func compareTables(a, b []string) int {
+	rank := func(path []string) int {
+		if r, ok := tableOrder[path[0]]; ok {
+			return r
+		}
+		return 999
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// Format formats a containerd config
+func (f *ContainerdFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The root keys start with version, tables are ordered as containerd config
+// default writes them, and nested plugin tables are indented by their depth
+func (f *ContainerdFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatTOMLContext(ctx, data, opts, func(doc *formatter.TOMLDocument) {
+		doc.IndentTables = true
+		if opts.PreserveKeyOrder {
+			return
+		}
+		formatter.SortTOMLEntries(doc.Root.Entries, rankIn(rootOrder))
+		for _, table := range doc.Tables {
+			if order := tableKeyOrder(table.Path()); order != nil {
+				formatter.SortTOMLEntries(table.Entries, rankIn(order))
+			}
+		}
+		doc.SortTablesFunc(compareTables)
+	})
+}
+
+// rankIn returns a rank function for SortTOMLEntries; keys not in order keep
+// their place after the known ones
+func rankIn(order map[string]int) func(string) int {
+	return func(key string) int {
+		if r, ok := order[key]; ok {
+			return r
+		}
+		return 999
+	}
+}
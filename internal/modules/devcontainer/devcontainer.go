@@ -0,0 +1,179 @@
+package devcontainer
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// DevContainerFormatter formats Dev Container configuration files
+// (.devcontainer/devcontainer.json), which are JSONC rather than YAML
+type DevContainerFormatter struct{}
+
+// New creates a new DevContainerFormatter
+func New() *DevContainerFormatter {
+	return &DevContainerFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *DevContainerFormatter) Name() string {
+	return "devcontainer"
+}
+
+// CanHandle checks if this file is a Dev Container configuration file
+// Only the file name is looked at; its keys are too generic to go by
+func (f *DevContainerFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	return base == "devcontainer.json" || base == ".devcontainer.json"
+}
+
+// Format formats a devcontainer.json file with consistent indentation and ordering
+// Comments are kept; trailing commas are dropped
+func (f *DevContainerFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *DevContainerFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatJSONContext(ctx, data, opts, func(path []string, node *formatter.JSONNode) {
+		f.formatNode(node, path, opts)
+	})
+}
+
+// formatNode formats one value; FormatJSON calls it for every value with its
+// key path
+func (f *DevContainerFormatter) formatNode(node *formatter.JSONNode, path []string, opts formatter.Options) {
+	if node.Kind == formatter.JSONObject {
+		sortObject(node, path, opts)
+	}
+
+	// Ports read best in numeric order
+	if !opts.PreserveValues && len(path) == 1 && path[0] == "forwardPorts" {
+		sortPorts(node)
+	}
+}
+
+// sortObject sorts the members of an object according to Dev Container conventions
+// Only the top level and build are sorted; features, customizations and
+// environment variables keep their order
+func sortObject(node *formatter.JSONNode, path []string, opts formatter.Options) {
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	switch {
+	case len(path) == 0:
+		formatter.SortJSONEntries(node.Entries, rank(topLevelOrder))
+	case len(path) == 1 && path[0] == "build":
+		formatter.SortJSONEntries(node.Entries, rank(buildOrder))
+	}
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// sortPorts sorts the forwardPorts list by port number
+// Items are numbers or "host:port" strings; the list is left alone if any
+// item is something else
+func sortPorts(node *formatter.JSONNode) {
+	if node.Kind != formatter.JSONArray {
+		return
+	}
+
+	ports := make(map[*formatter.JSONEntry]int, len(node.Entries))
+	for _, entry := range node.Entries {
+		if entry.Value.Kind != formatter.JSONScalar {
+			return
+		}
+		value := strings.Trim(entry.Value.Raw, `"`)
+		if i := strings.LastIndexByte(value, ':'); i >= 0 {
+			value = value[i+1:]
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		ports[entry] = port
+	}
+
+	sort.SliceStable(node.Entries, func(i, j int) bool {
+		return ports[node.Entries[i]] < ports[node.Entries[j]]
+	})
+}
+
+// topLevelOrder ranks the top-level properties: the name, where the container
+// comes from, what is added to it, then how it is run and set up
+var topLevelOrder = map[string]int{
+	"name": 1,
+
+	// Container source
+	"image":             10,
+	"build":             10,
+	"dockerFile":        10,
+	"context":           11,
+	"dockerComposeFile": 12,
+	"service":           13,
+	"runServices":       14,
+	"workspaceFolder":   15,
+	"workspaceMount":    16,
+	"shutdownAction":    17,
+
+	// Additions
+	"features":                    20,
+	"overrideFeatureInstallOrder": 21,
+	"customizations":              22,
+
+	// Ports
+	"forwardPorts":         30,
+	"portsAttributes":      31,
+	"otherPortsAttributes": 32,
+	"appPort":              33,
+
+	// Environment and users
+	"containerEnv":        40,
+	"remoteEnv":           41,
+	"containerUser":       42,
+	"remoteUser":          43,
+	"updateRemoteUserUID": 44,
+	"userEnvProbe":        45,
+
+	// Runtime
+	"runArgs":          50,
+	"mounts":           51,
+	"init":             52,
+	"privileged":       53,
+	"capAdd":           54,
+	"securityOpt":      55,
+	"overrideCommand":  56,
+	"hostRequirements": 57,
+
+	// Lifecycle commands, in the order they run
+	"initializeCommand":    60,
+	"onCreateCommand":      61,
+	"updateContentCommand": 62,
+	"postCreateCommand":    63,
+	"postStartCommand":     64,
+	"postAttachCommand":    65,
+	"waitFor":              66,
+}
+
+// buildOrder ranks the properties of build
+var buildOrder = map[string]int{
+	"dockerfile": 1,
+	"context":    2,
+	"target":     3,
+	"args":       4,
+	"options":    5,
+	"cacheFrom":  6,
+}
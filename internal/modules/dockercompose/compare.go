@@ -0,0 +1,194 @@
+package dockercompose
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Variant is the compose file of one environment
+type Variant struct {
+	Name string
+	Data []byte
+}
+
+// Comparison is a matrix of the per-service differences between a base
+// compose file and the variants of its environments
+type Comparison struct {
+	Environments []string             `json:"environments"`
+	Services     []ServiceDifferences `json:"services"`
+}
+
+// ServiceDifferences lists the fields of one service that differ between
+// environments
+type ServiceDifferences struct {
+	Service string            `json:"service"`
+	Fields  []FieldDifference `json:"fields"`
+}
+
+// FieldDifference is one field of a service across environments
+type FieldDifference struct {
+	// Field is the key path within the service, e.g. environment.LOG_LEVEL,
+	// or "" for the service itself, which is "defined" or not
+	Field string `json:"field"`
+
+	// Base and Values, by environment name, are flow-style YAML; "" means
+	// the field is not set
+	Base   string            `json:"base"`
+	Values map[string]string `json:"values"`
+}
+
+// concatenatedFields are the service lists docker compose adds to when an
+// override file sets them; other lists are replaced
+var concatenatedFields = map[string]bool{
+	"ports":          true,
+	"expose":         true,
+	"external_links": true,
+	"dns":            true,
+	"dns_search":     true,
+	"tmpfs":          true,
+}
+
+// CompareVariants compares the services of each variant with those of base.
+// Every file is formatted with opts first, so that values written
+// differently, such as environment lists and maps, compare equal. With
+// overlay, variants are override files merged onto base as docker compose
+// merges them; otherwise each is a complete compose file.
+func CompareVariants(base []byte, variants []Variant, overlay bool, opts formatter.Options) (*Comparison, error) {
+	baseRoot, err := formattedRoot(base, opts)
+	if err != nil {
+		return nil, err
+	}
+	baseNames, baseServices := composeServices(baseRoot)
+
+	comparison := &Comparison{Environments: []string{}, Services: []ServiceDifferences{}}
+	names := append([]string(nil), baseNames...)
+	rows := make(map[string][]*FieldDifference)
+	record := func(service, field, old, new, environment string) {
+		for _, row := range rows[service] {
+			if row.Field == field {
+				row.Values[environment] = new
+				return
+			}
+		}
+		rows[service] = append(rows[service], &FieldDifference{Field: field, Base: old, Values: map[string]string{environment: new}})
+	}
+
+	for _, variant := range variants {
+		root, err := formattedRoot(variant.Data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", variant.Name, err)
+		}
+		if overlay {
+			root = mergeOverlay(baseRoot, root, nil)
+		}
+		comparison.Environments = append(comparison.Environments, variant.Name)
+
+		variantNames, variantServices := composeServices(root)
+		for _, name := range variantNames {
+			if _, ok := baseServices[name]; !ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			a, b := baseServices[name], variantServices[name]
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				record(name, "", "", "defined", variant.Name)
+			case b == nil:
+				record(name, "", "defined", "", variant.Name)
+			default:
+				for _, change := range formatter.DiffNodes(a, b) {
+					record(name, change.Path, change.Old, change.New, variant.Name)
+				}
+			}
+		}
+	}
+
+	// Environments that did not change a field have the base value
+	for _, name := range names {
+		if len(rows[name]) == 0 {
+			continue
+		}
+		service := ServiceDifferences{Service: name}
+		for _, row := range rows[name] {
+			for _, environment := range comparison.Environments {
+				if _, ok := row.Values[environment]; !ok {
+					row.Values[environment] = row.Base
+				}
+			}
+			service.Fields = append(service.Fields, *row)
+		}
+		comparison.Services = append(comparison.Services, service)
+	}
+	return comparison, nil
+}
+
+// formattedRoot formats a compose file and returns its top-level mapping
+func formattedRoot(data []byte, opts formatter.Options) (*yaml.Node, error) {
+	formatted, err := New().Format(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := formatter.ParseDocuments(formatted)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 || len(docs[0].Content) == 0 || docs[0].Content[0].Kind != yaml.MappingNode {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return docs[0].Content[0], nil
+}
+
+// composeServices returns the names of the services of a compose file, in
+// order, and their definitions
+func composeServices(root *yaml.Node) ([]string, map[string]*yaml.Node) {
+	var names []string
+	definitions := make(map[string]*yaml.Node)
+	services := formatter.MappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return names, definitions
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		names = append(names, services.Content[i].Value)
+		definitions[services.Content[i].Value] = services.Content[i+1]
+	}
+	return names, definitions
+}
+
+// mergeOverlay merges an override file into base as docker compose does:
+// mappings are merged key by key, the lists in concatenatedFields are
+// appended to and other values are replaced. Neither node is modified.
+func mergeOverlay(base, overlay *yaml.Node, path []string) *yaml.Node {
+	switch {
+	case base == nil:
+		return overlay
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		merged := *base
+		merged.Content = append([]*yaml.Node(nil), base.Content...)
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			found := false
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if merged.Content[j].Value == key.Value {
+					merged.Content[j+1] = mergeOverlay(merged.Content[j+1], value, append(path[:len(path):len(path)], key.Value))
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Content = append(merged.Content, key, value)
+			}
+		}
+		return &merged
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && len(path) == 3 && path[0] == "services" && concatenatedFields[path[2]]:
+		merged := *base
+		merged.Content = append(append([]*yaml.Node(nil), base.Content...), overlay.Content...)
+		return &merged
+	}
+	return overlay
+}
@@ -1,11 +1,14 @@
 package dockercompose
 
 import (
-	"sort"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
-	"github.com/awsqed/config-formatter/formatter"
+	"github.com/awsqed/config-formatter/pkg/formatter"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,8 +18,15 @@ type DockerComposeFormatter struct {
 }
 
 // New creates a new DockerComposeFormatter
+// Top-level sections and services are separated by blank lines
 func New() *DockerComposeFormatter {
-	return &DockerComposeFormatter{}
+	return &DockerComposeFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}, {"services"}},
+			// Commands are argument lists
+			OrderSensitive: []string{"services.*.command", "services.*.entrypoint", "services.*.healthcheck.test"},
+		},
+	}
 }
 
 // Name returns the name of this formatter
@@ -27,14 +37,18 @@ func (f *DockerComposeFormatter) Name() string {
 // CanHandle checks if this file is a docker-compose file
 func (f *DockerComposeFormatter) CanHandle(filename string, data []byte) bool {
 	// Check filename patterns
-	if strings.Contains(filename, "docker-compose") ||
-		strings.Contains(filename, "compose.") ||
-		strings.HasSuffix(filename, "compose.yml") ||
-		strings.HasSuffix(filename, "compose.yaml") {
+	base := filepath.Base(filename)
+	if strings.Contains(base, "docker-compose") ||
+		strings.Contains(base, "compose.") ||
+		strings.HasSuffix(base, "compose.yml") ||
+		strings.HasSuffix(base, "compose.yaml") {
 		return true
 	}
 
 	// Check for docker-compose specific keys
+	if !formatter.MayHaveTopLevelKey(data, "services", "version") {
+		return false
+	}
 	var root yaml.Node
 	if err := yaml.Unmarshal(data, &root); err != nil {
 		return false
@@ -57,152 +71,174 @@ func (f *DockerComposeFormatter) CanHandle(filename string, data []byte) bool {
 }
 
 // Format formats a docker-compose YAML file with consistent indentation and ordering
-func (f *DockerComposeFormatter) Format(data []byte, indent int) ([]byte, error) {
-	return f.FormatYAML(data, indent, f.formatNode)
+func (f *DockerComposeFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+//
+// Services without a healthcheck that opts.HealthcheckPolicy covers get the
+// healthcheck their policy defines, when it defines one.
+//
+// A template that is only YAML once its ${VAR} placeholders are filled in is
+// formatted with the placeholders kept as opaque tokens.
+func (f *DockerComposeFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	format := func(data []byte) ([]byte, error) {
+		return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+			if isRoot {
+				insertHealthchecks(node, opts.HealthcheckPolicy)
+			}
+			f.formatNode(node, isRoot, opts)
+		})
+	}
+	formatted, err := format(data)
+	var parseErr *formatter.ParseError
+	if errors.As(err, &parseErr) && hasPlaceholders(data) {
+		if masked, placeholders := maskPlaceholders(data); placeholders != nil {
+			if formatted, maskedErr := format(masked); maskedErr == nil {
+				return unmaskPlaceholders(formatted, placeholders), nil
+			}
+		}
+	}
+	return formatted, err
+}
+
+// Lint reports problems in a docker-compose YAML file
+func (f *DockerComposeFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintOptions(data, formatter.DefaultOptions())
+}
+
+// LintOptions is Lint, with the host paths of opts.BindMountAllowlist
+// accepted as writable bind mounts, and the policy rules of CheckPolicy
+func (f *DockerComposeFormatter) LintOptions(data []byte, opts formatter.Options) ([]formatter.Issue, error) {
+	return f.lint(data, slices.Concat(rules, []formatter.Rule{{
+		ID: "compose/writable-sensitive-mount",
+		Check: func(root *yaml.Node) []formatter.Issue {
+			return checkWritableSensitiveMount(root, opts.BindMountAllowlist)
+		},
+	}}, policyRules(opts)))
+}
+
+// CheckPolicy reports the services opts.HealthcheckPolicy covers that have no
+// healthcheck, and the services without resource limits when
+// opts.RequireResourceLimits is set
+func (f *DockerComposeFormatter) CheckPolicy(data []byte, opts formatter.Options) ([]formatter.Issue, error) {
+	policy := policyRules(opts)
+	if len(policy) == 0 {
+		return nil, nil
+	}
+	return f.lint(data, policy)
+}
+
+// policyRules returns the rules of the policies opts turn on
+func policyRules(opts formatter.Options) []formatter.Rule {
+	var policy []formatter.Rule
+	if len(opts.HealthcheckPolicy) > 0 {
+		policy = append(policy, formatter.Rule{
+			ID: "compose/missing-healthcheck",
+			Check: func(root *yaml.Node) []formatter.Issue {
+				return checkMissingHealthcheck(root, opts.HealthcheckPolicy)
+			},
+		})
+	}
+	if opts.RequireResourceLimits {
+		policy = append(policy, formatter.Rule{
+			ID:    "compose/missing-resource-limits",
+			Check: checkMissingResourceLimits,
+		})
+	}
+	return policy
+}
+
+// lint runs rules on data; a template that is only YAML once its ${VAR}
+// placeholders are filled in is linted with the placeholders masked
+func (f *DockerComposeFormatter) lint(data []byte, rules []formatter.Rule) ([]formatter.Issue, error) {
+	issues, err := f.LintYAML(data, rules)
+	var parseErr *formatter.ParseError
+	if errors.As(err, &parseErr) && hasPlaceholders(data) {
+		if masked, placeholders := maskPlaceholders(data); placeholders != nil {
+			if maskedIssues, maskedErr := f.LintYAML(masked, rules); maskedErr == nil {
+				for i := range maskedIssues {
+					maskedIssues[i].Message = unmaskString(maskedIssues[i].Message, placeholders)
+				}
+				return maskedIssues, nil
+			}
+		}
+	}
+	return issues, err
 }
 
 // formatNode recursively formats nodes in the YAML tree
-func (f *DockerComposeFormatter) formatNode(node *yaml.Node, isRoot bool) {
-	f.formatNodeWithContext(node, isRoot, "")
+func (f *DockerComposeFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
 }
 
-// formatNodeWithContext recursively formats nodes with parent key tracking
-func (f *DockerComposeFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, parentKey string) {
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *DockerComposeFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
 	if node == nil {
 		return
 	}
 
 	// Process mapping nodes (objects)
-	if node.Kind == yaml.MappingNode {
-		f.sortMappingNode(node, isRoot)
+	// Services keep the order they were written in
+	if node.Kind == yaml.MappingNode && !(len(path) == 1 && path[0] == "services") {
+		f.sortMappingNode(node, isRoot, opts)
 	}
 
 	// Apply value normalization AFTER sorting, BEFORE recursion
-	f.normalizeValues(node, parentKey)
+	kind := node.Kind
+	if !opts.PreserveValues {
+		f.normalizeValues(node, path, opts)
+	}
+
+	// Values rebuilt by a normalizer (list to map, string to list) are already
+	// in their final form, and descending into them would normalize them again
+	if node.Kind != kind {
+		return
+	}
 
 	// Recursively format child nodes
 	// Check if this is the root document node
 	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
-		f.formatNodeWithContext(node.Content[0], true, "")
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
 		return
 	}
 
-	// For mapping nodes, track key names when recursing into values
+	// For mapping nodes, extend the path with key names when recursing into values
 	if node.Kind == yaml.MappingNode {
 		for i := 0; i < len(node.Content); i += 2 {
 			keyNode := node.Content[i]
 			valueNode := node.Content[i+1]
-			f.formatNodeWithContext(valueNode, false, keyNode.Value)
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
 		}
 	} else {
-		// For sequences and other nodes, don't update parent key
-		for _, child := range node.Content {
-			f.formatNodeWithContext(child, false, parentKey)
+		// Sequence items are addressed by index, matching formatter.Walk
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
 		}
 	}
 }
 
 // sortMappingNode sorts keys in a mapping node according to docker-compose conventions
-func (f *DockerComposeFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool) {
+func (f *DockerComposeFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, opts formatter.Options) {
 	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
 		return
 	}
 
-	// Create pairs of key-value nodes
-	type pair struct {
-		key         *yaml.Node
-		value       *yaml.Node
-		order       int
-		originalIdx int
-		hasComment  bool
-	}
-
-	var pairs []pair
-
-	for i := 0; i < len(node.Content); i += 2 {
-		keyNode := node.Content[i]
-		valueNode := node.Content[i+1]
-
-		hasComment := keyNode.HeadComment != "" || keyNode.LineComment != "" ||
-			keyNode.FootComment != "" || valueNode.HeadComment != ""
-
-		// Add empty line before each service entry if this is the services block
-		if isTopLevel && keyNode.Value == "services" && valueNode.Kind == yaml.MappingNode {
-			// Add empty line before each service entry
-			addServiceSpacing(valueNode)
-		}
-
-		pairs = append(pairs, pair{
-			key:         keyNode,
-			value:       valueNode,
-			order:       getKeyOrder(keyNode.Value, isTopLevel),
-			originalIdx: i,
-			hasComment:  hasComment,
-		})
-	}
-
-	// Sort pairs by order, then alphabetically, but keep commented blocks in original position
-	sort.SliceStable(pairs, func(i, j int) bool {
-		// If either pair has comments, preserve original order relative to each other
-		if pairs[i].hasComment || pairs[j].hasComment {
-			return pairs[i].originalIdx < pairs[j].originalIdx
-		}
-
-		if pairs[i].order != pairs[j].order {
-			return pairs[i].order < pairs[j].order
-		}
-		return pairs[i].key.Value < pairs[j].key.Value
-	})
-
-	// Add empty lines between top-level directives AFTER sorting
-	if isTopLevel {
-		for i := 1; i < len(pairs); i++ {
-			keyNode := pairs[i].key
-			if keyNode.HeadComment == "" {
-				keyNode.HeadComment = "\n"
-			} else if keyNode.HeadComment[0] != '\n' {
-				keyNode.HeadComment = "\n" + keyNode.HeadComment
-			}
-		}
-	}
-
-	// Rebuild the Content slice with sorted pairs
-	newContent := make([]*yaml.Node, 0, len(node.Content))
-	for _, p := range pairs {
-		newContent = append(newContent, p.key, p.value)
-	}
-	node.Content = newContent
-}
-
-// addServiceSpacing adds empty lines between service entries
-func addServiceSpacing(servicesNode *yaml.Node) {
-	if servicesNode.Kind != yaml.MappingNode || len(servicesNode.Content) == 0 {
+	if opts.PreserveKeyOrder {
 		return
 	}
-
-	// Add HeadComment with newline to each service key (except the first)
-	for i := 0; i < len(servicesNode.Content); i += 2 {
-		keyNode := servicesNode.Content[i]
-
-		// Add empty line before all services except the first
-		if i > 0 {
-			// If there's already a comment, prepend a newline
-			if keyNode.HeadComment != "" {
-				// Only add if it doesn't already start with newline
-				if keyNode.HeadComment[0] != '\n' {
-					keyNode.HeadComment = "\n" + keyNode.HeadComment
-				}
-			} else {
-				keyNode.HeadComment = "\n"
-			}
-		}
+	order := func(key string) int {
+		return getKeyOrder(key, isTopLevel)
 	}
+
+	formatter.SortMappingByRank(node, order)
 }
 
 // normalizeEnvironment converts environment array to map with smart quoting
-func (f *DockerComposeFormatter) normalizeEnvironment(node *yaml.Node) {
+func (f *DockerComposeFormatter) normalizeEnvironment(node *yaml.Node, opts formatter.Options) {
 	// Only process sequence nodes (arrays)
-	if node.Kind != yaml.SequenceNode {
+	if node.Kind != yaml.SequenceNode || slices.ContainsFunc(node.Content, interpolatedKey) {
 		return
 	}
 
@@ -259,7 +295,7 @@ func (f *DockerComposeFormatter) normalizeEnvironment(node *yaml.Node) {
 
 		// Apply smart quoting
 		if shouldQuoteValue(value) {
-			valueNode.Style = yaml.DoubleQuotedStyle
+			valueNode.Style = opts.QuoteStyle.NodeStyle()
 		} else {
 			valueNode.Style = 0 // Unquoted
 		}
@@ -270,8 +306,133 @@ func (f *DockerComposeFormatter) normalizeEnvironment(node *yaml.Node) {
 	node.Content = newContent
 }
 
+// normalizeAnnotations converts an annotations array to a map and sorts it by key
+// Unlike environment, a list with any malformed entry is left as written, since
+// dropping an annotation would silently change the container
+func (f *DockerComposeFormatter) normalizeAnnotations(node *yaml.Node, opts formatter.Options) {
+	if node.Kind == yaml.SequenceNode && !slices.ContainsFunc(node.Content, interpolatedKey) {
+		newContent := make([]*yaml.Node, 0, len(node.Content)*2)
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode || item.HeadComment != "" || item.LineComment != "" {
+				return
+			}
+			key, value, ok := parseEnvVar(item.Value)
+			if !ok {
+				return
+			}
+
+			valueNode := &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Tag:   "!!str",
+				Value: value,
+			}
+			if shouldQuoteValue(value) {
+				valueNode.Style = opts.QuoteStyle.NodeStyle()
+			}
+
+			newContent = append(newContent, &yaml.Node{
+				Kind:  yaml.ScalarNode,
+				Tag:   "!!str",
+				Value: key,
+			}, valueNode)
+		}
+
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		node.Style = 0
+		node.Content = newContent
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	// Sort annotation keys alphabetically; comments move with their keys
+	formatter.SortMapping(node, strings.Compare)
+}
+
+// normalizeStringOrList writes fields that accept a string or a list of strings
+// in one consistent shape: a list, or a plain string for single-item lists when
+// CollapseSingleItemLists is set
+func (f *DockerComposeFormatter) normalizeStringOrList(node *yaml.Node, opts formatter.Options) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if opts.CollapseSingleItemLists || node.Tag == "!!null" {
+			return
+		}
+		item := *node
+		item.HeadComment, item.LineComment, item.FootComment = "", "", ""
+		node.Kind = yaml.SequenceNode
+		node.Tag = "!!seq"
+		node.Style = 0
+		node.Value = ""
+		node.Content = []*yaml.Node{&item}
+	case yaml.SequenceNode:
+		if !opts.CollapseSingleItemLists || len(node.Content) != 1 {
+			return
+		}
+		item := node.Content[0]
+		if item.Kind != yaml.ScalarNode || item.HeadComment != "" || item.LineComment != "" {
+			return
+		}
+		node.Kind = yaml.ScalarNode
+		node.Tag = item.Tag
+		node.Style = item.Style
+		node.Value = item.Value
+		node.Content = nil
+	}
+}
+
+// normalizeBuild writes a build section in the form opts.BuildForm asks for:
+// build: ./dir expanded to a mapping with a context key, or a mapping holding
+// only a context written as its path
+func (f *DockerComposeFormatter) normalizeBuild(node *yaml.Node, opts formatter.Options) {
+	switch {
+	case opts.BuildForm == formatter.BuildFormLong && node.Kind == yaml.ScalarNode && node.Tag != "!!null":
+		// A comment after the path stays on its line
+		value := *node
+		value.HeadComment, value.FootComment = "", ""
+		node.LineComment = ""
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		node.Style = 0
+		node.Value = ""
+		node.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "context"}, &value}
+	case opts.BuildForm == formatter.BuildFormShort && node.Kind == yaml.MappingNode && len(node.Content) == 2:
+		key, value := node.Content[0], node.Content[1]
+		if key.Value != "context" || value.Kind != yaml.ScalarNode || key.HeadComment != "" || key.LineComment != "" {
+			return
+		}
+		if value.LineComment != "" {
+			node.LineComment = value.LineComment
+		}
+		node.Kind = yaml.ScalarNode
+		node.Tag = value.Tag
+		node.Style = value.Style
+		node.Value = value.Value
+		node.Content = nil
+	}
+}
+
+// normalizeBool writes boolean-like scalars as plain true/false
+func (f *DockerComposeFormatter) normalizeBool(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	switch strings.ToLower(node.Value) {
+	case "true":
+		node.Value = "true"
+	case "false":
+		node.Value = "false"
+	default:
+		return
+	}
+	node.Tag = "!!bool"
+	node.Style = 0
+}
+
 // normalizePorts ensures all port strings are quoted
-func (f *DockerComposeFormatter) normalizePorts(node *yaml.Node) {
+func (f *DockerComposeFormatter) normalizePorts(node *yaml.Node, opts formatter.Options) {
 	// Only process sequence nodes (arrays)
 	if node.Kind != yaml.SequenceNode {
 		return
@@ -282,20 +443,80 @@ func (f *DockerComposeFormatter) normalizePorts(node *yaml.Node) {
 		if item.Kind == yaml.ScalarNode {
 			// Ensure it's tagged as string and quoted
 			item.Tag = "!!str"
-			item.Style = yaml.DoubleQuotedStyle
+			item.Style = opts.QuoteStyle.NodeStyle()
 		}
 	}
 }
 
-// normalizeValues dispatches to specific normalizers based on parent key
-func (f *DockerComposeFormatter) normalizeValues(node *yaml.Node, parentKey string) {
+// normalizeValues dispatches to specific normalizers based on the key path
+func (f *DockerComposeFormatter) normalizeValues(node *yaml.Node, path []string, opts formatter.Options) {
+	parentKey := ""
+	if len(path) > 0 {
+		parentKey = path[len(path)-1]
+	}
+
 	switch parentKey {
 	case "environment":
-		f.normalizeEnvironment(node)
+		f.normalizeEnvironment(node, opts)
 	case "ports":
-		f.normalizePorts(node)
+		f.normalizePorts(node, opts)
 		// Add other cases as needed
 	}
+
+	// Service-level fields, matched by path so that identically named keys
+	// elsewhere (environment variables, labels, x- extensions) are left alone
+	if isServiceField(path) {
+		switch parentKey {
+		case "annotations":
+			f.normalizeAnnotations(node, opts)
+		case "label_file":
+			f.normalizeStringOrList(node, opts)
+		case "build":
+			f.normalizeBuild(node, opts)
+		case "attach":
+			f.normalizeBool(node)
+		}
+	}
+
+	if isDurationField(path) {
+		formatter.NormalizeDurationNode(node)
+	}
+}
+
+// isServiceField reports whether path points at a field of a service (services.<name>.<field>)
+func isServiceField(path []string) bool {
+	return len(path) == 3 && path[0] == "services"
+}
+
+// isDurationField reports whether path points at a field holding a duration
+func isDurationField(path []string) bool {
+	if len(path) < 3 || path[0] != "services" {
+		return false
+	}
+
+	field := path[len(path)-1]
+	switch len(path) {
+	case 3:
+		// services.<name>.stop_grace_period
+		return field == "stop_grace_period"
+	case 4:
+		// services.<name>.healthcheck.<field>
+		if path[2] == "healthcheck" {
+			return field == "interval" || field == "timeout" || field == "start_period" || field == "start_interval"
+		}
+	case 5:
+		// services.<name>.deploy.<policy>.<field>
+		if path[2] != "deploy" {
+			return false
+		}
+		switch path[3] {
+		case "restart_policy":
+			return field == "delay" || field == "window"
+		case "update_config", "rollback_config":
+			return field == "delay" || field == "monitor"
+		}
+	}
+	return false
 }
 
 // parseEnvVar splits "KEY=VALUE" into key and value
@@ -351,6 +572,8 @@ func shouldQuoteValue(value string) bool {
 	}
 
 	// Quote if contains special YAML characters
+	// '$' is deliberately absent: "$$" escapes and ${VAR} references must be
+	// emitted exactly as written, and a plain '$' never needs YAML quoting
 	specialChars := "{}[],:&*#?|-<>=!%@\\"
 	if strings.ContainsAny(value, specialChars) {
 		return true
@@ -0,0 +1,298 @@
+package dockercompose
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+func TestShouldQuoteValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", true},
+		{"plain", false},
+		{"two words", true},
+		{"8080", true},
+		{"yes", true},
+		{"a:b", true},
+		// "$$" escapes and bare references need no YAML quoting
+		{"$$HOME", false},
+		{"a$$b", false},
+		{"$HOME", false},
+		// Braced references are quoted for their braces, not their dollar
+		{"${VAR}", true},
+		{"$${VAR}", true},
+		{"${VAR:-x y}", true},
+	}
+	for _, tt := range tests {
+		if got := shouldQuoteValue(tt.value); got != tt.want {
+			t.Errorf("shouldQuoteValue(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestDollarRoundTrip checks that dollar escapes and interpolation references
+// come out of formatting exactly as written, in every scalar style, and where
+// normalization rewrites the node holding them
+func TestDollarRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		quote formatter.QuoteStyle
+		input string
+		// want is the formatted file; empty when it is the input
+		want string
+	}{
+		{
+			name: "label styles",
+			input: `services:
+  web:
+    image: nginx
+    labels:
+      double: "$${x}"
+      literal: |
+        echo $$HOME
+      plain: $$x
+      reference: ${VAR}
+      single: '$$x'
+      traefik.http.middlewares.auth.basicauth.users: "admin:$$apr1$$H6uskkkW$$IgXLP6ewTrSuBkTrqE8wj/"
+`,
+		},
+		{
+			name: "label list",
+			input: `services:
+  web:
+    image: nginx
+    labels:
+      - "traefik.http.middlewares.auth.basicauth.users=admin:$$apr1$$H6uskkkW$$IgXLP6ewTrSuBkTrqE8wj/"
+      - plain=$$x
+`,
+		},
+		{
+			name: "command",
+			input: `services:
+  web:
+    image: nginx
+    command: echo $$HOME $${USER} ${TAG}
+`,
+		},
+		{
+			name: "environment mapping",
+			input: `services:
+  web:
+    image: nginx
+    environment:
+      BRACED: "$${VAR}"
+      DEFAULT: "${VAR:-x y}"
+      ESCAPED: $$HOME
+      PLAIN: a$$b
+      REF: "${VAR}"
+`,
+		},
+		{
+			name: "environment list to mapping",
+			input: `services:
+  web:
+    image: nginx
+    environment:
+      - "BRACED=$${VAR}"
+      - DEFAULT=${VAR:-x y}
+      - 'ESCAPED=$$HOME'
+      - PLAIN=a$$b
+      - REF=${VAR}
+`,
+			want: `services:
+  web:
+    image: nginx
+    environment:
+      BRACED: "$${VAR}"
+      DEFAULT: "${VAR:-x y}"
+      ESCAPED: $$HOME
+      PLAIN: a$$b
+      REF: "${VAR}"
+`,
+		},
+		{
+			name:  "environment list to mapping, single quotes",
+			quote: formatter.QuoteSingle,
+			input: `services:
+  web:
+    image: nginx
+    environment:
+      - BRACED=$${VAR}
+      - ESCAPED=$$HOME
+      - REF=${VAR}
+`,
+			want: `services:
+  web:
+    image: nginx
+    environment:
+      BRACED: '$${VAR}'
+      ESCAPED: $$HOME
+      REF: '${VAR}'
+`,
+		},
+	}
+
+	f := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := formatter.DefaultOptions()
+			if tt.quote != "" {
+				opts.QuoteStyle = tt.quote
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.input
+			}
+
+			got, err := f.Format([]byte(tt.input), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+			}
+
+			again, err := f.Format(got, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("Format() is not idempotent, second pass =\n%s", again)
+			}
+		})
+	}
+}
+
+// TestCheckPolicy checks that the resource limits policy is only enforced
+// when it is turned on, and accepts both ways of setting limits
+func TestCheckPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		require bool
+		// rules are the rules of the issues reported, in order
+		rules []string
+	}{
+		{
+			name: "policy off",
+			input: `services:
+  web:
+    image: nginx
+`,
+		},
+		{
+			name: "missing limits",
+			input: `services:
+  web:
+    image: nginx
+`,
+			require: true,
+			rules:   []string{"compose/missing-resource-limits"},
+		},
+		{
+			name: "legacy keys",
+			input: `services:
+  web:
+    image: nginx
+    cpus: 0.5
+    mem_limit: 256m
+`,
+			require: true,
+		},
+		{
+			name: "deploy limits",
+			input: `services:
+  web:
+    image: nginx
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 256M
+`,
+			require: true,
+		},
+	}
+
+	f := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := formatter.DefaultOptions()
+			opts.RequireResourceLimits = tt.require
+			issues, err := formatter.CheckPolicy(f, []byte(tt.input), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rules []string
+			for _, issue := range issues {
+				rules = append(rules, issue.Rule)
+			}
+			if !slices.Equal(rules, tt.rules) {
+				t.Errorf("CheckPolicy() reported %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+// TestCheckPolicyHealthcheck checks that services the healthcheck policy
+// covers need a healthcheck, and that disabling it counts as one
+func TestCheckPolicyHealthcheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{
+			name: "missing",
+			input: `services:
+  db:
+    image: postgres:16
+  web:
+    image: nginx
+`,
+			want: 1,
+		},
+		{
+			name: "defined",
+			input: `services:
+  db:
+    healthcheck:
+      test: [CMD-SHELL, pg_isready]
+    image: postgres:16
+`,
+		},
+		{
+			name: "disabled",
+			input: `services:
+  db:
+    healthcheck:
+      disable: true
+    image: postgres
+`,
+		},
+	}
+
+	f := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := formatter.DefaultOptions()
+			opts.HealthcheckPolicy = []formatter.HealthcheckPolicy{{Image: "postgres"}}
+			issues, err := formatter.CheckPolicy(f, []byte(tt.input), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != tt.want {
+				t.Errorf("CheckPolicy() reported %v, want %d issues", issues, tt.want)
+			}
+			for _, issue := range issues {
+				if issue.Rule != "compose/missing-healthcheck" {
+					t.Errorf("issue %v is not from compose/missing-healthcheck", issue)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package dockercompose
+
+import (
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Document summarizes the services of a compose file as a markdown table:
+// each service with its image, published ports and dependencies
+func (f *DockerComposeFormatter) Document(data []byte) (string, error) {
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return "", err
+	}
+
+	var rows [][]string
+	for _, doc := range docs {
+		if len(doc.Content) == 0 {
+			continue
+		}
+		services := formatter.MappingValue(doc.Content[0], "services")
+		if services == nil || services.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			name, service := services.Content[i].Value, services.Content[i+1]
+			if service.Kind != yaml.MappingNode {
+				continue
+			}
+			rows = append(rows, []string{name, serviceImage(service), strings.Join(servicePorts(service), ", "), strings.Join(serviceDependencies(service), ", ")})
+		}
+	}
+	return formatter.MarkdownSection("Services", []string{"Service", "Image", "Ports", "Depends on"}, rows), nil
+}
+
+// serviceImage returns the image of a service, or the build context for a
+// service built without an image name
+func serviceImage(service *yaml.Node) string {
+	if image := formatter.MappingValue(service, "image"); image != nil {
+		return image.Value
+	}
+	build := formatter.MappingValue(service, "build")
+	switch {
+	case build == nil:
+		return ""
+	case build.Kind == yaml.ScalarNode:
+		return "build: " + build.Value
+	case formatter.MappingValue(build, "context") != nil:
+		return "build: " + formatter.MappingValue(build, "context").Value
+	}
+	return "build: ."
+}
+
+// servicePorts returns the ports of a service as published:target/protocol,
+// whichever syntax they are written in
+func servicePorts(service *yaml.Node) []string {
+	ports := formatter.MappingValue(service, "ports")
+	if ports == nil || ports.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var result []string
+	for _, port := range ports.Content {
+		if port.Kind == yaml.ScalarNode {
+			result = append(result, port.Value)
+			continue
+		}
+		target := formatter.MappingValue(port, "target")
+		if target == nil {
+			continue
+		}
+		text := target.Value
+		if published := formatter.MappingValue(port, "published"); published != nil {
+			text = published.Value + ":" + text
+			if hostIP := formatter.MappingValue(port, "host_ip"); hostIP != nil {
+				text = hostIP.Value + ":" + text
+			}
+		}
+		if protocol := formatter.MappingValue(port, "protocol"); protocol != nil && protocol.Value != "tcp" {
+			text += "/" + protocol.Value
+		}
+		result = append(result, text)
+	}
+	return result
+}
+
+// serviceDependencies returns the services a service depends on, in either
+// depends_on syntax
+func serviceDependencies(service *yaml.Node) []string {
+	dependsOn := formatter.MappingValue(service, "depends_on")
+	if dependsOn == nil {
+		return nil
+	}
+	var names []string
+	switch dependsOn.Kind {
+	case yaml.SequenceNode:
+		for _, item := range dependsOn.Content {
+			names = append(names, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(dependsOn.Content); i += 2 {
+			names = append(names, dependsOn.Content[i].Value)
+		}
+	}
+	return names
+}
@@ -0,0 +1,167 @@
+package dockercompose
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Variable is an environment variable referenced by a compose project
+type Variable struct {
+	Name string `json:"name"`
+
+	// Services lists the services referencing the variable, sorted; references
+	// outside services (networks, volumes, ...) are only in References
+	Services []string `json:"services"`
+
+	// HasDefault is true when every reference provides a default value, so
+	// the project works without the variable being set
+	HasDefault bool `json:"has_default"`
+
+	// Defaults lists the distinct default values given, in order of appearance
+	Defaults []string `json:"defaults,omitempty"`
+
+	// Required is true when some reference fails without the variable (${VAR:?err})
+	Required bool `json:"required"`
+
+	// InEnvFile is true when the .env file sets the variable
+	InEnvFile bool `json:"in_env_file"`
+
+	References []VariableReference `json:"references"`
+}
+
+// VariableReference is where a variable is referenced
+type VariableReference struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Service string `json:"service,omitempty"`
+}
+
+// EnvReport lists every variable referenced by the compose files in dir,
+// sorted by name. The files are those ValidateProject checks. envFile is the
+// .env file to look variables up in; a missing file counts as empty.
+func EnvReport(dir, envFile string) ([]*Variable, error) {
+	files, err := projectFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := readEnvFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*Variable)
+	defaulted := make(map[string]int)
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		formatter.Walk(&root, func(keyPath []string, node *yaml.Node) {
+			if node.Kind != yaml.ScalarNode {
+				return
+			}
+			service := ""
+			if len(keyPath) >= 2 && keyPath[0] == "services" {
+				service = keyPath[1]
+			}
+
+			for _, ref := range parseInterpolation(node.Value).Refs {
+				variable, ok := byName[ref.Name]
+				if !ok {
+					_, inEnvFile := env[ref.Name]
+					variable = &Variable{Name: ref.Name, Services: []string{}, InEnvFile: inEnvFile}
+					byName[ref.Name] = variable
+				}
+				variable.References = append(variable.References, VariableReference{
+					File:    file,
+					Line:    node.Line,
+					Column:  node.Column,
+					Service: service,
+				})
+				if service != "" && !slices.Contains(variable.Services, service) {
+					variable.Services = append(variable.Services, service)
+				}
+				if ref.HasDefault() {
+					defaulted[ref.Name]++
+					if !slices.Contains(variable.Defaults, ref.Argument) {
+						variable.Defaults = append(variable.Defaults, ref.Argument)
+					}
+				}
+				if ref.Operator == ":?" || ref.Operator == "?" {
+					variable.Required = true
+				}
+			}
+		})
+	}
+
+	variables := make([]*Variable, 0, len(byName))
+	for _, variable := range byName {
+		sort.Strings(variable.Services)
+		variable.HasDefault = defaulted[variable.Name] == len(variable.References)
+		variables = append(variables, variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables, nil
+}
+
+// readEnvFile returns the variables a .env file sets, with their values
+// Lines are NAME=value, optionally prefixed with "export"; blank lines and
+// comments are skipped. Quotes around a value are removed, as is a comment
+// after an unquoted one.
+func readEnvFile(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		if name = strings.TrimSpace(name); ok && name != "" {
+			values[name] = envValue(strings.TrimSpace(value))
+		}
+	}
+	return values, scanner.Err()
+}
+
+// envValue returns the value of a .env assignment without its quotes, or
+// without the comment after it when it is not quoted
+func envValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if before, _, found := strings.Cut(value, " #"); found {
+		value = strings.TrimSpace(before)
+	}
+	return value
+}
@@ -0,0 +1,314 @@
+package dockercompose
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// mappedFields are the service fields compose accepts as a KEY=VALUE list
+// or a mapping and merges key by key when a service extends another
+var mappedFields = []string{"environment", "labels", "annotations"}
+
+// ResolveExtends returns a compose file with the extends of every service
+// merged in, as docker compose merges them: the service's own settings
+// override those of the service it extends, mappings are merged key by key,
+// the lists of concatenatedFields are appended to, volumes are merged by
+// mount target, and anything else is replaced. Services extended from other
+// files have their relative paths rebased onto dir, the directory of the
+// file, and extends chains are followed to their end.
+func ResolveExtends(data []byte, dir string) ([]byte, error) {
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 || len(docs[0].Content) == 0 {
+		return data, nil
+	}
+	root := docs[0]
+	services := formatter.MappingValue(root.Content[0], "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return data, nil
+	}
+
+	r := &extendsResolver{files: map[string]*yaml.Node{"": services}, dir: dir}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		resolved, err := r.resolve("", services.Content[i].Value, nil)
+		if err != nil {
+			return nil, err
+		}
+		services.Content[i+1] = resolved
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// extendsResolver loads the files services extend, each once
+type extendsResolver struct {
+	// files holds the services mapping of each file by path; "" is the file
+	// being resolved
+	files map[string]*yaml.Node
+	dir   string
+}
+
+// fileDir returns the directory the relative paths of a file are resolved
+// from
+func (r *extendsResolver) fileDir(file string) string {
+	if file == "" {
+		return r.dir
+	}
+	return filepath.Dir(file)
+}
+
+// services returns the services mapping of file, loading it if needed
+func (r *extendsResolver) services(file string) (*yaml.Node, error) {
+	if services, ok := r.files[file]; ok {
+		return services, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := formatter.ParseDocuments(data)
+	if parseErr, ok := err.(*formatter.ParseError); ok {
+		parseErr.File = file
+	}
+	if err != nil {
+		return nil, err
+	}
+	var services *yaml.Node
+	if len(docs) > 0 && len(docs[0].Content) > 0 {
+		services = formatter.MappingValue(docs[0].Content[0], "services")
+	}
+	r.files[file] = services
+	return services, nil
+}
+
+// resolve returns the definition of service name in file with its extends
+// merged in; chain holds the services being resolved, to report cycles
+func (r *extendsResolver) resolve(file, name string, chain []string) (*yaml.Node, error) {
+	id := name
+	if file != "" {
+		id = file + ":" + name
+	}
+	if slices.Contains(chain, id) {
+		return nil, fmt.Errorf("extends cycle: %s", strings.Join(append(chain, id), " -> "))
+	}
+
+	services, err := r.services(file)
+	if err != nil {
+		return nil, err
+	}
+	definition := formatter.MappingValue(services, name)
+	if definition == nil {
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("service %q not found", name)
+		}
+		return nil, fmt.Errorf("service %q extends %q, which is not defined", chain[len(chain)-1], id)
+	}
+	extends := formatter.MappingValue(definition, "extends")
+	if extends == nil || definition.Kind != yaml.MappingNode {
+		return definition, nil
+	}
+
+	baseFile, baseName := file, extends.Value
+	if extends.Kind == yaml.MappingNode {
+		baseName = ""
+		if service := formatter.MappingValue(extends, "service"); service != nil {
+			baseName = service.Value
+		}
+		if path := formatter.MappingValue(extends, "file"); path != nil && path.Value != "" {
+			baseFile = filepath.Join(r.fileDir(file), path.Value)
+		}
+	}
+	if baseName == "" {
+		return nil, fmt.Errorf("service %q: extends needs a service", name)
+	}
+
+	base, err := r.resolve(baseFile, baseName, append(chain, id))
+	if err != nil {
+		return nil, err
+	}
+	base = cloneNode(base)
+	if baseDir, dir := r.fileDir(baseFile), r.fileDir(file); baseDir != dir {
+		rebasePaths(base, baseDir, dir)
+	}
+
+	local := *definition
+	local.Content = nil
+	for i := 0; i+1 < len(definition.Content); i += 2 {
+		if definition.Content[i].Value != "extends" {
+			local.Content = append(local.Content, definition.Content[i], definition.Content[i+1])
+		}
+	}
+	return mergeExtends(base, &local, name), nil
+}
+
+// mergeExtends merges the definition of a service onto the service it
+// extends
+func mergeExtends(base, local *yaml.Node, name string) *yaml.Node {
+	for _, field := range mappedFields {
+		setMappingValue(base, field, listToMapping(formatter.MappingValue(base, field)))
+		setMappingValue(local, field, listToMapping(formatter.MappingValue(local, field)))
+	}
+
+	// Mounts of the service replace those of the base on the same target
+	baseVolumes, localVolumes := formatter.MappingValue(base, "volumes"), formatter.MappingValue(local, "volumes")
+	if baseVolumes != nil && localVolumes != nil && baseVolumes.Kind == yaml.SequenceNode && localVolumes.Kind == yaml.SequenceNode {
+		merged := *localVolumes
+		merged.Content = nil
+		for _, item := range baseVolumes.Content {
+			target := mountTarget(item)
+			if target == "" || !slices.ContainsFunc(localVolumes.Content, func(n *yaml.Node) bool { return mountTarget(n) == target }) {
+				merged.Content = append(merged.Content, item)
+			}
+		}
+		merged.Content = append(merged.Content, localVolumes.Content...)
+		setMappingValue(local, "volumes", &merged)
+	}
+
+	return mergeOverlay(base, local, []string{"services", name})
+}
+
+// listToMapping turns a KEY=VALUE list into a mapping; a KEY without a value
+// becomes a key with a null value, which compose reads the same way. Other
+// nodes are returned as they are.
+func listToMapping(node *yaml.Node) *yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return node
+	}
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: node.HeadComment, LineComment: node.LineComment}
+	for _, item := range node.Content {
+		key, value, ok := strings.Cut(item.Value, "=")
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, HeadComment: item.HeadComment, LineComment: item.LineComment}
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		if !ok {
+			valueNode.Tag = "!!null"
+		}
+		mapping.Content = append(mapping.Content, keyNode, valueNode)
+	}
+	return mapping
+}
+
+// setMappingValue replaces the value of key in a mapping; nothing is done
+// when the key is not there
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+}
+
+// mountTarget returns the container path of a volume in short or long
+// syntax, or "" when it cannot be told
+func mountTarget(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(node.Value, ":")
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return parts[1]
+	case yaml.MappingNode:
+		if target := formatter.MappingValue(node, "target"); target != nil {
+			return target.Value
+		}
+	}
+	return ""
+}
+
+// rebasePaths rewrites the relative paths of a service defined in a file in
+// from so they stay correct in a file in to: the build context, env files
+// and the sources of bind mounts
+func rebasePaths(service *yaml.Node, from, to string) {
+	// Build contexts and env files are paths unless absolute or a URL;
+	// volume sources only when they start with "." (isRelativePath)
+	rebase := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || filepath.IsAbs(node.Value) ||
+			strings.Contains(node.Value, "://") || strings.HasPrefix(node.Value, "git@") || strings.HasPrefix(node.Value, "~") {
+			return
+		}
+		path, err := filepath.Rel(to, filepath.Join(from, node.Value))
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(path, ".") {
+			path = "./" + path
+		}
+		node.Value = filepath.ToSlash(path)
+	}
+
+	if build := formatter.MappingValue(service, "build"); build != nil {
+		if build.Kind == yaml.ScalarNode {
+			rebase(build)
+		} else {
+			rebase(formatter.MappingValue(build, "context"))
+		}
+	}
+	if envFile := formatter.MappingValue(service, "env_file"); envFile != nil {
+		if envFile.Kind == yaml.ScalarNode {
+			rebase(envFile)
+		}
+		for _, item := range envFile.Content {
+			if item.Kind == yaml.MappingNode {
+				rebase(formatter.MappingValue(item, "path"))
+			} else {
+				rebase(item)
+			}
+		}
+	}
+	if volumes := formatter.MappingValue(service, "volumes"); volumes != nil {
+		for _, item := range volumes.Content {
+			switch item.Kind {
+			case yaml.ScalarNode:
+				source, rest, ok := strings.Cut(item.Value, ":")
+				if ok && isRelativePath(source) {
+					node := &yaml.Node{Kind: yaml.ScalarNode, Value: source}
+					rebase(node)
+					item.Value = node.Value + ":" + rest
+				}
+			case yaml.MappingNode:
+				if source := formatter.MappingValue(item, "source"); source != nil && isRelativePath(source.Value) {
+					rebase(source)
+				}
+			}
+		}
+	}
+}
+
+// isRelativePath reports whether a volume source is a path relative to the
+// file rather than a volume name or an absolute path
+func isRelativePath(path string) bool {
+	return path == "." || path == ".." || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+}
+
+// cloneNode returns a deep copy of a node, so merged services share no nodes
+// that formatting one of them would rewrite
+func cloneNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	clone := *node
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = cloneNode(child)
+	}
+	return &clone
+}
@@ -0,0 +1,90 @@
+package dockercompose
+
+import (
+	"path"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// healthcheckPolicy returns the first policy whose pattern matches image,
+// written with or without its tag and digest, or nil
+func healthcheckPolicy(policies []formatter.HealthcheckPolicy, image string) *formatter.HealthcheckPolicy {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	for i, policy := range policies {
+		if ok, _ := path.Match(policy.Image, image); ok {
+			return &policies[i]
+		}
+		if ok, _ := path.Match(policy.Image, name); ok {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// forEachUncheckedService calls fn with the services a healthcheck policy
+// applies to that have no healthcheck; healthcheck: {disable: true} counts as
+// one. Services extending another are skipped, as they may inherit theirs.
+func forEachUncheckedService(root *yaml.Node, policies []formatter.HealthcheckPolicy, fn func(name string, service *yaml.Node, policy *formatter.HealthcheckPolicy)) {
+	if len(policies) == 0 {
+		return
+	}
+	forEachService(root, func(name string, service *yaml.Node) {
+		image := formatter.MappingValue(service, "image")
+		if image == nil || image.Kind != yaml.ScalarNode || mappingKey(service, "healthcheck") != nil || mappingKey(service, "extends") != nil {
+			return
+		}
+		if policy := healthcheckPolicy(policies, image.Value); policy != nil {
+			fn(name, service, policy)
+		}
+	})
+}
+
+// checkMissingHealthcheck flags services whose image a healthcheck policy
+// matches and that neither define a healthcheck nor disable it
+func checkMissingHealthcheck(root *yaml.Node, policies []formatter.HealthcheckPolicy) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachUncheckedService(root, policies, func(name string, service *yaml.Node, policy *formatter.HealthcheckPolicy) {
+		key := mappingKey(formatter.MappingValue(root.Content[0], "services"), name)
+		if policy.Healthcheck != nil {
+			issues = append(issues, formatter.NewIssue(key, "service %s has no healthcheck, which healthcheck_policy requires for %s images; formatting adds the one it defines", name, policy.Image))
+		} else {
+			issues = append(issues, formatter.NewIssue(key, "service %s has no healthcheck, which healthcheck_policy requires for %s images; add one, or healthcheck: {disable: true}", name, policy.Image))
+		}
+	})
+
+	return issues
+}
+
+// insertHealthchecks adds the healthcheck of the matching policy to services
+// without one, for the policies that define a healthcheck
+func insertHealthchecks(root *yaml.Node, policies []formatter.HealthcheckPolicy) {
+	forEachUncheckedService(root, policies, func(name string, service *yaml.Node, policy *formatter.HealthcheckPolicy) {
+		if policy.Healthcheck == nil {
+			return
+		}
+		service.Content = append(service.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "healthcheck"},
+			detachedClone(policy.Healthcheck))
+	})
+}
+
+// detachedClone copies a node from the config file, dropping its position so
+// it is not taken for a line of the file being formatted
+func detachedClone(node *yaml.Node) *yaml.Node {
+	clone := cloneNode(node)
+	var detach func(node *yaml.Node)
+	detach = func(node *yaml.Node) {
+		node.Line, node.Column = 0, 0
+		for _, child := range node.Content {
+			detach(child)
+		}
+	}
+	detach(clone)
+	return clone
+}
@@ -0,0 +1,158 @@
+package dockercompose
+
+import "strings"
+
+// variableRef is a single ${VAR} or $VAR reference found in a compose value
+type variableRef struct {
+	// Name is the referenced variable name
+	Name string
+
+	// Braced is true for ${VAR} references and false for $VAR
+	Braced bool
+
+	// Operator is the modifier used inside braces (":-", "-", ":?", "?", ":+", "+"), if any
+	Operator string
+
+	// Argument is the text following the operator, e.g. the default value
+	Argument string
+
+	// Offset is the byte offset of the leading '$' in the value
+	Offset int
+}
+
+// HasDefault reports whether the reference provides a fallback value
+func (r variableRef) HasDefault() bool {
+	return r.Operator == ":-" || r.Operator == "-"
+}
+
+// interpolation is the result of scanning a compose value for variable references
+type interpolation struct {
+	// Refs lists well-formed variable references in order of appearance,
+	// including references nested inside default values
+	Refs []variableRef
+
+	// Invalid lists byte offsets of '$' characters compose cannot interpret
+	Invalid []int
+}
+
+// interpolationOperators are the modifiers allowed after a braced variable name,
+// longest first so ":-" wins over "-"
+var interpolationOperators = []string{":-", ":?", ":+", "-", "?", "+"}
+
+// parseInterpolation scans a value using compose interpolation rules
+// "$$" is an escaped dollar sign and is never reported
+func parseInterpolation(value string) interpolation {
+	var result interpolation
+	scanInterpolation(value, 0, &result)
+	return result
+}
+
+// scanInterpolation scans value, reporting offsets relative to base
+func scanInterpolation(value string, base int, result *interpolation) {
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			continue
+		}
+
+		// Escaped dollar sign
+		if i+1 < len(value) && value[i+1] == '$' {
+			i++
+			continue
+		}
+
+		// Unbraced $VAR
+		if i+1 < len(value) && isNameStart(value[i+1]) {
+			end := i + 2
+			for end < len(value) && isNameChar(value[end]) {
+				end++
+			}
+			result.Refs = append(result.Refs, variableRef{Name: value[i+1 : end], Offset: base + i})
+			i = end - 1
+			continue
+		}
+
+		// Braced ${VAR...}
+		if i+1 < len(value) && value[i+1] == '{' {
+			closing := matchingBrace(value, i+1)
+			if closing == -1 {
+				result.Invalid = append(result.Invalid, base+i)
+				continue
+			}
+
+			body := value[i+2 : closing]
+			ref, ok := parseBraced(body)
+			if !ok {
+				result.Invalid = append(result.Invalid, base+i)
+				i = closing
+				continue
+			}
+			ref.Offset = base + i
+			result.Refs = append(result.Refs, ref)
+
+			// Defaults and replacements may themselves contain references
+			if ref.Operator != "" {
+				argStart := i + 2 + len(ref.Name) + len(ref.Operator)
+				scanInterpolation(ref.Argument, base+argStart, result)
+			}
+			i = closing
+			continue
+		}
+
+		// Anything else (trailing '$', "$1", "$-") is rejected by compose
+		result.Invalid = append(result.Invalid, base+i)
+	}
+}
+
+// parseBraced parses the inside of ${...}
+func parseBraced(body string) (variableRef, bool) {
+	if body == "" || !isNameStart(body[0]) {
+		return variableRef{}, false
+	}
+
+	end := 1
+	for end < len(body) && isNameChar(body[end]) {
+		end++
+	}
+	ref := variableRef{Name: body[:end], Braced: true}
+
+	rest := body[end:]
+	if rest == "" {
+		return ref, true
+	}
+	for _, op := range interpolationOperators {
+		if strings.HasPrefix(rest, op) {
+			ref.Operator = op
+			ref.Argument = rest[len(op):]
+			return ref, true
+		}
+	}
+
+	return variableRef{}, false
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1
+func matchingBrace(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isNameStart reports whether c may start a variable name
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameChar reports whether c may appear in a variable name
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,80 @@
+package dockercompose
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// SetLabels adds labels to a service of a compose file, as key and value
+// pairs, after removing the labels replaced reports true for. The labels
+// keep the form the service writes them in, a mapping or a KEY=VALUE list;
+// a service without labels gets a mapping. The file is returned unformatted.
+func SetLabels(data []byte, service string, labels [][2]string, replaced func(key string) bool) ([]byte, error) {
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	var definition *yaml.Node
+	if len(docs) > 0 && len(docs[0].Content) > 0 {
+		definition = formatter.MappingValue(formatter.MappingValue(docs[0].Content[0], "services"), service)
+	}
+	if definition == nil || definition.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("service %q not found", service)
+	}
+
+	node := formatter.MappingValue(definition, "labels")
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		definition.Content = append(definition.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "labels"}, node)
+	}
+	str := func(value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !replaced(node.Content[i].Value) {
+				kept = append(kept, node.Content[i], node.Content[i+1])
+			}
+		}
+		for _, label := range labels {
+			kept = append(kept, str(label[0]), str(label[1]))
+		}
+		node.Content = kept
+	case yaml.SequenceNode:
+		var kept []*yaml.Node
+		for _, item := range node.Content {
+			key, _, _ := strings.Cut(item.Value, "=")
+			if !replaced(key) {
+				kept = append(kept, item)
+			}
+		}
+		for _, label := range labels {
+			kept = append(kept, str(label[0]+"="+label[1]))
+		}
+		node.Content = kept
+	default:
+		return nil, fmt.Errorf("service %q: labels must be a mapping or a list", service)
+	}
+	// Generated labels are written in block style even when the others fit
+	// on one line
+	node.Style &^= yaml.FlowStyle
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(docs[0]); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
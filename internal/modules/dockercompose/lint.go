@@ -0,0 +1,602 @@
+package dockercompose
+
+import (
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// rules lists the lint checks applied to docker-compose files
+var rules = []formatter.Rule{
+	{ID: "compose/unescaped-dollar", Check: checkUnescapedDollar},
+	{ID: "compose/label-reserved-prefix", Check: checkLabelReservedPrefix},
+	{ID: "compose/label-duplicate-key", Check: checkLabelDuplicateKey},
+	{ID: "compose/label-value-length", Check: checkLabelValueLength},
+	{ID: "compose/static-ip-invalid", Check: checkStaticIPInvalid},
+	{ID: "compose/static-ip-outside-subnet", Check: checkStaticIPOutsideSubnet},
+	{ID: "compose/static-ip-duplicate", Check: checkStaticIPDuplicate},
+	{ID: "compose/image-and-build", Check: checkImageAndBuild},
+	{ID: "compose/container-name-replicas", Check: checkContainerNameReplicas},
+	{ID: "compose/network-mode-networks", Check: checkNetworkModeNetworks},
+
+	// The security rules flag settings that are sometimes needed, so they
+	// warn by default; lint_severity makes them errors
+	{ID: "compose/privileged", Severity: formatter.SeverityWarning, Check: checkPrivileged},
+	{ID: "compose/dangerous-capability", Severity: formatter.SeverityWarning, Check: checkDangerousCapability},
+	{ID: "compose/host-network", Severity: formatter.SeverityWarning, Check: checkHostNamespace("network_mode", "network")},
+	{ID: "compose/host-pid", Severity: formatter.SeverityWarning, Check: checkHostNamespace("pid", "process")},
+	{ID: "compose/no-new-privileges", Severity: formatter.SeverityWarning, Check: checkNoNewPrivileges},
+}
+
+// reservedLabelPrefixes are label namespaces reserved for Docker's own use
+var reservedLabelPrefixes = []string{"com.docker.", "io.docker.", "org.dockerproject."}
+
+// maxLabelValueLength is the longest label value accepted without a warning
+// Labels are stored in the container config, and the engine and Swarm reject
+// oversized metadata, so very large values are almost always a mistake
+const maxLabelValueLength = 64 * 1024
+
+// label is a single label entry, in either map or list form
+type label struct {
+	key   string
+	value string
+	node  *yaml.Node
+}
+
+// forEachLabels calls fn with the entries of every labels block in the document
+// (services, build, deploy, networks, volumes, configs and secrets)
+func forEachLabels(root *yaml.Node, fn func(labels []label)) {
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		// Labels always sit below a named entry (services.<name>.labels, ...),
+		// so a two-element path is a service or network that is called "labels"
+		if len(path) < 3 || path[len(path)-1] != "labels" {
+			return
+		}
+
+		var labels []label
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				labels = append(labels, label{
+					key:   node.Content[i].Value,
+					value: node.Content[i+1].Value,
+					node:  node.Content[i],
+				})
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					continue
+				}
+				key, value, _ := strings.Cut(item.Value, "=")
+				labels = append(labels, label{key: key, value: value, node: item})
+			}
+		default:
+			return
+		}
+
+		fn(labels)
+	})
+}
+
+// checkLabelReservedPrefix flags labels in namespaces reserved by Docker
+func checkLabelReservedPrefix(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachLabels(root, func(labels []label) {
+		for _, l := range labels {
+			for _, prefix := range reservedLabelPrefixes {
+				if strings.HasPrefix(strings.ToLower(l.key), prefix) {
+					issues = append(issues, formatter.NewIssue(l.node, "label %q uses the reserved %q namespace", l.key, prefix))
+					break
+				}
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkLabelDuplicateKey flags labels that repeat a key, or differ only by case
+func checkLabelDuplicateKey(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachLabels(root, func(labels []label) {
+		seen := make(map[string]string)
+		for _, l := range labels {
+			folded := strings.ToLower(l.key)
+			first, exists := seen[folded]
+			if !exists {
+				seen[folded] = l.key
+				continue
+			}
+			if first == l.key {
+				issues = append(issues, formatter.NewIssue(l.node, "label %q is defined more than once", l.key))
+			} else {
+				issues = append(issues, formatter.NewIssue(l.node, "label %q differs from %q only by case", l.key, first))
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkLabelValueLength flags label values larger than maxLabelValueLength
+func checkLabelValueLength(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachLabels(root, func(labels []label) {
+		for _, l := range labels {
+			if len(l.value) > maxLabelValueLength {
+				issues = append(issues, formatter.NewIssue(l.node, "label %q value is %d bytes, more than the %d byte limit", l.key, len(l.value), maxLabelValueLength))
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkUnescapedDollar flags values where a single '$' will be interpolated in a
+// way the author probably did not intend: '$' sequences compose rejects, and
+// hash-like values such as "$apr1$..." that silently expand to empty variables
+func checkUnescapedDollar(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		if node.Kind != yaml.ScalarNode {
+			return
+		}
+
+		result := parseInterpolation(node.Value)
+		if len(result.Invalid) > 0 {
+			issues = append(issues, formatter.NewIssue(node, "value %q contains a '$' compose cannot interpolate, use '$$' for a literal dollar sign", node.Value))
+			return
+		}
+
+		for _, ref := range result.Refs {
+			end := ref.Offset + 1 + len(ref.Name)
+			if !ref.Braced && end < len(node.Value) && node.Value[end] == '$' {
+				issues = append(issues, formatter.NewIssue(node, "value %q looks like a password hash but $%s will be interpolated, use '$$' for a literal dollar sign", node.Value, ref.Name))
+				return
+			}
+		}
+	})
+
+	return issues
+}
+
+// staticIP is an ipv4_address or ipv6_address assigned to a service on a network
+type staticIP struct {
+	service string
+	network string
+	key     string
+	node    *yaml.Node
+
+	// addr is invalid when the value does not parse
+	addr netip.Addr
+}
+
+// family returns the address family the key asks for, "ipv4" or "ipv6"
+func (ip staticIP) family() string {
+	return strings.TrimSuffix(ip.key, "_address")
+}
+
+// networkIPAM holds the subnets declared in a top-level network's ipam config
+type networkIPAM struct {
+	subnets  []netip.Prefix
+	external bool
+}
+
+// staticIPs returns every static address in services.<name>.networks
+// Values using interpolation are skipped since they are only known at runtime
+func staticIPs(root *yaml.Node) []staticIP {
+	var ips []staticIP
+
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		if len(path) != 5 || path[0] != "services" || path[2] != "networks" {
+			return
+		}
+		if (path[4] != "ipv4_address" && path[4] != "ipv6_address") || node.Kind != yaml.ScalarNode {
+			return
+		}
+		if strings.Contains(node.Value, "$") {
+			return
+		}
+
+		addr, _ := netip.ParseAddr(node.Value)
+		ips = append(ips, staticIP{service: path[1], network: path[3], key: path[4], node: node, addr: addr})
+	})
+
+	return ips
+}
+
+// networkIPAMs returns the IPAM subnets of every top-level network
+func networkIPAMs(root *yaml.Node) map[string]networkIPAM {
+	networks := make(map[string]networkIPAM)
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return networks
+	}
+	networksNode := formatter.MappingValue(root.Content[0], "networks")
+	if networksNode == nil || networksNode.Kind != yaml.MappingNode {
+		return networks
+	}
+
+	for i := 0; i+1 < len(networksNode.Content); i += 2 {
+		name, definition := networksNode.Content[i].Value, networksNode.Content[i+1]
+
+		var ipam networkIPAM
+		if external := formatter.MappingValue(definition, "external"); external != nil && external.Value != "false" {
+			ipam.external = true
+		}
+		if config := formatter.MappingValue(formatter.MappingValue(definition, "ipam"), "config"); config != nil && config.Kind == yaml.SequenceNode {
+			for _, entry := range config.Content {
+				subnet := formatter.MappingValue(entry, "subnet")
+				if subnet == nil {
+					continue
+				}
+				if prefix, err := netip.ParsePrefix(subnet.Value); err == nil {
+					ipam.subnets = append(ipam.subnets, prefix.Masked())
+				}
+			}
+		}
+		networks[name] = ipam
+	}
+
+	return networks
+}
+
+// checkStaticIPInvalid flags static addresses that do not parse, or belong to
+// the other address family than their key
+func checkStaticIPInvalid(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	for _, ip := range staticIPs(root) {
+		if !ip.addr.IsValid() {
+			issues = append(issues, formatter.NewIssue(ip.node, "%s %q is not a valid IP address", ip.key, ip.node.Value))
+			continue
+		}
+		if ip.addr.Is4() != (ip.family() == "ipv4") {
+			issues = append(issues, formatter.NewIssue(ip.node, "%s %s is not an %s address", ip.key, ip.node.Value, ip.family()))
+		}
+	}
+
+	return issues
+}
+
+// checkStaticIPOutsideSubnet flags static addresses that do not fall inside a
+// subnet of the network's IPAM config. Docker only accepts static addresses
+// on networks with a user-configured subnet, so a declared network without one
+// is reported as well. External networks are configured elsewhere and skipped.
+func checkStaticIPOutsideSubnet(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+	networks := networkIPAMs(root)
+
+	for _, ip := range staticIPs(root) {
+		if !ip.addr.IsValid() || ip.addr.Is4() != (ip.family() == "ipv4") {
+			continue
+		}
+		ipam, declared := networks[ip.network]
+		if !declared || ipam.external {
+			continue
+		}
+
+		var family []netip.Prefix
+		for _, subnet := range ipam.subnets {
+			if subnet.Addr().Is4() == ip.addr.Is4() {
+				family = append(family, subnet)
+			}
+		}
+		if len(family) == 0 {
+			issues = append(issues, formatter.NewIssue(ip.node, "%s needs an %s subnet in the ipam config of network %s", ip.key, ip.family(), ip.network))
+			continue
+		}
+
+		inside := false
+		for _, subnet := range family {
+			if subnet.Contains(ip.addr) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			subnets := make([]string, len(family))
+			for i, subnet := range family {
+				subnets[i] = subnet.String()
+			}
+			issues = append(issues, formatter.NewIssue(ip.node, "%s %s of service %s is outside the subnets of network %s (%s)", ip.key, ip.node.Value, ip.service, ip.network, strings.Join(subnets, ", ")))
+		}
+	}
+
+	return issues
+}
+
+// checkStaticIPDuplicate flags static addresses assigned to more than one
+// service on the same network
+func checkStaticIPDuplicate(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	type assignment struct {
+		network string
+		addr    netip.Addr
+	}
+	seen := make(map[assignment]staticIP)
+
+	for _, ip := range staticIPs(root) {
+		if !ip.addr.IsValid() {
+			continue
+		}
+		key := assignment{network: ip.network, addr: ip.addr}
+		if first, exists := seen[key]; exists {
+			issues = append(issues, formatter.NewIssue(ip.node, "%s %s of service %s is already assigned to service %s on network %s", ip.key, ip.node.Value, ip.service, first.service, ip.network))
+			continue
+		}
+		seen[key] = ip
+	}
+
+	return issues
+}
+
+// forEachService calls fn with the name and definition of every service
+func forEachService(root *yaml.Node, fn func(name string, service *yaml.Node)) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return
+	}
+	names, services := composeServices(root.Content[0])
+	for _, name := range names {
+		if services[name].Kind == yaml.MappingNode {
+			fn(name, services[name])
+		}
+	}
+}
+
+// mappingKey returns the key node of a mapping entry, or nil
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// checkImageAndBuild flags services with both an image and a build section.
+// Compose then pulls the image when it can and only builds it when the pull
+// fails, so a stale registry image silently wins over local changes. Setting
+// pull_policy, or a comment on the image line, documents that the image is
+// meant to be built and pushed under that name.
+func checkImageAndBuild(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		image := mappingKey(service, "image")
+		if image == nil || mappingKey(service, "build") == nil || mappingKey(service, "pull_policy") != nil {
+			return
+		}
+		if image.HeadComment != "" || image.LineComment != "" || formatter.MappingValue(service, "image").LineComment != "" {
+			return
+		}
+		issues = append(issues, formatter.NewIssue(image, "service %s sets both image and build, so compose pulls %s instead of building it when the registry has it; set pull_policy (e.g. build) or comment why the image is named", name, formatter.MappingValue(service, "image").Value))
+	})
+
+	return issues
+}
+
+// checkContainerNameReplicas flags services with a container_name that run
+// more than one container, which fails as container names must be unique
+func checkContainerNameReplicas(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		containerName := mappingKey(service, "container_name")
+		if containerName == nil {
+			return
+		}
+		for _, replicas := range []*yaml.Node{
+			formatter.MappingValue(formatter.MappingValue(service, "deploy"), "replicas"),
+			formatter.MappingValue(service, "scale"),
+		} {
+			if replicas == nil {
+				continue
+			}
+			if n, err := strconv.Atoi(replicas.Value); err == nil && n > 1 {
+				issues = append(issues, formatter.NewIssue(containerName, "service %s sets container_name but runs %d replicas, which cannot share a container name", name, n))
+				return
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkNetworkModeNetworks flags services setting both network_mode and
+// networks, which compose rejects when the service is created
+func checkNetworkModeNetworks(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		networkMode := mappingKey(service, "network_mode")
+		if networkMode == nil || mappingKey(service, "networks") == nil {
+			return
+		}
+		issues = append(issues, formatter.NewIssue(networkMode, "service %s sets network_mode, which cannot be combined with networks", name))
+	})
+
+	return issues
+}
+
+// sensitiveHostPaths are host paths a container should not be able to write
+// to: the Docker socket, which grants root on the host, the root of the
+// filesystem, and system directories, with everything below them except for
+// "/" itself
+var sensitiveHostPaths = []string{
+	"/",
+	"/var/run/docker.sock",
+	"/run/docker.sock",
+	"/etc",
+	"/boot",
+	"/dev",
+	"/proc",
+	"/sys",
+	"/root",
+	"/var/lib/docker",
+}
+
+// sensitiveHostPath returns the entry of sensitiveHostPaths that covers a
+// clean source path, or ""
+func sensitiveHostPath(source string) string {
+	for _, path := range sensitiveHostPaths {
+		if source == path || path != "/" && strings.HasPrefix(source, path+"/") {
+			return path
+		}
+	}
+	return ""
+}
+
+// checkWritableSensitiveMount flags bind mounts of sensitive host paths that
+// are not read-only (":ro" or read_only: true); a container that can write to
+// them can take over the host. Paths in allowlist, and everything below
+// them, are accepted.
+func checkWritableSensitiveMount(root *yaml.Node, allowlist []string) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		volumes := formatter.MappingValue(service, "volumes")
+		if volumes == nil || volumes.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, item := range volumes.Content {
+			bind, ok := parseBindMount(item)
+			if !ok || bind.readOnly || !filepath.IsAbs(bind.source) {
+				continue
+			}
+			source := filepath.Clean(bind.source)
+			path := sensitiveHostPath(source)
+			if path == "" || slices.ContainsFunc(allowlist, func(allowed string) bool {
+				allowed = filepath.Clean(allowed)
+				return source == allowed || strings.HasPrefix(source, strings.TrimSuffix(allowed, "/")+"/")
+			}) {
+				continue
+			}
+			if path == source {
+				issues = append(issues, formatter.NewIssue(item, "service %s mounts %s writable; add :ro, or allow it with bind_mount_allowlist", name, bind.source))
+			} else {
+				issues = append(issues, formatter.NewIssue(item, "service %s mounts %s, under %s, writable; add :ro, or allow it with bind_mount_allowlist", name, bind.source, path))
+			}
+		}
+	})
+
+	return issues
+}
+
+// dangerousCapabilities are the capabilities that give a container control
+// over the host, or over the other containers on it
+var dangerousCapabilities = []string{"ALL", "SYS_ADMIN", "SYS_MODULE", "SYS_PTRACE", "SYS_RAWIO", "DAC_READ_SEARCH", "NET_ADMIN"}
+
+// checkPrivileged flags services running privileged, which gives their
+// containers every capability and access to every host device
+func checkPrivileged(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		if privileged := formatter.MappingValue(service, "privileged"); privileged != nil && privileged.Value == "true" {
+			issues = append(issues, formatter.NewIssue(mappingKey(service, "privileged"), "service %s runs privileged, with every capability and host device; add the capabilities it needs with cap_add instead", name))
+		}
+	})
+
+	return issues
+}
+
+// checkDangerousCapability flags cap_add entries in dangerousCapabilities,
+// written with or without the CAP_ prefix
+func checkDangerousCapability(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		capAdd := formatter.MappingValue(service, "cap_add")
+		if capAdd == nil || capAdd.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, item := range capAdd.Content {
+			capability := strings.TrimPrefix(strings.ToUpper(item.Value), "CAP_")
+			if slices.Contains(dangerousCapabilities, capability) {
+				issues = append(issues, formatter.NewIssue(item, "service %s adds the %s capability, which gives its containers control over the host", name, capability))
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkHostNamespace returns a check flagging services that share a host
+// namespace through key: key: host
+func checkHostNamespace(key, namespace string) func(root *yaml.Node) []formatter.Issue {
+	return func(root *yaml.Node) []formatter.Issue {
+		var issues []formatter.Issue
+
+		forEachService(root, func(name string, service *yaml.Node) {
+			if value := formatter.MappingValue(service, key); value != nil && value.Value == "host" {
+				issues = append(issues, formatter.NewIssue(mappingKey(service, key), "service %s shares the host's %s namespace (%s: host)", name, namespace, key))
+			}
+		})
+
+		return issues
+	}
+}
+
+// checkNoNewPrivileges flags services whose security_opt does not set
+// no-new-privileges, so setuid binaries in their containers can gain
+// privileges
+func checkNoNewPrivileges(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		if securityOpt := formatter.MappingValue(service, "security_opt"); securityOpt != nil {
+			for _, item := range securityOpt.Content {
+				option := strings.ReplaceAll(strings.TrimSpace(item.Value), "=", ":")
+				if option == "no-new-privileges" || option == "no-new-privileges:true" {
+					return
+				}
+			}
+		}
+		key := mappingKey(formatter.MappingValue(root.Content[0], "services"), name)
+		issues = append(issues, formatter.NewIssue(key, "service %s does not set no-new-privileges:true in security_opt, so its processes can gain privileges through setuid binaries", name))
+	})
+
+	return issues
+}
+
+// checkMissingResourceLimits flags services without a memory or a CPU limit,
+// set with the legacy mem_limit and cpus keys or in deploy.resources.limits.
+// Services extending another are skipped, as they may inherit theirs.
+func checkMissingResourceLimits(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachService(root, func(name string, service *yaml.Node) {
+		if mappingKey(service, "extends") != nil {
+			return
+		}
+		limits := formatter.MappingValue(formatter.MappingValue(formatter.MappingValue(service, "deploy"), "resources"), "limits")
+		var missing []string
+		if mappingKey(service, "mem_limit") == nil && mappingKey(limits, "memory") == nil {
+			missing = append(missing, "memory (mem_limit or deploy.resources.limits.memory)")
+		}
+		if mappingKey(service, "cpus") == nil && mappingKey(limits, "cpus") == nil {
+			missing = append(missing, "CPU (cpus or deploy.resources.limits.cpus)")
+		}
+		if len(missing) > 0 {
+			key := mappingKey(formatter.MappingValue(root.Content[0], "services"), name)
+			issues = append(issues, formatter.NewIssue(key, "service %s has no %s limit, which require_resource_limits requires", name, strings.Join(missing, " or ")))
+		}
+	})
+
+	return issues
+}
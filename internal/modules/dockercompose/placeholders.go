@@ -0,0 +1,83 @@
+package dockercompose
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Compose templates rendered by CI before compose reads them, e.g. for a
+// matrix of services, use ${VAR} placeholders where compose itself does not
+// interpolate, such as in service names, and inside flow collections, where
+// the braces are YAML syntax. Such a template is formatted with every
+// placeholder swapped for an opaque token, then swapped back.
+
+// placeholderToken matches the tokens maskPlaceholders writes; a token is a
+// plain scalar in any YAML context
+var placeholderToken = regexp.MustCompile(`__compose_placeholder_(\d+)__`)
+
+// maskPlaceholders replaces every ${...} placeholder of data that ends on its
+// line with a token, returning the placeholders in token order; it returns
+// nil when data has none, or already has text looking like a token
+func maskPlaceholders(data []byte) ([]byte, []string) {
+	if placeholderToken.Match(data) {
+		return nil, nil
+	}
+	text := string(data)
+	var b strings.Builder
+	var placeholders []string
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "$$"):
+			// An escaped dollar sign
+			b.WriteString("$$")
+			i++
+			continue
+		case strings.HasPrefix(text[i:], "${"):
+			closing := matchingBrace(text, i+1)
+			if closing >= 0 && !strings.Contains(text[i:closing], "\n") {
+				b.WriteString("__compose_placeholder_" + strconv.Itoa(len(placeholders)) + "__")
+				placeholders = append(placeholders, text[i:closing+1])
+				i = closing
+				continue
+			}
+		}
+		b.WriteByte(text[i])
+	}
+	if placeholders == nil {
+		return nil, nil
+	}
+	return []byte(b.String()), placeholders
+}
+
+// unmaskPlaceholders puts the placeholders back in place of their tokens
+func unmaskPlaceholders(data []byte, placeholders []string) []byte {
+	return placeholderToken.ReplaceAllFunc(data, func(token []byte) []byte {
+		n, err := strconv.Atoi(string(placeholderToken.FindSubmatch(token)[1]))
+		if err != nil || n >= len(placeholders) {
+			return token
+		}
+		return []byte(placeholders[n])
+	})
+}
+
+// unmaskString is unmaskPlaceholders for a message
+func unmaskString(s string, placeholders []string) string {
+	return string(unmaskPlaceholders([]byte(s), placeholders))
+}
+
+// interpolatedKey reports whether a KEY=VALUE list entry has a placeholder in
+// its key, which compose would no longer interpolate once written as a
+// mapping key
+func interpolatedKey(item *yaml.Node) bool {
+	key, _, _ := strings.Cut(item.Value, "=")
+	return item.Kind == yaml.ScalarNode && strings.Contains(key, "${")
+}
+
+// hasPlaceholders reports whether data may hold a ${...} placeholder
+func hasPlaceholders(data []byte) bool {
+	return bytes.Contains(data, []byte("${"))
+}
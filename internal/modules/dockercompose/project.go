@@ -0,0 +1,563 @@
+package dockercompose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// baseFileNames are the default compose files, in the order docker compose looks for them
+var baseFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// overrideFileNames are loaded on top of the base file by a plain `docker compose up`
+var overrideFileNames = []string{"compose.override.yaml", "compose.override.yml", "docker-compose.override.yaml", "docker-compose.override.yml"}
+
+// Rule IDs of the checks run across the files of a project
+const (
+	rulePortConflict          = "compose/port-conflict"
+	ruleContainerNameConflict = "compose/container-name-conflict"
+	ruleWatchBindOverlap      = "compose/watch-bind-overlap"
+	ruleProjectNameConflict   = "compose/project-name-conflict"
+	ruleProjectNameEnv        = "compose/project-name-env"
+)
+
+// fileSet is one combination of compose files that can be started together
+type fileSet struct {
+	files []string
+
+	// explicit is true when the files have to be passed with -f, rather than
+	// being picked up by docker compose on its own
+	explicit bool
+}
+
+// projectService is a service merged from every file of a file set
+type projectService struct {
+	name     string
+	profiles []string
+
+	containerName *sourceNode
+	ports         []publishedPort
+	binds         []bindMount
+	watches       []watchRule
+}
+
+// sourceNode is a YAML node together with the file it was read from
+type sourceNode struct {
+	file string
+	node *yaml.Node
+}
+
+// publishedPort is a range of host ports published by a service
+type publishedPort struct {
+	sourceNode
+
+	hostIP   string
+	first    int
+	last     int
+	protocol string
+}
+
+// bindMount is a host path mounted into a service's containers
+type bindMount struct {
+	sourceNode
+
+	source   string
+	target   string
+	readOnly bool
+}
+
+// watchRule is a develop.watch entry that copies host files into the
+// containers of a service
+type watchRule struct {
+	sourceNode
+
+	path   string
+	target string
+}
+
+// ValidateProject checks the compose files in dir for conflicts that break
+// `docker compose up`: host ports published by more than one service and
+// duplicate container_name values. It also flags develop.watch rules syncing
+// files a bind mount of the same service already shares, and warns about
+// files naming the project differently from each other or from
+// COMPOSE_PROJECT_NAME in envFile, the .env file; a missing file counts as
+// empty.
+//
+// The default file set (base file plus its override) and every other
+// compose.<name>.yaml / docker-compose.<name>.yml combined with the base file
+// are checked. Services are only compared when some combination of profiles
+// starts both of them, and issues name the profiles that trigger the conflict.
+func ValidateProject(dir, envFile string) ([]formatter.Issue, error) {
+	sets, err := findFileSets(dir)
+	if err != nil {
+		return nil, err
+	}
+	env, err := readEnvFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+	files, err := projectFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := checkProjectNames(dir, files, env)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		services, err := loadFileSet(dir, set)
+		if err != nil {
+			return nil, err
+		}
+
+		// Conflicts in the base file show up in every file set, report them once
+		for _, c := range append(checkConflicts(services, set), checkWatchBinds(services)...) {
+			key := fmt.Sprintf("%s:%d:%d:%s:%s", c.issue.File, c.issue.Line, c.issue.Column, c.issue.Rule, c.with)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			issues = append(issues, c.issue)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
+
+// findFileSets returns the file combinations to check, default set first
+func findFileSets(dir string) ([]fileSet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	base := ""
+	for _, name := range baseFileNames {
+		if slices.Contains(names, name) {
+			base = name
+			break
+		}
+	}
+	if base == "" {
+		return nil, fmt.Errorf("no compose file found in %s (looked for %s)", dir, strings.Join(baseFileNames, ", "))
+	}
+
+	defaultSet := fileSet{files: []string{base}}
+	for _, name := range overrideFileNames {
+		if slices.Contains(names, name) {
+			defaultSet.files = append(defaultSet.files, name)
+			break
+		}
+	}
+	sets := []fileSet{defaultSet}
+
+	// Other compose.<name>.yaml files are variants layered on the base file
+	for _, name := range names {
+		if slices.Contains(baseFileNames, name) || slices.Contains(overrideFileNames, name) {
+			continue
+		}
+		ext := filepath.Ext(name)
+		if ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		if !strings.HasPrefix(name, "compose.") && !strings.HasPrefix(name, "docker-compose.") {
+			continue
+		}
+		sets = append(sets, fileSet{files: []string{base, name}, explicit: true})
+	}
+
+	return sets, nil
+}
+
+// loadFileSet merges the services of every file in the set, in order
+// Later files replace profiles, container_name and develop.watch, and add to
+// ports and volumes, as docker compose does when merging files
+func loadFileSet(dir string, set fileSet) ([]*projectService, error) {
+	var services []*projectService
+	byName := make(map[string]*projectService)
+
+	for _, file := range set.files {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+			continue
+		}
+		servicesNode := formatter.MappingValue(root.Content[0], "services")
+		if servicesNode == nil || servicesNode.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+			name, definition := servicesNode.Content[i].Value, servicesNode.Content[i+1]
+			if definition.Kind != yaml.MappingNode {
+				continue
+			}
+
+			service, ok := byName[name]
+			if !ok {
+				service = &projectService{name: name}
+				byName[name] = service
+				services = append(services, service)
+			}
+
+			if profiles := formatter.MappingValue(definition, "profiles"); profiles != nil && profiles.Kind == yaml.SequenceNode {
+				service.profiles = nil
+				for _, item := range profiles.Content {
+					if item.Kind == yaml.ScalarNode {
+						service.profiles = append(service.profiles, item.Value)
+					}
+				}
+			}
+			if containerName := formatter.MappingValue(definition, "container_name"); containerName != nil && containerName.Kind == yaml.ScalarNode {
+				service.containerName = &sourceNode{file: file, node: containerName}
+			}
+			if ports := formatter.MappingValue(definition, "ports"); ports != nil && ports.Kind == yaml.SequenceNode {
+				for _, item := range ports.Content {
+					if port, ok := parsePublishedPort(item); ok {
+						port.file = file
+						service.ports = append(service.ports, port)
+					}
+				}
+			}
+			if volumes := formatter.MappingValue(definition, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+				for _, item := range volumes.Content {
+					if bind, ok := parseBindMount(item); ok {
+						bind.file = file
+						service.binds = append(service.binds, bind)
+					}
+				}
+			}
+			if watch := formatter.MappingValue(formatter.MappingValue(definition, "develop"), "watch"); watch != nil && watch.Kind == yaml.SequenceNode {
+				service.watches = nil
+				for _, item := range watch.Content {
+					if rule, ok := parseWatchRule(item); ok {
+						rule.file = file
+						service.watches = append(service.watches, rule)
+					}
+				}
+			}
+		}
+	}
+
+	return services, nil
+}
+
+// parsePublishedPort reads the host side of a port entry in short or long syntax
+// ok is false when no host port is published or the entry uses interpolation
+func parsePublishedPort(node *yaml.Node) (publishedPort, bool) {
+	port := publishedPort{sourceNode: sourceNode{node: node}, protocol: "tcp"}
+	var published string
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		spec := node.Value
+		if before, proto, found := strings.Cut(spec, "/"); found {
+			spec, port.protocol = before, proto
+		}
+
+		// An IPv6 host IP is written in brackets: [::1]:8080:80
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]")
+			if end < 0 {
+				return port, false
+			}
+			port.hostIP = spec[1:end]
+			spec = strings.TrimPrefix(spec[end+1:], ":")
+			parts := strings.Split(spec, ":")
+			if len(parts) != 2 {
+				return port, false
+			}
+			published = parts[0]
+			break
+		}
+
+		parts := strings.Split(spec, ":")
+		switch len(parts) {
+		case 2:
+			published = parts[0]
+		case 3:
+			port.hostIP, published = parts[0], parts[1]
+		default:
+			// A bare container port is published on a random host port
+			return port, false
+		}
+
+	case yaml.MappingNode:
+		if value := formatter.MappingValue(node, "published"); value != nil {
+			published = value.Value
+		}
+		if value := formatter.MappingValue(node, "host_ip"); value != nil {
+			port.hostIP = value.Value
+		}
+		if value := formatter.MappingValue(node, "protocol"); value != nil {
+			port.protocol = value.Value
+		}
+
+	default:
+		return port, false
+	}
+
+	first, last, ok := parsePortRange(published)
+	if !ok {
+		return port, false
+	}
+	port.first, port.last = first, last
+	return port, true
+}
+
+// parsePortRange parses "8080" or "8000-8010"
+func parsePortRange(value string) (first, last int, ok bool) {
+	start, end, isRange := strings.Cut(value, "-")
+	first, err := strconv.Atoi(start)
+	if err != nil || first <= 0 {
+		return 0, 0, false
+	}
+	last = first
+	if isRange {
+		last, err = strconv.Atoi(end)
+		if err != nil || last < first {
+			return 0, 0, false
+		}
+	}
+	return first, last, true
+}
+
+// overlap returns the first host port that both entries publish
+func (p publishedPort) overlap(other publishedPort) (int, bool) {
+	if p.protocol != other.protocol || !hostIPsOverlap(p.hostIP, other.hostIP) {
+		return 0, false
+	}
+	first := max(p.first, other.first)
+	if first > min(p.last, other.last) {
+		return 0, false
+	}
+	return first, true
+}
+
+// hostIPsOverlap reports whether two bindings compete for the same address
+// An empty or unspecified address binds every interface
+func hostIPsOverlap(a, b string) bool {
+	wildcard := func(ip string) bool { return ip == "" || ip == "0.0.0.0" || ip == "::" }
+	return a == b || wildcard(a) || wildcard(b)
+}
+
+// conflict is an issue found between a service and an earlier one
+type conflict struct {
+	issue formatter.Issue
+	with  string
+}
+
+// checkConflicts reports port and container_name clashes between services
+// that can run at the same time
+func checkConflicts(services []*projectService, set fileSet) []conflict {
+	var conflicts []conflict
+
+	for j, later := range services {
+		for _, earlier := range services[:j] {
+			profiles := sharedProfiles(earlier.profiles, later.profiles)
+			command := upCommand(set, profiles)
+
+			if earlier.containerName != nil && later.containerName != nil &&
+				earlier.containerName.node.Value == later.containerName.node.Value {
+				issue := formatter.NewIssue(later.containerName.node,
+					"container_name %q of service %s is also used by service %s (%s:%d)%s",
+					later.containerName.node.Value, later.name, earlier.name,
+					earlier.containerName.file, earlier.containerName.node.Line, command)
+				issue.Rule = ruleContainerNameConflict
+				issue.File = later.containerName.file
+				conflicts = append(conflicts, conflict{issue: issue, with: earlier.name})
+			}
+
+			for _, port := range later.ports {
+				for _, other := range earlier.ports {
+					hostPort, ok := port.overlap(other)
+					if !ok {
+						continue
+					}
+					issue := formatter.NewIssue(port.node,
+						"host port %d/%s of service %s is also published by service %s (%s:%d)%s",
+						hostPort, port.protocol, later.name, earlier.name, other.file, other.node.Line, command)
+					issue.Rule = rulePortConflict
+					issue.File = port.file
+					conflicts = append(conflicts, conflict{issue: issue, with: earlier.name})
+					break
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// parseBindMount reads a bind mount in short ("./src:/app:ro") or long syntax
+// ok is false for named volumes, tmpfs mounts and paths using interpolation
+func parseBindMount(node *yaml.Node) (bindMount, bool) {
+	bind := bindMount{sourceNode: sourceNode{node: node}}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(node.Value, ":")
+		if len(parts) < 2 {
+			return bind, false
+		}
+		bind.source, bind.target = parts[0], parts[1]
+		if len(parts) > 2 {
+			bind.readOnly = slices.Contains(strings.Split(parts[2], ","), "ro")
+		}
+	case yaml.MappingNode:
+		if mountType := formatter.MappingValue(node, "type"); mountType == nil || mountType.Value != "bind" {
+			return bind, false
+		}
+		source, target := formatter.MappingValue(node, "source"), formatter.MappingValue(node, "target")
+		if source == nil || target == nil {
+			return bind, false
+		}
+		bind.source, bind.target = source.Value, target.Value
+		if readOnly := formatter.MappingValue(node, "read_only"); readOnly != nil {
+			bind.readOnly = readOnly.Value == "true"
+		}
+	default:
+		return bind, false
+	}
+	if !isHostPath(bind.source) || strings.Contains(bind.target, "$") {
+		return bind, false
+	}
+	return bind, true
+}
+
+// parseWatchRule reads a develop.watch entry whose action copies files into
+// the container; rebuild rules rebuild the image instead
+func parseWatchRule(node *yaml.Node) (watchRule, bool) {
+	rule := watchRule{sourceNode: sourceNode{node: node}}
+	path, action := formatter.MappingValue(node, "path"), formatter.MappingValue(node, "action")
+	if path == nil || action == nil || !strings.HasPrefix(action.Value, "sync") {
+		return rule, false
+	}
+	rule.node = path
+	rule.path = path.Value
+	if target := formatter.MappingValue(node, "target"); target != nil {
+		rule.target = target.Value
+	}
+	if strings.Contains(rule.path, "$") || strings.HasPrefix(rule.path, "~") {
+		return rule, false
+	}
+	return rule, !strings.Contains(rule.target, "$")
+}
+
+// isHostPath reports whether a volume source is a host path that can be
+// compared: relative to the project or absolute, without interpolation
+func isHostPath(source string) bool {
+	if strings.Contains(source, "$") || strings.HasPrefix(source, "~") {
+		return false
+	}
+	return strings.HasPrefix(source, ".") || filepath.IsAbs(source)
+}
+
+// pathsOverlap reports whether one path is the other or contains it
+func pathsOverlap(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/") || a == "." || b == "."
+}
+
+// checkWatchBinds reports develop.watch sync rules of a service that copy
+// host files a bind mount of the same service already shares, or into a
+// directory a bind mount covers; both sides then write the same files
+func checkWatchBinds(services []*projectService) []conflict {
+	var conflicts []conflict
+
+	for _, service := range services {
+		for _, rule := range service.watches {
+			for _, bind := range service.binds {
+				var overlap string
+				switch {
+				case pathsOverlap(rule.path, bind.source):
+					overlap = "path " + rule.path + " overlaps"
+				case rule.target != "" && pathsOverlap(rule.target, bind.target):
+					overlap = "target " + rule.target + " overlaps"
+				default:
+					continue
+				}
+				issue := formatter.NewIssue(rule.node,
+					"develop.watch %s the bind mount %s:%s of service %s (%s:%d), so the files are synced twice",
+					overlap, bind.source, bind.target, service.name, bind.file, bind.node.Line)
+				issue.Rule = ruleWatchBindOverlap
+				issue.File = rule.file
+				conflicts = append(conflicts, conflict{issue: issue, with: bind.source})
+				break
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// sharedProfiles returns a smallest set of profiles that starts both services
+// Services without profiles always start
+func sharedProfiles(a, b []string) []string {
+	for _, profile := range a {
+		if slices.Contains(b, profile) {
+			return []string{profile}
+		}
+	}
+
+	var profiles []string
+	if len(a) > 0 {
+		profiles = append(profiles, a[0])
+	}
+	if len(b) > 0 {
+		profiles = append(profiles, b[0])
+	}
+	return profiles
+}
+
+// upCommand describes the docker compose invocation that hits a conflict,
+// or returns "" for a plain `docker compose up`
+func upCommand(set fileSet, profiles []string) string {
+	if !set.explicit && len(profiles) == 0 {
+		return ""
+	}
+
+	args := []string{"docker", "compose"}
+	if set.explicit {
+		for _, file := range set.files {
+			args = append(args, "-f", file)
+		}
+	}
+	for _, profile := range profiles {
+		args = append(args, "--profile", profile)
+	}
+	args = append(args, "up")
+	return " with `" + strings.Join(args, " ") + "`"
+}
@@ -0,0 +1,55 @@
+package dockercompose
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// projectNameVariable is the environment variable naming the project; set in
+// the .env file, it takes precedence over the top-level name of the files
+const projectNameVariable = "COMPOSE_PROJECT_NAME"
+
+// checkProjectNames reports compose files of the project whose top-level
+// names differ from the name of an earlier file, and names that
+// COMPOSE_PROJECT_NAME in the .env file overrides; in both cases the
+// containers, networks and volumes of one file end up under the name of
+// another. Names using interpolation are not compared.
+func checkProjectNames(dir string, files []string, env map[string]string) ([]formatter.Issue, error) {
+	var issues []formatter.Issue
+
+	envName, fromEnv := env[projectNameVariable]
+	var first *sourceNode
+	for _, file := range files {
+		root, err := readComposeFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		name := formatter.MappingValue(root, "name")
+		if name == nil || name.Kind != yaml.ScalarNode || strings.Contains(name.Value, "$") {
+			continue
+		}
+
+		var issue formatter.Issue
+		switch {
+		case fromEnv && envName != "" && envName != name.Value:
+			issue = formatter.NewIssue(name, "name %q is overridden by %s=%s in the .env file, so the project runs as %s", name.Value, projectNameVariable, envName, envName)
+			issue.Rule = ruleProjectNameEnv
+		case first != nil && first.node.Value != name.Value:
+			issue = formatter.NewIssue(name, "name %q differs from name %q in %s:%d; combined, the files run as the project named last", name.Value, first.node.Value, first.file, first.node.Line)
+			issue.Rule = ruleProjectNameConflict
+		}
+		if first == nil {
+			first = &sourceNode{file: file, node: name}
+		}
+		if issue.Rule != "" {
+			issue.File = file
+			issue.Severity = formatter.SeverityWarning
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
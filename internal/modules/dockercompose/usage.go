@@ -0,0 +1,407 @@
+package dockercompose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/lockedfile"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule IDs of the resource usage checks
+const (
+	ruleUnusedResource    = "compose/unused-resource"
+	ruleUndefinedResource = "compose/undefined-resource"
+)
+
+// resourceSections are the top-level sections of shared resources, with the
+// name of one resource of each
+var resourceSections = []struct{ section, kind string }{
+	{"volumes", "volume"},
+	{"networks", "network"},
+	{"secrets", "secret"},
+	{"configs", "config"},
+}
+
+// Resource is a top-level volume, network, secret or config of a compose project
+type Resource struct {
+	// Kind is "volume", "network", "secret" or "config"
+	Kind string
+	Name string
+
+	// Services lists the services referencing the resource, sorted
+	Services []string
+
+	// definitions are the keys defining the resource, one per file
+	definitions []sourceNode
+}
+
+// Unused reports whether no service references the resource
+func (r *Resource) Unused() bool {
+	return len(r.Services) == 0
+}
+
+// Usage is the resource usage of a compose project
+type Usage struct {
+	// Resources lists the defined resources, by kind and then name
+	Resources []*Resource
+
+	// Issues are the unused definitions and references to undefined
+	// resources, ordered by position
+	Issues []formatter.Issue
+}
+
+// ProjectUsage cross-references the top-level volumes, networks, secrets and
+// configs of the compose project in dir with the services using them. The
+// files are those ValidateProject checks, taken together.
+func ProjectUsage(dir string) (*Usage, error) {
+	files, err := projectFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[string]*Resource)
+	var references []resourceReference
+	for _, file := range files {
+		root, err := readComposeFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			continue
+		}
+
+		for _, rs := range resourceSections {
+			section := formatter.MappingValue(root, rs.section)
+			if section == nil || section.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(section.Content); i += 2 {
+				key := section.Content[i]
+				id := rs.kind + " " + key.Value
+				resource, ok := resources[id]
+				if !ok {
+					resource = &Resource{Kind: rs.kind, Name: key.Value}
+					resources[id] = resource
+				}
+				resource.definitions = append(resource.definitions, sourceNode{file: file, node: key})
+			}
+		}
+
+		references = append(references, serviceReferences(root, file)...)
+	}
+
+	usage := &Usage{}
+	for _, ref := range references {
+		resource, ok := resources[ref.kind+" "+ref.name]
+		if !ok {
+			// The default network exists without being defined
+			if ref.kind == "network" && ref.name == "default" {
+				continue
+			}
+			issue := formatter.NewIssue(ref.node, "service %s uses undefined %s %q", ref.service, ref.kind, ref.name)
+			issue.Rule, issue.File = ruleUndefinedResource, ref.file
+			usage.Issues = append(usage.Issues, issue)
+			continue
+		}
+		if !slices.Contains(resource.Services, ref.service) {
+			resource.Services = append(resource.Services, ref.service)
+		}
+	}
+
+	for _, resource := range resources {
+		sort.Strings(resource.Services)
+		usage.Resources = append(usage.Resources, resource)
+		if !resource.Unused() {
+			continue
+		}
+		for _, def := range resource.definitions {
+			issue := formatter.NewIssue(def.node, "%s %q is not used by any service", resource.Kind, resource.Name)
+			issue.Rule, issue.File = ruleUnusedResource, def.file
+			usage.Issues = append(usage.Issues, issue)
+		}
+	}
+
+	order := make(map[string]int)
+	for i, rs := range resourceSections {
+		order[rs.kind] = i
+	}
+	sort.Slice(usage.Resources, func(i, j int) bool {
+		a, b := usage.Resources[i], usage.Resources[j]
+		if a.Kind != b.Kind {
+			return order[a.Kind] < order[b.Kind]
+		}
+		return a.Name < b.Name
+	})
+	sort.SliceStable(usage.Issues, func(i, j int) bool {
+		a, b := usage.Issues[i], usage.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return usage, nil
+}
+
+// projectFiles returns every compose file of the project in dir, base file first
+func projectFiles(dir string) ([]string, error) {
+	sets, err := findFileSets(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, set := range sets {
+		for _, file := range set.files {
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// readComposeFile parses a compose file, returning its top-level mapping or
+// nil for an empty file
+func readComposeFile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	return root.Content[0], nil
+}
+
+// resourceReference is a service's use of a named resource
+type resourceReference struct {
+	service string
+	kind    string
+	name    string
+	file    string
+	node    *yaml.Node
+}
+
+// serviceReferences returns the resources the services of a file refer to
+// Bind mounts, tmpfs mounts and values using interpolation are skipped
+func serviceReferences(root *yaml.Node, file string) []resourceReference {
+	services := formatter.MappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var refs []resourceReference
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		service, definition := services.Content[i].Value, services.Content[i+1]
+		if definition.Kind != yaml.MappingNode {
+			continue
+		}
+		add := func(kind string, node *yaml.Node) {
+			if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || strings.Contains(node.Value, "$") {
+				return
+			}
+			refs = append(refs, resourceReference{service: service, kind: kind, name: node.Value, file: file, node: node})
+		}
+
+		if volumes := formatter.MappingValue(definition, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+			for _, item := range volumes.Content {
+				add("volume", namedVolume(item))
+			}
+		}
+
+		// networks is a list of names or a mapping of names to settings
+		if networks := formatter.MappingValue(definition, "networks"); networks != nil {
+			switch networks.Kind {
+			case yaml.SequenceNode:
+				for _, item := range networks.Content {
+					add("network", item)
+				}
+			case yaml.MappingNode:
+				for j := 0; j < len(networks.Content); j += 2 {
+					add("network", networks.Content[j])
+				}
+			}
+		}
+
+		// secrets and configs are lists of names or {source: name} mappings
+		for _, kind := range []string{"secret", "config"} {
+			lists := []*yaml.Node{formatter.MappingValue(definition, kind+"s")}
+			if kind == "secret" {
+				if build := formatter.MappingValue(definition, "build"); build != nil && build.Kind == yaml.MappingNode {
+					lists = append(lists, formatter.MappingValue(build, "secrets"))
+				}
+			}
+			for _, list := range lists {
+				if list == nil || list.Kind != yaml.SequenceNode {
+					continue
+				}
+				for _, item := range list.Content {
+					if item.Kind == yaml.MappingNode {
+						item = formatter.MappingValue(item, "source")
+					}
+					add(kind, item)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// namedVolume returns the node naming the volume of a service volume entry,
+// or nil for a bind mount, a tmpfs mount or an anonymous volume
+// For the short syntax a new scalar node holding the source is returned,
+// positioned at the entry
+func namedVolume(item *yaml.Node) *yaml.Node {
+	switch item.Kind {
+	case yaml.MappingNode:
+		if volumeType := formatter.MappingValue(item, "type"); volumeType == nil || volumeType.Value != "volume" {
+			return nil
+		}
+		return formatter.MappingValue(item, "source")
+	case yaml.ScalarNode:
+		source, _, ok := strings.Cut(item.Value, ":")
+		if !ok || source == "" || strings.ContainsAny(source[:1], "./~") || strings.Contains(source, "/") {
+			return nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: source, Line: item.Line, Column: item.Column}
+	}
+	return nil
+}
+
+// RemoveUnused deletes the definitions of the unused resources of usage from
+// the files in dir, returning the files changed. Lines are removed from the
+// source, so the rest of each file is left as written; a section left empty is
+// removed too. Definitions in flow style ({...}) are left alone.
+func RemoveUnused(dir string, usage *Usage) ([]string, error) {
+	// The files are parsed again, so resources are matched by kind and name
+	remove := make(map[string]map[string]bool)
+	for _, resource := range usage.Resources {
+		if !resource.Unused() {
+			continue
+		}
+		for _, def := range resource.definitions {
+			if remove[def.file] == nil {
+				remove[def.file] = make(map[string]bool)
+			}
+			remove[def.file][resource.Kind+" "+resource.Name] = true
+		}
+	}
+
+	var changed []string
+	for file, ids := range remove {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return changed, err
+		}
+		root, err := readComposeFile(path)
+		if err != nil {
+			return changed, err
+		}
+		lines := strings.SplitAfter(string(data), "\n")
+
+		drop := make([]bool, len(lines))
+		removed := false
+		for _, rs := range resourceSections {
+			sectionKey, section := mappingEntry(root, rs.section)
+			if section == nil || section.Kind != yaml.MappingNode || section.Style&yaml.FlowStyle != 0 {
+				continue
+			}
+			remaining := 0
+			var ranges [][2]int
+			for i := 0; i+1 < len(section.Content); i += 2 {
+				key := section.Content[i]
+				if !ids[rs.kind+" "+key.Value] {
+					remaining++
+					continue
+				}
+				ranges = append(ranges, entryLines(lines, key.Line, key.Column-1))
+			}
+			if len(ranges) == 0 {
+				continue
+			}
+			if remaining == 0 {
+				ranges = [][2]int{entryLines(lines, sectionKey.Line, sectionKey.Column-1)}
+			}
+			for _, r := range ranges {
+				for l := r[0]; l <= r[1]; l++ {
+					drop[l-1] = true
+				}
+			}
+			removed = true
+		}
+		if !removed {
+			continue
+		}
+
+		var out strings.Builder
+		for i, text := range lines {
+			if drop[i] {
+				continue
+			}
+			// Removing an entry between two blank lines leaves only one
+			if strings.TrimSpace(text) == "" && i > 0 && drop[i-1] && strings.HasSuffix(out.String(), "\n\n") {
+				continue
+			}
+			out.WriteString(text)
+		}
+		if err := lockedfile.Replace(path, data, []byte(out.String())); err != nil {
+			return changed, err
+		}
+		changed = append(changed, file)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// mappingEntry returns the key and value nodes stored under key in a mapping node
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// entryLines returns the first and last line (1-based) of the block mapping
+// entry whose key is on line start at the given indentation: the comments
+// directly above it at the same indentation, and every line after it that is
+// indented deeper
+func entryLines(lines []string, start, indent int) [2]int {
+	first := start
+	for first > 1 {
+		text := lines[first-2]
+		trimmed := strings.TrimLeft(text, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(text)-len(trimmed) != indent {
+			break
+		}
+		first--
+	}
+
+	last := start
+	for l := start + 1; l <= len(lines); l++ {
+		text := strings.TrimRight(lines[l-1], "\r\n")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if len(text)-len(trimmed) <= indent {
+			break
+		}
+		last = l
+	}
+	return [2]int{first, last}
+}
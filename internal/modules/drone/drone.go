@@ -0,0 +1,238 @@
+package drone
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// DroneFormatter formats Drone and Woodpecker CI pipeline files
+// A file may hold several pipelines as separate YAML documents
+type DroneFormatter struct {
+	formatter.BaseFormatter
+}
+
+// stepLists are the top-level keys holding steps, as a list (Drone) or a
+// mapping from step name to step (Woodpecker); "pipeline" is Woodpecker's
+// old name for steps
+var stepLists = []string{"steps", "services", "pipeline"}
+
+// New creates a new DroneFormatter
+// Steps and services are separated by blank lines
+func New() *DroneFormatter {
+	return &DroneFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{"steps"}, {"services"}, {"pipeline"}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *DroneFormatter) Name() string {
+	return "drone"
+}
+
+// CanHandle checks if this file is a Drone or Woodpecker pipeline file
+func (f *DroneFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := filepath.Base(filename)
+	switch base {
+	case ".drone.yml", ".drone.yaml", ".woodpecker.yml", ".woodpecker.yaml":
+		return true
+	}
+	if filepath.Base(filepath.Dir(filename)) == ".woodpecker" {
+		return true
+	}
+
+	// Check for pipeline specific keys
+	if !formatter.MayHaveTopLevelKey(data, "kind", "steps", "pipeline") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// Look for pipeline indicators in top-level keys
+	// Drone documents declare their kind; Woodpecker files always have steps
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if content.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(content.Content); i += 2 {
+				key, value := content.Content[i].Value, content.Content[i+1].Value
+				if key == "kind" && (value == "pipeline" || value == "secret" || value == "signature") {
+					return true
+				}
+				if key == "steps" || key == "pipeline" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Format formats a Drone or Woodpecker YAML file with consistent indentation and ordering
+func (f *DroneFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *DroneFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *DroneFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *DroneFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to pipeline conventions
+// Only pipeline documents and steps are sorted; step lists, environment,
+// settings and conditions keep the order they were written in
+func (f *DroneFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var order func(key string) int
+	switch {
+	case isTopLevel:
+		order = getPipelineKeyOrder
+	case len(path) == 2 && slices.Contains(stepLists, path[0]):
+		order = getStepKeyOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// getPipelineKeyOrder returns the sort order for a key of a pipeline document
+// Identity first, then where it runs, then what it runs, then when it runs
+func getPipelineKeyOrder(key string) int {
+	pipelineOrder := map[string]int{
+		// Identity
+		"kind":    1,
+		"type":    2,
+		"name":    3,
+		"version": 4,
+
+		// Where it runs
+		"platform":   10,
+		"node":       11,
+		"labels":     12,
+		"workspace":  13,
+		"clone":      14,
+		"skip_clone": 15,
+
+		// What it runs
+		"variables":          20,
+		"matrix":             21,
+		"steps":              22,
+		"pipeline":           23,
+		"services":           24,
+		"volumes":            25,
+		"image_pull_secrets": 26,
+
+		// When it runs
+		"trigger":     30,
+		"when":        31,
+		"depends_on":  32,
+		"runs_on":     33,
+		"concurrency": 34,
+
+		// Secret and signature documents
+		"get":  40,
+		"data": 41,
+		"hmac": 42,
+	}
+
+	if order, ok := pipelineOrder[key]; ok {
+		return order
+	}
+	return 999
+}
+
+// getStepKeyOrder returns the sort order for a key of a step or service
+// What runs first, then its inputs, then the commands, then the conditions
+func getStepKeyOrder(key string) int {
+	stepOrder := map[string]int{
+		// Identity and image
+		"name":       1,
+		"image":      2,
+		"pull":       3,
+		"privileged": 4,
+		"detach":     5,
+		"user":       6,
+
+		// Inputs
+		"environment":  10,
+		"secrets":      11,
+		"settings":     12,
+		"volumes":      13,
+		"network_mode": 14,
+		"ports":        15,
+
+		// Commands
+		"entrypoint": 20,
+		"commands":   21,
+		"command":    22,
+
+		// Conditions
+		"when":            30,
+		"depends_on":      31,
+		"failure":         32,
+		"resources":       33,
+		"backend_options": 34,
+	}
+
+	if order, ok := stepOrder[key]; ok {
+		return order
+	}
+	return 999
+}
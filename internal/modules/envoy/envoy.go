@@ -0,0 +1,355 @@
+package envoy
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvoyFormatter formats Envoy bootstrap configuration files
+type EnvoyFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new EnvoyFormatter
+// Top-level sections, and the static listeners and clusters, are separated by
+// blank lines
+func New() *EnvoyFormatter {
+	return &EnvoyFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}, {"static_resources", "listeners"}, {"static_resources", "clusters"}},
+			// Filters run in the order they are listed, and the first
+			// matching route wins
+			OrderSensitive: []string{"listener_filters", "filters", "http_filters", "routes"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *EnvoyFormatter) Name() string {
+	return "envoy"
+}
+
+// CanHandle checks if this file is an Envoy bootstrap configuration file
+func (f *EnvoyFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	switch filepath.Base(filename) {
+	case "envoy.yaml", "envoy.yml":
+		return true
+	}
+
+	// Check for the bootstrap sections
+	if !formatter.MayHaveTopLevelKey(data, "static_resources", "dynamic_resources", "admin") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if formatter.MappingValue(content, "static_resources") != nil || formatter.MappingValue(content, "dynamic_resources") != nil {
+			return true
+		}
+		// admin on its own is too generic a key; Envoy's has a socket address
+		if admin := formatter.MappingValue(content, "admin"); admin != nil {
+			address := formatter.MappingValue(admin, "address")
+			return address != nil && (formatter.MappingValue(address, "socket_address") != nil || formatter.MappingValue(address, "pipe") != nil)
+		}
+	}
+
+	return false
+}
+
+// Format formats an Envoy YAML file with consistent indentation and ordering
+func (f *EnvoyFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *EnvoyFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in an Envoy config
+func (f *EnvoyFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *EnvoyFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *EnvoyFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// orderTable returns the order table for the mapping at path
+// Envoy reads protobuf messages, where field order carries no meaning, so
+// every message is sorted: the fields ranked by its table first, then the rest
+// alphabetically. Lists keep their order.
+func orderTable(node *yaml.Node, isTopLevel bool, path []string) map[string]int {
+	if isTopLevel {
+		return topLevelOrder
+	}
+	if formatter.MappingValue(node, "@type") != nil {
+		if typeURL := formatter.MappingValue(node, "@type").Value; strings.HasSuffix(typeURL, ".HttpConnectionManager") {
+			return httpConnectionManagerOrder
+		}
+		return typedConfigOrder
+	}
+	if len(path) == 1 {
+		return sectionTables[path[0]]
+	}
+	for _, t := range pathTables {
+		if formatter.MatchKeyPath(t.pattern, path) {
+			return t.table
+		}
+	}
+	return map[string]int{}
+}
+
+// sectionTables map top-level sections to their order tables
+var sectionTables = map[string]map[string]int{
+	"node":             nodeOrder,
+	"admin":            adminOrder,
+	"static_resources": staticResourcesOrder,
+}
+
+// pathTables map deeper key paths, as MatchKeyPath patterns, to their order
+// tables; the first match wins
+var pathTables = []struct {
+	pattern string
+	table   map[string]int
+}{
+	{"static_resources.listeners.*", listenerOrder},
+	{"**.filter_chains.*", filterChainOrder},
+	{"**.default_filter_chain", filterChainOrder},
+	{"**.listener_filters.*", filterOrder},
+	{"**.filters.*", filterOrder},
+	{"**.http_filters.*", filterOrder},
+	{"static_resources.clusters.*", clusterOrder},
+	{"**.load_assignment", loadAssignmentOrder},
+	{"**.lb_endpoints.*", lbEndpointOrder},
+	{"**.socket_address", socketAddressOrder},
+	{"**.route_config", routeConfigOrder},
+	{"**.virtual_hosts.*", virtualHostOrder},
+	{"**.routes.*", routeOrder},
+}
+
+// sortMappingNode sorts keys in a mapping node according to Envoy conventions
+func (f *EnvoyFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	table := orderTable(node, isTopLevel, path)
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder ranks the bootstrap sections: who this Envoy is, its admin
+// interface, what it serves, then how it is observed and tuned
+var topLevelOrder = map[string]int{
+	"node":              1,
+	"admin":             2,
+	"static_resources":  3,
+	"dynamic_resources": 4,
+	"cluster_manager":   5,
+	"hds_config":        6,
+
+	// Observability
+	"stats_config":         10,
+	"stats_sinks":          11,
+	"stats_flush_interval": 12,
+	"tracing":              13,
+
+	// Runtime and limits
+	"layered_runtime":  20,
+	"overload_manager": 21,
+	"watchdogs":        22,
+}
+
+// nodeOrder ranks the fields of node
+var nodeOrder = map[string]int{
+	"id":       1,
+	"cluster":  2,
+	"locality": 3,
+	"metadata": 4,
+}
+
+// adminOrder ranks the fields of admin
+var adminOrder = map[string]int{
+	"address":         1,
+	"access_log":      2,
+	"access_log_path": 3,
+	"profile_path":    4,
+}
+
+// staticResourcesOrder ranks the static resources: listeners, then the
+// clusters they route to
+var staticResourcesOrder = map[string]int{
+	"listeners": 1,
+	"clusters":  2,
+	"secrets":   3,
+}
+
+// listenerOrder ranks the fields of a listener: its name and address, then
+// its filters in the order connections meet them
+var listenerOrder = map[string]int{
+	"name":                 1,
+	"address":              2,
+	"additional_addresses": 3,
+	"listener_filters":     10,
+	"filter_chains":        11,
+	"default_filter_chain": 12,
+}
+
+// filterChainOrder ranks the fields of a filter chain: which connections it
+// takes, its filters, then TLS
+var filterChainOrder = map[string]int{
+	"name":               1,
+	"filter_chain_match": 2,
+	"filters":            3,
+	"transport_socket":   4,
+}
+
+// filterOrder ranks the fields of a listener, network or HTTP filter
+var filterOrder = map[string]int{
+	"name":                 1,
+	"typed_config":         2,
+	"config_discovery":     3,
+	"is_optional":          4,
+	"disabled":             5,
+	"filter_disabled":      6,
+	"typed_config_default": 7,
+}
+
+// typedConfigOrder puts the type URL of a typed config first
+var typedConfigOrder = map[string]int{
+	"@type": 0,
+}
+
+// httpConnectionManagerOrder ranks the fields of the HTTP connection manager:
+// its type, stats prefix and codec, the routes, then the HTTP filters
+var httpConnectionManagerOrder = map[string]int{
+	"@type":         0,
+	"stat_prefix":   1,
+	"codec_type":    2,
+	"route_config":  3,
+	"rds":           3,
+	"scoped_routes": 3,
+	"http_filters":  4,
+	"access_log":    5,
+}
+
+// clusterOrder ranks the fields of a cluster: its name and discovery type,
+// connection settings, its endpoints, then health checking and TLS
+var clusterOrder = map[string]int{
+	"name":            1,
+	"type":            2,
+	"cluster_type":    2,
+	"connect_timeout": 3,
+	"lb_policy":       4,
+	"load_assignment": 5,
+	"health_checks":   6,
+
+	"circuit_breakers":                 10,
+	"outlier_detection":                11,
+	"typed_extension_protocol_options": 12,
+	"transport_socket":                 13,
+}
+
+// loadAssignmentOrder ranks the fields of a load assignment
+var loadAssignmentOrder = map[string]int{
+	"cluster_name": 1,
+	"endpoints":    2,
+	"policy":       3,
+}
+
+// lbEndpointOrder ranks the fields of an endpoint
+var lbEndpointOrder = map[string]int{
+	"endpoint":              1,
+	"health_status":         2,
+	"load_balancing_weight": 3,
+	"metadata":              4,
+}
+
+// socketAddressOrder ranks the fields of a socket address
+var socketAddressOrder = map[string]int{
+	"protocol":   1,
+	"address":    2,
+	"port_value": 3,
+	"named_port": 3,
+}
+
+// routeConfigOrder ranks the fields of a route configuration
+var routeConfigOrder = map[string]int{
+	"name":          1,
+	"virtual_hosts": 2,
+}
+
+// virtualHostOrder ranks the fields of a virtual host: its name and domains,
+// then its routes
+var virtualHostOrder = map[string]int{
+	"name":    1,
+	"domains": 2,
+	"routes":  3,
+}
+
+// routeOrder ranks the fields of a route: its name, what it matches, then
+// what it does
+var routeOrder = map[string]int{
+	"name":            1,
+	"match":           2,
+	"route":           3,
+	"redirect":        3,
+	"direct_response": 3,
+}
@@ -0,0 +1,195 @@
+package esphome
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// ESPHomeFormatter formats ESPHome device configurations
+type ESPHomeFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new ESPHomeFormatter
+// Top-level blocks are separated by blank lines
+func New() *ESPHomeFormatter {
+	return &ESPHomeFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *ESPHomeFormatter) Name() string {
+	return "esphome"
+}
+
+// CanHandle checks if this file is an ESPHome configuration: a YAML file
+// with a top-level esphome block
+func (f *ESPHomeFormatter) CanHandle(filename string, data []byte) bool {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+	default:
+		return false
+	}
+	if !formatter.MayHaveTopLevelKey(data, "esphome") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return false
+	}
+	return formatter.MappingValue(root.Content[0], "esphome") != nil
+}
+
+// Format formats an ESPHome configuration
+func (f *ESPHomeFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The top level is ordered by topLevelOrder: substitutions and packages, the
+// device and its platform, the connectivity blocks and the logger, the other
+// blocks in their order, then the entity lists. The entities of each list are
+// sorted by name and start with their platform, name and id. Tags such as
+// !secret, !lambda and !include are kept as written.
+func (f *ESPHomeFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		if opts.PreserveKeyOrder || !isRoot || len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+			return
+		}
+		root := node.Content[0]
+		sortMapping(root, rank(topLevelOrder))
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i].Value, root.Content[i+1]
+			switch {
+			case key == "esphome" && value.Kind == yaml.MappingNode:
+				sortMapping(value, rank(esphomeOrder))
+			case isEntityList(key) && value.Kind == yaml.SequenceNode:
+				sortEntities(value)
+			}
+		}
+	})
+}
+
+// sortEntities sorts the entities of a list by name, or by id for those
+// without one, and orders the first keys of each; entities with neither, such
+// as sensors naming each of their readings, go last in their order. A list
+// holding anything but entities, or comments between them, keeps its order.
+func sortEntities(list *yaml.Node) {
+	for _, entity := range list.Content {
+		if entity.Kind == yaml.MappingNode {
+			sortMapping(entity, rank(entityOrder))
+		}
+	}
+	if slices.ContainsFunc(list.Content, func(entity *yaml.Node) bool {
+		return entity.Kind != yaml.MappingNode || entity.HeadComment != "" || entity.FootComment != "" ||
+			len(entity.Content) > 0 && entity.Content[0].HeadComment != ""
+	}) {
+		return
+	}
+	sort.SliceStable(list.Content, func(i, j int) bool {
+		a, b := strings.ToLower(entityName(list.Content[i])), strings.ToLower(entityName(list.Content[j]))
+		return a != "" && (b == "" || a < b)
+	})
+}
+
+// entityName returns the name of an entity, or its id
+func entityName(entity *yaml.Node) string {
+	for _, key := range []string{"name", "id"} {
+		if value := formatter.MappingValue(entity, key); value != nil && value.Kind == yaml.ScalarNode {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// rank returns a ranking function for an order table; unknown keys go last,
+// in their order
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// sortMapping orders the entries of a mapping by rank; entries of the same
+// rank keep their order, and comments move with the entries they describe
+func sortMapping(node *yaml.Node, rank func(key string) int) {
+	formatter.SortMapping(node, func(a, b string) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+}
+
+// entityListRank is the rank of the first entity list; the lists follow
+// every other block, in the order of entityLists
+const entityListRank = 1000
+
+// isEntityList reports whether a top-level block is a list of entities
+func isEntityList(key string) bool {
+	return slices.Contains(entityLists, key)
+}
+
+// entityLists are the top-level blocks listing entities, inputs first, then
+// outputs and controls
+var entityLists = []string{
+	"sensor", "binary_sensor", "text_sensor", "event",
+	"switch", "button", "number", "select", "text", "datetime",
+	"output", "light", "fan", "cover", "lock", "valve", "climate",
+	"media_player", "speaker", "microphone", "display", "touchscreen",
+}
+
+// topLevelOrder ranks the top-level blocks: what is substituted and merged
+// in, the device and the board it runs on, how it is reached and logs; other
+// blocks have rank 999 and keep their order before the entity lists
+var topLevelOrder = func() map[string]int {
+	order := map[string]int{
+		"substitutions": 1,
+		"packages":      2,
+		"esphome":       10,
+		"esp32":         11,
+		"esp8266":       11,
+		"rp2040":        11,
+		"bk72xx":        11,
+		"rtl87xx":       11,
+		"ln882x":        11,
+		"host":          11,
+		"wifi":          20,
+		"ethernet":      20,
+		"api":           21,
+		"ota":           22,
+		"logger":        23,
+	}
+	for i, list := range entityLists {
+		order[list] = entityListRank + i
+	}
+	return order
+}()
+
+// esphomeOrder ranks the keys of the esphome block: the device's names,
+// where it is, then its build settings and automations
+var esphomeOrder = map[string]int{
+	"name":          1,
+	"friendly_name": 2,
+	"area":          3,
+	"comment":       4,
+	"min_version":   5,
+	"project":       6,
+}
+
+// entityOrder ranks the first keys of an entity
+var entityOrder = map[string]int{
+	"platform": 1,
+	"name":     2,
+	"id":       3,
+}
@@ -0,0 +1,186 @@
+package fluentbit
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// The classic Fluent Bit format is INI-like: [SECTION] headers followed by
+// indented "Key value" lines, # comments on lines of their own and
+// @INCLUDE/@SET directives. A # after a value is part of the value.
+
+// sectionHeader matches a [SECTION] line
+var sectionHeader = regexp.MustCompile(`^\[([A-Za-z_]+)\]$`)
+
+// isClassic reports whether data looks like a classic config: a pipeline
+// section header on a line of its own
+func isClassic(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		match := sectionHeader.FindSubmatch(bytes.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		switch strings.ToUpper(string(match[1])) {
+		case "SERVICE", "INPUT", "FILTER", "OUTPUT":
+			return true
+		}
+	}
+	return false
+}
+
+// classicBlock is a top-level directive or a section, with the comments above it
+type classicBlock struct {
+	comments []string
+
+	// directive is an @INCLUDE or @SET line; "" for a section
+	directive string
+
+	section *classicSection
+}
+
+// classicSection is a [SECTION] and its entries
+type classicSection struct {
+	name    string
+	entries []*classicEntry
+
+	// foot are the indented comments after the last entry, such as
+	// commented-out settings
+	foot []string
+}
+
+// classicEntry is a "Key value" line with the comments above it
+type classicEntry struct {
+	comments []string
+	key      string
+	value    string
+}
+
+// parseClassic reads a classic config
+// Comments in column 0 go with the section or directive below them; indented
+// comments stay inside the section they are in
+func parseClassic(data []byte) ([]*classicBlock, []string, error) {
+	var blocks []*classicBlock
+	var pending []string
+	var current *classicSection
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		indented := line != "" && (raw[0] == ' ' || raw[0] == '\t')
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			if current != nil && indented {
+				current.foot = append(current.foot, line)
+				continue
+			}
+			pending = append(pending, line)
+		case strings.HasPrefix(line, "@"):
+			if current != nil && indented {
+				// A directive inside a section is kept there as an entry
+				current.entries = append(current.entries, &classicEntry{comments: current.foot, key: line})
+				current.foot = nil
+				continue
+			}
+			blocks = append(blocks, &classicBlock{comments: pending, directive: line})
+			pending = nil
+			current = nil
+		case sectionHeader.MatchString(line):
+			current = &classicSection{name: sectionHeader.FindStringSubmatch(line)[1]}
+			blocks = append(blocks, &classicBlock{comments: pending, section: current})
+			pending = nil
+		default:
+			if current == nil {
+				return nil, nil, &formatter.ParseError{Language: "Fluent Bit config", Line: n + 1, Message: "setting outside of a section"}
+			}
+			key, value := line, ""
+			if i := strings.IndexAny(line, " \t"); i >= 0 {
+				key, value = line[:i], strings.TrimSpace(line[i:])
+			}
+			// Comments in column 0 between settings belong to the next one
+			comments := append(current.foot, pending...)
+			current.entries = append(current.entries, &classicEntry{comments: comments, key: key, value: value})
+			current.foot = nil
+			pending = nil
+		}
+	}
+
+	// Comments in column 0 at the end of the file stay at the end
+	return blocks, pending, nil
+}
+
+// printClassic writes a classic config with settings indented by indent
+// spaces and their values aligned on one column per section
+// Sections are separated by blank lines; consecutive directives are kept
+// together
+func printClassic(blocks []*classicBlock, foot []string, indent int, upperNames bool) []byte {
+	var buf bytes.Buffer
+	pad := strings.Repeat(" ", indent)
+
+	for i, block := range blocks {
+		if i > 0 && (block.section != nil || blocks[i-1].section != nil || len(block.comments) > 0) {
+			buf.WriteByte('\n')
+		}
+		for _, comment := range block.comments {
+			buf.WriteString(comment + "\n")
+		}
+		if block.section == nil {
+			buf.WriteString(block.directive + "\n")
+			continue
+		}
+
+		name := block.section.name
+		if upperNames {
+			name = strings.ToUpper(name)
+		}
+		buf.WriteString("[" + name + "]\n")
+
+		width := 0
+		for _, entry := range block.section.entries {
+			if entry.value != "" && len(entry.key) > width {
+				width = len(entry.key)
+			}
+		}
+		for _, entry := range block.section.entries {
+			for _, comment := range entry.comments {
+				buf.WriteString(pad + comment + "\n")
+			}
+			buf.WriteString(pad + entry.key)
+			if entry.value != "" {
+				buf.WriteString(strings.Repeat(" ", width-len(entry.key)+1) + entry.value)
+			}
+			buf.WriteByte('\n')
+		}
+		for _, comment := range block.section.foot {
+			buf.WriteString(pad + comment + "\n")
+		}
+	}
+
+	if len(foot) > 0 && len(blocks) > 0 {
+		buf.WriteByte('\n')
+	}
+	for _, comment := range foot {
+		buf.WriteString(comment + "\n")
+	}
+	return buf.Bytes()
+}
+
+// sortClassicEntries moves the keys identifying a plugin (Name, Alias, Tag,
+// Match) to the top of each section
+// The other settings keep their order: some plugins read repeated keys
+// (modify's Add, rewrite_tag's Rule) in order
+func sortClassicEntries(blocks []*classicBlock) {
+	for _, block := range blocks {
+		if block.section == nil {
+			continue
+		}
+		entries := block.section.entries
+		sort.SliceStable(entries, func(i, j int) bool {
+			return pluginKeyOrder(entries[i].key) < pluginKeyOrder(entries[j].key)
+		})
+	}
+}
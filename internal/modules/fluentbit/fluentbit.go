@@ -0,0 +1,229 @@
+package fluentbit
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// FluentBitFormatter formats Fluent Bit configuration files, in both the
+// classic format (fluent-bit.conf) and the YAML format (fluent-bit.yaml)
+type FluentBitFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new FluentBitFormatter
+// In YAML configs top-level sections are separated by blank lines
+func New() *FluentBitFormatter {
+	return &FluentBitFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *FluentBitFormatter) Name() string {
+	return "fluentbit"
+}
+
+// CanHandle checks if this file is a Fluent Bit configuration file
+func (f *FluentBitFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if base == "fluent-bit" || base == "fluentbit" {
+		return true
+	}
+
+	// Classic configs are detected by their section headers
+	if filepath.Ext(filename) == ".conf" {
+		return isClassic(data)
+	}
+
+	// Check for Fluent Bit specific keys
+	if !formatter.MayHaveTopLevelKey(data, "pipeline") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// The pipeline mapping holds the inputs and outputs
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		pipeline := formatter.MappingValue(root.Content[0], "pipeline")
+		return pipeline != nil && (formatter.MappingValue(pipeline, "inputs") != nil || formatter.MappingValue(pipeline, "outputs") != nil)
+	}
+
+	return false
+}
+
+// Format formats a Fluent Bit config with consistent indentation and ordering
+func (f *FluentBitFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Classic configs are rewritten line by line; YAML configs go through FormatYAML
+func (f *FluentBitFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if isClassic(data) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		blocks, foot, err := parseClassic(data)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.PreserveKeyOrder {
+			sortClassicEntries(blocks)
+		}
+		return printClassic(blocks, foot, opts.Indent, !opts.PreserveValues), nil
+	}
+
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *FluentBitFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *FluentBitFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Fluent Bit conventions
+// Plugins only have their identifying keys moved up; their other settings
+// keep their order
+func (f *FluentBitFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var order func(key string) int
+	switch {
+	case isTopLevel:
+		order = rank(topLevelOrder)
+	case len(path) == 1 && path[0] == "pipeline":
+		order = rank(pipelineOrder)
+	case isPlugin(path):
+		order = pluginKeyOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	// Plugin settings of the same rank keep their order instead of being
+	// sorted by name
+	if isPlugin(path) {
+		formatter.SortMapping(node, func(a, b string) int {
+			return cmp.Compare(order(a), order(b))
+		})
+		return
+	}
+	formatter.SortMappingByRank(node, order)
+}
+
+// isPlugin reports whether path is a plugin: an input, filter or output of
+// the pipeline (pipeline.inputs.0), or a processor attached to an input or
+// output (pipeline.inputs.0.processors.logs.1)
+func isPlugin(path []string) bool {
+	if len(path) < 3 || path[0] != "pipeline" {
+		return false
+	}
+	switch path[1] {
+	case "inputs", "filters", "outputs":
+	default:
+		return false
+	}
+	return len(path) == 3 || len(path) == 6 && path[3] == "processors"
+}
+
+// pluginKeyOrder returns the sort order for a plugin setting, in either format
+// The name and the keys routing records to the plugin come first; keys are
+// case-insensitive
+func pluginKeyOrder(key string) int {
+	switch strings.ToLower(key) {
+	case "name":
+		return 1
+	case "alias":
+		return 2
+	case "tag":
+		return 3
+	case "match":
+		return 4
+	case "match_regex":
+		return 5
+	}
+	return 10
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// topLevelOrder ranks the top-level sections of a YAML config: variables and
+// included files, the service, what the pipeline uses, then the pipeline
+var topLevelOrder = map[string]int{
+	"env":               1,
+	"includes":          2,
+	"service":           3,
+	"parsers":           10,
+	"multiline_parsers": 11,
+	"plugins":           12,
+	"upstream_servers":  13,
+	"customs":           14,
+	"pipeline":          20,
+}
+
+// pipelineOrder ranks the pipeline stages in the order records pass them
+var pipelineOrder = map[string]int{
+	"inputs":  1,
+	"filters": 2,
+	"outputs": 3,
+}
@@ -0,0 +1,388 @@
+package flux
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// FluxFormatter formats Flux CD resources: Kustomization and HelmRelease, and
+// the GitRepository, OCIRepository, HelmRepository, HelmChart and Bucket
+// sources they read from
+type FluxFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new FluxFormatter
+func New() *FluxFormatter {
+	return &FluxFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			// Patches and post renderers are applied in order, and later
+			// values override earlier ones
+			OrderSensitive: []string{"spec.patches", "spec.postRenderers", "spec.valuesFrom", "valuesFiles"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *FluxFormatter) Name() string {
+	return "flux"
+}
+
+// specOrders ranks the fields of the spec of each resource the formatter
+// knows
+var specOrders = map[string]map[string]int{
+	"Kustomization":  kustomizationOrder,
+	"HelmRelease":    helmReleaseOrder,
+	"GitRepository":  sourceOrder,
+	"OCIRepository":  sourceOrder,
+	"HelmRepository": sourceOrder,
+	"HelmChart":      helmChartOrder,
+	"Bucket":         sourceOrder,
+}
+
+// kindTables lists the order tables below the spec of each resource, as
+// MatchKeyPath patterns; the first match wins
+var kindTables = map[string][]pathTable{
+	"Kustomization": {
+		{"spec.sourceRef", referenceOrder},
+		{"spec.dependsOn.*", referenceOrder},
+		{"spec.decryption", decryptionOrder},
+		{"spec.postBuild", postBuildOrder},
+		{"spec.postBuild.substituteFrom.*", referenceOrder},
+		{"spec.healthChecks.*", referenceOrder},
+		{"spec.patches.*", patchOrder},
+		{"spec.images.*", imageOrder},
+	},
+	"HelmRelease": {
+		{"spec.chart", chartTemplateOrder},
+		{"spec.chart.spec", helmChartOrder},
+		{"spec.chart.spec.sourceRef", referenceOrder},
+		{"spec.chartRef", referenceOrder},
+		{"spec.dependsOn.*", referenceOrder},
+		{"spec.valuesFrom.*", referenceOrder},
+	},
+	"GitRepository": {
+		{"spec.ref", gitReferenceOrder},
+	},
+	"OCIRepository": {
+		{"spec.ref", ociReferenceOrder},
+	},
+	"HelmChart": {
+		{"spec.sourceRef", referenceOrder},
+	},
+}
+
+// pathTable maps a key path, as a MatchKeyPath pattern, to an order table
+type pathTable struct {
+	pattern string
+	table   map[string]int
+}
+
+// CanHandle checks if this file holds Flux resources
+// Manifests have no telling file name, so only the apiVersion and kind are
+// used; one Flux document in a manifest is enough
+func (f *FluxFormatter) CanHandle(filename string, data []byte) bool {
+	// Most manifests are not ours; the API group has to appear somewhere
+	if !bytes.Contains(data, []byte("toolkit.fluxcd.io/")) {
+		return false
+	}
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return false
+	}
+	for _, doc := range docs {
+		if resourceKind(doc) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKind returns the kind of a Flux resource the formatter knows, or ""
+// for any other document
+// The API group tells a Flux Kustomization from a kustomize one
+// (kustomize.config.k8s.io)
+func resourceKind(doc *yaml.Node) string {
+	if len(doc.Content) == 0 {
+		return ""
+	}
+	apiVersion := formatter.MappingValue(doc.Content[0], "apiVersion")
+	kind := formatter.MappingValue(doc.Content[0], "kind")
+	if apiVersion == nil || kind == nil || specOrders[kind.Value] == nil {
+		return ""
+	}
+	group, _, _ := strings.Cut(apiVersion.Value, "/")
+	if !strings.HasSuffix(group, ".toolkit.fluxcd.io") {
+		return ""
+	}
+	return kind.Value
+}
+
+// Format formats a Flux resource file with consistent indentation and ordering
+func (f *FluxFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *FluxFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in Flux resources
+func (f *FluxFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode formats one document, choosing the order tables by its kind
+// Other Kubernetes resources in the same file are left as they are
+func (f *FluxFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	if !isRoot || node.Kind != yaml.DocumentNode {
+		return
+	}
+	if kind := resourceKind(node); kind != "" {
+		f.formatNodeWithContext(node.Content[0], kind, nil, opts)
+	}
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *FluxFormatter) formatNodeWithContext(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		f.sortMappingNode(node, kind, path, opts)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNodeWithContext(node.Content[i+1], kind, append(path, node.Content[i].Value), opts)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, kind, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// orderTable returns the order table for the mapping at path in a resource of
+// the given kind, or nil to leave it alone
+func orderTable(kind string, path []string) map[string]int {
+	if len(path) == 0 {
+		return topLevelOrder
+	}
+	if len(path) == 1 && path[0] == "metadata" {
+		return metadataOrder
+	}
+	if len(path) == 1 && path[0] == "spec" {
+		return specOrders[kind]
+	}
+	// secretRef, certSecretRef, proxySecretRef, kubeConfig.secretRef, ...
+	if strings.HasSuffix(path[len(path)-1], "SecretRef") || path[len(path)-1] == "secretRef" {
+		return referenceOrder
+	}
+	for _, t := range kindTables[kind] {
+		if formatter.MatchKeyPath(t.pattern, path) {
+			return t.table
+		}
+	}
+	return nil
+}
+
+// sortMappingNode sorts keys in a mapping node according to Flux conventions
+// Keys missing from the order table follow the known ones, by name; mappings
+// without a table, such as labels and Helm values, keep their order
+func (f *FluxFormatter) sortMappingNode(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	table := orderTable(kind, path)
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || table == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder is the usual order of a Kubernetes resource
+var topLevelOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"metadata":   3,
+	"spec":       4,
+	"status":     5,
+}
+
+// metadataOrder ranks the fields of metadata
+var metadataOrder = map[string]int{
+	"name":        1,
+	"namespace":   2,
+	"labels":      3,
+	"annotations": 4,
+}
+
+// kustomizationOrder ranks the fields of a Kustomization spec: how often it
+// reconciles, what it applies from where, then how
+var kustomizationOrder = map[string]int{
+	"interval":           1,
+	"retryInterval":      2,
+	"timeout":            3,
+	"sourceRef":          4,
+	"path":               5,
+	"prune":              6,
+	"wait":               7,
+	"force":              8,
+	"targetNamespace":    9,
+	"serviceAccountName": 10,
+	"kubeConfig":         11,
+	"dependsOn":          12,
+	"decryption":         13,
+	"postBuild":          14,
+	"commonMetadata":     15,
+	"namePrefix":         16,
+	"nameSuffix":         17,
+	"components":         18,
+	"patches":            19,
+	"images":             20,
+	"healthChecks":       21,
+	"suspend":            30,
+}
+
+// helmReleaseOrder ranks the fields of a HelmRelease spec: how often it
+// reconciles, the chart, where it is released, the actions, then the values
+var helmReleaseOrder = map[string]int{
+	"interval":           1,
+	"timeout":            2,
+	"chart":              3,
+	"chartRef":           4,
+	"releaseName":        5,
+	"targetNamespace":    6,
+	"storageNamespace":   7,
+	"serviceAccountName": 8,
+	"kubeConfig":         9,
+	"dependsOn":          10,
+	"install":            11,
+	"upgrade":            12,
+	"test":               13,
+	"rollback":           14,
+	"uninstall":          15,
+	"driftDetection":     16,
+	"values":             20,
+	"valuesFrom":         21,
+	"postRenderers":      22,
+	"suspend":            30,
+}
+
+// chartTemplateOrder ranks the fields of the chart template of a HelmRelease
+var chartTemplateOrder = map[string]int{
+	"metadata": 1,
+	"spec":     2,
+}
+
+// helmChartOrder ranks the fields of a HelmChart spec, also used for the
+// chart template of a HelmRelease
+var helmChartOrder = map[string]int{
+	"chart":                    1,
+	"version":                  2,
+	"sourceRef":                3,
+	"interval":                 4,
+	"reconcileStrategy":        5,
+	"valuesFiles":              6,
+	"ignoreMissingValuesFiles": 7,
+	"verify":                   8,
+	"suspend":                  30,
+}
+
+// sourceOrder ranks the fields of a source spec: how often it is fetched,
+// where from, then what is taken
+var sourceOrder = map[string]int{
+	"interval":           1,
+	"timeout":            2,
+	"provider":           3,
+	"url":                4,
+	"type":               5,
+	"bucketName":         6,
+	"endpoint":           7,
+	"region":             8,
+	"prefix":             9,
+	"ref":                10,
+	"secretRef":          11,
+	"certSecretRef":      12,
+	"proxySecretRef":     13,
+	"serviceAccountName": 14,
+	"passCredentials":    15,
+	"insecure":           16,
+	"verify":             17,
+	"recurseSubmodules":  18,
+	"include":            19,
+	"layerSelector":      20,
+	"ignore":             21,
+	"suspend":            30,
+}
+
+// gitReferenceOrder ranks the fields of a Git reference
+var gitReferenceOrder = map[string]int{
+	"branch": 1,
+	"tag":    2,
+	"semver": 3,
+	"name":   4,
+	"commit": 5,
+}
+
+// ociReferenceOrder ranks the fields of an OCI artifact reference
+var ociReferenceOrder = map[string]int{
+	"tag":          1,
+	"semver":       2,
+	"semverFilter": 3,
+	"digest":       4,
+}
+
+// referenceOrder ranks the fields of a reference to another object, such as
+// a sourceRef, a dependency, a health check or a secret
+var referenceOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"name":       3,
+	"namespace":  4,
+	"valuesKey":  5,
+	"targetPath": 6,
+	"optional":   7,
+}
+
+// decryptionOrder ranks the fields of the decryption settings
+var decryptionOrder = map[string]int{
+	"provider":  1,
+	"secretRef": 2,
+}
+
+// postBuildOrder ranks the fields of the post-build settings
+var postBuildOrder = map[string]int{
+	"substitute":     1,
+	"substituteFrom": 2,
+}
+
+// patchOrder ranks the fields of a patch: its target before the patch
+var patchOrder = map[string]int{
+	"target": 1,
+	"patch":  2,
+}
+
+// imageOrder ranks the fields of an image override
+var imageOrder = map[string]int{
+	"name":    1,
+	"newName": 2,
+	"newTag":  3,
+	"digest":  4,
+}
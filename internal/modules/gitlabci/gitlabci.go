@@ -0,0 +1,366 @@
+package gitlabci
+
+import (
+	"cmp"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// GitLabCIFormatter formats GitLab CI/CD pipeline files
+type GitLabCIFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new GitLabCIFormatter
+// Top-level sections are separated by blank lines
+func New() *GitLabCIFormatter {
+	return &GitLabCIFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+			// Stages and script lines run in order
+			OrderSensitive: []string{"stages", "script", "before_script", "after_script"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *GitLabCIFormatter) Name() string {
+	return "gitlab-ci"
+}
+
+// CanHandle checks if this file is a GitLab CI pipeline file
+func (f *GitLabCIFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	if strings.HasSuffix(filename, ".gitlab-ci.yml") || strings.HasSuffix(filename, ".gitlab-ci.yaml") {
+		return true
+	}
+
+	// Check for GitLab CI specific keys
+	if !formatter.MayHaveTopLevelKey(data, "stages", "workflow") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// Look for GitLab CI indicators in top-level keys
+	// Only keywords no other supported format uses at the top level are checked,
+	// since pipelines can also have top-level "services" or "image"
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if content.Kind == yaml.MappingNode {
+			for i := 0; i < len(content.Content); i += 2 {
+				key := content.Content[i].Value
+				if key == "stages" || key == "workflow" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Format formats a GitLab CI YAML file with consistent indentation and ordering
+func (f *GitLabCIFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *GitLabCIFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports repeated items in the lists whose order is significant
+func (f *GitLabCIFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *GitLabCIFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+// Job names are free-form, so whether a mapping is a job (and how its keys are
+// ordered) depends on its position rather than its key name
+func (f *GitLabCIFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to GitLab CI conventions
+// Mappings without an order table (variables, job inputs, ...) keep their order
+func (f *GitLabCIFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	order, alphabetical := keyOrderFor(path, isTopLevel)
+	if order == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	// At the top level jobs share one rank and keep their original order, since
+	// that is the order they are shown in and usually follows the stages
+	if !alphabetical {
+		formatter.SortMapping(node, func(a, b string) int {
+			return cmp.Compare(order(a), order(b))
+		})
+		return
+	}
+	formatter.SortMappingByRank(node, order)
+}
+
+// keyOrderFor returns the ranking function for the mapping at path, or nil if
+// the mapping keeps its original order. alphabetical reports whether keys with
+// the same rank are sorted by name.
+func keyOrderFor(path []string, isTopLevel bool) (order func(key string) int, alphabetical bool) {
+	if isTopLevel {
+		return getGlobalKeyOrder, false
+	}
+
+	// Jobs, hidden jobs (.template) and the default block are top-level mappings
+	if len(path) == 1 {
+		if _, global := globalKeyOrder[path[0]]; !global || path[0] == "default" {
+			return getJobKeyOrder, true
+		}
+	}
+
+	// Keyword blocks, found below a job or the default block (job.artifacts)
+	// or as list items (job.rules.0); deprecated global keywords sit one
+	// level higher
+	var table map[string]int
+	switch {
+	case len(path) == 2 && path[1] == "artifacts":
+		table = artifactsOrder
+	case matchesKeyword(path, "cache"):
+		table = cacheOrder
+	case len(path) == 2 && path[1] == "environment":
+		table = environmentOrder
+	case matchesKeyword(path, "image"), matchesKeyword(path, "services"):
+		table = imageOrder
+	case len(path) == 3 && path[1] == "rules":
+		table = ruleOrder
+	case path[0] == "include" && len(path) <= 2:
+		table = includeOrder
+	default:
+		return nil, false
+	}
+
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 1000
+	}, true
+}
+
+// matchesKeyword reports whether path is a keyword block of a job or the
+// default block, a list item of one, or the deprecated global form
+func matchesKeyword(path []string, keyword string) bool {
+	switch len(path) {
+	case 1:
+		return path[0] == keyword
+	case 2:
+		return path[1] == keyword || path[0] == keyword
+	case 3:
+		return path[1] == keyword
+	}
+	return false
+}
+
+// globalKeyOrder ranks the top-level keywords; everything else is a job
+var globalKeyOrder = map[string]int{
+	"stages":    1,
+	"variables": 2,
+	"include":   3,
+	"default":   4,
+	"workflow":  5,
+
+	// Deprecated global keywords, superseded by default
+	"image":         10,
+	"services":      11,
+	"cache":         12,
+	"before_script": 13,
+	"after_script":  14,
+}
+
+// getGlobalKeyOrder returns the sort order for a top-level key
+// Jobs all get the same rank so they stay in their original order
+func getGlobalKeyOrder(key string) int {
+	if order, ok := globalKeyOrder[key]; ok {
+		return order
+	}
+	return 100
+}
+
+// jobKeyOrder ranks job keywords
+// Where the job runs first, then what it runs, then when it runs, then what
+// it produces, then execution tuning
+var jobKeyOrder = map[string]int{
+	// Placement and environment
+	"stage":     1,
+	"extends":   2,
+	"image":     3,
+	"services":  4,
+	"tags":      5,
+	"variables": 6,
+
+	// Commands
+	"before_script": 10,
+	"script":        11,
+	"after_script":  12,
+
+	// Conditions and dependencies
+	"rules":               20,
+	"only":                21,
+	"except":              22,
+	"when":                23,
+	"allow_failure":       24,
+	"manual_confirmation": 25,
+	"start_in":            26,
+	"needs":               27,
+	"dependencies":        28,
+
+	// Results
+	"artifacts":   30,
+	"cache":       31,
+	"environment": 32,
+	"release":     33,
+	"coverage":    34,
+	"pages":       35,
+
+	// Execution
+	"trigger":        40,
+	"inherit":        41,
+	"parallel":       42,
+	"resource_group": 43,
+	"retry":          44,
+	"timeout":        45,
+	"interruptible":  46,
+	"id_tokens":      47,
+	"secrets":        48,
+	"hooks":          49,
+	"identity":       50,
+}
+
+// getJobKeyOrder returns the sort order for a key of a job or the default block
+func getJobKeyOrder(key string) int {
+	if order, ok := jobKeyOrder[key]; ok {
+		return order
+	}
+	return 999
+}
+
+// artifactsOrder ranks artifacts keys: what to keep, then how long, then reports
+var artifactsOrder = map[string]int{
+	"name":      1,
+	"paths":     2,
+	"exclude":   3,
+	"untracked": 4,
+	"when":      5,
+	"expire_in": 6,
+	"expose_as": 7,
+	"public":    8,
+	"access":    9,
+	"reports":   10,
+}
+
+// cacheOrder ranks cache keys
+var cacheOrder = map[string]int{
+	"key":           1,
+	"fallback_keys": 2,
+	"paths":         3,
+	"untracked":     4,
+	"unprotect":     5,
+	"when":          6,
+	"policy":        7,
+}
+
+// environmentOrder ranks environment keys
+var environmentOrder = map[string]int{
+	"name":            1,
+	"url":             2,
+	"action":          3,
+	"deployment_tier": 4,
+	"on_stop":         5,
+	"auto_stop_in":    6,
+	"kubernetes":      7,
+}
+
+// imageOrder ranks image and service keys
+var imageOrder = map[string]int{
+	"name":        1,
+	"alias":       2,
+	"entrypoint":  3,
+	"command":     4,
+	"pull_policy": 5,
+	"docker":      6,
+	"kubernetes":  7,
+	"variables":   8,
+}
+
+// ruleOrder ranks the keys of a rules entry: the condition first, then its effect
+var ruleOrder = map[string]int{
+	"if":            1,
+	"changes":       2,
+	"exists":        3,
+	"when":          10,
+	"allow_failure": 11,
+	"start_in":      12,
+	"needs":         13,
+	"variables":     14,
+	"interruptible": 15,
+}
+
+// includeOrder ranks the keys of an include entry: the source first
+var includeOrder = map[string]int{
+	"local":     1,
+	"project":   2,
+	"ref":       3,
+	"file":      4,
+	"remote":    5,
+	"template":  6,
+	"component": 7,
+	"inputs":    10,
+	"rules":     11,
+}
@@ -0,0 +1,241 @@
+package golangci
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// GolangCIFormatter formats golangci-lint configuration files (v1 and v2)
+type GolangCIFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new GolangCIFormatter
+// Top-level sections are separated by blank lines
+func New() *GolangCIFormatter {
+	return &GolangCIFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *GolangCIFormatter) Name() string {
+	return "golangci"
+}
+
+// CanHandle checks if this file is a golangci-lint configuration file
+func (f *GolangCIFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	switch filepath.Base(filename) {
+	case ".golangci.yml", ".golangci.yaml":
+		return true
+	}
+
+	// Check for golangci-lint specific keys
+	if !formatter.MayHaveTopLevelKey(data, "linters-settings", "linters") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// linters-settings only exists in golangci-lint v1 configs; v2 configs
+	// are recognized by linters together with another section
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if formatter.MappingValue(content, "linters-settings") != nil {
+			return true
+		}
+		if formatter.MappingValue(content, "linters") != nil &&
+			(formatter.MappingValue(content, "run") != nil || formatter.MappingValue(content, "issues") != nil ||
+				formatter.MappingValue(content, "formatters") != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Format formats a golangci-lint YAML file with consistent indentation and ordering
+func (f *GolangCIFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *GolangCIFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *GolangCIFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *GolangCIFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Linter name lists read best in alphabetical order
+	if !opts.PreserveValues && isLinterList(path) {
+		formatter.SortSequence(node, formatter.SequenceOptions{MoveComments: true})
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// isLinterList reports whether path holds a list of linter or formatter names
+func isLinterList(path []string) bool {
+	if len(path) != 2 || (path[0] != "linters" && path[0] != "formatters") {
+		return false
+	}
+	return path[1] == "enable" || path[1] == "disable"
+}
+
+// sortMappingNode sorts keys in a mapping node according to golangci-lint conventions
+// Settings of individual linters keep their order
+func (f *GolangCIFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var order func(key string) int
+	switch {
+	case isTopLevel:
+		order = rank(topLevelOrder)
+	case len(path) == 1 && path[0] == "run":
+		order = rank(runOrder)
+	case len(path) == 1 && (path[0] == "linters" || path[0] == "formatters"):
+		order = rank(lintersOrder)
+	case len(path) == 1 && path[0] == "issues":
+		order = rank(issuesOrder)
+	case isSettingsByLinter(path):
+		// Keyed by linter name
+		order = func(string) int { return 0 }
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// isSettingsByLinter reports whether path is the mapping from linter names to
+// their settings: linters-settings in v1, linters.settings and
+// formatters.settings in v2
+func isSettingsByLinter(path []string) bool {
+	switch len(path) {
+	case 1:
+		return path[0] == "linters-settings"
+	case 2:
+		return (path[0] == "linters" || path[0] == "formatters") && path[1] == "settings"
+	}
+	return false
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// topLevelOrder ranks the top-level sections: how golangci-lint runs, which
+// linters run and how they are set up, then which issues are reported
+var topLevelOrder = map[string]int{
+	"version":          1,
+	"run":              2,
+	"output":           3,
+	"linters":          4,
+	"linters-settings": 5,
+	"formatters":       6,
+	"issues":           7,
+	"severity":         8,
+}
+
+// runOrder ranks the run options
+var runOrder = map[string]int{
+	"go":                     1,
+	"timeout":                2,
+	"concurrency":            3,
+	"tests":                  4,
+	"build-tags":             5,
+	"modules-download-mode":  6,
+	"allow-parallel-runners": 7,
+	"allow-serial-runners":   8,
+	"relative-path-mode":     9,
+}
+
+// lintersOrder ranks the keys of the linters (and v2 formatters) section:
+// the baseline, the changes to it, then per-linter settings and exclusions
+var lintersOrder = map[string]int{
+	"default":     1,
+	"disable-all": 1,
+	"enable-all":  1,
+	"enable":      2,
+	"disable":     3,
+	"presets":     4,
+	"fast":        5,
+	"settings":    10,
+	"exclusions":  11,
+}
+
+// issuesOrder ranks the issue reporting options
+var issuesOrder = map[string]int{
+	"new":                   1,
+	"new-from-rev":          2,
+	"new-from-merge-base":   3,
+	"new-from-patch":        4,
+	"whole-files":           5,
+	"fix":                   6,
+	"max-issues-per-linter": 10,
+	"max-same-issues":       11,
+	"uniq-by-line":          12,
+	"exclude-use-default":   20,
+	"exclude-generated":     21,
+	"exclude-dirs":          22,
+	"exclude-files":         23,
+	"exclude":               24,
+	"exclude-rules":         25,
+	"include":               26,
+}
@@ -0,0 +1,260 @@
+package goreleaser
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// GoReleaserFormatter formats GoReleaser configuration files
+type GoReleaserFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new GoReleaserFormatter
+// Top-level sections are separated by blank lines
+func New() *GoReleaserFormatter {
+	return &GoReleaserFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *GoReleaserFormatter) Name() string {
+	return "goreleaser"
+}
+
+// CanHandle checks if this file is a GoReleaser configuration file
+func (f *GoReleaserFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	switch filepath.Base(filename) {
+	case ".goreleaser.yml", ".goreleaser.yaml", "goreleaser.yml", "goreleaser.yaml":
+		return true
+	}
+
+	// Check for GoReleaser specific keys
+	if !formatter.MayHaveTopLevelKey(data, "builds") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// builds alone is too generic, so another GoReleaser section is required
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if formatter.MappingValue(content, "builds") != nil &&
+			(formatter.MappingValue(content, "archives") != nil || formatter.MappingValue(content, "project_name") != nil ||
+				formatter.MappingValue(content, "release") != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Format formats a GoReleaser YAML file with consistent indentation and ordering
+func (f *GoReleaserFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *GoReleaserFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *GoReleaserFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *GoReleaserFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to GoReleaser conventions
+// Only the top level and build, archive and release entries are sorted
+func (f *GoReleaserFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	var table map[string]int
+	switch {
+	case isTopLevel:
+		table = topLevelOrder
+	case len(path) == 2 && path[0] == "builds":
+		table = buildOrder
+	case len(path) == 2 && path[0] == "archives":
+		table = archiveOrder
+	case len(path) == 1 && path[0] == "release":
+		table = releaseOrder
+	case len(path) == 1 && path[0] == "changelog":
+		table = changelogOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder ranks the top-level sections in the order GoReleaser runs
+// them: build, package, checksum and sign, publish, then announce
+var topLevelOrder = map[string]int{
+	"version":      1,
+	"project_name": 2,
+	"dist":         3,
+	"env":          4,
+	"before":       5,
+
+	// Build
+	"builds":             10,
+	"universal_binaries": 11,
+	"upx":                12,
+
+	// Package
+	"archives":         20,
+	"source":           21,
+	"nfpms":            22,
+	"snapcrafts":       23,
+	"dockers":          24,
+	"docker_manifests": 25,
+	"kos":              26,
+	"checksum":         27,
+	"signs":            28,
+	"docker_signs":     29,
+	"sboms":            30,
+	"snapshot":         31,
+
+	// Publish
+	"changelog":      40,
+	"release":        41,
+	"brews":          42,
+	"homebrew_casks": 43,
+	"scoops":         44,
+	"nix":            45,
+	"winget":         46,
+	"aurs":           47,
+	"blobs":          48,
+	"publishers":     49,
+	"milestones":     50,
+
+	// After the release
+	"announce": 60,
+	"after":    61,
+}
+
+// buildOrder ranks the keys of a build: what is built, for which platforms,
+// then how
+var buildOrder = map[string]int{
+	"id":      1,
+	"builder": 2,
+	"main":    3,
+	"dir":     4,
+	"binary":  5,
+
+	"goos":    10,
+	"goarch":  11,
+	"goarm":   12,
+	"goamd64": 13,
+	"ignore":  14,
+	"targets": 15,
+
+	"env":      20,
+	"flags":    21,
+	"tags":     22,
+	"ldflags":  23,
+	"gcflags":  24,
+	"asmflags": 25,
+	"hooks":    26,
+	"skip":     27,
+}
+
+// archiveOrder ranks the keys of an archive: which builds, the name, the
+// format, then the contents
+var archiveOrder = map[string]int{
+	"id":                1,
+	"ids":               2,
+	"builds":            2,
+	"name_template":     3,
+	"formats":           4,
+	"format":            4,
+	"format_overrides":  5,
+	"wrap_in_directory": 6,
+	"files":             7,
+}
+
+// releaseOrder ranks the release options: where it is published, then how
+var releaseOrder = map[string]int{
+	"github":        1,
+	"gitlab":        1,
+	"gitea":         1,
+	"draft":         10,
+	"prerelease":    11,
+	"make_latest":   12,
+	"mode":          13,
+	"name_template": 14,
+	"header":        15,
+	"footer":        16,
+	"extra_files":   17,
+	"disable":       20,
+	"skip_upload":   21,
+}
+
+// changelogOrder ranks the changelog options
+var changelogOrder = map[string]int{
+	"disable": 1,
+	"use":     2,
+	"format":  3,
+	"sort":    4,
+	"abbrev":  5,
+	"groups":  6,
+	"filters": 7,
+}
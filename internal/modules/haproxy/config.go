@@ -0,0 +1,416 @@
+package haproxy
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// An haproxy.cfg is a list of sections, each a keyword at the start of a line
+// ("global", "defaults", "frontend web", ...) followed by one setting per line.
+// Words are separated by whitespace outside quotes; an unquoted, unescaped
+// "#" starts a comment.
+
+// sectionKeywords are the keywords that start a section
+var sectionKeywords = map[string]bool{
+	"global":      true,
+	"defaults":    true,
+	"frontend":    true,
+	"backend":     true,
+	"listen":      true,
+	"peers":       true,
+	"resolvers":   true,
+	"userlist":    true,
+	"mailers":     true,
+	"program":     true,
+	"http-errors": true,
+	"ring":        true,
+	"cache":       true,
+	"fcgi-app":    true,
+	"log-forward": true,
+	"crt-store":   true,
+	"traces":      true,
+}
+
+// lineKind identifies the kind of a line in a section
+type lineKind int
+
+const (
+	lineBlank lineKind = iota
+	lineComment
+	lineEntry
+)
+
+// line is a setting, a comment or a blank line
+type line struct {
+	kind lineKind
+
+	// words are the words of a setting, as written
+	words []string
+
+	// comment is the text of a comment line, or the inline comment of a setting
+	comment string
+}
+
+// keyword returns the first word of a setting
+func (l *line) keyword() string {
+	if l.kind != lineEntry {
+		return ""
+	}
+	return l.words[0]
+}
+
+// section is a section header and its lines
+type section struct {
+	// comments are the comments in column 0 above the header
+	comments []string
+
+	keyword     string
+	args        []string
+	lineComment string
+	lines       []*line
+}
+
+// config is a parsed haproxy.cfg
+type config struct {
+	// preamble are the comments before the first section
+	preamble []string
+	sections []*section
+
+	// foot are the comments in column 0 after the last section
+	foot []string
+}
+
+// splitLine splits a line into words and an inline comment
+func splitLine(text string) ([]string, string) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' && i+1 < len(text) {
+				word.WriteByte(c)
+				i++
+				c = text[i]
+			} else if c == quote {
+				quote = 0
+			}
+			word.WriteByte(c)
+		case c == '\\' && i+1 < len(text):
+			word.WriteString(text[i : i+2])
+			inWord = true
+			i++
+		case c == '"' || c == '\'':
+			quote = c
+			word.WriteByte(c)
+			inWord = true
+		case c == '#':
+			if inWord {
+				words = append(words, word.String())
+			}
+			return words, strings.TrimSpace(text[i:])
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, ""
+}
+
+// parse reads an haproxy.cfg
+// Comments in column 0 go with the section header below them, unless a
+// setting of the same section follows; indented comments stay where they are
+func parse(data []byte) *config {
+	cfg := &config{}
+	var current *section
+	var pending []*line
+
+	for _, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimSpace(raw)
+		indented := text != "" && (raw[0] == ' ' || raw[0] == '\t')
+
+		switch {
+		case text == "":
+			pending = append(pending, &line{kind: lineBlank})
+			continue
+		case strings.HasPrefix(text, "#"):
+			comment := &line{kind: lineComment, comment: text}
+			if current != nil && indented {
+				current.lines = append(current.lines, pending...)
+				current.lines = append(current.lines, comment)
+				pending = nil
+				continue
+			}
+			pending = append(pending, comment)
+			continue
+		}
+
+		words, comment := splitLine(text)
+		if !indented && sectionKeywords[words[0]] {
+			next := &section{keyword: words[0], args: words[1:], lineComment: comment}
+			if current == nil {
+				cfg.preamble, next.comments = splitPreamble(pending)
+			} else {
+				for _, l := range pending {
+					if l.kind == lineComment {
+						next.comments = append(next.comments, l.comment)
+					}
+				}
+			}
+			pending = nil
+			current = next
+			cfg.sections = append(cfg.sections, current)
+			continue
+		}
+
+		if current == nil {
+			// Settings before any section are kept with the preamble
+			for _, l := range pending {
+				cfg.preamble = append(cfg.preamble, l.comment)
+			}
+			pending = nil
+			cfg.preamble = append(cfg.preamble, text)
+			continue
+		}
+		current.lines = append(current.lines, pending...)
+		current.lines = append(current.lines, &line{kind: lineEntry, words: words, comment: comment})
+		pending = nil
+	}
+
+	// Comments in column 0 at the end of the file stay at the end
+	for _, l := range pending {
+		if l.kind == lineComment {
+			cfg.foot = append(cfg.foot, l.comment)
+		}
+	}
+	return cfg
+}
+
+// splitPreamble splits the lines before the first section header into the
+// file's preamble and the header's comments: a blank line separates them,
+// otherwise every comment goes with the header. Preamble blank lines are kept
+// as "".
+func splitPreamble(lines []*line) (preamble, comments []string) {
+	last := -1
+	for i, l := range lines {
+		if l.kind == lineBlank {
+			last = i
+		}
+	}
+	for i, l := range lines {
+		switch {
+		case l.kind == lineComment && i < last:
+			preamble = append(preamble, l.comment)
+		case l.kind == lineComment:
+			comments = append(comments, l.comment)
+		case i < last && len(preamble) > 0 && preamble[len(preamble)-1] != "":
+			preamble = append(preamble, "")
+		}
+	}
+	return preamble, comments
+}
+
+// sortSections moves global first and the sections that are not proxies
+// (peers, resolvers, userlist, ...) after it; the proxies follow, a defaults
+// section with the proxies after it at a time, since a defaults section
+// applies to the proxies that follow it. Within each group frontends come
+// before backends and listen sections, keeping their order.
+func sortSections(sections []*section) []*section {
+	var head, other []*section
+	var groups [][]*section
+	for _, s := range sections {
+		switch s.keyword {
+		case "global":
+			head = append(head, s)
+		case "defaults":
+			groups = append(groups, []*section{s})
+		case "frontend", "backend", "listen":
+			if len(groups) == 0 {
+				groups = append(groups, nil)
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], s)
+		default:
+			other = append(other, s)
+		}
+	}
+
+	result := append(head, other...)
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return proxyOrder[group[i].keyword] < proxyOrder[group[j].keyword]
+		})
+		result = append(result, group...)
+	}
+	return result
+}
+
+// proxyOrder ranks the sections of a defaults group
+var proxyOrder = map[string]int{
+	"defaults": 0,
+	"frontend": 1,
+	"backend":  2,
+	"listen":   3,
+}
+
+// sortRules moves acl lines ahead of the use_backend lines in each run of
+// consecutive acl, use_backend and default_backend lines, with default_backend
+// last. The sort is stable: use_backend rules are tried in order, and an ACL
+// has to be declared before the rules using it, so moving ACLs up is safe.
+func sortRules(lines []*line) {
+	for start := 0; start < len(lines); {
+		end := start
+		for end < len(lines) && ruleOrder[lines[end].keyword()] > 0 {
+			end++
+		}
+		if end == start {
+			start++
+			continue
+		}
+		run := lines[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return ruleOrder[run[i].keyword()] < ruleOrder[run[j].keyword()]
+		})
+		start = end
+	}
+}
+
+// ruleOrder ranks the lines sortRules reorders
+var ruleOrder = map[string]int{
+	"acl":             1,
+	"use_backend":     2,
+	"default_backend": 3,
+}
+
+// alignedKeywords are the settings named by their first two words, such as
+// "timeout connect"; the values of consecutive lines of each are aligned
+var alignedKeywords = map[string]bool{
+	"timeout": true,
+	"option":  true,
+}
+
+// print writes the config with settings indented by indent spaces
+// Sections are separated by one blank line, runs of blank lines within a
+// section are collapsed and words are separated by one space
+func (cfg *config) print(indent int, alignComments bool) []byte {
+	var buf bytes.Buffer
+	for _, comment := range cfg.preamble {
+		buf.WriteString(comment + "\n")
+	}
+
+	pad := strings.Repeat(" ", indent)
+	for _, s := range cfg.sections {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		for _, comment := range s.comments {
+			buf.WriteString(comment + "\n")
+		}
+		writeLine(&buf, strings.Join(append([]string{s.keyword}, s.args...), " "), s.lineComment, 0)
+
+		lines := trimBlank(s.lines)
+		rendered := renderLines(lines)
+		width := 0
+		for i, l := range lines {
+			switch l.kind {
+			case lineBlank:
+				if lines[i-1].kind != lineBlank {
+					buf.WriteByte('\n')
+				}
+				continue
+			case lineComment:
+				buf.WriteString(pad + l.comment + "\n")
+				continue
+			}
+
+			// Consecutive settings share the comment column
+			if alignComments && (i == 0 || lines[i-1].kind != lineEntry) {
+				width = 0
+				for j := i; j < len(lines) && lines[j].kind == lineEntry; j++ {
+					if lines[j].comment != "" {
+						width = max(width, len(rendered[j]))
+					}
+				}
+			}
+			writeLine(&buf, pad+rendered[i], l.comment, len(pad)+width)
+		}
+	}
+
+	if len(cfg.foot) > 0 && buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	for _, comment := range cfg.foot {
+		buf.WriteString(comment + "\n")
+	}
+	return buf.Bytes()
+}
+
+// writeLine writes a line with an optional comment, padded to column
+func writeLine(buf *bytes.Buffer, text, comment string, column int) {
+	buf.WriteString(text)
+	if comment != "" {
+		buf.WriteString(strings.Repeat(" ", max(column-len(text), 0)+1) + comment)
+	}
+	buf.WriteByte('\n')
+}
+
+// renderLines joins the words of each setting
+// The names of consecutive timeout (and option) lines are padded so their
+// values line up
+func renderLines(lines []*line) []string {
+	rendered := make([]string, len(lines))
+	for i := 0; i < len(lines); {
+		keyword := lines[i].keyword()
+		if !alignedKeywords[keyword] {
+			if keyword != "" {
+				rendered[i] = strings.Join(lines[i].words, " ")
+			}
+			i++
+			continue
+		}
+
+		end := i
+		width := 0
+		for end < len(lines) && lines[end].keyword() == keyword {
+			if len(lines[end].words) > 2 {
+				width = max(width, len(lines[end].words[1]))
+			}
+			end++
+		}
+		for j := i; j < end; j++ {
+			words := lines[j].words
+			if len(words) <= 2 {
+				rendered[j] = strings.Join(words, " ")
+				continue
+			}
+			name := words[1] + strings.Repeat(" ", width-len(words[1]))
+			rendered[j] = keyword + " " + name + " " + strings.Join(words[2:], " ")
+		}
+		i = end
+	}
+	return rendered
+}
+
+// trimBlank drops leading and trailing blank lines
+func trimBlank(lines []*line) []*line {
+	for len(lines) > 0 && lines[0].kind == lineBlank {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1].kind == lineBlank {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,61 @@
+package haproxy
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// HAProxyFormatter formats HAProxy configuration files (haproxy.cfg)
+type HAProxyFormatter struct{}
+
+// New creates a new HAProxyFormatter
+func New() *HAProxyFormatter {
+	return &HAProxyFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *HAProxyFormatter) Name() string {
+	return "haproxy"
+}
+
+// proxyHeader matches a frontend, backend or listen section header
+var proxyHeader = regexp.MustCompile(`(?m)^(frontend|backend|listen)[ \t]+\S`)
+
+// CanHandle checks if this file is an HAProxy configuration file
+// haproxy.cfg and .cfg files under haproxy/ are claimed by name, other .cfg
+// files when they have a proxy section
+func (f *HAProxyFormatter) CanHandle(filename string, data []byte) bool {
+	if filepath.Base(filename) == "haproxy.cfg" {
+		return true
+	}
+	if filepath.Ext(filename) != ".cfg" {
+		return false
+	}
+	return filepath.Base(filepath.Dir(filename)) == "haproxy" || proxyHeader.Match(data)
+}
+
+// Format formats an HAProxy config with consistent indentation and ordering
+func (f *HAProxyFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Settings keep their order, apart from the acl and use_backend lines
+// reordered by sortRules, since many of them (http-request rules, server
+// lines) are applied in order
+func (f *HAProxyFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg := parse(data)
+	if !opts.PreserveKeyOrder {
+		cfg.sections = sortSections(cfg.sections)
+		for _, s := range cfg.sections {
+			sortRules(s.lines)
+		}
+	}
+	return cfg.print(opts.Indent, opts.AlignComments), nil
+}
@@ -0,0 +1,150 @@
+package ini
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// Dialect describes a flavor of INI-like config
+// Dialects are how programs with INI-style configs are supported without a
+// module of their own: each one is registered as a formatter of its own name,
+// detected by Match
+type Dialect struct {
+	// Name is the formatter name of the dialect, as used with -type
+	Name string
+
+	// Match reports whether a file is written in this dialect, by its name
+	Match func(filename string) bool
+
+	// Separator is written between a key and its value, e.g. " = " or "=";
+	// " " is for configs of "key value" lines, which are split on the first
+	// space instead of the first "="
+	Separator string
+
+	// InlineComments lists the characters that start a comment after a
+	// value when preceded by whitespace; empty if comments are only allowed
+	// on lines of their own
+	InlineComments string
+}
+
+// Dialects are tried in order; files with an .ini extension or that look like
+// INI fall back to Generic
+// Dialects has to be extended before the formatters are created
+var Dialects = []Dialect{
+	{
+		Name: "mosquitto",
+		Match: func(filename string) bool {
+			return filepath.Base(filename) == "mosquitto.conf" ||
+				filepath.Ext(filename) == ".conf" && strings.Contains(filepath.ToSlash(filename), "mosquitto/")
+		},
+		// A # after a value is part of the value
+		Separator: " ",
+	},
+	{
+		Name: "php",
+		Match: func(filename string) bool {
+			base := filepath.Base(filename)
+			return base == "php.ini" || base == "php-fpm.conf" ||
+				strings.HasPrefix(base, "php.ini-") ||
+				filepath.Ext(base) == ".conf" && strings.Contains(filepath.ToSlash(filename), "php-fpm.d/")
+		},
+		Separator:      " = ",
+		InlineComments: ";",
+	},
+}
+
+// Generic is used for INI files no other dialect claims
+var Generic = Dialect{
+	Name:           "ini",
+	Match:          func(filename string) bool { return filepath.Ext(filename) == ".ini" },
+	Separator:      " = ",
+	InlineComments: ";#",
+}
+
+// INIFormatter formats INI and INI-like configuration files in one dialect
+type INIFormatter struct {
+	dialect Dialect
+}
+
+// New creates a new INIFormatter for the generic dialect
+func New() *INIFormatter {
+	return NewDialect(Generic)
+}
+
+// NewDialect creates a new INIFormatter for dialect d
+func NewDialect(d Dialect) *INIFormatter {
+	return &INIFormatter{dialect: d}
+}
+
+// Formatters returns a formatter for each of the Dialects, followed by the
+// generic one, in detection order
+func Formatters() []formatter.Formatter {
+	var formatters []formatter.Formatter
+	for _, d := range Dialects {
+		formatters = append(formatters, NewDialect(d))
+	}
+	return append(formatters, New())
+}
+
+// Name returns the name of this formatter
+func (f *INIFormatter) Name() string {
+	return f.dialect.Name
+}
+
+// CanHandle checks if this file is written in the formatter's dialect
+// The generic formatter also takes .cfg and .conf files whose content starts
+// with a section header followed by key = value lines
+func (f *INIFormatter) CanHandle(filename string, data []byte) bool {
+	if f.dialect.Match(filename) {
+		return true
+	}
+	if f.dialect.Name != Generic.Name {
+		return false
+	}
+	switch filepath.Ext(filename) {
+	case ".cfg", ".conf":
+		return looksLikeINI(data)
+	}
+	return false
+}
+
+// looksLikeINI reports whether the first line that is not blank or a comment
+// is a section header followed by at least one key = value line
+func looksLikeINI(data []byte) bool {
+	header := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if !header {
+			if !formatter.IsINISectionHeader(line) {
+				return false
+			}
+			header = true
+			continue
+		}
+		return strings.Contains(line, "=")
+	}
+	return false
+}
+
+// Format formats an INI file
+func (f *INIFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Keys keep their order, since many programs read settings in order;
+// sections are sorted by name when opts.SortSections is set
+func (f *INIFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	syntax := formatter.INISyntax{Separator: f.dialect.Separator, InlineComments: f.dialect.InlineComments}
+	return formatter.FormatINIContext(ctx, data, opts, syntax, func(file *formatter.INIFile) {
+		if opts.SortSections && !opts.PreserveKeyOrder {
+			file.SortSections(nil)
+		}
+	})
+}
@@ -0,0 +1,392 @@
+package istio
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// IstioFormatter formats Istio networking resources: VirtualService,
+// DestinationRule and Gateway
+type IstioFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new IstioFormatter
+func New() *IstioFormatter {
+	return &IstioFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			// The first matching route wins
+			OrderSensitive: []string{"spec.http", "spec.tcp", "spec.tls"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *IstioFormatter) Name() string {
+	return "istio"
+}
+
+// kinds are the resources the formatter knows
+var kinds = map[string]bool{
+	"VirtualService":  true,
+	"DestinationRule": true,
+	"Gateway":         true,
+}
+
+// CanHandle checks if this file holds Istio networking resources
+// Istio resources share file names with every other Kubernetes manifest, so
+// only the apiVersion and kind are used; one Istio document in a manifest is
+// enough
+func (f *IstioFormatter) CanHandle(filename string, data []byte) bool {
+	// Most manifests are not ours; the API group has to appear somewhere
+	if !bytes.Contains(data, []byte("networking.istio.io/")) {
+		return false
+	}
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return false
+	}
+	for _, doc := range docs {
+		if resourceKind(doc) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceKind returns the kind of an Istio resource the formatter knows, or
+// "" for any other document
+func resourceKind(doc *yaml.Node) string {
+	if len(doc.Content) == 0 {
+		return ""
+	}
+	apiVersion := formatter.MappingValue(doc.Content[0], "apiVersion")
+	kind := formatter.MappingValue(doc.Content[0], "kind")
+	if apiVersion == nil || kind == nil || !strings.HasPrefix(apiVersion.Value, "networking.istio.io/") || !kinds[kind.Value] {
+		return ""
+	}
+	return kind.Value
+}
+
+// Format formats an Istio resource file with consistent indentation and ordering
+func (f *IstioFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *IstioFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in Istio resources
+func (f *IstioFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode formats one document, choosing the order tables by its kind
+// Other Kubernetes resources in the same file are left as they are
+func (f *IstioFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	if !isRoot || node.Kind != yaml.DocumentNode {
+		return
+	}
+	if kind := resourceKind(node); kind != "" {
+		f.formatNodeWithContext(node.Content[0], kind, nil, opts)
+	}
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *IstioFormatter) formatNodeWithContext(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		f.sortMappingNode(node, kind, path, opts)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			f.formatNodeWithContext(node.Content[i+1], kind, append(path, node.Content[i].Value), opts)
+		}
+	case yaml.SequenceNode:
+		// Host and gateway lists are sets; sorted they diff cleanly
+		if !opts.PreserveValues && isHostList(path) {
+			formatter.SortSequence(node, formatter.SequenceOptions{})
+		}
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, kind, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// hostLists are the key paths of the host and gateway lists sorted by name
+var hostLists = []string{
+	"spec.hosts",
+	"spec.gateways",
+	"spec.exportTo",
+	"spec.servers.*.hosts",
+	"spec.*.*.match.*.gateways",
+}
+
+// isHostList reports whether path is one of the hostLists
+func isHostList(path []string) bool {
+	for _, pattern := range hostLists {
+		if formatter.MatchKeyPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderTable returns the order table for the mapping at path in a resource of
+// the given kind, or nil to leave it alone
+func orderTable(kind string, path []string) map[string]int {
+	if len(path) == 0 {
+		return topLevelOrder
+	}
+	if len(path) == 1 && path[0] == "metadata" {
+		return metadataOrder
+	}
+	if len(path) == 1 && path[0] == "spec" {
+		return specOrders[kind]
+	}
+	for _, t := range kindTables[kind] {
+		if formatter.MatchKeyPath(t.pattern, path) {
+			return t.table
+		}
+	}
+	return nil
+}
+
+// pathTable maps a key path, as a MatchKeyPath pattern, to an order table
+type pathTable struct {
+	pattern string
+	table   map[string]int
+}
+
+// specOrders ranks the fields of the spec of each kind
+var specOrders = map[string]map[string]int{
+	"VirtualService":  virtualServiceOrder,
+	"DestinationRule": destinationRuleOrder,
+	"Gateway":         gatewayOrder,
+}
+
+// kindTables lists the order tables below the spec of each kind; the first
+// match wins
+var kindTables = map[string][]pathTable{
+	"VirtualService": {
+		{"spec.*.*.match.*", matchOrder},
+		{"spec.*.*.route.*", routeDestinationOrder},
+		{"spec.http.*.mirror", destinationOrder},
+		{"spec.*.*.route.*.destination", destinationOrder},
+		{"spec.http.*", httpRouteOrder},
+		{"spec.tcp.*", tcpRouteOrder},
+		{"spec.tls.*", tcpRouteOrder},
+	},
+	"DestinationRule": {
+		{"spec.subsets.*", subsetOrder},
+		{"spec.trafficPolicy", trafficPolicyOrder},
+		{"spec.subsets.*.trafficPolicy", trafficPolicyOrder},
+		{"**.portLevelSettings.*", trafficPolicyOrder},
+		{"**.trafficPolicy.tls", clientTLSOrder},
+		{"**.portLevelSettings.*.tls", clientTLSOrder},
+	},
+	"Gateway": {
+		{"spec.servers.*", serverOrder},
+		{"spec.servers.*.port", portOrder},
+		{"spec.servers.*.tls", serverTLSOrder},
+	},
+}
+
+// sortMappingNode sorts keys in a mapping node according to Istio conventions
+// Keys missing from the order table follow the known ones, by name; mappings
+// without a table, such as labels and headers, keep their order
+func (f *IstioFormatter) sortMappingNode(node *yaml.Node, kind string, path []string, opts formatter.Options) {
+	table := orderTable(kind, path)
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 || table == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder is the usual order of a Kubernetes resource
+var topLevelOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"metadata":   3,
+	"spec":       4,
+	"status":     5,
+}
+
+// metadataOrder ranks the fields of metadata
+var metadataOrder = map[string]int{
+	"name":        1,
+	"namespace":   2,
+	"labels":      3,
+	"annotations": 4,
+}
+
+// virtualServiceOrder ranks the fields of a VirtualService spec: what it
+// applies to, then its routes
+var virtualServiceOrder = map[string]int{
+	"hosts":    1,
+	"gateways": 2,
+	"exportTo": 3,
+	"http":     4,
+	"tls":      5,
+	"tcp":      6,
+}
+
+// httpRouteOrder ranks the fields of an HTTP route: what it matches, where it
+// sends the request, then how
+var httpRouteOrder = map[string]int{
+	"name":  1,
+	"match": 2,
+
+	// Destination
+	"route":          10,
+	"redirect":       11,
+	"directResponse": 12,
+	"delegate":       13,
+	"rewrite":        14,
+
+	// Behavior
+	"timeout":          20,
+	"retries":          21,
+	"fault":            22,
+	"mirror":           23,
+	"mirrorPercentage": 24,
+	"mirrors":          25,
+	"corsPolicy":       26,
+	"headers":          27,
+}
+
+// tcpRouteOrder ranks the fields of a TCP or TLS route
+var tcpRouteOrder = map[string]int{
+	"match": 1,
+	"route": 2,
+}
+
+// matchOrder ranks the fields of a match condition: the request line, then
+// headers, then where the request comes from
+var matchOrder = map[string]int{
+	"name":            1,
+	"uri":             2,
+	"scheme":          3,
+	"method":          4,
+	"authority":       5,
+	"port":            6,
+	"sniHosts":        7,
+	"headers":         10,
+	"queryParams":     11,
+	"withoutHeaders":  12,
+	"ignoreUriCase":   13,
+	"sourceLabels":    20,
+	"sourceNamespace": 21,
+	"gateways":        22,
+}
+
+// routeDestinationOrder ranks the fields of a weighted route destination
+var routeDestinationOrder = map[string]int{
+	"destination": 1,
+	"weight":      2,
+	"headers":     3,
+}
+
+// destinationOrder ranks the fields of a destination
+var destinationOrder = map[string]int{
+	"host":   1,
+	"subset": 2,
+	"port":   3,
+}
+
+// destinationRuleOrder ranks the fields of a DestinationRule spec
+var destinationRuleOrder = map[string]int{
+	"host":             1,
+	"workloadSelector": 2,
+	"exportTo":         3,
+	"trafficPolicy":    4,
+	"subsets":          5,
+}
+
+// subsetOrder ranks the fields of a subset
+var subsetOrder = map[string]int{
+	"name":          1,
+	"labels":        2,
+	"trafficPolicy": 3,
+}
+
+// trafficPolicyOrder ranks the fields of a traffic policy
+var trafficPolicyOrder = map[string]int{
+	"port":              1,
+	"loadBalancer":      2,
+	"connectionPool":    3,
+	"outlierDetection":  4,
+	"tls":               5,
+	"tunnel":            6,
+	"portLevelSettings": 7,
+}
+
+// clientTLSOrder ranks the fields of the TLS settings of a traffic policy
+var clientTLSOrder = map[string]int{
+	"mode":              1,
+	"credentialName":    2,
+	"clientCertificate": 3,
+	"privateKey":        4,
+	"caCertificates":    5,
+	"sni":               6,
+	"subjectAltNames":   7,
+}
+
+// gatewayOrder ranks the fields of a Gateway spec
+var gatewayOrder = map[string]int{
+	"selector": 1,
+	"servers":  2,
+}
+
+// serverOrder ranks the fields of a Gateway server
+var serverOrder = map[string]int{
+	"name":            1,
+	"port":            2,
+	"bind":            3,
+	"hosts":           4,
+	"tls":             5,
+	"defaultEndpoint": 6,
+}
+
+// portOrder ranks the fields of a Gateway port
+var portOrder = map[string]int{
+	"number":     1,
+	"name":       2,
+	"protocol":   3,
+	"targetPort": 4,
+}
+
+// serverTLSOrder ranks the fields of the TLS settings of a Gateway server
+var serverTLSOrder = map[string]int{
+	"mode":               1,
+	"credentialName":     2,
+	"serverCertificate":  3,
+	"privateKey":         4,
+	"caCertificates":     5,
+	"httpsRedirect":      6,
+	"minProtocolVersion": 7,
+	"maxProtocolVersion": 8,
+	"cipherSuites":       9,
+}
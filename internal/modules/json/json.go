@@ -0,0 +1,43 @@
+package json
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// JSONFormatter formats JSON, JSONC and JSON5 files no more specific
+// formatter claims
+type JSONFormatter struct{}
+
+// New creates a new JSONFormatter
+func New() *JSONFormatter {
+	return &JSONFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *JSONFormatter) Name() string {
+	return "json"
+}
+
+// CanHandle checks if this file is a JSON file
+func (f *JSONFormatter) CanHandle(filename string, data []byte) bool {
+	switch filepath.Ext(filename) {
+	case ".json", ".jsonc", ".json5":
+		return true
+	}
+	return false
+}
+
+// Format formats a JSON file
+func (f *JSONFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Members keep their order, since without knowing the program reading the
+// file there is no convention to sort them by
+func (f *JSONFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatJSONContext(ctx, data, opts, nil)
+}
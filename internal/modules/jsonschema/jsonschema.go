@@ -0,0 +1,213 @@
+package jsonschema
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// metaSchema matches a $schema member naming a JSON Schema meta-schema
+// Config files often point $schema at the schema they follow, so only a
+// json-schema.org meta-schema marks the file as a schema itself
+var metaSchema = regexp.MustCompile(`"\$schema"\s*:\s*"https?://json-schema\.org/`)
+
+// JSONSchemaFormatter formats JSON Schema documents
+type JSONSchemaFormatter struct{}
+
+// New creates a new JSONSchemaFormatter
+func New() *JSONSchemaFormatter {
+	return &JSONSchemaFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *JSONSchemaFormatter) Name() string {
+	return "json-schema"
+}
+
+// CanHandle checks if this file is a JSON Schema: a .schema.json file, or a
+// JSON file whose $schema is a json-schema.org meta-schema
+func (f *JSONSchemaFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	if strings.HasSuffix(base, ".schema.json") {
+		return true
+	}
+	return filepath.Ext(base) == ".json" && metaSchema.Match(data)
+}
+
+// Format formats a JSON Schema
+func (f *JSONSchemaFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The keywords of every schema, nested ones included, are ordered by
+// keywordOrder; properties and definitions are sorted by name and required
+// lists alphabetically, which changes nothing for validators. Values such
+// as default, const and examples are data and keep their order.
+func (f *JSONSchemaFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatJSONContext(ctx, data, opts, func(path []string, node *formatter.JSONNode) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		if node.Kind == formatter.JSONObject && isSchema(path) {
+			formatter.SortJSONEntries(node.Entries, rank)
+			return
+		}
+		// Below are the values of the keywords of a schema
+		if len(path) == 0 || !isSchema(path[:len(path)-1]) {
+			return
+		}
+		switch keyword := path[len(path)-1]; {
+		case node.Kind == formatter.JSONObject && slices.Contains(sortedMaps, keyword):
+			formatter.SortJSONEntries(node.Entries, func(string) int { return 0 })
+		case node.Kind == formatter.JSONArray && keyword == "required":
+			sortStrings(node)
+		}
+	})
+}
+
+// sortedMaps are the keywords whose members are sorted by name
+var sortedMaps = []string{"properties", "$defs", "definitions"}
+
+// schemaMaps are the keywords whose members are each a schema
+var schemaMaps = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas", "dependencies"}
+
+// schemaLists are the keywords whose items are each a schema; items is
+// also in schemaValues, as older drafts accept a list or a single schema
+var schemaLists = []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"}
+
+// schemaValues are the keywords whose value is a schema
+var schemaValues = []string{
+	"items", "additionalItems", "contains", "unevaluatedItems",
+	"additionalProperties", "unevaluatedProperties", "propertyNames",
+	"not", "if", "then", "else",
+}
+
+// isSchema reports whether the value at path is a schema: the root, or a
+// value a keyword of an enclosing schema holds schemas in
+func isSchema(path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	last := path[len(path)-1]
+	if len(path) >= 2 && isSchema(path[:len(path)-2]) {
+		keyword := path[len(path)-2]
+		if slices.Contains(schemaMaps, keyword) {
+			return true
+		}
+		if _, err := strconv.Atoi(last); err == nil && slices.Contains(schemaLists, keyword) {
+			return true
+		}
+	}
+	return slices.Contains(schemaValues, last) && isSchema(path[:len(path)-1])
+}
+
+// rank returns the position of a keyword in a schema; unknown keywords, such
+// as vendor extensions, go last
+func rank(keyword string) int {
+	if order, ok := keywordOrder[keyword]; ok {
+		return order
+	}
+	return 999
+}
+
+// keywordOrder ranks the keywords of a schema: identification, annotations,
+// the type and the constraints on its values, the keywords of arrays and
+// objects, the applicators that combine schemas, and the definitions last
+var keywordOrder = map[string]int{
+	// Identification
+	"$schema":        0,
+	"$id":            1,
+	"$anchor":        2,
+	"$dynamicAnchor": 3,
+	"$vocabulary":    4,
+	"$comment":       5,
+	"$ref":           6,
+	"$dynamicRef":    7,
+
+	// Annotations
+	"title":       10,
+	"description": 11,
+	"deprecated":  12,
+	"readOnly":    13,
+	"writeOnly":   14,
+
+	// Type and values
+	"type":             20,
+	"enum":             21,
+	"const":            22,
+	"format":           23,
+	"default":          24,
+	"examples":         25,
+	"minimum":          30,
+	"exclusiveMinimum": 31,
+	"maximum":          32,
+	"exclusiveMaximum": 33,
+	"multipleOf":       34,
+	"minLength":        40,
+	"maxLength":        41,
+	"pattern":          42,
+	"contentEncoding":  43,
+	"contentMediaType": 44,
+	"contentSchema":    45,
+
+	// Arrays
+	"items":            50,
+	"prefixItems":      51,
+	"additionalItems":  52,
+	"contains":         53,
+	"minContains":      54,
+	"maxContains":      55,
+	"minItems":         56,
+	"maxItems":         57,
+	"uniqueItems":      58,
+	"unevaluatedItems": 59,
+
+	// Objects
+	"properties":            60,
+	"required":              61,
+	"patternProperties":     62,
+	"additionalProperties":  63,
+	"unevaluatedProperties": 64,
+	"propertyNames":         65,
+	"dependentRequired":     66,
+	"dependentSchemas":      67,
+	"dependencies":          68,
+	"minProperties":         69,
+	"maxProperties":         70,
+
+	// Applicators
+	"allOf": 80,
+	"anyOf": 81,
+	"oneOf": 82,
+	"not":   83,
+	"if":    84,
+	"then":  85,
+	"else":  86,
+
+	// Definitions
+	"$defs":       90,
+	"definitions": 91,
+}
+
+// sortStrings sorts an array of strings alphabetically; arrays holding
+// anything else or comments are left as they are
+func sortStrings(node *formatter.JSONNode) {
+	values := make(map[*formatter.JSONEntry]string, len(node.Entries))
+	for _, entry := range node.Entries {
+		value, err := strconv.Unquote(entry.Value.Raw)
+		if entry.Value.Kind != formatter.JSONScalar || err != nil || entry.HasComments() {
+			return
+		}
+		values[entry] = value
+	}
+	sort.SliceStable(node.Entries, func(i, j int) bool {
+		return values[node.Entries[i]] < values[node.Entries[j]]
+	})
+}
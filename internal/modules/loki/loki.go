@@ -0,0 +1,282 @@
+package loki
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// LokiFormatter formats Grafana Loki and Promtail configuration files
+type LokiFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new LokiFormatter
+// Top-level sections are separated by blank lines
+func New() *LokiFormatter {
+	return &LokiFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+			// Promtail runs pipeline stages and relabeling rules in order
+			OrderSensitive: []string{"pipeline_stages", "relabel_configs"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *LokiFormatter) Name() string {
+	return "loki"
+}
+
+// CanHandle checks if this file is a Loki or Promtail configuration file
+func (f *LokiFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	switch filepath.Base(filename) {
+	case "loki.yml", "loki.yaml", "loki-config.yml", "loki-config.yaml",
+		"promtail.yml", "promtail.yaml", "promtail-config.yml", "promtail-config.yaml":
+		return true
+	}
+
+	// Check for Loki and Promtail specific keys
+	if !formatter.MayHaveTopLevelKey(data, "scrape_configs", "schema_config", "limits_config") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if isPromtail(content) && formatter.MappingValue(content, "scrape_configs") != nil {
+			return true
+		}
+		return formatter.MappingValue(content, "schema_config") != nil || formatter.MappingValue(content, "limits_config") != nil
+	}
+
+	return false
+}
+
+// isPromtail reports whether the top-level mapping is a Promtail config
+// Prometheus configs share scrape_configs, but only Promtail ships logs to
+// clients and records positions
+func isPromtail(node *yaml.Node) bool {
+	return formatter.MappingValue(node, "positions") != nil || formatter.MappingValue(node, "clients") != nil
+}
+
+// Format formats a Loki or Promtail YAML file with consistent indentation and ordering
+func (f *LokiFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *LokiFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports repeated items in the lists whose order is significant
+func (f *LokiFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+// Each document is either a Loki or a Promtail config, which decides the
+// order of the top-level sections
+func (f *LokiFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	promtail := false
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		promtail = isPromtail(node.Content[0])
+	}
+	f.formatNodeWithContext(node, isRoot, nil, promtail, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *LokiFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, promtail bool, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, promtail, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, promtail, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), promtail, opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), promtail, opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Loki and Promtail conventions
+// Component settings, labels and pipeline stages keep their order
+func (f *LokiFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, promtail bool, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	order := keyOrderFor(path, isTopLevel, promtail)
+	if order == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// keyOrderFor returns the ranking function for the mapping at path, or nil if
+// the mapping keeps its original order
+func keyOrderFor(path []string, isTopLevel bool, promtail bool) func(key string) int {
+	if isTopLevel {
+		if promtail {
+			return rank(promtailOrder)
+		}
+		return rank(lokiOrder)
+	}
+
+	if promtail {
+		switch {
+		case len(path) == 2 && path[0] == "scrape_configs":
+			return func(key string) int {
+				if order, ok := promtailScrapeConfigOrder[key]; ok {
+					return order
+				}
+				if strings.HasSuffix(key, "_sd_configs") {
+					return promtailScrapeConfigOrder["static_configs"] + 1
+				}
+				// Anything else is a log source (journal, syslog, kafka, ...)
+				return 20
+			}
+		case len(path) == 4 && path[0] == "scrape_configs" && path[2] == "static_configs":
+			return rank(staticConfigOrder)
+		}
+		return nil
+	}
+
+	if len(path) == 3 && path[0] == "schema_config" && path[1] == "configs" {
+		return rank(periodConfigOrder)
+	}
+	return nil
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// lokiOrder ranks the top-level Loki sections in the order of the
+// configuration reference: process settings and shared defaults, the write
+// and read paths, storage and schema, then limits and cluster membership
+var lokiOrder = map[string]int{
+	// Process
+	"target":       1,
+	"auth_enabled": 2,
+	"server":       3,
+	"common":       4,
+
+	// Write and read paths
+	"distributor":      10,
+	"ingester_client":  11,
+	"ingester":         12,
+	"pattern_ingester": 13,
+	"querier":          14,
+	"query_scheduler":  15,
+	"frontend":         16,
+	"frontend_worker":  17,
+	"query_range":      18,
+	"ruler":            19,
+	"ruler_storage":    20,
+	"index_gateway":    21,
+	"bloom_build":      22,
+	"bloom_gateway":    23,
+
+	// Storage
+	"storage_config":     30,
+	"chunk_store_config": 31,
+	"schema_config":      32,
+	"compactor":          33,
+	"table_manager":      34,
+
+	// Limits and runtime
+	"limits_config":      40,
+	"runtime_config":     41,
+	"operational_config": 42,
+
+	// Cluster and telemetry
+	"memberlist":   50,
+	"kafka_config": 51,
+	"tracing":      52,
+	"analytics":    53,
+}
+
+// periodConfigOrder ranks the keys of a schema_config period: when it starts,
+// where chunks and the index go, then the index layout
+var periodConfigOrder = map[string]int{
+	"from":         1,
+	"store":        2,
+	"object_store": 3,
+	"schema":       4,
+	"index":        5,
+	"chunks":       6,
+	"row_shards":   7,
+}
+
+// promtailOrder ranks the top-level Promtail sections: the server, where logs
+// are sent and where reading left off, then what is read
+var promtailOrder = map[string]int{
+	"server":         1,
+	"clients":        2,
+	"positions":      3,
+	"scrape_configs": 4,
+	"limits_config":  5,
+	"target_config":  6,
+	"options":        7,
+	"tracing":        8,
+}
+
+// promtailScrapeConfigOrder ranks the keys of a Promtail scrape config: the
+// job, how lines are decoded and processed, where they come from, then
+// relabeling
+var promtailScrapeConfigOrder = map[string]int{
+	"job_name":        1,
+	"encoding":        2,
+	"decompression":   3,
+	"pipeline_stages": 4,
+	"static_configs":  10,
+	"relabel_configs": 30,
+}
+
+// staticConfigOrder ranks the keys of a static config: targets before labels
+var staticConfigOrder = map[string]int{
+	"targets": 1,
+	"labels":  2,
+}
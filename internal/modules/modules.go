@@ -0,0 +1,98 @@
+// Package modules lists the formatters shipped with config-formatter
+package modules
+
+import (
+	"github.com/awsqed/config-formatter/internal/modules/alertmanager"
+	"github.com/awsqed/config-formatter/internal/modules/argocd"
+	"github.com/awsqed/config-formatter/internal/modules/authentik"
+	"github.com/awsqed/config-formatter/internal/modules/bitbucket"
+	"github.com/awsqed/config-formatter/internal/modules/buildkite"
+	"github.com/awsqed/config-formatter/internal/modules/certmanager"
+	"github.com/awsqed/config-formatter/internal/modules/cloudformation"
+	"github.com/awsqed/config-formatter/internal/modules/consul"
+	"github.com/awsqed/config-formatter/internal/modules/containerd"
+	"github.com/awsqed/config-formatter/internal/modules/devcontainer"
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/internal/modules/drone"
+	"github.com/awsqed/config-formatter/internal/modules/envoy"
+	"github.com/awsqed/config-formatter/internal/modules/esphome"
+	"github.com/awsqed/config-formatter/internal/modules/fluentbit"
+	"github.com/awsqed/config-formatter/internal/modules/flux"
+	"github.com/awsqed/config-formatter/internal/modules/gitlabci"
+	"github.com/awsqed/config-formatter/internal/modules/golangci"
+	"github.com/awsqed/config-formatter/internal/modules/goreleaser"
+	"github.com/awsqed/config-formatter/internal/modules/haproxy"
+	"github.com/awsqed/config-formatter/internal/modules/ini"
+	"github.com/awsqed/config-formatter/internal/modules/istio"
+	"github.com/awsqed/config-formatter/internal/modules/json"
+	"github.com/awsqed/config-formatter/internal/modules/jsonschema"
+	"github.com/awsqed/config-formatter/internal/modules/loki"
+	"github.com/awsqed/config-formatter/internal/modules/mysql"
+	"github.com/awsqed/config-formatter/internal/modules/netplan"
+	"github.com/awsqed/config-formatter/internal/modules/nginx"
+	"github.com/awsqed/config-formatter/internal/modules/nomad"
+	"github.com/awsqed/config-formatter/internal/modules/prometheus"
+	"github.com/awsqed/config-formatter/internal/modules/redis"
+	"github.com/awsqed/config-formatter/internal/modules/skaffold"
+	"github.com/awsqed/config-formatter/internal/modules/ssh"
+	"github.com/awsqed/config-formatter/internal/modules/supervisor"
+	"github.com/awsqed/config-formatter/internal/modules/terraform"
+	"github.com/awsqed/config-formatter/internal/modules/toml"
+	"github.com/awsqed/config-formatter/internal/modules/traefik"
+	"github.com/awsqed/config-formatter/internal/modules/vault"
+	"github.com/awsqed/config-formatter/internal/modules/wireguard"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// All returns every built-in formatter in auto-detection order
+// CI pipelines come first because they may have a top-level "services" key,
+// which the docker-compose content check would claim; Buildkite and Fluent Bit
+// go before Drone, which claims any top-level "steps" or "pipeline", and Loki
+// goes before Prometheus, which claims any top-level "scrape_configs". Tool
+// configs with a top-level "version" key go before docker-compose for the same
+// reason, as does Consul, whose JSON configs may list "services" too. The INI
+// dialects, nginx, HAProxy, TOML, HCL (Terraform, Nomad and Vault) and JSON
+// only match file names that are not YAML, so they go last; nginx and HAProxy
+// go after the INI dialects, whose .conf and .cfg files they would otherwise
+// check for blocks and sections, containerd goes before the generic TOML
+// formatter, and JSON goes after the Dev Container and JSON Schema
+// formatters, which only claim devcontainer.json and schemas.
+// SSH configs are only claimed by name; they, WireGuard, supervisord and
+// Redis configs go before the INI dialects, which would check their .conf
+// files for settings, and so do MySQL option files, since the generic INI
+// formatter would claim my.ini.
+func All() formatter.Registry {
+	registry := formatter.Registry{
+		gitlabci.New(),
+		buildkite.New(),
+		fluentbit.New(),
+		drone.New(),
+		bitbucket.New(),
+		loki.New(),
+		prometheus.New(),
+		alertmanager.New(),
+		golangci.New(),
+		goreleaser.New(),
+		skaffold.New(),
+		envoy.New(),
+		istio.New(),
+		certmanager.New(),
+		argocd.New(),
+		flux.New(),
+		cloudformation.New(),
+		netplan.New(),
+		devcontainer.New(),
+		esphome.New(),
+		authentik.New(),
+		consul.New(),
+		dockercompose.New(),
+		traefik.New(),
+		ssh.New(),
+		wireguard.New(),
+		supervisor.New(),
+		redis.New(),
+		mysql.New(),
+	}
+	registry = append(registry, ini.Formatters()...)
+	return append(registry, nginx.New(), haproxy.New(), containerd.New(), toml.New(), terraform.New(), nomad.New(), vault.New(), jsonschema.New(), json.New())
+}
@@ -0,0 +1,348 @@
+package mysql
+
+import (
+	"cmp"
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// syntax is how MySQL and MariaDB read option files: "key = value" lines with
+// values aligned, options such as skip-name-resolve written on their own, and
+// "#" starting an inline comment
+var syntax = formatter.INISyntax{Separator: " = ", InlineComments: "#", AlignValues: true, BareKeys: true}
+
+// groupHeader matches the header of an option group only MySQL and MariaDB
+// read, which marks a .cnf file as an option file rather than, say, an
+// OpenSSL config
+var groupHeader = regexp.MustCompile(`(?m)^\s*\[(client|client-server|mysql|mysqld|mysqld_safe|mysqldump|server|mariadb|mariadbd|galera)\]`)
+
+// MySQLFormatter formats MySQL and MariaDB option files (my.cnf)
+type MySQLFormatter struct{}
+
+// New creates a new MySQLFormatter
+func New() *MySQLFormatter {
+	return &MySQLFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *MySQLFormatter) Name() string {
+	return "mysql"
+}
+
+// CanHandle checks if this file is a MySQL option file: my.cnf, .my.cnf or
+// my.ini, a .cnf file in a mysql or mariadb directory such as
+// /etc/mysql/conf.d, or a .cnf file with a [mysqld] or [client] group
+func (f *MySQLFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	switch {
+	case base == "my.cnf" || base == ".my.cnf" || base == "my.ini":
+		return true
+	case filepath.Ext(base) != ".cnf":
+		return false
+	}
+	path := filepath.ToSlash(filename)
+	return strings.Contains(path, "mysql/") || strings.Contains(path, "mysql.conf.d/") ||
+		strings.Contains(path, "mariadb.conf.d/") || groupHeader.Match(data)
+}
+
+// Format formats a MySQL option file
+func (f *MySQLFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Option groups are ordered by groupOrder: the clients, then the server, then
+// mysqldump and the other tools. The options of server groups are grouped by
+// what they set, with a blank line between groups unless opts.BlankLines is
+// BlankLinesNone; under BlankLinesPreserve they are only ordered between the
+// blank lines already there.
+func (f *MySQLFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatINIContext(ctx, data, opts, syntax, func(file *formatter.INIFile) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		for _, s := range file.Sections {
+			if isServerGroup(s.Name) {
+				groupOptions(s, opts.BlankLines)
+			}
+		}
+		// Included files override what comes before them, so groups around
+		// an !include keep their order
+		if slices.ContainsFunc(file.Sections, hasOtherLines) {
+			return
+		}
+		sort.SliceStable(file.Sections, func(i, j int) bool {
+			return groupRank(file.Sections[i].Name) < groupRank(file.Sections[j].Name)
+		})
+	})
+}
+
+// hasOtherLines reports whether a group has lines that are not options or
+// comments, such as !include directives
+func hasOtherLines(s *formatter.INISection) bool {
+	return slices.ContainsFunc(s.Lines, func(l formatter.INILine) bool {
+		return l.Kind == formatter.INIOther
+	})
+}
+
+// groupOptions orders the options of a server group by category, keeping the
+// order of options of the same category, since the last of a repeated option
+// wins. Comments move with the option below them. The blank lines between
+// options are replaced by one between categories, unless the group has
+// comments followed by a blank line, such as banners or commented-out
+// options, or lines that are not options; those groups, and all groups under
+// BlankLinesPreserve, are ordered between their blank lines instead.
+func groupOptions(s *formatter.INISection, blankLines formatter.BlankLinePolicy) {
+	if blankLines == formatter.BlankLinesPreserve || hasOtherLines(s) || hasDetachedComments(s.Lines) {
+		s.SortKeys(category)
+		return
+	}
+
+	// Each option with the comments above it, and the comments closing the
+	// group
+	var options [][]formatter.INILine
+	var pending []formatter.INILine
+	for _, l := range s.Lines {
+		switch l.Kind {
+		case formatter.INIComment:
+			pending = append(pending, l)
+		case formatter.INIEntry:
+			options = append(options, append(pending, l))
+			pending = nil
+		}
+	}
+	key := func(option []formatter.INILine) string { return option[len(option)-1].Key }
+	slices.SortStableFunc(options, func(a, b []formatter.INILine) int {
+		return cmp.Compare(category(key(a)), category(key(b)))
+	})
+
+	lines := make([]formatter.INILine, 0, len(s.Lines))
+	for i, option := range options {
+		if i > 0 && category(key(option)) != category(key(options[i-1])) && blankLines != formatter.BlankLinesNone {
+			lines = append(lines, formatter.INILine{Kind: formatter.INIBlank})
+		}
+		lines = append(lines, option...)
+	}
+	s.Lines = append(lines, pending...)
+}
+
+// hasDetachedComments reports whether a comment is followed by a blank line
+// rather than by the option it describes
+func hasDetachedComments(lines []formatter.INILine) bool {
+	for i := 0; i+1 < len(lines); i++ {
+		if lines[i].Kind == formatter.INIComment && lines[i+1].Kind == formatter.INIBlank {
+			return true
+		}
+	}
+	return false
+}
+
+// isServerGroup reports whether the server reads an option group: [mysqld],
+// [server], MariaDB's [mariadb] and [mariadbd], the groups of one version
+// such as [mysqld-8.0], and the [mysqldN] groups of mysqld_multi
+func isServerGroup(name string) bool {
+	name = strings.ToLower(name)
+	for _, group := range []string{"mysqld", "server", "mariadb", "mariadbd", "galera"} {
+		if rest, ok := strings.CutPrefix(name, group); ok && (rest == "" || isDigits(rest) || isVersion(rest)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigits reports whether s is a non-empty run of digits
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// isVersion reports whether s is the suffix of a version group, such as
+// "-8.0"
+func isVersion(s string) bool {
+	version, ok := strings.CutPrefix(s, "-")
+	return ok && version != "" && strings.Trim(version, "0123456789.") == ""
+}
+
+// groupRank returns where an option group goes: the groups every client
+// reads, the command-line client, the server and the scripts starting it,
+// then mysqldump; other groups, such as those of the other tools, keep their
+// order after them
+func groupRank(name string) int {
+	name = strings.ToLower(name)
+	if rank, ok := groupOrder[name]; ok {
+		return rank
+	}
+	if isServerGroup(name) {
+		return groupOrder["mysqld"]
+	}
+	return 999
+}
+
+// groupOrder ranks the option groups: general ones before the programs that
+// read them, as the MySQL manual writes them, so that the more specific
+// group comes later and wins
+var groupOrder = map[string]int{
+	"client":         1,
+	"client-server":  2,
+	"client-mariadb": 3,
+	"mysql":          4,
+	"mariadb-client": 4,
+	"server":         10,
+	"mysqld":         11,
+	"mariadb":        12,
+	"mariadbd":       12,
+	"galera":         13,
+	"embedded":       14,
+	"mysqld_safe":    15,
+	"mariadbd-safe":  15,
+	"mysqld_multi":   16,
+	"mysqldump":      20,
+	"mariadb-dump":   20,
+}
+
+// Categories of server options, in the order they are written
+const (
+	categoryGeneral = iota
+	categoryConnection
+	categorySecurity
+	categoryCaches
+	categoryInnoDB
+	categoryReplication
+	categoryLogging
+	categoryOther
+)
+
+// category returns the category of a server option; MySQL reads "-" and "_"
+// in option names alike, and the loose- prefix and the skip-, enable- and
+// disable- prefixes of boolean options do not change what they set
+func category(key string) int {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	key = strings.TrimPrefix(key, "loose_")
+	if c, ok := categories[key]; ok {
+		return c
+	}
+	for _, prefix := range []string{"skip_", "enable_", "disable_"} {
+		if option, ok := strings.CutPrefix(key, prefix); ok {
+			if c, ok := categories[option]; ok {
+				return c
+			}
+			key = option
+		}
+	}
+	for _, p := range categoryPrefixes {
+		if strings.HasPrefix(key, p.prefix) {
+			return p.category
+		}
+	}
+	return categoryOther
+}
+
+// categoryPrefixes are the categories of the option families that share a
+// prefix
+var categoryPrefixes = []struct {
+	prefix   string
+	category int
+}{
+	{"innodb_", categoryInnoDB},
+	{"ssl_", categorySecurity},
+	{"tls_", categorySecurity},
+	{"binlog_", categoryReplication},
+	{"relay_log", categoryReplication},
+	{"replicate_", categoryReplication},
+	{"replica_", categoryReplication},
+	{"slave_", categoryReplication},
+	{"gtid_", categoryReplication},
+	{"wsrep_", categoryReplication},
+	{"log_slow_", categoryLogging},
+	{"query_cache_", categoryCaches},
+	{"myisam_", categoryCaches},
+}
+
+// categories are the categories of single server options: where the server
+// runs and its defaults, how clients reach it, who may connect, caches and
+// buffers, InnoDB, replication and the binary log, then the other logs
+var categories = map[string]int{
+	"user":                            categoryGeneral,
+	"pid_file":                        categoryGeneral,
+	"basedir":                         categoryGeneral,
+	"datadir":                         categoryGeneral,
+	"tmpdir":                          categoryGeneral,
+	"lc_messages_dir":                 categoryGeneral,
+	"lc_messages":                     categoryGeneral,
+	"character_set_server":            categoryGeneral,
+	"collation_server":                categoryGeneral,
+	"init_connect":                    categoryGeneral,
+	"default_storage_engine":          categoryGeneral,
+	"default_time_zone":               categoryGeneral,
+	"sql_mode":                        categoryGeneral,
+	"lower_case_table_names":          categoryGeneral,
+	"explicit_defaults_for_timestamp": categoryGeneral,
+	"event_scheduler":                 categoryGeneral,
+	"port":                            categoryConnection,
+	"socket":                          categoryConnection,
+	"bind_address":                    categoryConnection,
+	"mysqlx_bind_address":             categoryConnection,
+	"mysqlx_port":                     categoryConnection,
+	"mysqlx_socket":                   categoryConnection,
+	"networking":                      categoryConnection,
+	"name_resolve":                    categoryConnection,
+	"host_cache_size":                 categoryConnection,
+	"max_connections":                 categoryConnection,
+	"max_user_connections":            categoryConnection,
+	"max_connect_errors":              categoryConnection,
+	"back_log":                        categoryConnection,
+	"connect_timeout":                 categoryConnection,
+	"wait_timeout":                    categoryConnection,
+	"interactive_timeout":             categoryConnection,
+	"net_read_timeout":                categoryConnection,
+	"net_write_timeout":               categoryConnection,
+	"max_allowed_packet":              categoryConnection,
+	"require_secure_transport":        categorySecurity,
+	"default_authentication_plugin":   categorySecurity,
+	"authentication_policy":           categorySecurity,
+	"local_infile":                    categorySecurity,
+	"secure_file_priv":                categorySecurity,
+	"symbolic_links":                  categorySecurity,
+	"key_buffer_size":                 categoryCaches,
+	"table_open_cache":                categoryCaches,
+	"table_definition_cache":          categoryCaches,
+	"open_files_limit":                categoryCaches,
+	"thread_cache_size":               categoryCaches,
+	"thread_stack":                    categoryCaches,
+	"sort_buffer_size":                categoryCaches,
+	"read_buffer_size":                categoryCaches,
+	"read_rnd_buffer_size":            categoryCaches,
+	"join_buffer_size":                categoryCaches,
+	"tmp_table_size":                  categoryCaches,
+	"max_heap_table_size":             categoryCaches,
+	"bulk_insert_buffer_size":         categoryCaches,
+	"server_id":                       categoryReplication,
+	"log_bin":                         categoryReplication,
+	"log_bin_index":                   categoryReplication,
+	"sync_binlog":                     categoryReplication,
+	"max_binlog_size":                 categoryReplication,
+	"expire_logs_days":                categoryReplication,
+	"log_replica_updates":             categoryReplication,
+	"log_slave_updates":               categoryReplication,
+	"enforce_gtid_consistency":        categoryReplication,
+	"read_only":                       categoryReplication,
+	"super_read_only":                 categoryReplication,
+	"report_host":                     categoryReplication,
+	"log_error":                       categoryLogging,
+	"log_error_verbosity":             categoryLogging,
+	"log_warnings":                    categoryLogging,
+	"log_output":                      categoryLogging,
+	"log_timestamps":                  categoryLogging,
+	"general_log":                     categoryLogging,
+	"general_log_file":                categoryLogging,
+	"slow_query_log":                  categoryLogging,
+	"slow_query_log_file":             categoryLogging,
+	"long_query_time":                 categoryLogging,
+	"log_queries_not_using_indexes":   categoryLogging,
+	"syslog":                          categoryLogging,
+}
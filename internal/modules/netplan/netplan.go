@@ -0,0 +1,343 @@
+package netplan
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"strconv"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// NetplanFormatter formats netplan network configuration (/etc/netplan/*.yaml)
+type NetplanFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new NetplanFormatter
+func New() *NetplanFormatter {
+	return &NetplanFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			// Routing policy rules are matched in order
+			OrderSensitive: []string{"routing-policy"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *NetplanFormatter) Name() string {
+	return "netplan"
+}
+
+// CanHandle checks if this file is a netplan configuration file: a YAML file
+// in a netplan directory, or one whose top-level network mapping declares
+// devices or a renderer
+func (f *NetplanFormatter) CanHandle(filename string, data []byte) bool {
+	ext := filepath.Ext(filename)
+	if ext != ".yaml" && ext != ".yml" {
+		return false
+	}
+	if filepath.Base(filepath.Dir(filename)) == "netplan" {
+		return true
+	}
+
+	if !formatter.MayHaveTopLevelKey(data, "network") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return false
+	}
+	network := formatter.MappingValue(root.Content[0], "network")
+	if network == nil || network.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(network.Content); i += 2 {
+		if _, ok := deviceTypes[network.Content[i].Value]; ok || network.Content[i].Value == "renderer" {
+			return true
+		}
+	}
+	return false
+}
+
+// Format formats a netplan YAML file with consistent indentation and ordering
+func (f *NetplanFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *NetplanFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports issues in netplan configuration
+func (f *NetplanFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *NetplanFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *NetplanFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, path, opts)
+	}
+
+	if !opts.PreserveValues && isAddressField(path) {
+		normalizeAddress(node, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// orderTable returns the order table of the mapping at path, or nil to leave
+// it as it is; an empty table sorts the keys by name
+func orderTable(path []string) map[string]int {
+	switch {
+	case len(path) == 1 && path[0] == "network":
+		return networkOrder
+	case len(path) == 2 && path[0] == "network" && deviceTypes[path[1]] != nil:
+		// Interfaces by name
+		return map[string]int{}
+	case len(path) == 3 && path[0] == "network" && deviceTypes[path[1]] != nil:
+		return deviceTypes[path[1]]
+	case len(path) == 4 && path[0] == "network" && path[3] == "nameservers":
+		return nameserversOrder
+	case len(path) == 5 && path[0] == "network" && path[3] == "routes":
+		return routeOrder
+	}
+	return nil
+}
+
+// sortMappingNode sorts keys in a mapping node according to netplan conventions
+func (f *NetplanFormatter) sortMappingNode(node *yaml.Node, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+	var table map[string]int
+	if len(path) == 0 {
+		table = topLevelOrder
+	} else if table = orderTable(path); table == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// isAddressField reports whether path points at an address or CIDR value:
+// an item of an interface's or its nameservers' addresses, a gateway, or the
+// to, via and from of a route or routing policy
+func isAddressField(path []string) bool {
+	if len(path) < 4 || path[0] != "network" || deviceTypes[path[1]] == nil {
+		return false
+	}
+	rel := path[3:]
+	switch {
+	case len(rel) == 2 && rel[0] == "addresses":
+		return true
+	case len(rel) == 1 && (rel[0] == "gateway4" || rel[0] == "gateway6"):
+		return true
+	case len(rel) == 3 && rel[0] == "nameservers" && rel[1] == "addresses":
+		return true
+	case len(rel) == 3 && (rel[0] == "routes" || rel[0] == "routing-policy"):
+		return rel[2] == "to" || rel[2] == "via" || rel[2] == "from"
+	}
+	return false
+}
+
+// normalizeAddress writes an IPv4 address or CIDR as a plain string and an
+// IPv6 one quoted, as its colons need in flow lists, however it was written;
+// other values, such as "default" routes, are left as they are
+func normalizeAddress(node *yaml.Node, opts formatter.Options) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	var addr netip.Addr
+	if prefix, err := netip.ParsePrefix(node.Value); err == nil {
+		addr = prefix.Addr()
+	} else if addr, err = netip.ParseAddr(node.Value); err != nil {
+		return
+	}
+	node.Tag = "!!str"
+	node.Style = 0
+	if addr.Is6() {
+		node.Style = opts.QuoteStyle.NodeStyle()
+	}
+}
+
+// topLevelOrder puts network first; netplan reads nothing else
+var topLevelOrder = map[string]int{
+	"network": 1,
+}
+
+// networkOrder ranks the keys of the network mapping: the schema version and
+// backend, then the device types, physical devices before the virtual ones
+// built on them
+var networkOrder = map[string]int{
+	"version":  1,
+	"renderer": 2,
+
+	"ethernets":         10,
+	"modems":            11,
+	"wifis":             12,
+	"bonds":             13,
+	"bridges":           14,
+	"vlans":             15,
+	"tunnels":           16,
+	"vrfs":              17,
+	"dummy-devices":     18,
+	"virtual-ethernets": 19,
+	"nm-devices":        20,
+}
+
+// interfaceOrder ranks the keys of an interface: how it is identified, its
+// links to other devices, addressing, then link settings
+var interfaceOrder = map[string]int{
+	"match":      1,
+	"set-name":   2,
+	"id":         3,
+	"link":       4,
+	"interfaces": 5,
+	"renderer":   6,
+
+	"dhcp4":           10,
+	"dhcp6":           11,
+	"dhcp4-overrides": 12,
+	"dhcp6-overrides": 13,
+	"accept-ra":       14,
+	"link-local":      15,
+
+	"addresses":      20,
+	"gateway4":       21,
+	"gateway6":       22,
+	"nameservers":    23,
+	"routes":         24,
+	"routing-policy": 25,
+
+	"mtu":        30,
+	"macaddress": 31,
+	"wakeonlan":  32,
+	"optional":   33,
+
+	"parameters": 40,
+}
+
+// tunnelOrder ranks the keys of a tunnel: its mode and endpoints come before
+// the interface settings
+var tunnelOrder = map[string]int{
+	"mode":   1,
+	"local":  2,
+	"remote": 3,
+	"key":    4,
+	"keys":   4,
+	"ttl":    5,
+
+	"dhcp4":          10,
+	"dhcp6":          11,
+	"addresses":      20,
+	"gateway4":       21,
+	"gateway6":       22,
+	"nameservers":    23,
+	"routes":         24,
+	"routing-policy": 25,
+	"mtu":            30,
+	"optional":       33,
+}
+
+// wifiOrder ranks the keys of a wifi interface, with its access points
+// after the addressing
+var wifiOrder = map[string]int{
+	"match":    1,
+	"set-name": 2,
+	"renderer": 6,
+
+	"dhcp4":       10,
+	"dhcp6":       11,
+	"addresses":   20,
+	"gateway4":    21,
+	"gateway6":    22,
+	"nameservers": 23,
+	"routes":      24,
+
+	"access-points":     30,
+	"regulatory-domain": 31,
+	"wakeonwlan":        32,
+	"optional":          33,
+}
+
+// deviceTypes maps each device type to the order table of its interfaces
+var deviceTypes = map[string]map[string]int{
+	"ethernets":         interfaceOrder,
+	"modems":            interfaceOrder,
+	"wifis":             wifiOrder,
+	"bonds":             interfaceOrder,
+	"bridges":           interfaceOrder,
+	"vlans":             interfaceOrder,
+	"tunnels":           tunnelOrder,
+	"vrfs":              interfaceOrder,
+	"dummy-devices":     interfaceOrder,
+	"virtual-ethernets": interfaceOrder,
+	"nm-devices":        interfaceOrder,
+}
+
+// nameserversOrder puts the servers before the search domains
+var nameserversOrder = map[string]int{
+	"addresses": 1,
+	"search":    2,
+}
+
+// routeOrder ranks the keys of a route: destination and gateway first
+var routeOrder = map[string]int{
+	"to":      1,
+	"via":     2,
+	"from":    3,
+	"metric":  4,
+	"table":   5,
+	"on-link": 6,
+	"scope":   7,
+	"type":    8,
+	"mtu":     9,
+}
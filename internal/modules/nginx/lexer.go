@@ -0,0 +1,230 @@
+package nginx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// tokenKind identifies the kind of a token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenSemicolon
+	tokenOpen
+	tokenClose
+	tokenComment
+
+	// tokenRaw is the body of a *_by_lua_block, kept as written
+	tokenRaw
+)
+
+// token is one lexical element of an nginx config
+type token struct {
+	kind tokenKind
+	text string
+	line int
+	col  int
+
+	// newline is set when the token is the first on its line, and blank when
+	// a blank line separates it from the previous token
+	newline bool
+	blank   bool
+}
+
+// describe names a token in error messages
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of file"
+	case tokenSemicolon:
+		return `";"`
+	case tokenOpen:
+		return `"{"`
+	case tokenClose:
+		return `"}"`
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lexer splits an nginx config into tokens
+// Words end at whitespace, ";", "{" and "}" outside quotes, and keep their
+// quotes; "#" at the start of a word begins a comment. As in nginx, "${"
+// inside a word starts a variable rather than a block.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+
+	// lastLine is the line the previous token ended on
+	lastLine int
+
+	// statement holds the words of the directive being read, to recognize
+	// the Lua blocks whose body is not nginx syntax
+	statement []string
+}
+
+// lex returns the tokens of data, ending with a tokenEOF
+func lex(data []byte) ([]token, error) {
+	lx := &lexer{src: string(data), line: 1, col: 1}
+	var tokens []token
+	for {
+		t, err := lx.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+		if t.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// advance moves past n bytes, keeping track of the position
+func (lx *lexer) advance(n int) {
+	for _, c := range []byte(lx.src[lx.pos : lx.pos+n]) {
+		if c == '\n' {
+			lx.line++
+			lx.col = 1
+		} else {
+			lx.col++
+		}
+	}
+	lx.pos += n
+}
+
+// errorf returns a parse error at the current position
+func (lx *lexer) errorf(line, col int, format string, args ...any) error {
+	return &formatter.ParseError{Language: "nginx config", Line: line, Col: col, Message: fmt.Sprintf(format, args...)}
+}
+
+// next reads the next token
+func (lx *lexer) next() (token, error) {
+	newlines := 0
+	for lx.pos < len(lx.src) && strings.IndexByte(" \t\r\n", lx.src[lx.pos]) >= 0 {
+		if lx.src[lx.pos] == '\n' {
+			newlines++
+		}
+		lx.advance(1)
+	}
+	defer func() { lx.lastLine = lx.line }()
+
+	t := token{line: lx.line, col: lx.col, newline: lx.line > lx.lastLine, blank: newlines > 1}
+	if lx.pos >= len(lx.src) {
+		t.kind = tokenEOF
+		return t, nil
+	}
+
+	switch c := lx.src[lx.pos]; c {
+	case ';':
+		t.kind, t.text = tokenSemicolon, ";"
+		lx.statement = nil
+		lx.advance(1)
+	case '{':
+		t.kind, t.text = tokenOpen, "{"
+		lua := len(lx.statement) > 0 && strings.HasSuffix(lx.statement[0], "_by_lua_block")
+		lx.statement = nil
+		lx.advance(1)
+		if lua {
+			return lx.raw(t)
+		}
+	case '}':
+		t.kind, t.text = tokenClose, "}"
+		lx.statement = nil
+		lx.advance(1)
+	case '#':
+		end := strings.IndexByte(lx.src[lx.pos:], '\n')
+		if end < 0 {
+			end = len(lx.src) - lx.pos
+		}
+		t.kind, t.text = tokenComment, strings.TrimRight(lx.src[lx.pos:lx.pos+end], " \t\r")
+		lx.advance(end)
+	default:
+		end := lx.pos
+		for end < len(lx.src) {
+			ch := lx.src[end]
+			if strings.IndexByte(" \t\r\n;}", ch) >= 0 {
+				break
+			}
+			if ch == '{' {
+				if end == lx.pos || lx.src[end-1] != '$' {
+					break
+				}
+				closing := strings.IndexByte(lx.src[end:], '}')
+				if closing < 0 {
+					return t, lx.errorf(t.line, t.col, "unterminated variable")
+				}
+				end += closing + 1
+				continue
+			}
+			// A quoted string is one word, which may go on, as in ($a = 'b')
+			if ch == '"' || ch == '\'' {
+				closing := end + 1
+				for closing < len(lx.src) && lx.src[closing] != ch {
+					if lx.src[closing] == '\\' {
+						closing++
+					}
+					closing++
+				}
+				if closing >= len(lx.src) {
+					return t, lx.errorf(t.line, t.col, "unterminated string")
+				}
+				end = closing + 1
+				continue
+			}
+			if ch == '\\' && end+1 < len(lx.src) {
+				end++
+			}
+			end++
+		}
+		t.kind, t.text = tokenWord, lx.src[lx.pos:end]
+		lx.advance(end - lx.pos)
+		lx.statement = append(lx.statement, t.text)
+	}
+	return t, nil
+}
+
+// raw reads the body of a Lua block up to its closing brace, which it
+// consumes; braces in Lua strings and comments are not counted
+func (lx *lexer) raw(open token) (token, error) {
+	start := lx.pos
+	depth := 1
+	for lx.pos < len(lx.src) {
+		switch c := lx.src[lx.pos]; c {
+		case '"', '\'':
+			end := lx.pos + 1
+			for end < len(lx.src) && lx.src[end] != c && lx.src[end] != '\n' {
+				if lx.src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			lx.advance(min(end+1, len(lx.src)) - lx.pos)
+			continue
+		case '-':
+			if strings.HasPrefix(lx.src[lx.pos:], "--") {
+				end := strings.IndexByte(lx.src[lx.pos:], '\n')
+				if end < 0 {
+					end = len(lx.src) - lx.pos
+				}
+				lx.advance(end)
+				continue
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				body := lx.src[start:lx.pos]
+				lx.advance(1)
+				return token{kind: tokenRaw, text: body, line: open.line, col: open.col}, nil
+			}
+		}
+		lx.advance(1)
+	}
+	return open, lx.errorf(open.line, open.col, `unexpected end of file, expecting "}"`)
+}
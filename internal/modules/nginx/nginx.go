@@ -0,0 +1,116 @@
+package nginx
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// NginxFormatter formats nginx configuration files
+type NginxFormatter struct{}
+
+// New creates a new NginxFormatter
+func New() *NginxFormatter {
+	return &NginxFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *NginxFormatter) Name() string {
+	return "nginx"
+}
+
+// blockStart matches a line opening one of the blocks nginx configs are built from
+var blockStart = regexp.MustCompile(`(?m)^\s*(http|events|server|upstream|stream|location|map|types)\b[^;{}#\n]*\{`)
+
+// CanHandle checks if this file is an nginx configuration file
+// nginx.conf and files in nginx's config directories are claimed by name;
+// other .conf files when they open an http, server, location or similar block
+func (f *NginxFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	if base == "nginx.conf" || filepath.Ext(filename) == ".nginx" {
+		return true
+	}
+	switch filepath.Base(filepath.Dir(filename)) {
+	case "sites-available", "sites-enabled":
+		return true
+	}
+	return filepath.Ext(filename) == ".conf" && blockStart.Match(data)
+}
+
+// Format formats an nginx config with consistent indentation and ordering
+func (f *NginxFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Comments are kept with the directive below them; arguments and their
+// quoting are kept as written
+func (f *NginxFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.PreserveKeyOrder {
+		sortServers(cfg.directives, nil, opts.KeepOrder)
+	}
+	return cfg.print(opts), nil
+}
+
+// sortServers orders the directives of every server block, except those
+// whose block path (e.g. http.server) matches a keep_order pattern
+// The sort is stable and locations share a rank, so regex locations, which
+// nginx tries in order, keep their order, as do the rewrite module's
+// directives (rewrite, return, set, if), which run in order
+func sortServers(list []*directive, path []string, keepOrder []string) {
+	for _, d := range list {
+		if !d.block || d.raw != nil {
+			continue
+		}
+		childPath := append(slices.Clip(path), d.name)
+		sortServers(d.children, childPath, keepOrder)
+
+		if d.name != "server" || slices.ContainsFunc(keepOrder, func(pattern string) bool {
+			return formatter.MatchKeyPath(pattern, childPath)
+		}) {
+			continue
+		}
+		sort.SliceStable(d.children, func(i, j int) bool {
+			return serverRank(d.children[i]) < serverRank(d.children[j])
+		})
+	}
+}
+
+// serverRank ranks a directive in a server block: what the server answers
+// to, where its files are, then everything else, then its locations
+func serverRank(d *directive) int {
+	if order, ok := serverOrder[d.name]; ok {
+		return order
+	}
+	if strings.HasPrefix(d.name, "ssl_") {
+		return 20
+	}
+	return 500
+}
+
+// serverOrder ranks the directives of a server block
+var serverOrder = map[string]int{
+	"listen":      1,
+	"server_name": 2,
+
+	"root":  10,
+	"index": 11,
+
+	// Other ssl_* directives rank 20
+	"ssl_certificate":     18,
+	"ssl_certificate_key": 19,
+
+	"location": 900,
+}
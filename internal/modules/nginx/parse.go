@@ -0,0 +1,191 @@
+package nginx
+
+import (
+	"fmt"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// directive is a simple directive ("name args;") or a block directive
+// ("name args { ... }") with the comments around it
+type directive struct {
+	// comments are the comments on lines of their own above the directive,
+	// with "" where a blank line separated them
+	comments []string
+
+	// blankBefore is set when a blank line preceded the directive and its comments
+	blankBefore bool
+
+	name string
+	args []arg
+
+	// lineComment follows the ";" or "{" on the same line
+	lineComment string
+
+	// block is set for block directives; children are the directives in it and
+	// foot the comments after the last of them
+	block    bool
+	children []*directive
+	foot     []string
+
+	// raw is the body of a *_by_lua_block, which is not nginx syntax
+	raw *string
+
+	// closeComment follows the closing "}" on the same line
+	closeComment string
+}
+
+// arg is one argument of a directive
+type arg struct {
+	text string
+
+	// newline is set when the argument started a new line in the input
+	newline bool
+}
+
+// config is a parsed nginx config
+type config struct {
+	directives []*directive
+
+	// foot are the comments after the last directive
+	foot []string
+}
+
+// parser builds directives from tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse reads an nginx config
+func parse(data []byte) (*config, error) {
+	tokens, err := lex(data)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	directives, foot, err := p.block(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &config{directives: directives, foot: foot}, nil
+}
+
+// next returns the next token and moves past it
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// sameLineComment consumes and returns a comment on the line of the previous
+// token, or returns ""
+func (p *parser) sameLineComment() string {
+	if t := p.tokens[p.pos]; t.kind == tokenComment && !t.newline {
+		p.pos++
+		return t.text
+	}
+	return ""
+}
+
+// errorf returns a parse error at t
+func errorf(t token, format string, args ...any) error {
+	return &formatter.ParseError{Language: "nginx config", Line: t.line, Col: t.col, Message: fmt.Sprintf(format, args...)}
+}
+
+// block reads directives up to the "}" closing open, or to the end of the
+// file when open is nil
+func (p *parser) block(open *token) ([]*directive, []string, error) {
+	var directives []*directive
+	var pending []string
+	blankPending := false
+
+	// note records a blank line before the next comment or directive
+	note := func(t token) {
+		if !t.blank {
+			return
+		}
+		if len(pending) == 0 {
+			blankPending = true
+		} else {
+			pending = append(pending, "")
+		}
+	}
+
+	for {
+		t := p.next()
+		switch t.kind {
+		case tokenEOF:
+			if open != nil {
+				return nil, nil, errorf(t, `unexpected end of file, expecting "}" to close the block at line %d`, open.line)
+			}
+			return directives, foot(pending, blankPending), nil
+		case tokenClose:
+			if open == nil {
+				return nil, nil, errorf(t, `unexpected "}"`)
+			}
+			return directives, foot(pending, blankPending), nil
+		case tokenComment:
+			note(t)
+			pending = append(pending, t.text)
+		case tokenWord:
+			note(t)
+			d, err := p.directive(t)
+			if err != nil {
+				return nil, nil, err
+			}
+			d.comments = append(pending, d.comments...)
+			d.blankBefore = blankPending
+			directives = append(directives, d)
+			pending, blankPending = nil, false
+		default:
+			return nil, nil, errorf(t, "unexpected %s", t.describe())
+		}
+	}
+}
+
+// foot returns the comments ending a block, starting with "" when a blank
+// line separated them from the last directive
+func foot(comments []string, blank bool) []string {
+	if blank && len(comments) > 0 {
+		return append([]string{""}, comments...)
+	}
+	return comments
+}
+
+// directive reads the rest of the directive named by name
+// Comments between the arguments are moved above the directive
+func (p *parser) directive(name token) (*directive, error) {
+	d := &directive{name: name.text}
+	for {
+		t := p.next()
+		switch t.kind {
+		case tokenWord:
+			d.args = append(d.args, arg{text: t.text, newline: t.newline})
+		case tokenComment:
+			d.comments = append(d.comments, t.text)
+		case tokenSemicolon:
+			d.lineComment = p.sameLineComment()
+			return d, nil
+		case tokenOpen:
+			d.block = true
+			d.lineComment = p.sameLineComment()
+			children, foot, err := p.block(&t)
+			if err != nil {
+				return nil, err
+			}
+			d.children, d.foot = children, foot
+			d.closeComment = p.sameLineComment()
+			return d, nil
+		case tokenRaw:
+			d.block = true
+			d.raw = &t.text
+			d.closeComment = p.sameLineComment()
+			return d, nil
+		default:
+			return nil, errorf(t, `unexpected %s, directive %q is missing ";"`, t.describe(), d.name)
+		}
+	}
+}
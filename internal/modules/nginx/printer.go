@@ -0,0 +1,214 @@
+package nginx
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// printer writes a config with blocks indented by opts.Indent spaces
+// Under BlankLinesSections block directives are set apart by blank lines and
+// other blank lines are dropped; BlankLinesPreserve keeps them where the input
+// had them and BlankLinesNone writes none
+type printer struct {
+	buf  bytes.Buffer
+	opts formatter.Options
+}
+
+// print writes the config
+func (c *config) print(opts formatter.Options) []byte {
+	pr := &printer{opts: opts}
+	pr.directives(c.directives, 0)
+	pr.foot(c.foot, len(c.directives) > 0, "")
+	return pr.buf.Bytes()
+}
+
+// blank writes a blank line, unless at the start of the file or a block, or
+// after another blank line
+func (pr *printer) blank() {
+	b := pr.buf.Bytes()
+	if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n\n")) && !bytes.HasSuffix(b, []byte("{\n")) {
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// separate reports whether a blank line goes before a directive that had one
+// above it in the input; section is set next to a block directive
+func (pr *printer) separate(blankBefore, section bool) bool {
+	switch pr.opts.BlankLines {
+	case formatter.BlankLinesNone:
+		return false
+	case formatter.BlankLinesPreserve:
+		return blankBefore
+	}
+	return section
+}
+
+// comments writes comments on lines of their own, prefixed by pad; the blank
+// lines between them are only kept under BlankLinesPreserve
+func (pr *printer) comments(comments []string, pad string) {
+	for _, comment := range comments {
+		if comment == "" {
+			if pr.opts.BlankLines == formatter.BlankLinesPreserve {
+				pr.blank()
+			}
+			continue
+		}
+		pr.buf.WriteString(pad + comment + "\n")
+	}
+}
+
+// foot writes the comments ending a block or the file; a leading "" marks a
+// blank line above them
+func (pr *printer) foot(comments []string, afterDirectives bool, pad string) {
+	if len(comments) > 0 && comments[0] == "" {
+		if afterDirectives && pr.opts.BlankLines != formatter.BlankLinesNone {
+			pr.blank()
+		}
+		comments = comments[1:]
+	}
+	pr.comments(comments, pad)
+}
+
+// multiline reports whether a directive's arguments span several lines
+func (d *directive) multiline() bool {
+	for _, a := range d.args[min(1, len(d.args)):] {
+		if a.newline {
+			return true
+		}
+	}
+	return false
+}
+
+// directives writes the directives of a block at the given depth
+// The arguments of consecutive one-line simple directives start on a common
+// column; a comment, a blank line or a block directive starts a new run. With
+// AlignComments their line comments also share a column.
+func (pr *printer) directives(list []*directive, depth int) {
+	pad := strings.Repeat(" ", depth*pr.opts.Indent)
+
+	blankAbove := func(i int) bool {
+		return i > 0 && pr.separate(list[i].blankBefore, list[i].block || list[i-1].block)
+	}
+	aligned := func(i int) bool {
+		return !list[i].block && !list[i].multiline()
+	}
+	startsRun := func(i int) bool {
+		return i == 0 || len(list[i].comments) > 0 || blankAbove(i) || !aligned(i) || !aligned(i-1)
+	}
+
+	rendered := make([]string, len(list))
+	for i := range list {
+		if !aligned(i) || !startsRun(i) {
+			continue
+		}
+		end := i + 1
+		for end < len(list) && !startsRun(end) {
+			end++
+		}
+		width := 0
+		for j := i; j < end; j++ {
+			if len(list[j].args) > 0 {
+				width = max(width, len(list[j].name))
+			}
+		}
+		for j := i; j < end; j++ {
+			rendered[j] = list[j].render(width) + ";"
+		}
+		if !pr.opts.AlignComments {
+			continue
+		}
+		commentColumn := 0
+		for j := i; j < end; j++ {
+			if list[j].lineComment != "" {
+				commentColumn = max(commentColumn, len(rendered[j]))
+			}
+		}
+		for j := i; j < end; j++ {
+			if list[j].lineComment != "" {
+				rendered[j] += strings.Repeat(" ", commentColumn-len(rendered[j]))
+			}
+		}
+	}
+
+	for i, d := range list {
+		if blankAbove(i) {
+			pr.blank()
+		}
+		pr.comments(d.comments, pad)
+
+		if rendered[i] != "" {
+			pr.buf.WriteString(pad + rendered[i])
+			pr.lineComment(d.lineComment)
+			continue
+		}
+
+		line := d.render(len(d.name))
+		line = strings.ReplaceAll(line, "\n", "\n"+pad)
+		if !d.block {
+			pr.buf.WriteString(pad + line + ";")
+			pr.lineComment(d.lineComment)
+			continue
+		}
+
+		pr.buf.WriteString(pad + line + " {")
+		if d.raw != nil {
+			body := strings.TrimRight(*d.raw, " \t")
+			pr.buf.WriteString(body)
+			if strings.HasSuffix(body, "\n") {
+				pr.buf.WriteString(pad)
+			}
+			pr.buf.WriteString("}")
+			pr.lineComment(d.closeComment)
+			continue
+		}
+		if len(d.children) == 0 && len(d.foot) == 0 && d.lineComment == "" {
+			pr.buf.WriteString("}")
+			pr.lineComment(d.closeComment)
+			continue
+		}
+		pr.lineComment(d.lineComment)
+		pr.directives(d.children, depth+1)
+		pr.foot(d.foot, len(d.children) > 0, pad+strings.Repeat(" ", pr.opts.Indent))
+		pr.buf.WriteString(pad + "}")
+		pr.lineComment(d.closeComment)
+	}
+}
+
+// lineComment ends the current line with an optional comment
+func (pr *printer) lineComment(comment string) {
+	if comment != "" {
+		pr.buf.WriteString(" " + comment)
+	}
+	pr.buf.WriteByte('\n')
+}
+
+// render writes the name and arguments of a directive, the name padded to
+// width; arguments that started a line in the input start one again, lined up
+// with the last argument of the first line, as log_format formats are written
+func (d *directive) render(width int) string {
+	var b strings.Builder
+	b.WriteString(d.name)
+	if len(d.args) == 0 {
+		return b.String()
+	}
+	b.WriteString(strings.Repeat(" ", width-len(d.name)+1))
+	column := 0
+	wrapped := false
+	for i, a := range d.args {
+		switch {
+		case i == 0:
+		case a.newline:
+			b.WriteString("\n" + strings.Repeat(" ", column))
+			wrapped = true
+		default:
+			b.WriteByte(' ')
+		}
+		if !wrapped {
+			column = b.Len()
+		}
+		b.WriteString(a.text)
+	}
+	return b.String()
+}
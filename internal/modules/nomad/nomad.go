@@ -0,0 +1,216 @@
+package nomad
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// jobBlock matches a top-level job block, which marks an .hcl file as a
+// Nomad job
+var jobBlock = regexp.MustCompile(`(?m)^job\s+"[^"]*"\s*\{`)
+
+// NomadFormatter formats Nomad job specifications
+type NomadFormatter struct{}
+
+// New creates a new NomadFormatter
+func New() *NomadFormatter {
+	return &NomadFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *NomadFormatter) Name() string {
+	return "nomad"
+}
+
+// CanHandle checks if this file is a Nomad job: a .nomad or .nomad.hcl file,
+// or an .hcl file with a top-level job block
+func (f *NomadFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	switch {
+	case strings.HasSuffix(base, ".nomad") || strings.HasSuffix(base, ".nomad.hcl"):
+		return true
+	case filepath.Ext(base) == ".hcl":
+		return jobBlock.Match(data)
+	}
+	return false
+}
+
+// Format formats a Nomad job
+func (f *NomadFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The attributes and stanzas of job, group and task blocks, and of the
+// blocks nested in them, are ordered by stanzaOrders: what and where a block
+// runs, how it is placed and updated, then its groups or tasks. Stanzas of
+// the same type keep their order, as do the top-level blocks, such as
+// variables.
+func (f *NomadFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatHCLContext(ctx, data, opts, func(file *formatter.HCLFile) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		for _, job := range file.Body.Blocks("job") {
+			formatStanza(job, "job")
+		}
+	})
+}
+
+// formatStanza orders the items of a block of the given kind, the names of
+// the blocks enclosing it joined by dots, and of the blocks nested in it
+func formatStanza(block *formatter.HCLItem, kind string) {
+	order, ok := stanzaOrders[kind]
+	if !ok {
+		return
+	}
+	formatter.SortHCLItems(block.Body.Items, func(item *formatter.HCLItem) int {
+		if r, ok := order[item.Name]; ok {
+			return r
+		}
+		if item.IsBlock() {
+			return otherStanzaRank
+		}
+		return otherAttributeRank
+	})
+	for _, item := range block.Body.Items {
+		if item.IsBlock() {
+			formatStanza(item, stanzaKind(kind, item.Name))
+		}
+	}
+}
+
+// stanzaKind returns the kind of a stanza named name inside a block of kind
+// parent; groups and tasks are known by their own name wherever they are
+func stanzaKind(parent, name string) string {
+	switch name {
+	case "group", "task":
+		return name
+	}
+	return parent + "." + name
+}
+
+// Ranks of the attributes and stanzas missing from an order table: after the
+// known attributes, and after the known stanzas except the groups or tasks
+const (
+	otherAttributeRank = 50
+	otherStanzaRank    = 900
+)
+
+// stanzaOrders ranks the attributes and stanzas of the blocks of a job
+// Attributes rank below otherAttributeRank, stanzas above it
+var stanzaOrders = map[string]map[string]int{
+	"job": {
+		"region":      1,
+		"datacenters": 2,
+		"node_pool":   3,
+		"namespace":   4,
+		"type":        5,
+		"priority":    6,
+		"all_at_once": 7,
+
+		"constraint":    100,
+		"affinity":      101,
+		"spread":        102,
+		"update":        103,
+		"migrate":       104,
+		"reschedule":    105,
+		"periodic":      106,
+		"parameterized": 107,
+		"multiregion":   108,
+		"vault":         109,
+		"ui":            110,
+		"meta":          111,
+		"group":         1000,
+	},
+	"group": {
+		"count":                        1,
+		"shutdown_delay":               2,
+		"stop_after_client_disconnect": 3,
+		"max_client_disconnect":        4,
+		"prevent_reschedule_on_lost":   5,
+
+		"constraint":     100,
+		"affinity":       101,
+		"spread":         102,
+		"network":        103,
+		"service":        104,
+		"volume":         105,
+		"restart":        106,
+		"reschedule":     107,
+		"update":         108,
+		"migrate":        109,
+		"disconnect":     110,
+		"ephemeral_disk": 111,
+		"scaling":        112,
+		"consul":         113,
+		"vault":          114,
+		"meta":           115,
+		"task":           1000,
+	},
+	"task": {
+		"driver":         1,
+		"user":           2,
+		"leader":         3,
+		"kill_timeout":   4,
+		"kill_signal":    5,
+		"shutdown_delay": 6,
+		"kind":           7,
+
+		"config":       100,
+		"env":          101,
+		"template":     102,
+		"artifact":     103,
+		"resources":    104,
+		"volume_mount": 105,
+		"service":      106,
+		"lifecycle":    107,
+		"restart":      108,
+		"logs":         109,
+		"constraint":   110,
+		"affinity":     111,
+		"vault":        112,
+		"identity":     113,
+		"consul":       114,
+		"meta":         115,
+	},
+	"task.resources": {
+		"cpu":        1,
+		"cores":      2,
+		"memory":     3,
+		"memory_max": 4,
+		"device":     100,
+		"numa":       101,
+	},
+	"group.network": {
+		"mode":     1,
+		"hostname": 2,
+		"port":     100,
+		"dns":      101,
+	},
+	"group.service": {
+		"name":     1,
+		"port":     2,
+		"provider": 3,
+		"tags":     4,
+		"check":    100,
+		"connect":  101,
+	},
+	"task.service": {
+		"name":     1,
+		"port":     2,
+		"provider": 3,
+		"tags":     4,
+		"check":    100,
+	},
+	"task.template": {
+		"source":      1,
+		"data":        2,
+		"destination": 3,
+		"change_mode": 4,
+	},
+}
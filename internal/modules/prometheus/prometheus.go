@@ -0,0 +1,313 @@
+package prometheus
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// PrometheusFormatter formats Prometheus server configuration files
+type PrometheusFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new PrometheusFormatter
+// Top-level sections are separated by blank lines
+func New() *PrometheusFormatter {
+	return &PrometheusFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+			// Relabeling rules are applied in order
+			OrderSensitive: []string{"relabel_configs", "metric_relabel_configs", "write_relabel_configs", "alert_relabel_configs"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *PrometheusFormatter) Name() string {
+	return "prometheus"
+}
+
+// CanHandle checks if this file is a Prometheus configuration file
+func (f *PrometheusFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := filepath.Base(filename)
+	if base == "prometheus.yml" || base == "prometheus.yaml" {
+		return true
+	}
+
+	// Check for Prometheus specific keys
+	if !formatter.MayHaveTopLevelKey(data, "scrape_configs", "rule_files", "scrape_config_files") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// Look for Prometheus indicators in top-level keys
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if content.Kind == yaml.MappingNode {
+			for i := 0; i < len(content.Content); i += 2 {
+				key := content.Content[i].Value
+				if key == "scrape_configs" || key == "rule_files" || key == "scrape_config_files" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Format formats a Prometheus YAML file with consistent indentation and ordering
+func (f *PrometheusFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *PrometheusFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports repeated items in the lists whose order is significant
+func (f *PrometheusFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, nil)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *PrometheusFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *PrometheusFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Jobs are ordered by name on request
+	if opts.SortScrapeConfigs && len(path) == 1 && path[0] == "scrape_configs" {
+		formatter.SortSequence(node, formatter.SequenceOptions{Field: "job_name", MoveComments: true})
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Prometheus conventions
+// Labels and other user-defined mappings keep their order
+func (f *PrometheusFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	order := keyOrderFor(path, isTopLevel)
+	if order == nil {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// keyOrderFor returns the ranking function for the mapping at path, or nil if
+// the mapping keeps its original order
+func keyOrderFor(path []string, isTopLevel bool) func(key string) int {
+	if isTopLevel {
+		return rank(topLevelOrder)
+	}
+
+	// Scrape jobs may use any number of service discovery mechanisms
+	// (kubernetes_sd_configs, dns_sd_configs, ...), which rank with the targets
+	if len(path) == 2 && path[0] == "scrape_configs" {
+		return func(key string) int {
+			if strings.HasSuffix(key, "_sd_configs") {
+				return scrapeConfigOrder["static_configs"] + 1
+			}
+			return rank(scrapeConfigOrder)(key)
+		}
+	}
+
+	var table map[string]int
+	switch {
+	case len(path) == 1 && path[0] == "global":
+		table = globalOrder
+	case len(path) == 1 && path[0] == "alerting":
+		table = alertingOrder
+	case len(path) == 3 && path[0] == "alerting" && path[1] == "alertmanagers":
+		table = alertmanagerOrder
+	case len(path) == 2 && (path[0] == "remote_write" || path[0] == "remote_read"):
+		table = remoteOrder
+	case len(path) >= 2 && path[len(path)-2] == "static_configs":
+		table = staticConfigOrder
+	case len(path) >= 2 && isRelabelList(path[len(path)-2]):
+		table = relabelOrder
+	default:
+		return nil
+	}
+	return rank(table)
+}
+
+// rank returns a ranking function for an order table; unknown keys go last
+func rank(table map[string]int) func(key string) int {
+	return func(key string) int {
+		if order, ok := table[key]; ok {
+			return order
+		}
+		return 999
+	}
+}
+
+// isRelabelList reports whether key holds a list of relabeling rules
+func isRelabelList(key string) bool {
+	switch key {
+	case "relabel_configs", "metric_relabel_configs", "alert_relabel_configs", "write_relabel_configs":
+		return true
+	}
+	return false
+}
+
+// topLevelOrder ranks the top-level sections: server settings, then rules and
+// alerting, then what is scraped, then where samples go
+var topLevelOrder = map[string]int{
+	"global":              1,
+	"runtime":             2,
+	"rule_files":          3,
+	"alerting":            4,
+	"scrape_config_files": 5,
+	"scrape_configs":      6,
+	"remote_write":        7,
+	"remote_read":         8,
+	"storage":             9,
+	"tracing":             10,
+	"otlp":                11,
+}
+
+// globalOrder ranks the global defaults
+var globalOrder = map[string]int{
+	"scrape_interval":         1,
+	"scrape_timeout":          2,
+	"scrape_protocols":        3,
+	"evaluation_interval":     4,
+	"rule_query_offset":       5,
+	"external_labels":         6,
+	"query_log_file":          7,
+	"scrape_failure_log_file": 8,
+}
+
+// alertingOrder ranks the alerting keys: relabeling happens before sending
+var alertingOrder = map[string]int{
+	"alert_relabel_configs": 1,
+	"alertmanagers":         2,
+}
+
+// alertmanagerOrder ranks the keys of an alertmanagers entry
+var alertmanagerOrder = map[string]int{
+	"scheme":          1,
+	"path_prefix":     2,
+	"api_version":     3,
+	"timeout":         4,
+	"static_configs":  10,
+	"relabel_configs": 11,
+}
+
+// scrapeConfigOrder ranks the keys of a scrape job: its name and timing, then
+// the endpoint, then the targets, then relabeling
+var scrapeConfigOrder = map[string]int{
+	// Identity and timing
+	"job_name":        1,
+	"scrape_interval": 2,
+	"scrape_timeout":  3,
+
+	// Endpoint
+	"metrics_path":     10,
+	"scheme":           11,
+	"params":           12,
+	"honor_labels":     13,
+	"honor_timestamps": 14,
+	"basic_auth":       15,
+	"authorization":    16,
+	"oauth2":           17,
+	"tls_config":       18,
+	"proxy_url":        19,
+
+	// Targets: static lists first, then service discovery
+	"static_configs": 20,
+
+	// Relabeling, in the order it is applied
+	"relabel_configs":        40,
+	"metric_relabel_configs": 41,
+
+	// Limits
+	"sample_limit":    50,
+	"label_limit":     51,
+	"target_limit":    52,
+	"body_size_limit": 53,
+}
+
+// remoteOrder ranks the keys of a remote_write or remote_read entry
+var remoteOrder = map[string]int{
+	"name":                  1,
+	"url":                   2,
+	"remote_timeout":        3,
+	"headers":               4,
+	"basic_auth":            5,
+	"authorization":         6,
+	"oauth2":                7,
+	"tls_config":            8,
+	"write_relabel_configs": 10,
+	"queue_config":          11,
+	"metadata_config":       12,
+}
+
+// staticConfigOrder ranks the keys of a static_configs entry
+var staticConfigOrder = map[string]int{
+	"targets": 1,
+	"labels":  2,
+}
+
+// relabelOrder ranks the keys of a relabeling rule: the input, the match, then
+// the output and the action taking it
+var relabelOrder = map[string]int{
+	"source_labels": 1,
+	"separator":     2,
+	"regex":         3,
+	"modulus":       4,
+	"target_label":  5,
+	"replacement":   6,
+	"action":        7,
+}
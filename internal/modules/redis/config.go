@@ -0,0 +1,321 @@
+package redis
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// A redis.conf is a list of directives, one per line: a keyword and its
+// arguments, separated by spaces; arguments with spaces are quoted. Lines
+// starting with "#" are comments; there are no inline comments. The
+// redis.conf Redis ships divides its directives into sections, each opening
+// with a banner comment such as
+//
+//	################################## NETWORK #####################################
+
+// banner matches a section banner and captures its name
+var banner = regexp.MustCompile(`^###+\s+(.*?)\s+###+$`)
+
+// directive is one directive line with the comments above it; "" marks a
+// blank line among them
+type directive struct {
+	comments []string
+	keyword  string
+	args     string
+}
+
+// section is a group of directives, with the banner it opens with
+type section struct {
+	name string
+	// banner is the banner line as written, or "" for a section the input
+	// had no banner for
+	banner string
+	// rank orders the sections: the position of a documented section in
+	// sectionNames, or the rank of the section before an undocumented one
+	rank int
+
+	directives []*directive
+
+	// foot are the comments after the last directive
+	foot []string
+}
+
+// config is a parsed redis.conf
+type config struct {
+	// header are the comments at the top of the file, before the first
+	// banner or separated from the first directive by a blank line
+	header   []string
+	sections []*section
+}
+
+// sectionRank returns the position of a documented section, or -1
+func sectionRank(name string) int {
+	return slices.Index(sectionNames, name)
+}
+
+// sectionName returns the name of a banner, in upper case and with the names
+// older releases used replaced by the current ones
+func sectionName(text string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(text), " "))
+	if current, ok := sectionAliases[name]; ok {
+		return current
+	}
+	return name
+}
+
+// section returns the section named name, adding it with rank when there is
+// none yet
+func (cfg *config) section(name string, rank int) *section {
+	for _, s := range cfg.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	s := &section{name: name, rank: rank}
+	cfg.sections = append(cfg.sections, s)
+	return s
+}
+
+// splitDirective splits a line into its keyword and arguments
+func splitDirective(text string) (keyword, args string) {
+	end := strings.IndexAny(text, " \t")
+	if end < 0 {
+		return text, ""
+	}
+	return text[:end], strings.TrimLeft(text[end:], " \t")
+}
+
+// parse reads a redis.conf
+// With group set, documented directives go to their section; other
+// directives, and every directive without group, go to the section they were
+// written in: the one of the last banner, or of the directive before them.
+// Grouping is skipped when an include follows other directives, since
+// moving it would change which settings it overrides.
+func parse(data []byte, group bool) *config {
+	lines := strings.Split(string(data), "\n")
+	if group && includeAfterDirective(lines) {
+		group = false
+	}
+
+	cfg := &config{}
+	var current, last *section
+	// pending are the comments since the last directive or banner, with ""
+	// for the blank lines between them
+	var pending []string
+	started := false
+
+	for _, raw := range lines {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "":
+			if len(pending) == 0 || pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+			continue
+		case banner.MatchString(text):
+			switch {
+			case !started:
+				cfg.header = pending
+			case current != nil:
+				current.foot = append(current.foot, pending...)
+			default:
+				last.foot = append(last.foot, pending...)
+			}
+			started = true
+			name := sectionName(banner.FindStringSubmatch(text)[1])
+			rank := sectionRank(name)
+			if rank < 0 {
+				// An undocumented section stays after the one before it
+				rank = -1
+				if current != nil {
+					rank = current.rank
+				}
+			}
+			current = cfg.section(name, rank)
+			if current.banner == "" {
+				current.banner = text
+			}
+			pending = nil
+			continue
+		case strings.HasPrefix(text, "#"):
+			pending = append(pending, text)
+			continue
+		}
+
+		if !started {
+			cfg.header, pending = splitComments(pending)
+			started = true
+		}
+		keyword, args := splitDirective(text)
+		target := current
+		if name, ok := directiveSections[strings.ToLower(keyword)]; ok && group {
+			target = cfg.section(name, sectionRank(name))
+		} else if target == nil {
+			target = last
+		}
+		if target == nil {
+			target = cfg.section("", -1)
+		}
+		if target != last && last != nil && target.banner == "" && len(target.directives) == 0 {
+			// Like the header, comments separated from the first directive
+			// of a section without banner by a blank line end the section
+			// before it
+			var foot []string
+			foot, pending = splitComments(pending)
+			last.foot = append(last.foot, foot...)
+		}
+		target.directives = append(target.directives, &directive{comments: pending, keyword: keyword, args: args})
+		last = target
+		pending = nil
+	}
+
+	switch {
+	case current != nil:
+		current.foot = append(current.foot, pending...)
+	case last != nil:
+		last.foot = append(last.foot, pending...)
+	default:
+		cfg.header = append(cfg.header, pending...)
+	}
+	return cfg
+}
+
+// includeAfterDirective reports whether an include directive follows another
+// directive
+func includeAfterDirective(lines []string) bool {
+	seen := false
+	for _, raw := range lines {
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		keyword, _ := splitDirective(text)
+		if strings.EqualFold(keyword, "include") {
+			if seen {
+				return true
+			}
+			continue
+		}
+		seen = true
+	}
+	return false
+}
+
+// splitComments splits the comments above the first directive at their last
+// blank line, marked by "": those before it are the header of the file
+func splitComments(pending []string) (header, comments []string) {
+	for i := len(pending) - 1; i >= 0; i-- {
+		if pending[i] == "" {
+			return pending[:i], pending[i+1:]
+		}
+	}
+	return nil, pending
+}
+
+// sortSections orders the sections as the redis.conf Redis ships does;
+// undocumented sections stay after the section before them
+func (cfg *config) sortSections() {
+	sort.SliceStable(cfg.sections, func(i, j int) bool {
+		return cfg.sections[i].rank < cfg.sections[j].rank
+	})
+}
+
+// printer writes a config
+type printer struct {
+	buf  bytes.Buffer
+	opts formatter.Options
+}
+
+// print writes the config with the values of consecutive directives in one
+// column. Sections are separated by one blank line, and blank lines between
+// comments and directives are kept as one, unless opts.BlankLines is
+// BlankLinesNone.
+func (cfg *config) print(opts formatter.Options) []byte {
+	pr := &printer{opts: opts}
+	pr.comments(trimBlank(cfg.header))
+	for _, s := range cfg.sections {
+		if s.banner == "" && len(s.directives) == 0 && len(trimBlank(s.foot)) == 0 {
+			continue
+		}
+		pr.separate()
+		if s.banner != "" {
+			pr.buf.WriteString(s.banner + "\n")
+		}
+		pr.directives(s.directives, s.banner == "")
+		// A blank line before the foot is kept
+		if foot := trimBlank(s.foot); len(foot) > 0 && s.foot[0] == "" {
+			pr.comments(append([]string{""}, foot...))
+		} else {
+			pr.comments(foot)
+		}
+	}
+	return pr.buf.Bytes()
+}
+
+// separate writes the blank line between two sections
+func (pr *printer) separate() {
+	if pr.buf.Len() > 0 && pr.opts.BlankLines != formatter.BlankLinesNone {
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// separated reports whether a blank line goes before a directive or its
+// comments
+func (pr *printer) separated(d *directive) bool {
+	return slices.Contains(d.comments, "") && pr.opts.BlankLines != formatter.BlankLinesNone
+}
+
+// directives writes directives with their comments, the values of each run
+// of directives between blank lines in one column; a blank line before the
+// first is dropped when trimFirst is set
+func (pr *printer) directives(directives []*directive, trimFirst bool) {
+	width := 0
+	for i, d := range directives {
+		comments := d.comments
+		if i == 0 && trimFirst {
+			comments = trimBlank(comments)
+		}
+		if i == 0 || pr.separated(d) {
+			width = 0
+			for j := i; j < len(directives) && (j == i || !pr.separated(directives[j])); j++ {
+				width = max(width, len(directives[j].keyword))
+			}
+		}
+		pr.comments(comments)
+		if d.args == "" {
+			pr.buf.WriteString(d.keyword + "\n")
+			continue
+		}
+		pr.buf.WriteString(d.keyword + strings.Repeat(" ", width-len(d.keyword)+1) + d.args + "\n")
+	}
+}
+
+// comments writes comments on lines of their own, with "" as a blank line
+func (pr *printer) comments(comments []string) {
+	for _, comment := range comments {
+		if comment == "" {
+			if pr.opts.BlankLines != formatter.BlankLinesNone {
+				pr.buf.WriteByte('\n')
+			}
+			continue
+		}
+		pr.buf.WriteString(comment + "\n")
+	}
+}
+
+// trimBlank drops the blank line markers at the start and end of a list of
+// comments
+func trimBlank(comments []string) []string {
+	for len(comments) > 0 && comments[0] == "" {
+		comments = comments[1:]
+	}
+	for len(comments) > 0 && comments[len(comments)-1] == "" {
+		comments = comments[:len(comments)-1]
+	}
+	return comments
+}
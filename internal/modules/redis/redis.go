@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// redisDirective matches a directive only Redis configs have, which marks a
+// .conf file as one
+var redisDirective = regexp.MustCompile(`(?m)^\s*(appendonly|appendfsync|maxmemory-policy|protected-mode|requirepass|masterauth|replicaof|slaveof|cluster-enabled|rdbcompression)\s`)
+
+// RedisFormatter formats Redis server configuration files (redis.conf)
+type RedisFormatter struct{}
+
+// New creates a new RedisFormatter
+func New() *RedisFormatter {
+	return &RedisFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *RedisFormatter) Name() string {
+	return "redis"
+}
+
+// CanHandle checks if this file is a Redis config: redis.conf, a .conf file
+// whose name starts with redis (redis-6379.conf) or that is in a redis
+// directory, or one with a directive only Redis has
+// Sentinel configs (sentinel.conf) have directives of their own and are not
+// claimed.
+func (f *RedisFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	if filepath.Ext(base) != ".conf" || strings.HasPrefix(base, "sentinel") {
+		return false
+	}
+	if strings.HasPrefix(base, "redis") || filepath.Base(filepath.Dir(filename)) == "redis" {
+		return true
+	}
+	return redisDirective.Match(data)
+}
+
+// Format formats a Redis config with grouped directives and aligned values
+func (f *RedisFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Directives are grouped into the sections of the redis.conf Redis ships
+// (INCLUDES, MODULES, NETWORK, GENERAL, SNAPSHOTTING, ...), in that order,
+// with the comments above them. Section banners such as
+// "##### NETWORK #####" are kept, and directives Redis does not document stay
+// in the section they were written in. Within a section, directives keep
+// their order, so of a directive set twice the last value still wins.
+func (f *RedisFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg := parse(data, !opts.PreserveKeyOrder)
+	if !opts.PreserveValues {
+		for _, s := range cfg.sections {
+			for _, d := range s.directives {
+				if _, ok := directiveSections[strings.ToLower(d.keyword)]; ok {
+					d.keyword = strings.ToLower(d.keyword)
+				}
+			}
+		}
+	}
+	if !opts.PreserveKeyOrder {
+		cfg.sortSections()
+	}
+	return cfg.print(opts), nil
+}
+
+// sectionNames are the sections of the redis.conf Redis ships, in order
+var sectionNames = []string{
+	"INCLUDES",
+	"MODULES",
+	"NETWORK",
+	"TLS/SSL",
+	"GENERAL",
+	"SNAPSHOTTING",
+	"REPLICATION",
+	"KEYS TRACKING",
+	"SECURITY",
+	"CLIENTS",
+	"MEMORY MANAGEMENT",
+	"LAZY FREEING",
+	"THREADED I/O",
+	"KERNEL OOM CONTROL",
+	"KERNEL TRANSPARENT HUGEPAGE CONTROL",
+	"APPEND ONLY MODE",
+	"SHUTDOWN",
+	"NON-DETERMINISTIC LONG BLOCKING COMMANDS",
+	"REDIS CLUSTER",
+	"CLUSTER DOCKER/NAT SUPPORT",
+	"SLOW LOG",
+	"LATENCY MONITOR",
+	"LATENCY TRACKING",
+	"EVENT NOTIFICATION",
+	"ADVANCED CONFIG",
+	"ACTIVE DEFRAGMENTATION",
+}
+
+// sectionAliases are the names older releases gave sections
+var sectionAliases = map[string]string{
+	"LUA SCRIPTING":      "NON-DETERMINISTIC LONG BLOCKING COMMANDS",
+	"TLS":                "TLS/SSL",
+	"LIMITS":             "CLIENTS",
+	"APPEND ONLY":        "APPEND ONLY MODE",
+	"CLUSTER DOCKER/NAT": "CLUSTER DOCKER/NAT SUPPORT",
+}
+
+// directiveSections maps each directive, in lower case, to its section
+var directiveSections = map[string]string{}
+
+func init() {
+	for section, directives := range sectionDirectives {
+		for _, directive := range directives {
+			directiveSections[directive] = section
+		}
+	}
+}
+
+// sectionDirectives lists the directives each section documents, including
+// the slave names older releases used for replica settings
+var sectionDirectives = map[string][]string{
+	"INCLUDES": {"include"},
+	"MODULES":  {"loadmodule"},
+	"NETWORK": {
+		"bind", "bind-source-addr", "protected-mode", "enable-protected-configs", "enable-debug-command",
+		"enable-module-command", "port", "tcp-backlog", "unixsocket", "unixsocketperm", "timeout",
+		"tcp-keepalive", "socket-mark-id",
+	},
+	"TLS/SSL": {
+		"tls-port", "tls-cert-file", "tls-key-file", "tls-key-file-pass", "tls-client-cert-file",
+		"tls-client-key-file", "tls-client-key-file-pass", "tls-dh-params-file", "tls-ca-cert-file",
+		"tls-ca-cert-dir", "tls-auth-clients", "tls-replication", "tls-cluster", "tls-protocols",
+		"tls-ciphers", "tls-ciphersuites", "tls-prefer-server-ciphers", "tls-session-caching",
+		"tls-session-cache-size", "tls-session-cache-timeout",
+	},
+	"GENERAL": {
+		"daemonize", "supervised", "pidfile", "loglevel", "logfile", "syslog-enabled", "syslog-ident",
+		"syslog-facility", "crash-log-enabled", "crash-memcheck-enabled", "databases", "always-show-logo",
+		"hide-user-data-from-log", "set-proc-title", "proc-title-template", "locale-collate",
+	},
+	"SNAPSHOTTING": {
+		"save", "stop-writes-on-bgsave-error", "rdbcompression", "rdbchecksum", "sanitize-dump-payload",
+		"dbfilename", "rdb-del-sync-files", "dir",
+	},
+	"REPLICATION": {
+		"replicaof", "slaveof", "masterauth", "masteruser", "replica-serve-stale-data",
+		"slave-serve-stale-data", "replica-read-only", "slave-read-only", "repl-diskless-sync",
+		"repl-diskless-sync-delay", "repl-diskless-sync-max-replicas", "repl-diskless-load",
+		"repl-ping-replica-period", "repl-ping-slave-period", "repl-timeout", "repl-disable-tcp-nodelay",
+		"repl-backlog-size", "repl-backlog-ttl", "replica-priority", "slave-priority",
+		"propagation-error-behavior", "replica-ignore-disk-write-errors", "replica-announced",
+		"min-replicas-to-write", "min-replicas-max-lag", "min-slaves-to-write", "min-slaves-max-lag",
+		"replica-announce-ip", "replica-announce-port", "slave-announce-ip", "slave-announce-port",
+	},
+	"KEYS TRACKING": {"tracking-table-max-keys"},
+	"SECURITY":      {"acllog-max-len", "aclfile", "requirepass", "acl-pubsub-default", "rename-command", "user"},
+	"CLIENTS":       {"maxclients"},
+	"MEMORY MANAGEMENT": {
+		"maxmemory", "maxmemory-policy", "maxmemory-samples", "maxmemory-eviction-tenacity",
+		"replica-ignore-maxmemory", "slave-ignore-maxmemory", "active-expire-effort",
+	},
+	"LAZY FREEING": {
+		"lazyfree-lazy-eviction", "lazyfree-lazy-expire", "lazyfree-lazy-server-del", "replica-lazy-flush",
+		"slave-lazy-flush", "lazyfree-lazy-user-del", "lazyfree-lazy-user-flush",
+	},
+	"THREADED I/O":                        {"io-threads", "io-threads-do-reads"},
+	"KERNEL OOM CONTROL":                  {"oom-score-adj", "oom-score-adj-values"},
+	"KERNEL TRANSPARENT HUGEPAGE CONTROL": {"disable-thp"},
+	"APPEND ONLY MODE": {
+		"appendonly", "appendfilename", "appenddirname", "appendfsync", "no-appendfsync-on-rewrite",
+		"auto-aof-rewrite-percentage", "auto-aof-rewrite-min-size", "aof-load-truncated",
+		"aof-use-rdb-preamble", "aof-timestamp-enabled",
+	},
+	"SHUTDOWN": {"shutdown-timeout", "shutdown-on-sigint", "shutdown-on-sigterm"},
+	"NON-DETERMINISTIC LONG BLOCKING COMMANDS": {"lua-time-limit", "busy-reply-threshold"},
+	"REDIS CLUSTER": {
+		"cluster-enabled", "cluster-config-file", "cluster-node-timeout", "cluster-port",
+		"cluster-replica-validity-factor", "cluster-slave-validity-factor", "cluster-migration-barrier",
+		"cluster-allow-replica-migration", "cluster-require-full-coverage", "cluster-replica-no-failover",
+		"cluster-slave-no-failover", "cluster-allow-reads-when-down", "cluster-allow-pubsubshard-when-down",
+		"cluster-link-sendbuf-limit", "cluster-announce-hostname", "cluster-announce-human-nodename",
+		"cluster-preferred-endpoint-type",
+	},
+	"CLUSTER DOCKER/NAT SUPPORT": {
+		"cluster-announce-ip", "cluster-announce-tls-port", "cluster-announce-port", "cluster-announce-bus-port",
+	},
+	"SLOW LOG":           {"slowlog-log-slower-than", "slowlog-max-len"},
+	"LATENCY MONITOR":    {"latency-monitor-threshold"},
+	"LATENCY TRACKING":   {"latency-tracking", "latency-tracking-info-percentiles"},
+	"EVENT NOTIFICATION": {"notify-keyspace-events"},
+	"ADVANCED CONFIG": {
+		"hash-max-listpack-entries", "hash-max-listpack-value", "hash-max-ziplist-entries",
+		"hash-max-ziplist-value", "list-max-listpack-size", "list-max-ziplist-size", "list-compress-depth",
+		"set-max-intset-entries", "set-max-listpack-entries", "set-max-listpack-value",
+		"zset-max-listpack-entries", "zset-max-listpack-value", "zset-max-ziplist-entries",
+		"zset-max-ziplist-value", "hll-sparse-max-bytes", "stream-node-max-bytes", "stream-node-max-entries",
+		"activerehashing", "client-output-buffer-limit", "client-query-buffer-limit", "lua-replicate-commands",
+		"proto-max-bulk-len", "hz", "dynamic-hz", "aof-rewrite-incremental-fsync", "rdb-save-incremental-fsync",
+		"lfu-log-factor", "lfu-decay-time", "max-new-connections-per-cycle", "max-new-tls-connections-per-cycle",
+	},
+	"ACTIVE DEFRAGMENTATION": {
+		"activedefrag", "active-defrag-ignore-bytes", "active-defrag-threshold-lower",
+		"active-defrag-threshold-upper", "active-defrag-cycle-min", "active-defrag-cycle-max",
+		"active-defrag-max-scan-fields", "jemalloc-bg-thread", "server-cpulist", "bio-cpulist",
+		"aof-rewrite-cpulist", "bgsave-cpulist", "ignore-warnings",
+	},
+}
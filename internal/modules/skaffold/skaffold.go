@@ -0,0 +1,212 @@
+package skaffold
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// SkaffoldFormatter formats Skaffold configuration files (skaffold.yaml)
+type SkaffoldFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new SkaffoldFormatter
+// Profiles are separated by blank lines; the top level is kept together like
+// the header of a Kubernetes resource
+func New() *SkaffoldFormatter {
+	return &SkaffoldFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{"profiles"}},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *SkaffoldFormatter) Name() string {
+	return "skaffold"
+}
+
+// CanHandle checks if this file is a Skaffold configuration file
+func (f *SkaffoldFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	base := filepath.Base(filename)
+	if base == "skaffold.yaml" || base == "skaffold.yml" {
+		return true
+	}
+
+	// Check for the Skaffold API version
+	if !bytes.Contains(data, []byte("skaffold/")) {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		apiVersion := formatter.MappingValue(root.Content[0], "apiVersion")
+		return apiVersion != nil && strings.HasPrefix(apiVersion.Value, "skaffold/")
+	}
+
+	return false
+}
+
+// Format formats a Skaffold YAML file with consistent indentation and ordering
+func (f *SkaffoldFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *SkaffoldFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *SkaffoldFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+func (f *SkaffoldFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// Sequence items are identified by their index
+		for i, child := range node.Content {
+			f.formatNodeWithContext(child, false, append(path, strconv.Itoa(i)), opts)
+		}
+	}
+}
+
+// sortMappingNode sorts keys in a mapping node according to Skaffold conventions
+// Builder, deployer and patch settings keep their order
+func (f *SkaffoldFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	// Profiles override the pipeline sections of the config they are in, so
+	// the same paths are matched below profiles.N
+	rel := path
+	if len(path) >= 2 && path[0] == "profiles" {
+		rel = path[2:]
+	}
+
+	var table map[string]int
+	switch {
+	case isTopLevel:
+		table = topLevelOrder
+	case len(path) == 2 && path[0] == "profiles":
+		table = profileOrder
+	case len(rel) == 1 && rel[0] == "build":
+		table = buildOrder
+	case len(rel) == 3 && rel[0] == "build" && rel[1] == "artifacts":
+		table = artifactOrder
+	default:
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if rank, ok := table[key]; ok {
+			return rank
+		}
+		return 999
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// topLevelOrder ranks the top-level keys: the resource header, then the
+// pipeline stages in the order Skaffold runs them, then profiles
+var topLevelOrder = map[string]int{
+	"apiVersion": 1,
+	"kind":       2,
+	"metadata":   3,
+	"requires":   4,
+
+	// Pipeline
+	"build":            10,
+	"test":             11,
+	"manifests":        12,
+	"deploy":           13,
+	"portForward":      14,
+	"resourceSelector": 15,
+	"verify":           16,
+	"customActions":    17,
+
+	"profiles": 20,
+}
+
+// profileOrder ranks the keys of a profile: its name and when it applies,
+// then the pipeline sections it overrides, then patches
+var profileOrder = map[string]int{
+	"name":                   1,
+	"activation":             2,
+	"requiresAllActivations": 3,
+
+	"build":            10,
+	"test":             11,
+	"manifests":        12,
+	"deploy":           13,
+	"portForward":      14,
+	"resourceSelector": 15,
+	"verify":           16,
+	"customActions":    17,
+
+	"patches": 20,
+}
+
+// buildOrder ranks the build options: what is built, how it is tagged, then
+// where it is built
+var buildOrder = map[string]int{
+	"artifacts":          1,
+	"tagPolicy":          2,
+	"platforms":          3,
+	"insecureRegistries": 4,
+	"local":              10,
+	"googleCloudBuild":   10,
+	"cluster":            10,
+}
+
+// artifactOrder ranks the keys of an artifact: the image and its source, the
+// builder (docker, ko, jib, ...), then sync and dependencies
+var artifactOrder = map[string]int{
+	"image":     1,
+	"context":   2,
+	"platforms": 3,
+	"sync":      20,
+	"requires":  21,
+	"hooks":     22,
+}
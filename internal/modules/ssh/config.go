@@ -0,0 +1,229 @@
+package ssh
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// An ssh_config or sshd_config is a list of options, one per line: a keyword
+// and its arguments, separated by whitespace or "=". Host and Match lines
+// start a block that applies the options below them, up to the next block,
+// to the hosts or connections they match. Lines starting with "#" are
+// comments; there are no inline comments.
+
+// blockKeywords are the keywords that start a block, in lower case
+var blockKeywords = map[string]bool{
+	"host":  true,
+	"match": true,
+}
+
+// option is one keyword line with the comments above it
+type option struct {
+	comments []string
+	keyword  string
+	args     string
+
+	// blankBefore is set when a blank line preceded the option or its
+	// comments
+	blankBefore bool
+}
+
+// block is a Host or Match block; the options before the first block are in
+// a block without keyword
+type block struct {
+	// comments are the comments above the header
+	comments []string
+
+	keyword string
+	args    string
+	options []*option
+
+	// foot are the comments after the last option
+	foot []string
+}
+
+// config is a parsed ssh_config or sshd_config
+type config struct {
+	// global holds the options before the first block
+	global *block
+	blocks []*block
+}
+
+// splitOption splits a line into its keyword and arguments
+func splitOption(text string) (keyword, args string) {
+	end := strings.IndexAny(text, " \t=")
+	if end < 0 {
+		return text, ""
+	}
+	keyword, args = text[:end], strings.TrimLeft(text[end:], " \t")
+	// One "=" may separate the keyword from its arguments
+	args = strings.TrimLeft(strings.TrimPrefix(args, "="), " \t")
+	return keyword, args
+}
+
+// parse reads an ssh_config or sshd_config
+// Comments go with the option or block header below them; comments at the
+// end of a block, separated from the next header by a blank line, stay at the
+// end of the block
+func parse(data []byte) *config {
+	cfg := &config{global: &block{}}
+	current := cfg.global
+	// pending are the comments since the last option or header, with ""
+	// for the blank lines between them
+	var pending []string
+	blank := false
+
+	for _, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "":
+			if len(pending) > 0 && pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+			blank = true
+			continue
+		case strings.HasPrefix(text, "#"):
+			pending = append(pending, text)
+			continue
+		}
+
+		keyword, args := splitOption(text)
+		if blockKeywords[strings.ToLower(keyword)] {
+			// Comments separated from the header by a blank line end the
+			// block before it
+			foot, comments := splitComments(pending)
+			current.foot = append(current.foot, foot...)
+			current = &block{comments: comments, keyword: keyword, args: args}
+			cfg.blocks = append(cfg.blocks, current)
+		} else {
+			current.options = append(current.options, &option{comments: trimBlank(pending), keyword: keyword, args: args, blankBefore: blank})
+		}
+		pending = nil
+		blank = false
+	}
+
+	current.foot = append(current.foot, trimBlank(pending)...)
+	return cfg
+}
+
+// splitComments splits the comments above a block header at their last
+// blank line, marked by "": those before it end the previous block
+func splitComments(pending []string) (foot, comments []string) {
+	for i := len(pending) - 1; i >= 0; i-- {
+		if pending[i] == "" {
+			return trimBlank(pending[:i]), trimBlank(pending[i+1:])
+		}
+	}
+	return nil, pending
+}
+
+// trimBlank drops the blank line markers from a list of comments
+func trimBlank(comments []string) []string {
+	var result []string
+	for _, comment := range comments {
+		if comment != "" {
+			result = append(result, comment)
+		}
+	}
+	return result
+}
+
+// sortOptions orders the options of a block by rank; options of equal rank,
+// such as repeated IdentityFile lines, keep their order, which ssh tries
+// them in
+func sortOptions(options []*option, rank func(keyword string) int) {
+	sort.SliceStable(options, func(i, j int) bool {
+		return rank(options[i].keyword) < rank(options[j].keyword)
+	})
+}
+
+// sortHosts orders each run of consecutive Host blocks by their patterns.
+// Match blocks stay where they are, and so does a catch-all "Host *", which
+// comes after the blocks it sets defaults for.
+func sortHosts(blocks []*block) {
+	sortable := func(b *block) bool {
+		return strings.EqualFold(b.keyword, "host") && b.args != "*"
+	}
+	for start := 0; start < len(blocks); {
+		end := start
+		for end < len(blocks) && sortable(blocks[end]) {
+			end++
+		}
+		if end == start {
+			start++
+			continue
+		}
+		run := blocks[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return strings.ToLower(run[i].args) < strings.ToLower(run[j].args)
+		})
+		start = end
+	}
+}
+
+// printer writes a config
+type printer struct {
+	buf  bytes.Buffer
+	opts formatter.Options
+}
+
+// print writes the config with the options of blocks indented by
+// opts.Indent spaces and the arguments of consecutive options in one column
+// Blocks are separated by one blank line, unless opts.BlankLines is
+// BlankLinesNone; blank lines between options are only kept under
+// BlankLinesPreserve
+func (cfg *config) print(opts formatter.Options) []byte {
+	pr := &printer{opts: opts}
+	pr.options(cfg.global.options, "")
+	pr.comments(cfg.global.foot, "")
+
+	pad := strings.Repeat(" ", opts.Indent)
+	for _, b := range cfg.blocks {
+		if pr.buf.Len() > 0 && opts.BlankLines != formatter.BlankLinesNone {
+			pr.buf.WriteByte('\n')
+		}
+		pr.comments(b.comments, "")
+		pr.buf.WriteString(strings.TrimSpace(b.keyword+" "+b.args) + "\n")
+		pr.options(b.options, pad)
+		pr.comments(b.foot, pad)
+	}
+	return pr.buf.Bytes()
+}
+
+// separate reports whether a blank line goes before an option
+func (pr *printer) separate(o *option) bool {
+	return o.blankBefore && pr.opts.BlankLines == formatter.BlankLinesPreserve
+}
+
+// options writes options prefixed by pad, with the arguments of each run of
+// options between blank lines in one column
+func (pr *printer) options(options []*option, pad string) {
+	width := 0
+	for i, o := range options {
+		if i == 0 || pr.separate(o) {
+			if i > 0 {
+				pr.buf.WriteByte('\n')
+			}
+			width = 0
+			for j := i; j < len(options) && (j == i || !pr.separate(options[j])); j++ {
+				width = max(width, len(options[j].keyword))
+			}
+		}
+		pr.comments(o.comments, pad)
+		if o.args == "" {
+			pr.buf.WriteString(pad + o.keyword + "\n")
+			continue
+		}
+		pr.buf.WriteString(pad + o.keyword + strings.Repeat(" ", width-len(o.keyword)+1) + o.args + "\n")
+	}
+}
+
+// comments writes comments on lines of their own, prefixed by pad
+func (pr *printer) comments(comments []string, pad string) {
+	for _, comment := range comments {
+		pr.buf.WriteString(pad + comment + "\n")
+	}
+}
@@ -0,0 +1,173 @@
+package ssh
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// SSHFormatter formats OpenSSH client and server configuration files
+// (~/.ssh/config, ssh_config, sshd_config)
+type SSHFormatter struct{}
+
+// New creates a new SSHFormatter
+func New() *SSHFormatter {
+	return &SSHFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *SSHFormatter) Name() string {
+	return "ssh"
+}
+
+// CanHandle checks if this file is an OpenSSH configuration file
+// ssh_config and sshd_config are claimed by name, as are config in a .ssh
+// directory and the .conf files of ssh_config.d and sshd_config.d
+func (f *SSHFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	dir := filepath.Base(filepath.Dir(filename))
+	switch {
+	case base == "ssh_config" || base == "sshd_config":
+		return true
+	case base == "config" && dir == ".ssh":
+		return true
+	case filepath.Ext(base) == ".conf" && (dir == "ssh_config.d" || dir == "sshd_config.d"):
+		return true
+	}
+	return false
+}
+
+// Format formats an OpenSSH config with aligned arguments and ordered options
+func (f *SSHFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The options of Host and Match blocks are ordered by optionOrder; the
+// options before the first block keep their order. Host blocks are sorted by
+// pattern when opts.SortSections is set, since for options set in several
+// matching blocks the first value wins.
+func (f *SSHFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg := parse(data)
+	if !opts.PreserveValues {
+		for _, b := range append([]*block{cfg.global}, cfg.blocks...) {
+			b.keyword = canonicalKeyword(b.keyword)
+			for _, o := range b.options {
+				o.keyword = canonicalKeyword(o.keyword)
+			}
+		}
+	}
+	if !opts.PreserveKeyOrder {
+		for _, b := range cfg.blocks {
+			sortOptions(b.options, rank)
+		}
+		if opts.SortSections {
+			sortHosts(cfg.blocks)
+		}
+	}
+	return cfg.print(opts), nil
+}
+
+// rank returns the position of an option in a block; keywords are case
+// insensitive, and unknown ones keep their order after the known ones
+func rank(keyword string) int {
+	if order, ok := optionOrder[strings.ToLower(keyword)]; ok {
+		return order
+	}
+	return 999
+}
+
+// canonicalKeyword writes a known keyword in the case the OpenSSH manuals
+// use, such as HostName for hostname
+func canonicalKeyword(keyword string) string {
+	if canonical, ok := keywords[strings.ToLower(keyword)]; ok {
+		return canonical
+	}
+	return keyword
+}
+
+// keywords maps the lower case keywords the formatter knows to their case in
+// the manuals
+var keywords = map[string]string{
+	"host":    "Host",
+	"match":   "Match",
+	"include": "Include",
+}
+
+// optionOrder ranks the options of a block by their lower case keyword
+var optionOrder = map[string]int{}
+
+func init() {
+	for i, keyword := range orderedKeywords {
+		keywords[strings.ToLower(keyword)] = keyword
+		optionOrder[strings.ToLower(keyword)] = i
+	}
+}
+
+// orderedKeywords lists the options of a block in order: where to connect
+// and as whom, how to authenticate, proxies and forwarding, connection
+// sharing and keepalives, host key checking, then the restrictions sshd
+// Match blocks apply
+var orderedKeywords = []string{
+	// Destination
+	"HostName",
+	"User",
+	"Port",
+	"AddressFamily",
+	"BindAddress",
+
+	// Authentication
+	"IdentityFile",
+	"IdentitiesOnly",
+	"CertificateFile",
+	"IdentityAgent",
+	"AddKeysToAgent",
+	"UseKeychain",
+	"PreferredAuthentications",
+	"AuthenticationMethods",
+	"PubkeyAuthentication",
+	"PasswordAuthentication",
+	"KbdInteractiveAuthentication",
+
+	// Proxies
+	"ProxyJump",
+	"ProxyCommand",
+
+	// Forwarding
+	"ForwardAgent",
+	"ForwardX11",
+	"ForwardX11Trusted",
+	"LocalForward",
+	"RemoteForward",
+	"DynamicForward",
+
+	// Connection sharing and keepalives
+	"ControlMaster",
+	"ControlPath",
+	"ControlPersist",
+	"ConnectTimeout",
+	"ServerAliveInterval",
+	"ServerAliveCountMax",
+	"TCPKeepAlive",
+
+	// Host keys
+	"StrictHostKeyChecking",
+	"UserKnownHostsFile",
+	"HostKeyAlias",
+	"UpdateHostKeys",
+
+	// Server restrictions
+	"ChrootDirectory",
+	"ForceCommand",
+	"PermitTTY",
+	"AllowTcpForwarding",
+	"AllowAgentForwarding",
+	"X11Forwarding",
+	"PermitTunnel",
+	"GatewayPorts",
+}
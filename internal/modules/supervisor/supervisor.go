@@ -0,0 +1,169 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// syntax is how supervisord reads its files: "key = value" lines with values
+// aligned, ";" starting an inline comment
+var syntax = formatter.INISyntax{Separator: " = ", InlineComments: ";", AlignValues: true}
+
+// SupervisorFormatter formats supervisord configs (supervisord.conf and the
+// program files it includes)
+type SupervisorFormatter struct{}
+
+// New creates a new SupervisorFormatter
+func New() *SupervisorFormatter {
+	return &SupervisorFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *SupervisorFormatter) Name() string {
+	return "supervisor"
+}
+
+// CanHandle checks if this file is a supervisord config: supervisord.conf,
+// a .conf or .ini file in a supervisor or supervisord.d directory, or a
+// .conf file with a [supervisord] or [program:x] section
+func (f *SupervisorFormatter) CanHandle(filename string, data []byte) bool {
+	base := filepath.Base(filename)
+	dir := filepath.Base(filepath.Dir(filename))
+	ext := filepath.Ext(base)
+	switch {
+	case base == "supervisord.conf":
+		return true
+	case (dir == "supervisor" || dir == "supervisord.d" || dir == "conf.d" && strings.Contains(filepath.ToSlash(filename), "supervisor")) &&
+		(ext == ".conf" || ext == ".ini"):
+		return true
+	case ext == ".conf":
+		return bytes.Contains(data, []byte("[supervisord]")) || bytes.Contains(data, []byte("[program:"))
+	}
+	return false
+}
+
+// Format formats a supervisord config
+func (f *SupervisorFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The keys of process sections are ordered by programOrder and the process
+// sections are sorted by name after the others, which keep their order;
+// supervisord starts processes by priority, not by where they are written.
+// With opts.SortSections the other sections are sorted by name too.
+func (f *SupervisorFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatINIContext(ctx, data, opts, syntax, func(file *formatter.INIFile) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		for _, s := range file.Sections {
+			if sectionRank(s.Name) > 0 && sectionRank(s.Name) < groupRank {
+				s.SortKeys(keyRank)
+			}
+		}
+		if opts.SortSections {
+			file.SortSections(sectionRank)
+			return
+		}
+		sort.SliceStable(file.Sections, func(i, j int) bool {
+			a, b := file.Sections[i].Name, file.Sections[j].Name
+			ra, rb := sectionRank(a), sectionRank(b)
+			if ra == 0 || rb == 0 || ra != rb {
+				return ra < rb
+			}
+			return strings.ToLower(a) < strings.ToLower(b)
+		})
+	})
+}
+
+// groupRank is the rank of [group:x] sections, which list programs and go
+// after them
+const groupRank = 4
+
+// sectionRank returns where a section goes: the supervisord, supervisorctl,
+// server and include sections first, then programs, FastCGI programs, event
+// listeners and groups
+func sectionRank(name string) int {
+	kind, _, ok := strings.Cut(strings.ToLower(name), ":")
+	if !ok {
+		return 0
+	}
+	switch kind {
+	case "program":
+		return 1
+	case "fcgi-program":
+		return 2
+	case "eventlistener":
+		return 3
+	case "group":
+		return groupRank
+	}
+	return 0
+}
+
+// programOrder ranks the keys of [program:x], [fcgi-program:x] and
+// [eventlistener:x] sections: what runs and how many, where and as whom,
+// when it starts and restarts, how it stops, then its logs
+var programOrder = map[string]int{
+	"command":         0,
+	"process_name":    1,
+	"numprocs":        2,
+	"numprocs_start":  3,
+	"socket":          4,
+	"socket_backlog":  5,
+	"socket_owner":    6,
+	"socket_mode":     7,
+	"events":          8,
+	"buffer_size":     9,
+	"result_handler":  10,
+	"directory":       11,
+	"user":            12,
+	"umask":           13,
+	"environment":     14,
+	"priority":        15,
+	"autostart":       16,
+	"autorestart":     17,
+	"startsecs":       18,
+	"startretries":    19,
+	"exitcodes":       20,
+	"stopsignal":      21,
+	"stopwaitsecs":    22,
+	"stopasgroup":     23,
+	"killasgroup":     24,
+	"redirect_stderr": 25,
+	"serverurl":       40,
+}
+
+// logOrder ranks the settings of the stdout and stderr logs, which follow
+// the other keys, stdout first
+var logOrder = map[string]int{
+	"logfile":          0,
+	"logfile_maxbytes": 1,
+	"logfile_backups":  2,
+	"capture_maxbytes": 3,
+	"events_enabled":   4,
+	"syslog":           5,
+}
+
+// keyRank returns the position of a key in a process section; unknown keys
+// keep their order after the known ones
+func keyRank(key string) int {
+	key = strings.ToLower(key)
+	if r, ok := programOrder[key]; ok {
+		return r
+	}
+	for i, stream := range []string{"stdout_", "stderr_"} {
+		if setting, ok := strings.CutPrefix(key, stream); ok {
+			if r, ok := logOrder[setting]; ok {
+				return programOrder["redirect_stderr"] + 1 + i*len(logOrder) + r
+			}
+		}
+	}
+	return 999
+}
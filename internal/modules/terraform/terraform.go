@@ -0,0 +1,170 @@
+package terraform
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// TerraformFormatter formats Terraform and OpenTofu configurations (.tf) and
+// variable files (.tfvars)
+type TerraformFormatter struct{}
+
+// New creates a new TerraformFormatter
+func New() *TerraformFormatter {
+	return &TerraformFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *TerraformFormatter) Name() string {
+	return "terraform"
+}
+
+// CanHandle checks if this file is a Terraform configuration or variable file
+func (f *TerraformFormatter) CanHandle(filename string, data []byte) bool {
+	switch filepath.Ext(filename) {
+	case ".tf", ".tfvars":
+		return true
+	}
+	return false
+}
+
+// Format formats a Terraform file
+func (f *TerraformFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Beyond what terraform fmt does, the attributes of blocks are ordered by
+// convention, meta-arguments such as count and for_each first and
+// depends_on last, nested blocks follow the attributes with lifecycle last,
+// and required_providers is sorted by name. Top-level blocks, locals and
+// variable files keep their order.
+func (f *TerraformFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatHCLContext(ctx, data, opts, func(file *formatter.HCLFile) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		for _, item := range file.Body.Items {
+			if item.IsBlock() {
+				formatBlock(item, nil)
+			}
+		}
+	})
+}
+
+// formatBlock orders the items of a block and of the blocks nested in it;
+// parents are the types of the enclosing blocks
+func formatBlock(block *formatter.HCLItem, parents []string) {
+	path := append(parents, block.Name)
+	kind := strings.Join(path, ".")
+	if kind == "locals" {
+		return
+	}
+	if kind == "terraform.required_providers" {
+		sort.SliceStable(block.Body.Items, func(i, j int) bool {
+			return block.Body.Items[i].Key() < block.Body.Items[j].Key()
+		})
+		for _, item := range block.Body.Items {
+			if item.Value != nil && item.Value.Object != nil {
+				formatter.SortHCLItems(item.Value.Object.Items, rank(providerRequirementOrder))
+			}
+		}
+		return
+	}
+
+	order := blockOrders[kind]
+	if len(parents) > 0 && block.Name == "dynamic" {
+		order = blockOrders["dynamic"]
+	}
+	formatter.SortHCLItems(block.Body.Items, rank(order))
+
+	// Meta-arguments are set apart from the arguments after them
+	for i, item := range block.Body.Items {
+		if i > 0 && !item.IsBlock() && order[item.Name] >= 0 && order[block.Body.Items[i-1].Name] < 0 && !block.Body.Items[i-1].IsBlock() {
+			item.Separate = true
+		}
+	}
+
+	for _, item := range block.Body.Items {
+		if item.IsBlock() {
+			formatBlock(item, path)
+		}
+	}
+}
+
+// Attribute and block ranks: meta-arguments have negative ranks, other
+// attributes come next, then nested blocks, then lifecycle and depends_on
+const (
+	attributeRank = 0
+	blockRank     = 10
+	lifecycleRank = 20
+	dependsOnRank = 30
+)
+
+// rank returns a ranking function for an order table, which lists the
+// attributes that go before the others with negative ranks
+func rank(order map[string]int) func(item *formatter.HCLItem) int {
+	return func(item *formatter.HCLItem) int {
+		switch {
+		case item.IsBlock() && item.Name == "lifecycle":
+			return lifecycleRank
+		case item.IsBlock():
+			return blockRank
+		case item.Name == "depends_on":
+			return dependsOnRank
+		}
+		if r, ok := order[item.Key()]; ok {
+			return r
+		}
+		return attributeRank
+	}
+}
+
+// metaArguments ranks the meta-arguments of resource and data blocks
+var metaArguments = map[string]int{"count": -3, "for_each": -2, "provider": -1}
+
+// blockOrders ranks the attributes of blocks by their type, with the types
+// of enclosing blocks joined by dots; blocks missing here only get their
+// attributes before their nested blocks
+var blockOrders = map[string]map[string]int{
+	"resource":  metaArguments,
+	"data":      metaArguments,
+	"ephemeral": metaArguments,
+	"module": {
+		"source":    -5,
+		"version":   -4,
+		"count":     -3,
+		"for_each":  -2,
+		"providers": -1,
+	},
+	"variable": {
+		"type":        -6,
+		"description": -5,
+		"default":     -4,
+		"sensitive":   -3,
+		"nullable":    -2,
+		"ephemeral":   -1,
+	},
+	"output": {
+		"description": -4,
+		"value":       -3,
+		"sensitive":   -2,
+		"ephemeral":   -1,
+	},
+	"provider":  {"alias": -1},
+	"terraform": {"required_version": -2, "experiments": -1},
+	"dynamic":   {"for_each": -3, "iterator": -2, "labels": -1},
+	"moved":     {"from": -2, "to": -1},
+	"import":    {"to": -3, "id": -2, "provider": -1},
+}
+
+// providerRequirementOrder ranks the keys of a required_providers entry
+var providerRequirementOrder = map[string]int{
+	"source":                -3,
+	"version":               -2,
+	"configuration_aliases": -1,
+}
@@ -0,0 +1,127 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// format formats input with opts, failing the test on an error or when a
+// second pass changes the result
+func format(t *testing.T, input string, opts formatter.Options) string {
+	t.Helper()
+	f := New()
+	got, err := f.Format([]byte(input), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := f.Format(got, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(got) {
+		t.Errorf("second pass =\n%s\nwant the first pass\n%s", again, got)
+	}
+	return string(got)
+}
+
+func TestModuleSourceFirst(t *testing.T) {
+	got := format(t, `module "vpc" {
+  cidr    = "10.0.0.0/16"
+  version = "5.0.0"
+  source  = "terraform-aws-modules/vpc/aws"
+}
+`, formatter.DefaultOptions())
+	want := `module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+
+  cidr = "10.0.0.0/16"
+}
+`
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestMetaArguments checks that the meta-arguments of a resource frame its
+// attributes: for_each before them, lifecycle and depends_on after them, each
+// set apart by a blank line
+func TestMetaArguments(t *testing.T) {
+	got := format(t, `resource "aws_instance" "web" {
+  depends_on = [aws_security_group.web]
+  lifecycle {
+    create_before_destroy = true
+  }
+  ami = "ami-123456"
+  for_each = var.instances
+}
+`, formatter.DefaultOptions())
+	want := `resource "aws_instance" "web" {
+  for_each = var.instances
+
+  ami = "ami-123456"
+
+  lifecycle {
+    create_before_destroy = true
+  }
+
+  depends_on = [aws_security_group.web]
+}
+`
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestRequiredProviders checks that providers are sorted by name and each
+// reads source before version, whether written as a block or inline
+func TestRequiredProviders(t *testing.T) {
+	got := format(t, `terraform {
+  required_providers {
+    random = { source = "hashicorp/random", version = "~> 3.0" }
+    aws = {
+      version = "~> 5.0"
+      source  = "hashicorp/aws"
+    }
+  }
+}
+`, formatter.DefaultOptions())
+	want := `terraform {
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = { source = "hashicorp/random", version = "~> 3.0" }
+  }
+}
+`
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestOrderKept checks what keeps the order it was written in: locals always,
+// and every attribute with sorting turned off
+func TestOrderKept(t *testing.T) {
+	locals := `locals {
+  zeta  = "last"
+  alpha = "first"
+}
+`
+	if got := format(t, locals, formatter.DefaultOptions()); got != locals {
+		t.Errorf("Format() =\n%s\nwant the locals in their order", got)
+	}
+
+	module := `module "vpc" {
+  cidr   = "10.0.0.0/16"
+  source = "terraform-aws-modules/vpc/aws"
+}
+`
+	opts := formatter.DefaultOptions()
+	opts.PreserveKeyOrder = true
+	if got := format(t, module, opts); got != module {
+		t.Errorf("Format() with PreserveKeyOrder =\n%s\nwant the attributes in their order", got)
+	}
+}
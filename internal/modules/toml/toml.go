@@ -0,0 +1,43 @@
+package toml
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// TOMLFormatter formats TOML files no more specific formatter claims
+type TOMLFormatter struct{}
+
+// New creates a new TOMLFormatter
+func New() *TOMLFormatter {
+	return &TOMLFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *TOMLFormatter) Name() string {
+	return "toml"
+}
+
+// CanHandle checks if this file is a TOML file
+func (f *TOMLFormatter) CanHandle(filename string, data []byte) bool {
+	return filepath.Ext(filename) == ".toml"
+}
+
+// Format formats a TOML file
+func (f *TOMLFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// Keys keep their order, since without knowing the program reading the file
+// there is no convention to sort them by; tables are sorted by name when
+// opts.SortSections is set
+func (f *TOMLFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatTOMLContext(ctx, data, opts, func(doc *formatter.TOMLDocument) {
+		if opts.SortSections && !opts.PreserveKeyOrder {
+			doc.SortTables()
+		}
+	})
+}
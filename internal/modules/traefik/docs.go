@@ -0,0 +1,66 @@
+package traefik
+
+import (
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// routerProtocols are the sections holding routers, with their headings
+var routerProtocols = []struct{ section, title string }{
+	{"http", "HTTP Routers"},
+	{"tcp", "TCP Routers"},
+	{"udp", "UDP Routers"},
+}
+
+// Document summarizes the routers of a Traefik dynamic config as markdown
+// tables, one per protocol: each router with its rule, entry points and service
+func (f *TraefikFormatter) Document(data []byte) (string, error) {
+	docs, err := formatter.ParseDocuments(data)
+	if err != nil {
+		return "", err
+	}
+
+	var sections []string
+	for _, protocol := range routerProtocols {
+		var rows [][]string
+		for _, doc := range docs {
+			if len(doc.Content) == 0 {
+				continue
+			}
+			section := formatter.MappingValue(doc.Content[0], protocol.section)
+			if section == nil || section.Kind != yaml.MappingNode {
+				continue
+			}
+			forEachEntry(formatter.MappingValue(section, "routers"), func(name string, router *yaml.Node) {
+				row := []string{name}
+				// UDP routers have no rule
+				if protocol.section != "udp" {
+					row = append(row, scalarValue(formatter.MappingValue(router, "rule")))
+				}
+				entryPoints := strings.Join(scalarList(formatter.MappingValue(router, "entryPoints")), ", ")
+				if entryPoints == "" {
+					entryPoints = "all"
+				}
+				row = append(row, entryPoints, scalarValue(formatter.MappingValue(router, "service")))
+				rows = append(rows, row)
+			})
+		}
+
+		header := []string{"Router", "Rule", "Entry points", "Service"}
+		if protocol.section == "udp" {
+			header = []string{"Router", "Entry points", "Service"}
+		}
+		sections = append(sections, formatter.MarkdownSection(protocol.title, header, rows))
+	}
+	return formatter.JoinSections(sections...), nil
+}
+
+// scalarValue returns the value of a scalar node, or "" for a missing node
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
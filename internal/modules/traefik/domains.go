@@ -0,0 +1,137 @@
+package traefik
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// coversDomain reports whether a certificate for name also covers domain:
+// the same name, or a wildcard (*.example.com) matching one more label
+func coversDomain(name, domain string) bool {
+	name, domain = strings.ToLower(name), strings.ToLower(domain)
+	if name == domain {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(name, "*")
+	if !ok || !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	label, ok := strings.CutSuffix(domain, suffix)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+// normalizeDomains sorts the sans of each entry of a tls.domains list and
+// drops those its main domain or another of its sans already covers
+// Entries with comments on their sans are left alone.
+func (f *TraefikFormatter) normalizeDomains(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, entry := range node.Content {
+		main := formatter.MappingValue(entry, "main")
+		sans := formatter.MappingValue(entry, "sans")
+		if main == nil || main.Kind != yaml.ScalarNode || sans == nil || sans.Kind != yaml.SequenceNode {
+			continue
+		}
+		if slices.ContainsFunc(sans.Content, func(item *yaml.Node) bool {
+			return item.Kind != yaml.ScalarNode || item.HeadComment != "" || item.LineComment != "" || item.FootComment != ""
+		}) {
+			continue
+		}
+
+		kept := sans.Content[:0:0]
+		for i, item := range sans.Content {
+			covered := coversDomain(main.Value, item.Value)
+			for j, other := range sans.Content {
+				// Of two equal names the first is kept
+				if j != i && coversDomain(other.Value, item.Value) && (!strings.EqualFold(other.Value, item.Value) || j < i) {
+					covered = true
+				}
+			}
+			if !covered {
+				kept = append(kept, item)
+			}
+		}
+		slices.SortStableFunc(kept, func(a, b *yaml.Node) int {
+			return strings.Compare(strings.ToLower(a.Value), strings.ToLower(b.Value))
+		})
+		sans.Content = kept
+	}
+}
+
+// routerDomains is a router requesting certificates for its tls.domains
+type routerDomains struct {
+	name    string
+	domains []*yaml.Node
+}
+
+// checkTLSDomainOverlap flags routers whose tls.domains request a
+// certificate for names the tls.domains of an earlier router already cover,
+// so the resolver obtains overlapping certificates
+func checkTLSDomainOverlap(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return issues
+	}
+	var routers []routerDomains
+	for _, protocol := range []string{"http", "tcp"} {
+		section := formatter.MappingValue(formatter.MappingValue(root.Content[0], protocol), "routers")
+		if section == nil || section.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			router := routerDomains{name: section.Content[i].Value}
+			domains := formatter.MappingValue(formatter.MappingValue(section.Content[i+1], "tls"), "domains")
+			if domains == nil || domains.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, entry := range domains.Content {
+				if main := formatter.MappingValue(entry, "main"); main != nil && main.Kind == yaml.ScalarNode {
+					router.domains = append(router.domains, main)
+				}
+				if sans := formatter.MappingValue(entry, "sans"); sans != nil && sans.Kind == yaml.SequenceNode {
+					for _, item := range sans.Content {
+						if item.Kind == yaml.ScalarNode {
+							router.domains = append(router.domains, item)
+						}
+					}
+				}
+			}
+			routers = append(routers, router)
+		}
+	}
+
+	for i, router := range routers {
+		for _, earlier := range routers[:i] {
+			var overlap []string
+			var first *yaml.Node
+			for _, domain := range router.domains {
+				if slices.ContainsFunc(earlier.domains, func(name *yaml.Node) bool { return coversDomain(name.Value, domain.Value) }) {
+					overlap = append(overlap, domain.Value)
+					if first == nil {
+						first = domain
+					}
+				}
+			}
+			if first != nil {
+				issues = append(issues, formatter.NewIssue(first, "router %s requests a certificate for %s, which the tls.domains of router %s already cover; share one certificate between them", router.name, quoteList(overlap), earlier.name))
+			}
+		}
+	}
+
+	return issues
+}
+
+// quoteList writes names as a comma-separated list of quoted names
+func quoteList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,80 @@
+package traefik
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ipRangeLists are the IP range lists Traefik accepts, by list key and the
+// block it belongs to
+var ipRangeLists = map[string][]string{
+	"sourceRange": {"ipAllowList", "ipWhiteList"},
+	"trustedIPs":  {"forwardedHeaders", "proxyProtocol"},
+}
+
+// isIPRangeList reports whether path points at one of the ipRangeLists
+func isIPRangeList(path []string) bool {
+	if len(path) < 2 {
+		return false
+	}
+	for _, parent := range ipRangeLists[path[len(path)-1]] {
+		if path[len(path)-2] == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIPRange parses a CIDR range or a single address, which Traefik treats
+// as a range of one (/32 or /128)
+func parseIPRange(value string) (netip.Prefix, bool) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix, err == nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// compareIPRanges orders ranges numerically: IPv4 before IPv6, then by network
+// address, then wider ranges first
+func compareIPRanges(a, b netip.Prefix) int {
+	if c := a.Masked().Addr().Compare(b.Masked().Addr()); c != 0 {
+		return c
+	}
+	if a.Bits() != b.Bits() {
+		return a.Bits() - b.Bits()
+	}
+	return a.Addr().Compare(b.Addr())
+}
+
+// normalizeIPRanges sorts an IP range list numerically
+// Lists with an invalid entry or comments are left alone; the lint rules
+// report invalid entries
+func (f *TraefikFormatter) normalizeIPRanges(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		return
+	}
+
+	ranges := make(map[*yaml.Node]netip.Prefix, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.HeadComment != "" || item.LineComment != "" || item.FootComment != "" {
+			return
+		}
+		prefix, ok := parseIPRange(item.Value)
+		if !ok {
+			return
+		}
+		ranges[item] = prefix
+	}
+
+	sort.SliceStable(node.Content, func(i, j int) bool {
+		return compareIPRanges(ranges[node.Content[i]], ranges[node.Content[j]]) < 0
+	})
+}
@@ -0,0 +1,73 @@
+package traefik
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ServiceLabels describes how Traefik routes to a container, for the labels
+// of its docker provider
+type ServiceLabels struct {
+	// Name is the name of the router and load balancer service, usually the
+	// compose service name
+	Name string
+
+	// Rule is the router rule, such as Host(`example.com`)
+	Rule string
+
+	// Port is the port the container listens on
+	Port int
+
+	// EntryPoint is the entry point the router listens on
+	EntryPoint string
+
+	// TLS enables TLS on the router, with certificates from CertResolver
+	// when it is set
+	TLS          bool
+	CertResolver string
+}
+
+// routerName matches the names Traefik accepts for routers and services
+var routerName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidName reports whether name can be used as a router and service name
+func ValidName(name string) bool {
+	return routerName.MatchString(name)
+}
+
+// HostRule returns the rule matching a host name; a value that already is a
+// rule, such as Host(`a`) || Host(`b`), is returned as it is
+func HostRule(value string) string {
+	if strings.Contains(value, "(") {
+		return value
+	}
+	return "Host(`" + value + "`)"
+}
+
+// Labels returns the labels configuring s, as key and value pairs sorted by
+// key, the order the compose formatter writes a labels mapping in
+func (s ServiceLabels) Labels() [][2]string {
+	router := "traefik.http.routers." + s.Name
+	labels := [][2]string{
+		{"traefik.enable", "true"},
+		{router + ".entrypoints", s.EntryPoint},
+		{router + ".rule", s.Rule},
+	}
+	if s.TLS {
+		labels = append(labels, [2]string{router + ".tls", "true"})
+		if s.CertResolver != "" {
+			labels = append(labels, [2]string{router + ".tls.certresolver", s.CertResolver})
+		}
+	}
+	return append(labels, [2]string{"traefik.http.services." + s.Name + ".loadbalancer.server.port", strconv.Itoa(s.Port)})
+}
+
+// Owns reports whether a label is one Labels writes, or configures the
+// router or service of s otherwise; these are replaced when the labels are
+// generated again
+func (s ServiceLabels) Owns(key string) bool {
+	return key == "traefik.enable" ||
+		strings.HasPrefix(key, "traefik.http.routers."+s.Name+".") ||
+		strings.HasPrefix(key, "traefik.http.services."+s.Name+".")
+}
@@ -0,0 +1,257 @@
+package traefik
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// rules lists the lint checks applied to Traefik configuration files
+var rules = []formatter.Rule{
+	{ID: "traefik/basicauth-plaintext", Check: checkBasicAuthPlaintext},
+	{ID: "traefik/invalid-ip-range", Check: checkInvalidIPRange},
+	{ID: "traefik/ip-range-overlap", Check: checkIPRangeOverlap},
+	{ID: "traefik/redirect-unknown-entrypoint", Check: checkRedirectUnknownEntryPoint},
+	{ID: "traefik/api-insecure", Check: checkAPIInsecure},
+	{ID: "traefik/dashboard-without-tls", Check: checkDashboardWithoutTLS},
+	{ID: "traefik/insecure-skip-verify", Check: checkInsecureSkipVerify},
+	{ID: "traefik/tcp-rule", Check: checkTCPRule},
+	{ID: "traefik/hostsni-without-tls", Check: checkHostSNIWithoutTLS},
+	{ID: "traefik/udp-rule", Check: checkUDPRule},
+	{ID: "traefik/tls-domain-overlap", Severity: formatter.SeverityWarning, Check: checkTLSDomainOverlap},
+}
+
+// htpasswdPrefixes are the hash formats Traefik accepts in basicAuth users
+var htpasswdPrefixes = []string{
+	"$apr1$", // MD5 (Apache)
+	"$2a$",   // BCrypt
+	"$2b$",
+	"$2y$",
+	"{SHA}", // SHA1
+}
+
+// checkBasicAuthPlaintext flags basicAuth users whose password does not look
+// like an htpasswd hash, which usually means a plaintext password was pasted in
+func checkBasicAuthPlaintext(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		if len(path) < 2 || path[len(path)-1] != "users" || path[len(path)-2] != "basicAuth" {
+			return
+		}
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				continue
+			}
+			user, hash, ok := strings.Cut(item.Value, ":")
+			if !ok {
+				issues = append(issues, formatter.NewIssue(item, "basicAuth user entry %q is not in user:hash form", item.Value))
+				continue
+			}
+			if !isHtpasswdHash(hash) {
+				issues = append(issues, formatter.NewIssue(item, "password for basicAuth user %q looks like plaintext, use an htpasswd hash", user))
+			}
+		}
+	})
+
+	return issues
+}
+
+// isHtpasswdHash reports whether the value starts with a known htpasswd hash prefix
+func isHtpasswdHash(value string) bool {
+	for _, prefix := range htpasswdPrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachIPRangeList calls fn with the items of every IP range list
+// (ipAllowList.sourceRange, forwardedHeaders.trustedIPs, ...)
+func forEachIPRangeList(root *yaml.Node, fn func(items []*yaml.Node)) {
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		if !isIPRangeList(path) || node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		var items []*yaml.Node
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				items = append(items, item)
+			}
+		}
+		fn(items)
+	})
+}
+
+// checkInvalidIPRange flags IP range entries that are neither an address nor a CIDR
+func checkInvalidIPRange(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachIPRangeList(root, func(items []*yaml.Node) {
+		for _, item := range items {
+			if _, ok := parseIPRange(item.Value); !ok {
+				issues = append(issues, formatter.NewIssue(item, "%q is not an IP address or CIDR range such as 10.0.0.0/8", item.Value))
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkIPRangeOverlap flags IP ranges that repeat or fall inside another range
+// of the same list, which usually means the list was edited by hand over time
+func checkIPRangeOverlap(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachIPRangeList(root, func(items []*yaml.Node) {
+		type entry struct {
+			node   *yaml.Node
+			prefix netip.Prefix
+		}
+		var entries []entry
+		for _, item := range items {
+			if prefix, ok := parseIPRange(item.Value); ok {
+				entries = append(entries, entry{node: item, prefix: prefix.Masked()})
+			}
+		}
+
+		for j, later := range entries {
+			for _, earlier := range entries[:j] {
+				if !later.prefix.Overlaps(earlier.prefix) {
+					continue
+				}
+
+				// Report on the narrower range, since that is the redundant one
+				switch {
+				case later.prefix == earlier.prefix:
+					issues = append(issues, formatter.NewIssue(later.node, "%s duplicates %s", later.node.Value, earlier.node.Value))
+				case later.prefix.Bits() > earlier.prefix.Bits():
+					issues = append(issues, formatter.NewIssue(later.node, "%s is already covered by %s", later.node.Value, earlier.node.Value))
+				default:
+					issues = append(issues, formatter.NewIssue(earlier.node, "%s is already covered by %s", earlier.node.Value, later.node.Value))
+				}
+				break
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkRedirectUnknownEntryPoint flags entry point redirections whose target
+// is not an entry point of the same static config. A target written as a
+// port, such as ":443", is not checked.
+func checkRedirectUnknownEntryPoint(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return issues
+	}
+	entryPoints := formatter.MappingValue(root.Content[0], "entryPoints")
+	if entryPoints == nil || entryPoints.Kind != yaml.MappingNode {
+		return issues
+	}
+
+	for i := 0; i+1 < len(entryPoints.Content); i += 2 {
+		name := entryPoints.Content[i].Value
+		redirect := formatter.MappingValue(formatter.MappingValue(formatter.MappingValue(entryPoints.Content[i+1], "http"), "redirections"), "entryPoint")
+		to := formatter.MappingValue(redirect, "to")
+		if to == nil || to.Kind != yaml.ScalarNode || strings.HasPrefix(to.Value, ":") || strings.Contains(to.Value, "$") {
+			continue
+		}
+		if formatter.MappingValue(entryPoints, to.Value) == nil {
+			issues = append(issues, formatter.NewIssue(to, "entry point %s redirects to entry point %q, which is not defined", name, to.Value))
+		}
+	}
+
+	return issues
+}
+
+// checkAPIInsecure flags api.insecure: true, which serves the API and the
+// dashboard on the traefik entry point without TLS or authentication
+func checkAPIInsecure(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return issues
+	}
+	insecure := formatter.MappingValue(formatter.MappingValue(root.Content[0], "api"), "insecure")
+	if insecure != nil && insecure.Value == "true" {
+		issues = append(issues, formatter.NewIssue(insecure, "api.insecure serves the API and dashboard without TLS or authentication; route to api@internal through a router with TLS and an auth middleware instead"))
+	}
+
+	return issues
+}
+
+// checkDashboardWithoutTLS flags HTTP routers to the API or dashboard
+// (api@internal, dashboard@internal) without TLS, unless every entry point
+// they listen on has TLS in the same config
+func checkDashboardWithoutTLS(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return issues
+	}
+	entryPoints := formatter.MappingValue(root.Content[0], "entryPoints")
+	routers := formatter.MappingValue(formatter.MappingValue(root.Content[0], "http"), "routers")
+	if routers == nil || routers.Kind != yaml.MappingNode {
+		return issues
+	}
+
+	for i := 0; i+1 < len(routers.Content); i += 2 {
+		name, router := routers.Content[i].Value, routers.Content[i+1]
+		service := formatter.MappingValue(router, "service")
+		if service == nil || service.Value != "api@internal" && service.Value != "dashboard@internal" {
+			continue
+		}
+		if formatter.MappingValue(router, "tls") != nil {
+			continue
+		}
+
+		// Without entryPoints a router listens on every entry point
+		secured := false
+		if listens := formatter.MappingValue(router, "entryPoints"); listens != nil && listens.Kind == yaml.SequenceNode && len(listens.Content) > 0 {
+			secured = true
+			for _, item := range listens.Content {
+				entryPoint := formatter.MappingValue(entryPoints, item.Value)
+				if formatter.MappingValue(formatter.MappingValue(entryPoint, "http"), "tls") == nil {
+					secured = false
+				}
+			}
+		}
+		if !secured {
+			issues = append(issues, formatter.NewIssue(service, "router %s serves %s without TLS; add tls to the router or listen on entry points with TLS only", name, service.Value))
+		}
+	}
+
+	return issues
+}
+
+// checkInsecureSkipVerify flags servers transports that skip verifying the
+// certificates of the backends, static (serversTransport) or dynamic
+// (http.serversTransports, tcp.serversTransports)
+func checkInsecureSkipVerify(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	formatter.Walk(root, func(path []string, node *yaml.Node) {
+		if len(path) < 2 || path[len(path)-1] != "insecureSkipVerify" || node.Value != "true" {
+			return
+		}
+		for _, key := range path {
+			if key == "serversTransport" || key == "serversTransports" {
+				issues = append(issues, formatter.NewIssue(node, "%s turns off certificate verification of the backends, which lets anyone on the path impersonate them; trust their CA with rootCAs instead", strings.Join(path, ".")))
+				return
+			}
+		}
+	})
+
+	return issues
+}
@@ -0,0 +1,415 @@
+package traefik
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Report is the middleware and TLS usage of a set of Traefik dynamic
+// configuration files, taken together as the file provider merges them
+type Report struct {
+	Middlewares []*Middleware `json:"middlewares"`
+
+	// SharedChains are the middleware lists used as is by several routers
+	SharedChains []*SharedChain `json:"shared_chains"`
+
+	// RouterChains are the effective middleware lists of the routers using
+	// middlewares
+	RouterChains []*RouterChain `json:"router_chains"`
+
+	TLSOptions    []*TLSOption    `json:"tls_options"`
+	CertResolvers []*CertResolver `json:"cert_resolvers"`
+}
+
+// Middleware is a middleware defined or referenced in the files
+type Middleware struct {
+	// Protocol is "http" or "tcp"
+	Protocol string `json:"protocol"`
+
+	// Name is the name as defined, or as referenced when the middleware is
+	// not defined in the files (e.g. "auth@docker")
+	Name string `json:"name"`
+
+	// Type is the kind of middleware, such as "basicAuth"
+	Type string `json:"type,omitempty"`
+
+	Defined bool `json:"defined"`
+
+	// Routers lists the routers using the middleware, directly or through a
+	// chain, sorted
+	Routers []string `json:"routers"`
+
+	// Chains lists the chain middlewares including the middleware, sorted
+	Chains []string `json:"chains,omitempty"`
+
+	// Unused is true for a defined middleware no router uses
+	Unused bool `json:"unused"`
+}
+
+// SharedChain is a list of middlewares several routers use in the same order
+type SharedChain struct {
+	Protocol    string   `json:"protocol"`
+	Middlewares []string `json:"middlewares"`
+	Routers     []string `json:"routers"`
+}
+
+// RouterChain is the list of middlewares a router runs, in order, with each
+// chain middleware replaced by its members
+type RouterChain struct {
+	Protocol    string   `json:"protocol"`
+	Router      string   `json:"router"`
+	Middlewares []string `json:"middlewares"`
+
+	// Cycles lists the chains that include themselves, directly or through
+	// other chains, each as the path of chains from the router's reference
+	// back to the repeated one. Traefik refuses to build such a router.
+	Cycles [][]string `json:"cycles,omitempty"`
+
+	// Duplicates lists the middlewares that appear more than once in
+	// Middlewares, which then run twice
+	Duplicates []string `json:"duplicates,omitempty"`
+}
+
+// TLSOption is a set of TLS options defined or referenced in the files
+// Routers with TLS enabled and no options use "default"
+type TLSOption struct {
+	Name    string   `json:"name"`
+	Defined bool     `json:"defined"`
+	Routers []string `json:"routers"`
+}
+
+// CertResolver is a certificate resolver referenced by routers
+// Resolvers are defined in the static configuration, so only references are
+// known
+type CertResolver struct {
+	Name    string   `json:"name"`
+	Routers []string `json:"routers"`
+}
+
+// middlewareProtocols are the sections whose routers use middlewares
+var middlewareProtocols = []string{"http", "tcp"}
+
+// UsageReport reads the Traefik dynamic configuration files at paths, a
+// directory standing for the YAML files in it, and reports which routers use
+// each middleware, TLS option and certificate resolver. Only YAML is read.
+func UsageReport(paths ...string) (*Report, error) {
+	files, err := reportFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	type router struct {
+		protocol, name string
+		middlewares    []string
+	}
+	var routers []router
+	middlewares := make(map[string]*Middleware)
+	chains := make(map[string][]string)
+	options := make(map[string]*TLSOption)
+	resolvers := make(map[string]*CertResolver)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := formatter.ParseDocuments(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, doc := range docs {
+			if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+				continue
+			}
+			root := doc.Content[0]
+
+			for _, protocol := range middlewareProtocols {
+				section := formatter.MappingValue(root, protocol)
+				if section == nil || section.Kind != yaml.MappingNode {
+					continue
+				}
+
+				forEachEntry(formatter.MappingValue(section, "middlewares"), func(name string, definition *yaml.Node) {
+					m := &Middleware{Protocol: protocol, Name: name, Defined: true}
+					if definition.Kind == yaml.MappingNode && len(definition.Content) > 0 {
+						m.Type = definition.Content[0].Value
+						if chain := formatter.MappingValue(definition, "chain"); chain != nil {
+							chains[protocol+" "+name] = scalarList(formatter.MappingValue(chain, "middlewares"))
+						}
+					}
+					middlewares[protocol+" "+name] = m
+				})
+
+				forEachEntry(formatter.MappingValue(section, "routers"), func(name string, definition *yaml.Node) {
+					routers = append(routers, router{protocol, name, scalarList(formatter.MappingValue(definition, "middlewares"))})
+
+					tls := formatter.MappingValue(definition, "tls")
+					if tls == nil || tls.Value == "false" {
+						return
+					}
+					option := "default"
+					if value := formatter.MappingValue(tls, "options"); value != nil && value.Value != "" {
+						option = localName(value.Value)
+					}
+					if options[option] == nil {
+						options[option] = &TLSOption{Name: option}
+					}
+					options[option].Routers = appendUnique(options[option].Routers, name)
+					if value := formatter.MappingValue(tls, "certResolver"); value != nil && value.Value != "" {
+						if resolvers[value.Value] == nil {
+							resolvers[value.Value] = &CertResolver{Name: value.Value}
+						}
+						resolvers[value.Value].Routers = appendUnique(resolvers[value.Value].Routers, name)
+					}
+				})
+			}
+
+			if tls := formatter.MappingValue(root, "tls"); tls != nil && tls.Kind == yaml.MappingNode {
+				forEachEntry(formatter.MappingValue(tls, "options"), func(name string, _ *yaml.Node) {
+					if options[name] == nil {
+						options[name] = &TLSOption{Name: name}
+					}
+					options[name].Defined = true
+				})
+			}
+		}
+	}
+
+	// lookup returns the middleware a reference names, recording it when it is
+	// not defined in the files
+	lookup := func(protocol, ref string) *Middleware {
+		name := localName(ref)
+		if m, ok := middlewares[protocol+" "+name]; ok {
+			return m
+		}
+		m := &Middleware{Protocol: protocol, Name: name}
+		middlewares[protocol+" "+name] = m
+		return m
+	}
+
+	for id, members := range chains {
+		protocol, name, _ := strings.Cut(id, " ")
+		for _, ref := range members {
+			m := lookup(protocol, ref)
+			m.Chains = appendUnique(m.Chains, name)
+		}
+	}
+
+	shared := make(map[string]*SharedChain)
+	var routerChains []*RouterChain
+	for _, r := range routers {
+		// Follow chains, guarding against a chain including itself
+		seen := make(map[string]bool)
+		var use func(ref string)
+		use = func(ref string) {
+			m := lookup(r.protocol, ref)
+			id := m.Protocol + " " + m.Name
+			if seen[id] {
+				return
+			}
+			seen[id] = true
+			m.Routers = appendUnique(m.Routers, r.name)
+			if m.Defined {
+				for _, member := range chains[id] {
+					use(member)
+				}
+			}
+		}
+		for _, ref := range r.middlewares {
+			use(ref)
+		}
+
+		if len(r.middlewares) == 0 {
+			continue
+		}
+		routerChains = append(routerChains, flatten(r.protocol, r.name, r.middlewares, lookup, chains))
+
+		key := r.protocol + " " + strings.Join(r.middlewares, ",")
+		if shared[key] == nil {
+			shared[key] = &SharedChain{Protocol: r.protocol, Middlewares: r.middlewares}
+		}
+		shared[key].Routers = appendUnique(shared[key].Routers, r.name)
+	}
+
+	report := &Report{
+		Middlewares:   []*Middleware{},
+		SharedChains:  []*SharedChain{},
+		RouterChains:  []*RouterChain{},
+		TLSOptions:    []*TLSOption{},
+		CertResolvers: []*CertResolver{},
+	}
+	for _, m := range middlewares {
+		if m.Routers == nil {
+			m.Routers = []string{}
+		}
+		sort.Strings(m.Routers)
+		sort.Strings(m.Chains)
+		m.Unused = m.Defined && len(m.Routers) == 0
+		report.Middlewares = append(report.Middlewares, m)
+	}
+	sort.Slice(report.Middlewares, func(i, j int) bool {
+		a, b := report.Middlewares[i], report.Middlewares[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Name < b.Name
+	})
+
+	for _, chain := range shared {
+		if len(chain.Routers) < 2 {
+			continue
+		}
+		sort.Strings(chain.Routers)
+		report.SharedChains = append(report.SharedChains, chain)
+	}
+	sort.Slice(report.SharedChains, func(i, j int) bool {
+		a, b := report.SharedChains[i], report.SharedChains[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return strings.Join(a.Middlewares, ",") < strings.Join(b.Middlewares, ",")
+	})
+
+	report.RouterChains = append(report.RouterChains, routerChains...)
+	sort.Slice(report.RouterChains, func(i, j int) bool {
+		a, b := report.RouterChains[i], report.RouterChains[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.Router < b.Router
+	})
+
+	for _, option := range options {
+		if option.Routers == nil {
+			option.Routers = []string{}
+		}
+		sort.Strings(option.Routers)
+		report.TLSOptions = append(report.TLSOptions, option)
+	}
+	sort.Slice(report.TLSOptions, func(i, j int) bool {
+		return report.TLSOptions[i].Name < report.TLSOptions[j].Name
+	})
+	for _, resolver := range resolvers {
+		sort.Strings(resolver.Routers)
+		report.CertResolvers = append(report.CertResolvers, resolver)
+	}
+	sort.Slice(report.CertResolvers, func(i, j int) bool {
+		return report.CertResolvers[i].Name < report.CertResolvers[j].Name
+	})
+	return report, nil
+}
+
+// flatten resolves the middlewares of a router into the list it runs,
+// replacing each defined chain with its members, recursively. A chain met
+// again below itself is recorded as a cycle and not followed.
+func flatten(protocol, router string, refs []string, lookup func(protocol, ref string) *Middleware, chains map[string][]string) *RouterChain {
+	result := &RouterChain{Protocol: protocol, Router: router, Middlewares: []string{}}
+	var expand func(ref string, path []string)
+	expand = func(ref string, path []string) {
+		m := lookup(protocol, ref)
+		members, isChain := chains[m.Protocol+" "+m.Name]
+		if !m.Defined || !isChain {
+			result.Middlewares = append(result.Middlewares, m.Name)
+			return
+		}
+		if slices.Contains(path, m.Name) {
+			cycle := append(slices.Clone(path), m.Name)
+			if !slices.ContainsFunc(result.Cycles, func(c []string) bool { return slices.Equal(c, cycle) }) {
+				result.Cycles = append(result.Cycles, cycle)
+			}
+			return
+		}
+		for _, member := range members {
+			expand(member, append(slices.Clip(path), m.Name))
+		}
+	}
+	for _, ref := range refs {
+		expand(ref, nil)
+	}
+
+	counts := make(map[string]int)
+	for _, name := range result.Middlewares {
+		counts[name]++
+		if counts[name] == 2 {
+			result.Duplicates = append(result.Duplicates, name)
+		}
+	}
+	return result
+}
+
+// reportFiles expands the directories among paths into the YAML files they
+// contain, sorted
+func reportFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// forEachEntry calls fn with the key and value of each entry of a mapping node
+func forEachEntry(node *yaml.Node, fn func(name string, value *yaml.Node)) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i].Value, node.Content[i+1])
+	}
+}
+
+// scalarList returns the values of a sequence of scalars, or the value of a
+// single scalar, as Traefik accepts both for middleware lists
+func scalarList(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode && node.Value != "" {
+		return []string{node.Value}
+	}
+	var values []string
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode && item.Value != "" {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+// localName strips the @file provider suffix, which names an object of the
+// file provider itself; references to other providers are left as written
+func localName(ref string) string {
+	return strings.TrimSuffix(ref, "@file")
+}
+
+// appendUnique appends value to list unless it is already there
+func appendUnique(list []string, value string) []string {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
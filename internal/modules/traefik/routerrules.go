@@ -0,0 +1,154 @@
+package traefik
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// tcpMatchers are the matchers a TCP router rule may use
+var tcpMatchers = []string{"HostSNI", "HostSNIRegexp", "ClientIP", "ALPN"}
+
+// matcher is a call in a router rule, such as HostSNI(`example.com`)
+type matcher struct {
+	name string
+	args []string
+}
+
+// parseMatchers returns the matchers of a router rule, in their order; the
+// operators (&&, ||, !) and parentheses between them are skipped
+func parseMatchers(rule string) []matcher {
+	var matchers []matcher
+	for i := 0; i < len(rule); {
+		c := rule[i]
+		if !isIdentByte(c) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(rule) && isIdentByte(rule[i]) {
+			i++
+		}
+		name := rule[start:i]
+		for i < len(rule) && rule[i] == ' ' {
+			i++
+		}
+		if i >= len(rule) || rule[i] != '(' {
+			continue
+		}
+
+		// The arguments are strings quoted with backticks or double quotes
+		m := matcher{name: name}
+		for i++; i < len(rule) && rule[i] != ')'; i++ {
+			if quote := rule[i]; quote == '`' || quote == '"' {
+				end := strings.IndexByte(rule[i+1:], quote)
+				if end < 0 {
+					i = len(rule)
+					break
+				}
+				m.args = append(m.args, rule[i+1:i+1+end])
+				i += end + 1
+			}
+		}
+		matchers = append(matchers, m)
+		i++
+	}
+	return matchers
+}
+
+// isIdentByte reports whether c can be part of a matcher name
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// forEachRouter calls fn with the name and definition of every router of a
+// protocol (http, tcp, udp) in a dynamic config
+func forEachRouter(root *yaml.Node, protocol string, fn func(name *yaml.Node, router *yaml.Node)) {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return
+	}
+	routers := formatter.MappingValue(formatter.MappingValue(root.Content[0], protocol), "routers")
+	if routers == nil || routers.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(routers.Content); i += 2 {
+		if routers.Content[i+1].Kind == yaml.MappingNode {
+			fn(routers.Content[i], routers.Content[i+1])
+		}
+	}
+}
+
+// checkTCPRule flags TCP routers without a rule, and TCP rules using matchers
+// of HTTP routers, such as Host or PathPrefix, which Traefik rejects when it
+// loads the router
+func checkTCPRule(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachRouter(root, "tcp", func(name, router *yaml.Node) {
+		rule := formatter.MappingValue(router, "rule")
+		if rule == nil {
+			issues = append(issues, formatter.NewIssue(name, "tcp router %s has no rule; use HostSNI(`*`) to match every connection", name.Value))
+			return
+		}
+		if rule.Kind != yaml.ScalarNode || strings.Contains(rule.Value, "$") {
+			return
+		}
+		matchers := parseMatchers(rule.Value)
+		if len(matchers) == 0 {
+			issues = append(issues, formatter.NewIssue(rule, "tcp router %s has a rule without matchers, such as HostSNI(`example.com`)", name.Value))
+			return
+		}
+		for _, m := range matchers {
+			if slices.Contains(tcpMatchers, m.name) {
+				continue
+			}
+			if m.name == "Host" || m.name == "HostRegexp" {
+				issues = append(issues, formatter.NewIssue(rule, "tcp router %s uses %s, which is an HTTP matcher; use HostSNI", name.Value, m.name))
+			} else {
+				issues = append(issues, formatter.NewIssue(rule, "tcp router %s uses %s, which is not a TCP matcher (%s)", name.Value, m.name, strings.Join(tcpMatchers, ", ")))
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkHostSNIWithoutTLS flags TCP routers matching a server name without
+// tls: the name is only sent in a TLS handshake, so the router never matches.
+// HostSNI(`*`), matching every connection, works without TLS.
+func checkHostSNIWithoutTLS(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachRouter(root, "tcp", func(name, router *yaml.Node) {
+		rule := formatter.MappingValue(router, "rule")
+		if rule == nil || rule.Kind != yaml.ScalarNode || formatter.MappingValue(router, "tls") != nil {
+			return
+		}
+		for _, m := range parseMatchers(rule.Value) {
+			if m.name == "HostSNIRegexp" || m.name == "HostSNI" && slices.ContainsFunc(m.args, func(arg string) bool { return arg != "*" }) {
+				issues = append(issues, formatter.NewIssue(rule, "tcp router %s matches a server name with %s but has no tls; only HostSNI(`*`) matches connections without TLS", name.Value, m.name))
+				return
+			}
+		}
+	})
+
+	return issues
+}
+
+// checkUDPRule flags UDP routers with a rule: UDP has no host names or paths
+// to match, and Traefik rejects the router when it loads it
+func checkUDPRule(root *yaml.Node) []formatter.Issue {
+	var issues []formatter.Issue
+
+	forEachRouter(root, "udp", func(name, router *yaml.Node) {
+		for _, key := range []string{"rule", "ruleSyntax", "tls"} {
+			if value := formatter.MappingValue(router, key); value != nil {
+				issues = append(issues, formatter.NewIssue(value, "udp router %s has %s, which UDP routers do not support; a UDP router only names its entry points and service", name.Value, key))
+			}
+		}
+	})
+
+	return issues
+}
@@ -0,0 +1,593 @@
+package traefik
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// TraefikFormatter formats Traefik configuration files
+type TraefikFormatter struct {
+	formatter.BaseFormatter
+}
+
+// New creates a new TraefikFormatter
+// Top-level sections are separated by blank lines
+func New() *TraefikFormatter {
+	return &TraefikFormatter{
+		BaseFormatter: formatter.BaseFormatter{
+			BlankLinesBetween: [][]string{{}},
+			// Middlewares run in the order they are listed
+			OrderSensitive: []string{"http.middlewares.*.chain.middlewares", "http.routers.*.middlewares", "entryPoints.*.http.middlewares"},
+		},
+	}
+}
+
+// Name returns the name of this formatter
+func (f *TraefikFormatter) Name() string {
+	return "traefik"
+}
+
+// CanHandle checks if this file is a Traefik configuration file
+func (f *TraefikFormatter) CanHandle(filename string, data []byte) bool {
+	// Check filename patterns
+	if strings.Contains(filepath.Base(filename), "traefik") {
+		return true
+	}
+
+	// Check for traefik-specific keys
+	if !formatter.MayHaveTopLevelKey(data, "http", "tcp", "udp", "entryPoints", "providers", "certificatesResolvers", "api") {
+		return false
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false
+	}
+
+	// Look for traefik indicators in top-level keys
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		content := root.Content[0]
+		if content.Kind == yaml.MappingNode {
+			for i := 0; i < len(content.Content); i += 2 {
+				key := content.Content[i].Value
+				if key == "http" || key == "tcp" || key == "udp" ||
+					key == "entryPoints" || key == "providers" ||
+					key == "certificatesResolvers" || key == "api" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Format formats a Traefik YAML file with consistent indentation and ordering
+func (f *TraefikFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+func (f *TraefikFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatYAMLContext(ctx, data, opts, func(node *yaml.Node, isRoot bool) {
+		f.formatNode(node, isRoot, opts)
+	})
+}
+
+// Lint reports problems in a Traefik YAML file
+func (f *TraefikFormatter) Lint(data []byte) ([]formatter.Issue, error) {
+	return f.LintYAML(data, rules)
+}
+
+// formatNode recursively formats nodes in the YAML tree
+func (f *TraefikFormatter) formatNode(node *yaml.Node, isRoot bool, opts formatter.Options) {
+	f.formatNodeWithContext(node, isRoot, nil, opts)
+}
+
+// formatNodeWithContext recursively formats nodes with key path tracking
+// Traefik reuses key names at several levels (middlewares, certificates, tls),
+// so nested ordering needs the full path rather than just the parent key
+func (f *TraefikFormatter) formatNodeWithContext(node *yaml.Node, isRoot bool, path []string, opts formatter.Options) {
+	if node == nil {
+		return
+	}
+
+	// Process mapping nodes (objects)
+	if node.Kind == yaml.MappingNode {
+		f.sortMappingNode(node, isRoot, path, opts)
+	}
+
+	// Apply value normalization AFTER sorting, BEFORE recursion
+	if !opts.PreserveValues {
+		f.normalizeValues(node, path)
+	}
+
+	// Recursively format child nodes
+	// Check if this is the root document node
+	if isRoot && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		f.formatNodeWithContext(node.Content[0], true, nil, opts)
+		return
+	}
+
+	// For mapping nodes, extend the path with key names when recursing into values
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			valueNode := node.Content[i+1]
+			f.formatNodeWithContext(valueNode, false, append(path, keyNode.Value), opts)
+		}
+	} else {
+		// For sequences and other nodes, don't extend the path
+		for _, child := range node.Content {
+			f.formatNodeWithContext(child, false, path, opts)
+		}
+	}
+}
+
+// normalizeValues dispatches to specific normalizers based on the key path
+// of the value
+func (f *TraefikFormatter) normalizeValues(node *yaml.Node, path []string) {
+	if isDuration(path) {
+		formatter.NormalizeDurationNode(node)
+		return
+	}
+	n := len(path)
+	if n == 0 {
+		return
+	}
+	switch path[n-1] {
+	case "users":
+		// Only the users of auth middlewares are user:hash entries
+		if n >= 2 && (path[n-2] == "basicAuth" || path[n-2] == "digestAuth") {
+			f.normalizeUsers(node)
+		}
+	case "sourceRange", "trustedIPs":
+		f.normalizeIPRanges(node)
+	case "domains":
+		f.normalizeDomains(node)
+	}
+}
+
+// isDuration reports whether the value at path is one of the Traefik durations
+// normalized: the timings of health checks, servers transports and their
+// forwarding timeouts, found by path as getContextKeyOrder finds their tables.
+// Keys of the same names elsewhere, such as in plugin settings, are values the
+// plugin reads its own way and are left as written.
+func isDuration(path []string) bool {
+	n := len(path)
+	if n < 2 {
+		return false
+	}
+	key, parent := path[n-1], path[n-2]
+	switch {
+	case parent == "healthCheck":
+		return slices.Contains([]string{"interval", "unhealthyInterval", "timeout"}, key)
+	case parent == "forwardingTimeouts":
+		return slices.Contains([]string{"dialTimeout", "responseHeaderTimeout", "idleConnTimeout", "readIdleTimeout", "pingTimeout"}, key)
+	case n >= 4 && path[n-4] == "tcp" && path[n-3] == "serversTransports",
+		n == 2 && path[0] == "tcpServersTransport":
+		return slices.Contains([]string{"dialTimeout", "dialKeepAlive", "terminationDelay"}, key)
+	}
+	return false
+}
+
+// normalizeUsers sorts basicAuth/digestAuth user entries by username
+// The entries themselves are left untouched so htpasswd hashes keep their exact
+// spelling and quoting style
+func (f *TraefikFormatter) normalizeUsers(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		return
+	}
+
+	// Leave commented lists alone so comments stay next to their entries
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return
+		}
+		if item.HeadComment != "" || item.LineComment != "" || item.FootComment != "" {
+			return
+		}
+	}
+
+	sort.SliceStable(node.Content, func(i, j int) bool {
+		return username(node.Content[i].Value) < username(node.Content[j].Value)
+	})
+}
+
+// username returns the user part of a "user:hash" entry
+func username(entry string) string {
+	name, _, _ := strings.Cut(entry, ":")
+	return name
+}
+
+// sortMappingNode sorts keys in a mapping node according to Traefik conventions
+func (f *TraefikFormatter) sortMappingNode(node *yaml.Node, isTopLevel bool, path []string, opts formatter.Options) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		return
+	}
+
+	if opts.PreserveKeyOrder {
+		return
+	}
+	order := func(key string) int {
+		if order, ok := getContextKeyOrder(key, path); ok {
+			return order
+		}
+		return getKeyOrder(key, isTopLevel)
+	}
+
+	formatter.SortMappingByRank(node, order)
+}
+
+// getContextKeyOrder returns the sort order for keys of mappings whose meaning
+// depends on where they appear in the document. ok is false when the mapping has
+// no dedicated table and the generic getKeyOrder lookup applies.
+//
+// The generic lookup checks every table in turn, so a key such as "certificates"
+// always gets its TLS rank. Blocks like serversTransports reuse those names with
+// a different intended order and are matched by path here instead.
+func getContextKeyOrder(key string, path []string) (order int, ok bool) {
+	// Servers transport keys order (http.serversTransports.*, static serversTransport)
+	// Identity and trust first, then connection pooling, then timeouts
+	serversTransportOrder := map[string]int{
+		"serverName":          1,
+		"insecureSkipVerify":  2,
+		"rootCAs":             3,
+		"certificates":        4,
+		"peerCertURI":         5,
+		"spiffe":              6,
+		"maxIdleConnsPerHost": 10,
+		"disableHTTP2":        11,
+		"forwardingTimeouts":  20,
+	}
+
+	// TCP servers transport keys order (tcp.serversTransports.*, static tcpServersTransport)
+	tcpServersTransportOrder := map[string]int{
+		"dialTimeout":      1,
+		"dialKeepAlive":    2,
+		"terminationDelay": 3,
+		"proxyProtocol":    4,
+		"tls":              5,
+	}
+
+	// Forwarding timeouts keys order, following the lifetime of a request:
+	// connect, wait for response, keep idle, HTTP/2 health pings
+	forwardingTimeoutsOrder := map[string]int{
+		"dialTimeout":           1,
+		"responseHeaderTimeout": 2,
+		"idleConnTimeout":       3,
+		"readIdleTimeout":       4,
+		"pingTimeout":           5,
+	}
+
+	// Sticky cookie keys order (loadBalancer.sticky.cookie)
+	// Identity, then security attributes, then scope and lifetime
+	stickyCookieOrder := map[string]int{
+		"name":        1,
+		"secure":      2,
+		"httpOnly":    3,
+		"sameSite":    4,
+		"partitioned": 5,
+		"domain":      10,
+		"path":        11,
+		"maxAge":      12,
+	}
+
+	// Health check keys order (loadBalancer.healthCheck)
+	// What to probe, how often, then how to reach it and what to send
+	healthCheckOrder := map[string]int{
+		"path":              1,
+		"interval":          2,
+		"unhealthyInterval": 3,
+		"timeout":           4,
+		"scheme":            5,
+		"mode":              6,
+		"hostname":          7,
+		"port":              8,
+		"method":            9,
+		"status":            10,
+		"followRedirects":   11,
+		"headers":           12,
+	}
+
+	// Entry point redirection keys order (entryPoints.*.http.redirections.entryPoint)
+	// Where to, then how
+	redirectEntryPointOrder := map[string]int{
+		"to":        1,
+		"scheme":    2,
+		"permanent": 3,
+		"priority":  4,
+	}
+
+	var table map[string]int
+	n := len(path)
+	switch {
+	case n == 5 && path[0] == "entryPoints" && path[2] == "http" && path[3] == "redirections" && path[4] == "entryPoint":
+		table = redirectEntryPointOrder
+	case n >= 3 && path[n-3] == "tcp" && path[n-2] == "serversTransports":
+		table = tcpServersTransportOrder
+	case n >= 4 && path[n-4] == "tcp" && path[n-3] == "serversTransports" && path[n-1] == "tls":
+		table = serversTransportOrder
+	case n >= 2 && path[n-2] == "serversTransports":
+		table = serversTransportOrder
+	case n == 1 && path[0] == "serversTransport":
+		table = serversTransportOrder
+	case n == 1 && path[0] == "tcpServersTransport":
+		table = tcpServersTransportOrder
+	case n == 2 && path[0] == "tcpServersTransport" && path[1] == "tls":
+		table = serversTransportOrder
+	case n >= 1 && path[n-1] == "forwardingTimeouts":
+		table = forwardingTimeoutsOrder
+	case n >= 2 && path[n-2] == "sticky" && path[n-1] == "cookie":
+		table = stickyCookieOrder
+	case n >= 1 && path[n-1] == "healthCheck":
+		table = healthCheckOrder
+	default:
+		return 0, false
+	}
+
+	if order, ok := table[key]; ok {
+		return order, true
+	}
+
+	// Unknown keys sort alphabetically after the known ones
+	return 1000, true
+}
+
+// getKeyOrder returns the sort order for Traefik configuration keys
+// Lower numbers come first
+//
+// Ordering Philosophy:
+// Traefik configuration follows a protocol-layered approach with consistent subsection patterns.
+// Top-level: Static config → EntryPoints → Providers → Dynamic config (http/tcp/udp/tls)
+// Within protocols: Routers (traffic rules) → Services (backends) → Middlewares (transformations)
+//
+// Context-aware ordering: We check top-level maps first when isTopLevel=true, then fall through
+// to subsection maps. This prevents key collisions where "middlewares" exists at both http-level
+// and router-level with different intended orderings.
+func getKeyOrder(key string, isTopLevel bool) int {
+	// Top-level Traefik configuration keys order
+	topLevelOrder := map[string]int{
+		// Global configuration
+		"global":       1,
+		"log":          2,
+		"accessLog":    3,
+		"api":          4,
+		"ping":         5,
+		"metrics":      6,
+		"tracing":      7,
+		"hostResolver": 8,
+
+		// Entry points and providers
+		"entryPoints":           10,
+		"providers":             11,
+		"certificatesResolvers": 12,
+		"serversTransport":      13,
+		"tcpServersTransport":   14,
+
+		// Protocol-specific configurations (http, tcp, udp)
+		"http": 100,
+		"tcp":  101,
+		"udp":  102,
+		"tls":  103,
+
+		// Experimental and other
+		"experimental": 200,
+		"pilot":        201,
+	}
+
+	// HTTP section keys order (http.routers, http.services, etc.)
+	// Based on official Traefik docs: routers, services, middlewares, serversTransports
+	httpOrder := map[string]int{
+		"routers":           1,
+		"services":          2,
+		"middlewares":       3,
+		"serversTransports": 4,
+	}
+
+	// TCP section keys order
+	tcpOrder := map[string]int{
+		"routers":           1,
+		"services":          2,
+		"middlewares":       3,
+		"serversTransports": 4,
+	}
+
+	// UDP section keys order
+	udpOrder := map[string]int{
+		"routers":  1,
+		"services": 2,
+	}
+
+	// Router configuration keys order (applies to http/tcp routers)
+	// Based on official docs: entryPoints, rule, priority, service, middlewares, tls, plus newer fields
+	routerOrder := map[string]int{
+		"entryPoints":   1,
+		"rule":          2,
+		"ruleSyntax":    3,
+		"priority":      4,
+		"service":       5,
+		"middlewares":   6,
+		"tls":           7,
+		"parentRefs":    8,
+		"observability": 9,
+	}
+
+	// Service configuration keys order
+	// Official service types: loadBalancer, weighted, mirroring, failover, highestRandomWeight
+	serviceOrder := map[string]int{
+		"loadBalancer":        1,
+		"weighted":            2,
+		"mirroring":           3,
+		"failover":            4,
+		"highestRandomWeight": 5,
+	}
+
+	// LoadBalancer configuration keys order
+	// Official properties: servers, strategy, healthCheck, passiveHealthCheck, sticky, serversTransport, passHostHeader, responseForwarding
+	loadBalancerOrder := map[string]int{
+		"servers":            1,
+		"strategy":           2,
+		"healthCheck":        3,
+		"passiveHealthCheck": 4,
+		"sticky":             5,
+		"serversTransport":   6,
+		"passHostHeader":     7,
+		"responseForwarding": 8,
+	}
+
+	// Middleware configuration keys order
+	// Organized by middleware type: path modification → filtering → transformation → auth → advanced
+	middlewareOrder := map[string]int{
+		// Path modification middlewares (1-10)
+		"addPrefix":        1,
+		"stripPrefix":      2,
+		"stripPrefixRegex": 3,
+		"replacePath":      4,
+		"replacePathRegex": 5,
+
+		// Chaining and filtering (10-20)
+		"chain":       10,
+		"ipWhiteList": 11, // Deprecated, kept for backward compatibility
+		"ipAllowList": 12,
+
+		// Headers and errors (20-30)
+		"headers": 20,
+		"errors":  21,
+
+		// Rate limiting and circuit breaking (30-40)
+		"rateLimit":      30,
+		"circuitBreaker": 31,
+		"inFlightReq":    32,
+
+		// Redirects (40-50)
+		"redirectRegex":  40,
+		"redirectScheme": 41,
+
+		// Authentication (50-60)
+		"basicAuth":   50,
+		"digestAuth":  51,
+		"forwardAuth": 52,
+
+		// Content processing (60-70)
+		"buffering":   60,
+		"compress":    61,
+		"contentType": 62,
+		"grpcWeb":     63,
+
+		// TLS and advanced (70-80)
+		"passTLSClientCert": 70,
+		"retry":             71,
+		"plugin":            80, // Custom plugin middleware
+	}
+
+	// Entry point configuration keys order
+	entryPointOrder := map[string]int{
+		"address":          1,
+		"asDefault":        2,
+		"transport":        3,
+		"http":             4,
+		"http2":            5,
+		"http3":            6,
+		"proxyProtocol":    7,
+		"forwardedHeaders": 8,
+	}
+
+	// TLS configuration keys order
+	tlsOrder := map[string]int{
+		"certificates": 1,
+		"options":      2,
+		"stores":       3,
+		"certResolver": 4,
+		"domains":      5,
+	}
+
+	// Provider configuration keys order
+	providerOrder := map[string]int{
+		"providersThrottleDuration": 1,
+		"docker":                    10,
+		"file":                      11,
+		"kubernetes":                12,
+		"kubernetesGateway":         13,
+		"kubernetesCRD":             14,
+		"consulCatalog":             15,
+		"nomad":                     16,
+		"ecs":                       17,
+		"marathon":                  18,
+		"rancher":                   19,
+		"rest":                      20,
+		"etcd":                      21,
+		"consul":                    22,
+		"zooKeeper":                 23,
+		"redis":                     24,
+		"http":                      25,
+	}
+
+	// Check which order map to use based on context
+	// Priority: Check most specific context first, then fall back to generic
+
+	// Only check top-level when actually at top level
+	if isTopLevel {
+		if order, ok := topLevelOrder[key]; ok {
+			return order
+		}
+	}
+
+	// For subsections, check more specific maps first before generic protocol-level maps
+	// This prevents "middlewares" at router-level from matching "middlewares" at http-level
+
+	// Router-specific keys (nested under http.routers.*, tcp.routers.*, etc.)
+	if order, ok := routerOrder[key]; ok {
+		return order
+	}
+
+	// Service-specific keys (nested under http.services.*, tcp.services.*, etc.)
+	if order, ok := serviceOrder[key]; ok {
+		return order
+	}
+
+	// LoadBalancer-specific keys (nested under services.*.loadBalancer)
+	if order, ok := loadBalancerOrder[key]; ok {
+		return order
+	}
+
+	// Middleware-specific keys (nested under http.middlewares.*)
+	if order, ok := middlewareOrder[key]; ok {
+		return order
+	}
+
+	// EntryPoint-specific keys (nested under entryPoints.*)
+	if order, ok := entryPointOrder[key]; ok {
+		return order
+	}
+
+	// TLS-specific keys (nested under tls.*)
+	if order, ok := tlsOrder[key]; ok {
+		return order
+	}
+
+	// Provider-specific keys (nested under providers.*)
+	if order, ok := providerOrder[key]; ok {
+		return order
+	}
+
+	// Protocol-level keys (http, tcp, udp subsections like routers, services, middlewares)
+	// Checked last to avoid overriding more specific nested keys
+	if order, ok := httpOrder[key]; ok {
+		return order
+	}
+	if order, ok := tcpOrder[key]; ok {
+		return order
+	}
+	if order, ok := udpOrder[key]; ok {
+		return order
+	}
+
+	// Default order for unknown keys (alphabetical sorting will apply)
+	return 1000
+}
@@ -0,0 +1,168 @@
+package traefik
+
+import (
+	"testing"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// TestSortUsers checks that basicAuth and digestAuth users are sorted by
+// username, and that each entry keeps its hash and quotes as written; other
+// users lists keep their order
+func TestSortUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "sorted users",
+			input: `http:
+  middlewares:
+    auth:
+      basicAuth:
+        users:
+          - 'admin:$2y$05$abcdefghijklmnopqrstuv'
+          - bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+          - "zed:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"
+`,
+			want: `http:
+  middlewares:
+    auth:
+      basicAuth:
+        users:
+          - 'admin:$2y$05$abcdefghijklmnopqrstuv'
+          - bob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+          - "zed:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"
+`,
+		},
+		{
+			name: "unsorted users",
+			input: `http:
+  middlewares:
+    auth:
+      basicAuth:
+        users:
+          - "zed:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"
+          - carol:$apr1$xyz$abc
+          - 'admin:$2y$05$abcdefghijklmnopqrstuv'
+`,
+			want: `http:
+  middlewares:
+    auth:
+      basicAuth:
+        users:
+          - 'admin:$2y$05$abcdefghijklmnopqrstuv'
+          - carol:$apr1$xyz$abc
+          - "zed:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"
+`,
+		},
+		{
+			name: "digest users",
+			input: `http:
+  middlewares:
+    auth:
+      digestAuth:
+        users:
+          - "bob:traefik:$$not-a-hash$"
+          - admin:traefik:a2688e031edb4be6a3797f3882655c05
+`,
+			want: `http:
+  middlewares:
+    auth:
+      digestAuth:
+        users:
+          - admin:traefik:a2688e031edb4be6a3797f3882655c05
+          - "bob:traefik:$$not-a-hash$"
+`,
+		},
+		{
+			name: "users of a plugin",
+			input: `experimental:
+  plugins:
+    myplug:
+      users:
+        - zed
+        - admin
+`,
+			want: `experimental:
+  plugins:
+    myplug:
+      users:
+        - zed
+        - admin
+`,
+		},
+	}
+
+	f := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Format([]byte(tt.input), formatter.DefaultOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeDurations checks that the timings of health checks and servers
+// transports are normalized, and that keys of the same names elsewhere are not
+func TestNormalizeDurations(t *testing.T) {
+	input := `http:
+  services:
+    app:
+      loadBalancer:
+        healthCheck:
+          interval: 90s
+          timeout: 1h0m0s
+  serversTransports:
+    backend:
+      forwardingTimeouts:
+        dialTimeout: 60s
+tcp:
+  serversTransports:
+    db:
+      dialTimeout: 120s
+      terminationDelay: 100ms
+experimental:
+  plugins:
+    myplug:
+      interval: 1.5s
+      timeout: 90s
+`
+	want := `http:
+  services:
+    app:
+      loadBalancer:
+        healthCheck:
+          interval: 1m30s
+          timeout: 1h
+  serversTransports:
+    backend:
+      forwardingTimeouts:
+        dialTimeout: 1m
+
+tcp:
+  serversTransports:
+    db:
+      dialTimeout: 2m
+      terminationDelay: 100ms
+
+experimental:
+  plugins:
+    myplug:
+      interval: 1.5s
+      timeout: 90s
+`
+	got, err := New().Format([]byte(input), formatter.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// serverBlock matches a top-level storage or listener block, which marks an
+// .hcl file as a Vault server config
+var serverBlock = regexp.MustCompile(`(?m)^(storage|backend|listener)\s+"[^"]*"\s*\{`)
+
+// VaultFormatter formats HashiCorp Vault server configs
+type VaultFormatter struct{}
+
+// New creates a new VaultFormatter
+func New() *VaultFormatter {
+	return &VaultFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *VaultFormatter) Name() string {
+	return "vault"
+}
+
+// CanHandle checks if this file is a Vault server config: vault.hcl, or an
+// .hcl file with a top-level storage or listener block
+func (f *VaultFormatter) CanHandle(filename string, data []byte) bool {
+	switch {
+	case filepath.Base(filename) == "vault.hcl":
+		return true
+	case filepath.Ext(filename) == ".hcl":
+		return serverBlock.Match(data)
+	}
+	return false
+}
+
+// Format formats a Vault server config
+func (f *VaultFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The top level is ordered by topLevelOrder: where data is stored, how
+// clients reach the server and how it unseals, then its addresses; other
+// settings follow, and blocks of the same type keep their order. The
+// attributes of listener and storage blocks are ordered too.
+func (f *VaultFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatHCLContext(ctx, data, opts, func(file *formatter.HCLFile) {
+		if opts.PreserveKeyOrder {
+			return
+		}
+		formatter.SortHCLItems(file.Body.Items, rank(topLevelOrder))
+		for _, item := range file.Body.Items {
+			switch {
+			case item.IsBlock() && item.Name == "listener":
+				formatter.SortHCLItems(item.Body.Items, rank(listenerOrder))
+			case item.IsBlock() && (item.Name == "storage" || item.Name == "ha_storage" || item.Name == "backend"):
+				formatter.SortHCLItems(item.Body.Items, rank(storageOrder))
+			}
+		}
+	})
+}
+
+// Ranks of the items missing from an order table: other attributes, then
+// other blocks
+const (
+	otherAttributeRank = 50
+	otherBlockRank     = 100
+)
+
+// rank returns a ranking function for an order table
+func rank(order map[string]int) func(item *formatter.HCLItem) int {
+	return func(item *formatter.HCLItem) int {
+		if r, ok := order[item.Name]; ok {
+			return r
+		}
+		if item.IsBlock() {
+			return otherBlockRank
+		}
+		return otherAttributeRank
+	}
+}
+
+// topLevelOrder ranks the top-level blocks and attributes of a server config
+var topLevelOrder = map[string]int{
+	"storage":      1,
+	"backend":      1,
+	"ha_storage":   2,
+	"ha_backend":   2,
+	"listener":     3,
+	"seal":         4,
+	"telemetry":    5,
+	"api_addr":     6,
+	"cluster_addr": 7,
+	"cluster_name": 8,
+}
+
+// listenerOrder ranks the attributes of a listener: its addresses, TLS, then
+// the handling of client addresses; nested blocks, such as telemetry and
+// custom_response_headers, go last
+var listenerOrder = map[string]int{
+	"address":                               1,
+	"cluster_address":                       2,
+	"socket_mode":                           3,
+	"socket_user":                           4,
+	"socket_group":                          5,
+	"tls_disable":                           10,
+	"tls_cert_file":                         11,
+	"tls_key_file":                          12,
+	"tls_client_ca_file":                    13,
+	"tls_min_version":                       14,
+	"tls_max_version":                       15,
+	"tls_cipher_suites":                     16,
+	"tls_require_and_verify_client_cert":    17,
+	"tls_disable_client_certs":              18,
+	"proxy_protocol_behavior":               20,
+	"proxy_protocol_authorized_addrs":       21,
+	"x_forwarded_for_authorized_addrs":      22,
+	"x_forwarded_for_hop_skips":             23,
+	"x_forwarded_for_reject_not_authorized": 24,
+	"x_forwarded_for_reject_not_present":    25,
+}
+
+// storageOrder ranks the attributes of a storage backend: where it keeps its
+// data and as which node; retry_join and other blocks go last
+var storageOrder = map[string]int{
+	"path":    1,
+	"address": 2,
+	"node_id": 3,
+}
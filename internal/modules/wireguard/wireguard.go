@@ -0,0 +1,186 @@
+package wireguard
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// syntax is how wg and wg-quick read their files: "Key = Value" lines, with
+// "#" starting a comment
+var syntax = formatter.INISyntax{Separator: " = ", InlineComments: "#"}
+
+// WireGuardFormatter formats WireGuard and wg-quick configs (wg0.conf)
+type WireGuardFormatter struct{}
+
+// New creates a new WireGuardFormatter
+func New() *WireGuardFormatter {
+	return &WireGuardFormatter{}
+}
+
+// Name returns the name of this formatter
+func (f *WireGuardFormatter) Name() string {
+	return "wireguard"
+}
+
+// CanHandle checks if this file is a WireGuard config: a .conf file in a
+// wireguard directory, or one with an [Interface] section and a private key
+// or peers
+func (f *WireGuardFormatter) CanHandle(filename string, data []byte) bool {
+	if filepath.Ext(filename) != ".conf" {
+		return false
+	}
+	if filepath.Base(filepath.Dir(filename)) == "wireguard" {
+		return true
+	}
+	return bytes.Contains(data, []byte("[Interface]")) &&
+		(bytes.Contains(data, []byte("PrivateKey")) || bytes.Contains(data, []byte("[Peer]")))
+}
+
+// Format formats a WireGuard config
+func (f *WireGuardFormatter) Format(data []byte, opts formatter.Options) ([]byte, error) {
+	return f.FormatContext(context.Background(), data, opts)
+}
+
+// FormatContext is Format, abandoning the work once ctx is done
+// The keys of [Interface] and [Peer] sections are ordered as wg-quick
+// documents them, and the CIDRs of AllowedIPs are sorted. Peers are sorted
+// when opts.SortSections is set.
+func (f *WireGuardFormatter) FormatContext(ctx context.Context, data []byte, opts formatter.Options) ([]byte, error) {
+	return formatter.FormatINIContext(ctx, data, opts, syntax, func(file *formatter.INIFile) {
+		if !opts.PreserveValues {
+			for _, s := range file.Sections {
+				for i, l := range s.Lines {
+					if l.Kind == formatter.INIEntry && strings.EqualFold(l.Key, "AllowedIPs") {
+						s.Lines[i].Value = sortAllowedIPs(l.Value)
+					}
+				}
+			}
+		}
+		if opts.PreserveKeyOrder {
+			return
+		}
+		for _, s := range file.Sections {
+			switch {
+			case strings.EqualFold(s.Name, "Interface"):
+				s.SortKeys(rankIn(interfaceOrder))
+			case strings.EqualFold(s.Name, "Peer"):
+				s.SortKeys(rankIn(peerOrder))
+			}
+		}
+		if opts.SortSections {
+			sortPeers(file.Sections)
+		}
+	})
+}
+
+// interfaceOrder ranks the keys of [Interface]: addressing and the key pair,
+// then the wg-quick settings, with the hooks in the order they run
+var interfaceOrder = map[string]int{
+	"address":    0,
+	"listenport": 1,
+	"privatekey": 2,
+	"dns":        3,
+	"mtu":        4,
+	"table":      5,
+	"fwmark":     6,
+	"preup":      7,
+	"postup":     8,
+	"predown":    9,
+	"postdown":   10,
+	"saveconfig": 11,
+}
+
+// peerOrder ranks the keys of [Peer]: who the peer is, what it may send,
+// then how to reach it
+var peerOrder = map[string]int{
+	"publickey":           0,
+	"presharedkey":        1,
+	"allowedips":          2,
+	"endpoint":            3,
+	"persistentkeepalive": 4,
+}
+
+// rankIn returns a rank function for SortKeys; keys are case insensitive,
+// and unknown ones keep their place after the known ones. Repeated keys,
+// such as several PostUp commands, keep their order.
+func rankIn(order map[string]int) func(string) int {
+	return func(key string) int {
+		if r, ok := order[strings.ToLower(key)]; ok {
+			return r
+		}
+		return 999
+	}
+}
+
+// sortAllowedIPs sorts a comma-separated list of CIDRs, IPv4 before IPv6,
+// then by address and prefix length; the list is left as written when one
+// of them does not parse
+func sortAllowedIPs(value string) string {
+	fields := strings.Split(value, ",")
+	prefixes := make([]netip.Prefix, 0, len(fields))
+	for _, field := range fields {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(field))
+		if err != nil {
+			return value
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		a, b := prefixes[i], prefixes[j]
+		if a.Addr().Is4() != b.Addr().Is4() {
+			return a.Addr().Is4()
+		}
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+	cidrs := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		cidrs[i] = prefix.String()
+	}
+	return strings.Join(cidrs, ", ")
+}
+
+// sortPeers sorts the [Peer] sections after the [Interface] section: peers
+// with a comment above their header by that comment, which usually names
+// the peer, then the others by public key
+func sortPeers(sections []*formatter.INISection) {
+	type peerKey struct {
+		named bool
+		name  string
+	}
+	key := func(s *formatter.INISection) peerKey {
+		if len(s.Comments) > 0 {
+			name := strings.TrimSpace(strings.TrimLeft(s.Comments[len(s.Comments)-1], "#;"))
+			return peerKey{true, strings.ToLower(name)}
+		}
+		for _, l := range s.Lines {
+			if l.Kind == formatter.INIEntry && strings.EqualFold(l.Key, "PublicKey") {
+				return peerKey{false, l.Value}
+			}
+		}
+		return peerKey{}
+	}
+	isPeer := func(s *formatter.INISection) bool {
+		return strings.EqualFold(s.Name, "Peer")
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		a, b := sections[i], sections[j]
+		if !isPeer(a) || !isPeer(b) {
+			// The interface, and anything else, goes before the peers
+			return !isPeer(a) && isPeer(b)
+		}
+		ka, kb := key(a), key(b)
+		if ka.named != kb.named {
+			return ka.named
+		}
+		return ka.name < kb.name
+	})
+}
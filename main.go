@@ -2,116 +2,335 @@ package main
 
 import (
 	"flag"
-	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"slices"
+	"strings"
 
-	"github.com/awsqed/config-formatter/formatter"
-	"github.com/awsqed/config-formatter/modules/dockercompose"
-	"github.com/awsqed/config-formatter/modules/traefik"
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/modules"
 )
 
-var formatters = []formatter.Formatter{
-	dockercompose.New(),
-	traefik.New(),
+// Version and BuildTime are set at build time with
+// -ldflags "-X main.Version=... -X main.BuildTime=..."
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
+// formatters are tried in order during auto-detection
+var formatters = modules.All()
+
+// commands maps subcommand names to their implementations
+// Anything else on the command line is handled as flags for formatting a file
+var commands = map[string]func(args []string) int{
+	"compare":        runCompare,
+	"config":         runConfig,
+	"diff":           runDiff,
+	"docs":           runDocs,
+	"env-report":     runEnvReport,
+	"generate":       runGenerate,
+	"migrate-style":  runMigrateStyle,
+	"new":            runNew,
+	"self-update":    runSelfUpdate,
+	"traefik-report": runTraefikReport,
+	"usage":          runUsage,
+	"validate":       runValidate,
 }
 
 func main() {
-	inputFile := flag.String("input", "", "Input config file (required)")
+	// Subcommands have no -color flag, so color follows the environment until
+	// the settings are resolved
+	colorMode := colorAuto
+	if envSettings, err := config.FromEnv(); err == nil && envSettings.Color != nil {
+		colorMode = *envSettings.Color
+	}
+	setupColor(colorMode)
+	if envSettings, err := config.FromEnv(); err == nil && envSettings.Offline != nil && *envSettings.Offline {
+		disableNetwork()
+	}
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	inputFile := flag.String("input", "", "Input config file, or a directory to format recursively (required unless -stdin is set)")
 	outputFile := flag.String("output", "", "Output file (if not specified, prints to stdout)")
 	indent := flag.Int("indent", 2, "Number of spaces for indentation")
 	inPlace := flag.Bool("w", false, "Write result to source file instead of stdout")
 	check := flag.Bool("check", false, "Check if file is formatted without making changes")
-	formatterType := flag.String("type", "", "Formatter type to use (docker-compose, traefik). Auto-detected if not specified")
+	lint := flag.Bool("lint", false, "Report lint issues instead of formatting")
+	manifestFile := flag.String("manifest", "", "Write a JSON manifest with the input and output hashes, version and options of every formatted file")
+	resolveExtends := flag.Bool("resolve-extends", false, "Print the compose file with every service's extends merged in, to audit what the services run with")
+	debugStyles := flag.Bool("debug-styles", false, "Report every scalar whose quoting style changes during formatting")
+	quoteStyle := flag.String("quote-style", "double", "Quotes used when a value has to be quoted (double, single)")
+	preset := flag.String("preset", "", "Settings preset to start from (k8s-style, minimal-diff, relaxed, strict)")
+	sortKeys := flag.Bool("sort-keys", true, "Order keys by the formatter's conventions (-sort-keys=false keeps the original order)")
+	normalize := flag.Bool("normalize", true, "Rewrite values into canonical form (-normalize=false leaves them as written)")
+	blankLines := flag.String("blank-lines", "sections", "Where blank lines go: sections, none, preserve")
+	documentMarkers := flag.String("document-markers", "never", "Where YAML documents get \"---\" and \"...\" markers: never (only between documents), always, preserve")
+	expandTabs := flag.Bool("expand-tabs", false, "Convert the tabs indenting YAML lines to spaces before parsing, instead of reporting them")
+	alignComments := flag.Bool("align-comments", false, "Line up inline comments of consecutive lines in a block on a common column")
+	collapseLists := flag.Bool("collapse-lists", false, "Write single-item lists as a plain value where the field allows either (e.g. label_file)")
+	buildForm := flag.String("build-form", "keep", "How compose build sections are written: keep, long (build: {context: dir}) or short (build: dir)")
+	sortScrapeConfigs := flag.Bool("sort-scrape-configs", false, "Order the Prometheus scrape_configs list by job_name")
+	sortSections := flag.Bool("sort-sections", false, "Order the sections of INI files, the tables of TOML files, SSH Host blocks and WireGuard peers by name")
+	bindMountAllowlist := flag.String("bind-mount-allowlist", "", "Comma-separated host paths that -lint accepts as writable compose bind mounts (e.g. /etc/nginx,/var/run/docker.sock)")
+	lintSeverity := flag.String("lint-severity", "", "Comma-separated rule=severity pairs setting the severity of lint rules: error, warning or off (e.g. compose/privileged=error)")
+	keepOrder := flag.String("keep-order", "", "Comma-separated key paths whose children are never reordered (e.g. services.*.command,relabel_configs)")
+	formatterType := flag.String("type", "", "Formatter type to use (docker-compose, traefik, gitlab-ci, drone, buildkite, bitbucket, prometheus, alertmanager, loki, golangci, goreleaser, skaffold, envoy, istio, cert-manager, argocd, flux, cloudformation, netplan, devcontainer, esphome, authentik, fluentbit, ini, nginx, haproxy, ssh, wireguard, supervisor, redis, mysql, containerd, toml, terraform, nomad, vault, consul, json-schema, json). Auto-detected if not specified")
+	stdin := flag.Bool("stdin", false, "Read the config from stdin instead of -input")
+	assumeFilename := flag.String("assume-filename", "", "Filename used for auto-detection and messages when reading from stdin")
+	configFile := flag.String("config", "", "Config file to use (default: .config-formatter.yaml discovered from the input's directory)")
+	diff := flag.Bool("diff", false, "Print a unified diff of the formatting changes instead of the formatted file")
+	color := flag.String("color", "auto", "When to use color: auto, always, never (auto honors NO_COLOR)")
+	editorMode := flag.Bool("editor-mode", false, "Editor integration: stdout carries only the formatted document, failures are reported by exit code")
+	maxUnformatted := flag.String("max-unformatted", "", "With -check or -lint on a directory, only fail when more files than this count or percentage (e.g. 10 or 5%) are unformatted")
+	offline := flag.Bool("offline", false, "Refuse all network access (formatting, linting and validation never need it)")
+	jobs := flag.Int("jobs", 0, "Number of files formatted at once when formatting a directory (0, the default, uses one per CPU)")
+	progress := flag.Bool("progress", true, "Show a progress bar when formatting a directory on a terminal (never in CI)")
 
 	flag.Parse()
 
-	if *inputFile == "" {
-		fmt.Fprintln(os.Stderr, "Error: -input flag is required")
+	if *stdin {
+		if *inputFile != "" {
+			printError("Error: -input and -stdin cannot be used together")
+			os.Exit(1)
+		}
+		if *inPlace {
+			printError("Error: -w cannot be used with -stdin")
+			os.Exit(1)
+		}
+	} else if *inputFile == "" {
+		printError("Error: -input flag is required")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Read input file
-	data, err := os.ReadFile(*inputFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-		os.Exit(1)
+	// Only flags set explicitly on the command line override the config file
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	var flagSettings config.Settings
+	if setFlags["type"] {
+		flagSettings.Type = formatterType
 	}
-
-	// Select the appropriate formatter
-	var selectedFormatter formatter.Formatter
-	filename := filepath.Base(*inputFile)
-
-	if *formatterType != "" {
-		// Use specified formatter type
-		for _, f := range formatters {
-			if f.Name() == *formatterType {
-				selectedFormatter = f
-				break
-			}
+	if setFlags["indent"] {
+		flagSettings.Indent = indent
+	}
+	if setFlags["quote-style"] {
+		if *quoteStyle != "double" && *quoteStyle != "single" {
+			printError("Error: -quote-style must be double or single")
+			os.Exit(1)
 		}
-		if selectedFormatter == nil {
-			fmt.Fprintf(os.Stderr, "Error: unknown formatter type '%s'\n", *formatterType)
-			fmt.Fprintln(os.Stderr, "Available formatters:")
-			for _, f := range formatters {
-				fmt.Fprintf(os.Stderr, "  - %s\n", f.Name())
-			}
+		flagSettings.QuoteStyle = quoteStyle
+	}
+	if setFlags["preset"] {
+		if !slices.Contains(config.PresetNames(), *preset) {
+			printError("Error: -preset must be one of %s", strings.Join(config.PresetNames(), ", "))
 			os.Exit(1)
 		}
-	} else {
-		// Auto-detect formatter based on file content and name
-		for _, f := range formatters {
-			if f.CanHandle(filename, data) {
-				selectedFormatter = f
-				break
+		flagSettings.Preset = preset
+	}
+	if setFlags["sort-keys"] {
+		flagSettings.SortKeys = sortKeys
+	}
+	if setFlags["normalize"] {
+		flagSettings.Normalize = normalize
+	}
+	if setFlags["blank-lines"] {
+		if *blankLines != "sections" && *blankLines != "none" && *blankLines != "preserve" {
+			printError("Error: -blank-lines must be sections, none or preserve")
+			os.Exit(1)
+		}
+		flagSettings.BlankLines = blankLines
+	}
+	if setFlags["document-markers"] {
+		if *documentMarkers != "never" && *documentMarkers != "always" && *documentMarkers != "preserve" {
+			printError("Error: -document-markers must be never, always or preserve")
+			os.Exit(1)
+		}
+		flagSettings.DocumentMarkers = documentMarkers
+	}
+	if setFlags["expand-tabs"] {
+		flagSettings.ExpandTabs = expandTabs
+	}
+	if setFlags["align-comments"] {
+		flagSettings.AlignComments = alignComments
+	}
+	if setFlags["collapse-lists"] {
+		flagSettings.CollapseLists = collapseLists
+	}
+	if setFlags["build-form"] {
+		if *buildForm != "keep" && *buildForm != "long" && *buildForm != "short" {
+			printError("Error: -build-form must be keep, long or short")
+			os.Exit(1)
+		}
+		flagSettings.BuildForm = buildForm
+	}
+	if setFlags["sort-scrape-configs"] {
+		flagSettings.SortScrapeConfigs = sortScrapeConfigs
+	}
+	if setFlags["sort-sections"] {
+		flagSettings.SortSections = sortSections
+	}
+	if setFlags["keep-order"] {
+		paths := []string{}
+		for _, path := range strings.Split(*keepOrder, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
 			}
 		}
-		if selectedFormatter == nil {
-			fmt.Fprintln(os.Stderr, "Error: could not auto-detect config type")
-			fmt.Fprintln(os.Stderr, "Please specify formatter type with -type flag")
-			fmt.Fprintln(os.Stderr, "Available formatters:")
-			for _, f := range formatters {
-				fmt.Fprintf(os.Stderr, "  - %s\n", f.Name())
+		flagSettings.KeepOrder = &paths
+	}
+	if setFlags["bind-mount-allowlist"] {
+		paths := []string{}
+		for _, path := range strings.Split(*bindMountAllowlist, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
 			}
+		}
+		flagSettings.BindMountAllow = &paths
+	}
+	if setFlags["lint-severity"] {
+		severities := map[string]string{}
+		for _, pair := range strings.Split(*lintSeverity, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			rule, severity, _ := strings.Cut(pair, "=")
+			if severity != "error" && severity != "warning" && severity != "off" {
+				printError("Error: -lint-severity %q must be rule=error, rule=warning or rule=off", pair)
+				os.Exit(1)
+			}
+			severities[strings.TrimSpace(rule)] = severity
+		}
+		flagSettings.LintSeverity = &severities
+	}
+	if setFlags["jobs"] {
+		if *jobs < 0 {
+			printError("Error: -jobs must be at least 0")
 			os.Exit(1)
 		}
+		flagSettings.Jobs = jobs
+	}
+	if setFlags["color"] {
+		if *color != colorAuto && *color != colorAlways && *color != colorNever {
+			printError("Error: -color must be auto, always or never")
+			os.Exit(1)
+		}
+		flagSettings.Color = color
+	}
+	if setFlags["offline"] {
+		flagSettings.Offline = offline
 	}
 
-	// Format the config file
-	formatted, err := selectedFormatter.Format(data, *indent)
+	envSettings, err := config.FromEnv()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error formatting file: %v\n", err)
+		printError("Error: %v", err)
 		os.Exit(1)
 	}
 
-	// Check mode
-	if *check {
-		if string(data) != string(formatted) {
-			fmt.Fprintf(os.Stderr, "File is not formatted (detected as %s)\n", selectedFormatter.Name())
+	r := &runner{
+		configFile:     *configFile,
+		envSettings:    envSettings,
+		flagSettings:   flagSettings,
+		check:          *check,
+		diff:           *diff,
+		lint:           *lint,
+		debugStyles:    *debugStyles,
+		resolveExtends: *resolveExtends,
+		inPlace:        *inPlace,
+		stdout:         os.Stdout,
+		stderr:         os.Stderr,
+		status:         os.Stdout,
+	}
+
+	if *manifestFile != "" {
+		if *lint {
+			printError("Error: -manifest records formatted files and cannot be used with -lint")
 			os.Exit(1)
 		}
-		fmt.Printf("File is formatted (detected as %s)\n", selectedFormatter.Name())
-		return
+		r.manifest = newManifest()
+	}
+
+	if setFlags["max-unformatted"] {
+		if r.maxUnformatted, err = parseThreshold(*maxUnformatted); err != nil {
+			printError("Error: -max-unformatted: %v", err)
+			os.Exit(1)
+		}
+		if !*check && !*lint {
+			printError("Error: -max-unformatted needs -check or -lint")
+			os.Exit(1)
+		}
+	}
+
+	// Status messages go to stdout, except in editor mode where stdout is
+	// reserved for the formatted document
+	if *editorMode {
+		r.status = io.Discard
+	}
+
+	// A directory is formatted recursively
+	if !*stdin {
+		if info, err := os.Stat(*inputFile); err == nil && info.IsDir() {
+			if *outputFile != "" {
+				printError("Error: -output cannot be used with a directory")
+				os.Exit(1)
+			}
+			if *resolveExtends {
+				printError("Error: -resolve-extends cannot be used with a directory")
+				os.Exit(1)
+			}
+			if !*inPlace && !*check && !*diff && !*lint {
+				printError("Error: formatting a directory needs -w, -check, -diff or -lint")
+				os.Exit(1)
+			}
+			os.Exit(r.writeManifest(*manifestFile, r.runDirectory(*inputFile, *progress)))
+		}
+	}
+	if r.maxUnformatted != nil {
+		printError("Error: -max-unformatted only applies to a directory")
+		os.Exit(1)
+	}
+	if *resolveExtends && (*inPlace || *check || *diff || *lint) {
+		printError("Error: -resolve-extends prints the resolved file and cannot be used with -w, -check, -diff or -lint")
+		os.Exit(1)
 	}
 
+	// Read input
+	var data []byte
+	displayName := *inputFile
+	if *stdin {
+		data, err = io.ReadAll(os.Stdin)
+		displayName = *assumeFilename
+		if displayName == "" {
+			displayName = "<stdin>"
+		}
+	} else {
+		data, err = os.ReadFile(*inputFile)
+	}
+	if err != nil {
+		printError("Error reading file: %v", err)
+		os.Exit(1)
+	}
+
+	settings, err := r.settings(displayName)
+	if err != nil {
+		os.Exit(1)
+	}
+	setupColor(*settings.Color)
+
 	// Determine output destination
-	var output string
+	output := *outputFile
 	if *inPlace {
 		output = *inputFile
-	} else if *outputFile != "" {
-		output = *outputFile
 	}
 
-	// Write output
-	if output != "" {
-		err = os.WriteFile(output, formatted, 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Formatted file written to: %s (using %s formatter)\n", output, selectedFormatter.Name())
-	} else {
-		fmt.Print(string(formatted))
+	code := 0
+	if r.failed(r.formatFile(displayName, data, settings, output)) {
+		code = 1
 	}
+	os.Exit(r.writeManifest(*manifestFile, code))
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/awsqed/config-formatter/internal/config"
+)
+
+// manifest records what every formatted file was produced from, for
+// -manifest, so a pipeline can attest that configs were formatted by an
+// approved release and configuration
+type manifest struct {
+	Tool    string          `json:"tool"`
+	Version string          `json:"version"`
+	Files   []manifestEntry `json:"files"`
+}
+
+// manifestEntry is one formatted file
+type manifestEntry struct {
+	Path      string `json:"path"`
+	Formatter string `json:"formatter"`
+
+	// InputSHA256 and OutputSHA256 are the hex SHA-256 of the file before
+	// and after formatting; they are equal for a formatted file
+	InputSHA256  string `json:"input_sha256"`
+	OutputSHA256 string `json:"output_sha256"`
+
+	// Options are the settings the file was formatted with, by config file
+	// name, defaults included
+	Options map[string]string `json:"options"`
+}
+
+// newManifest returns an empty manifest for this release
+func newManifest() *manifest {
+	return &manifest{Tool: "config-formatter", Version: Version, Files: []manifestEntry{}}
+}
+
+// add records a formatted file
+func (m *manifest) add(path, formatterName string, input, output []byte, settings config.Settings) {
+	values := settings.Values()
+	// Jobs, color and offline only affect the command line, not the output
+	delete(values, "jobs")
+	delete(values, "color")
+	delete(values, "offline")
+	m.Files = append(m.Files, manifestEntry{
+		Path:         path,
+		Formatter:    formatterName,
+		InputSHA256:  sha256Hex(input),
+		OutputSHA256: sha256Hex(output),
+		Options:      values,
+	})
+}
+
+// write saves the manifest as indented JSON
+func (m *manifest) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
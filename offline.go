@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// errOffline is returned for every request once the network is disabled
+var errOffline = errors.New("network access is disabled (offline mode)")
+
+// offlineTransport refuses every request
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
+
+// disableNetwork makes every HTTP request fail for the rest of the process
+// Nothing but self-update talks to the network, and it uses the default
+// transport, so this guards against any future code path that might
+func disableNetwork() {
+	http.DefaultTransport = offlineTransport{}
+}
+
+// networkDisabled reports whether disableNetwork has been called
+func networkDisabled() bool {
+	_, ok := http.DefaultTransport.(offlineTransport)
+	return ok
+}
@@ -0,0 +1,94 @@
+package main
+
+// orderChange is a change to the key ordering rules of a formatter, recorded
+// so migrate-style can re-order only what a release changed
+type orderChange struct {
+	// version is the release the change shipped in; empty for changes made
+	// since the last release, which ship with this build. Cutting a release
+	// fills it in.
+	version string
+
+	formatter   string
+	description string
+
+	// paths are the key paths whose children may be ordered differently, as
+	// MatchKeyPath patterns
+	paths []string
+}
+
+// orderChanges lists the ordering changes of every formatter, oldest first
+// A change to an ordering table, or to how the formatter sorts, adds an entry
+// here; formatters new in a release need none, as no file was formatted with
+// an older release of them.
+var orderChanges = []orderChange{
+	{
+		formatter:   "traefik",
+		description: "basicAuth and digestAuth users are sorted by name",
+		paths:       []string{"basicAuth.users", "digestAuth.users"},
+	},
+	{
+		formatter:   "traefik",
+		description: "servers transport and forwarding timeout keys have their own order",
+		paths: []string{
+			"**.serversTransports.*", "**.serversTransports.*.tls", "serversTransport",
+			"tcpServersTransport", "tcpServersTransport.tls", "forwardingTimeouts",
+		},
+	},
+	{
+		formatter:   "traefik",
+		description: "sticky cookie and health check keys have their own order",
+		paths:       []string{"**.sticky.cookie", "healthCheck"},
+	},
+	{
+		formatter:   "docker-compose",
+		description: "annotations are sorted by key",
+		paths:       []string{"services.*.annotations"},
+	},
+	{
+		formatter:   "traefik",
+		description: "IP allow-list ranges and trusted IPs are sorted by address",
+		paths:       []string{"sourceRange", "trustedIPs"},
+	},
+	{
+		formatter:   "traefik",
+		description: "entry point redirection keys have their own order",
+		paths:       []string{"entryPoints.*.http.redirections.entryPoint"},
+	},
+	{
+		formatter:   "traefik",
+		description: "certificate domain sans are sorted",
+		paths:       []string{"**.domains.*.sans"},
+	},
+	// Commented entries used to keep their place while the others were
+	// sorted around them; they are sorted too now, taking their comments
+	// along, in any mapping
+	{
+		formatter:   "docker-compose",
+		description: "commented keys are sorted with the others",
+		paths:       []string{"**"},
+	},
+	{
+		formatter:   "traefik",
+		description: "commented keys are sorted with the others",
+		paths:       []string{"**"},
+	},
+}
+
+// orderChangesSince returns the ordering changes made to formatter after the
+// release fromVersion, up to and including this build
+func orderChangesSince(fromVersion, formatter string) []orderChange {
+	var changes []orderChange
+	for _, change := range orderChanges {
+		if formatter != "" && change.formatter != formatter {
+			continue
+		}
+		if change.version != "" && compareVersions(change.version, fromVersion) <= 0 {
+			continue
+		}
+		if change.version != "" && Version != "dev" && compareVersions(change.version, Version) > 0 {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
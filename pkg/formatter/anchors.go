@@ -0,0 +1,94 @@
+package formatter
+
+import "gopkg.in/yaml.v3"
+
+// orderAnchors moves each entry that defines an anchor above the first
+// sibling entry using it through an alias
+// A YAML alias has to come after its anchor, and sorting keys can move a
+// definition such as "x-common: &common" below the services merging it, which
+// would make the output unreadable
+func orderAnchors(node *yaml.Node) {
+	if node == nil {
+		return
+	}
+	for _, child := range node.Content {
+		orderAnchors(child)
+	}
+
+	var entries [][]*yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			entries = append(entries, node.Content[i:i+2])
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			entries = append(entries, []*yaml.Node{item})
+		}
+	default:
+		return
+	}
+
+	// Each move puts a definition above one of its users, so valid input
+	// settles after at most one move per pair of entries
+	for moves := 0; moves < len(entries)*len(entries); moves++ {
+		from, to := misplacedAnchor(entries)
+		if from < 0 {
+			break
+		}
+		entry := entries[from]
+		entries = append(entries[:from], entries[from+1:]...)
+		entries = append(entries[:to], append([][]*yaml.Node{entry}, entries[to:]...)...)
+	}
+
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for _, entry := range entries {
+		content = append(content, entry...)
+	}
+	node.Content = content
+}
+
+// misplacedAnchor finds an entry defining an anchor that an earlier entry
+// uses, returning its index and the index of the first user, or -1, -1
+func misplacedAnchor(entries [][]*yaml.Node) (from, to int) {
+	for j, entry := range entries {
+		anchors := make(map[*yaml.Node]bool)
+		for _, node := range entry {
+			collectAnchors(node, anchors)
+		}
+		if len(anchors) == 0 {
+			continue
+		}
+		for i := 0; i < j; i++ {
+			for _, node := range entries[i] {
+				if usesAnchor(node, anchors) {
+					return j, i
+				}
+			}
+		}
+	}
+	return -1, -1
+}
+
+// collectAnchors adds the nodes with an anchor in the tree to anchors
+func collectAnchors(node *yaml.Node, anchors map[*yaml.Node]bool) {
+	if node.Anchor != "" {
+		anchors[node] = true
+	}
+	for _, child := range node.Content {
+		collectAnchors(child, anchors)
+	}
+}
+
+// usesAnchor reports whether the tree holds an alias of one of anchors
+func usesAnchor(node *yaml.Node, anchors map[*yaml.Node]bool) bool {
+	if node.Kind == yaml.AliasNode {
+		return anchors[node.Alias]
+	}
+	for _, child := range node.Content {
+		if usesAnchor(child, anchors) {
+			return true
+		}
+	}
+	return false
+}
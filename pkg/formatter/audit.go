@@ -0,0 +1,89 @@
+package formatter
+
+import "gopkg.in/yaml.v3"
+
+// StyleChange describes a scalar whose emitted style differs from the input
+type StyleChange struct {
+	// Line and Column locate the scalar in the input (1-based)
+	Line   int
+	Column int
+
+	// Value is the scalar value as it appears in the output
+	Value string
+
+	// From and To name the input and output styles, e.g. "plain" and "double-quoted"
+	From string
+	To   string
+}
+
+// scalarStyle records the style a scalar had in the input
+type scalarStyle struct {
+	style  yaml.Style
+	line   int
+	column int
+}
+
+// recordStyles remembers the style of every scalar in the tree, keys included
+func recordStyles(node *yaml.Node, styles map[*yaml.Node]scalarStyle) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.ScalarNode {
+		styles[node] = scalarStyle{style: node.Style, line: node.Line, column: node.Column}
+	}
+	for _, child := range node.Content {
+		recordStyles(child, styles)
+	}
+}
+
+// auditStyles walks the formatted tree alongside the re-parsed output and reports
+// every scalar carried over from the input whose emitted style changed. Scalars
+// created by normalizers have no input counterpart and are not reported.
+func auditStyles(formatted, emitted *yaml.Node, styles map[*yaml.Node]scalarStyle, report func(StyleChange)) {
+	if formatted == nil || emitted == nil || formatted.Kind != emitted.Kind {
+		return
+	}
+
+	if formatted.Kind == yaml.ScalarNode {
+		original, ok := styles[formatted]
+		if !ok {
+			return
+		}
+		from, to := styleName(original.style), styleName(emitted.Style)
+		if from != to {
+			report(StyleChange{
+				Line:   original.line,
+				Column: original.column,
+				Value:  emitted.Value,
+				From:   from,
+				To:     to,
+			})
+		}
+		return
+	}
+
+	// The output is a faithful rendering of the formatted tree, so the two only
+	// diverge structurally if something is badly wrong; stop rather than guess
+	if len(formatted.Content) != len(emitted.Content) {
+		return
+	}
+	for i := range formatted.Content {
+		auditStyles(formatted.Content[i], emitted.Content[i], styles, report)
+	}
+}
+
+// styleName returns a readable name for a scalar style
+func styleName(style yaml.Style) string {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		return "double-quoted"
+	case style&yaml.SingleQuotedStyle != 0:
+		return "single-quoted"
+	case style&yaml.LiteralStyle != 0:
+		return "literal"
+	case style&yaml.FoldedStyle != 0:
+		return "folded"
+	default:
+		return "plain"
+	}
+}
@@ -0,0 +1,165 @@
+package formatter
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Blank lines are placed after encoding rather than by the emitter: the
+// formatted tree is matched against the re-parsed output to find the line each
+// entry starts on (above its head comment), and a blank line is inserted there.
+// This keeps blank lines independent of comments and works with any Encoder.
+
+// recordBlankLines adds the mapping keys and sequence items of root that are
+// preceded by a blank line (above any head comment) in the source to blankBefore
+func recordBlankLines(root *yaml.Node, data []byte, blankBefore map[*yaml.Node]bool) {
+	lines := bytes.Split(data, []byte("\n"))
+
+	forEachEntry(root, func(entry *yaml.Node, first bool) {
+		if first {
+			return
+		}
+		start := firstLine(entry)
+		// lines is 0-based, so the line before start is lines[start-2]
+		if start >= 2 && start-2 < len(lines) && len(bytes.TrimSpace(lines[start-2])) == 0 {
+			blankBefore[entry] = true
+		}
+	})
+}
+
+// sectionEntries adds the entries of the collections at the given key paths,
+// except the first of each, to entries; these are what the sections policy
+// separates
+func sectionEntries(root *yaml.Node, paths [][]string, entries map[*yaml.Node]bool) {
+	Walk(root, func(path []string, node *yaml.Node) {
+		if !slices.ContainsFunc(paths, func(p []string) bool { return slices.Equal(p, path) }) {
+			return
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 2; i < len(node.Content); i += 2 {
+				entries[node.Content[i]] = true
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content[min(1, len(node.Content)):] {
+				entries[item] = true
+			}
+		}
+	})
+}
+
+// insertBlankLines writes a blank line above each marked entry of the
+// formatted documents in output, which must be their encoding, and between
+// the head comment and the key of each entry in banners
+func insertBlankLines(output []byte, docs []*yaml.Node, marked, banners map[*yaml.Node]bool) []byte {
+	if len(marked) == 0 && len(banners) == 0 {
+		return output
+	}
+
+	emitted, err := ParseDocuments(output)
+	if err != nil || len(emitted) != len(docs) {
+		return output
+	}
+
+	// Collect the 1-based output lines to insert a blank line above
+	targets := make(map[int]bool)
+	for i := range docs {
+		matchEntries(docs[i], emitted[i], func(entry, emittedEntry, previous *yaml.Node) {
+			// The encoder drops the blank line ending a head comment
+			if banners[entry] && emittedEntry.HeadComment != "" {
+				targets[emittedEntry.Line] = true
+			}
+			if !marked[entry] {
+				return
+			}
+			// The head comment is measured on the formatted tree, as the
+			// output may read a banner as the foot comment of the entry
+			// before until the blank line above it is there
+			line := emittedEntry.Line
+			if entry.HeadComment != "" {
+				line -= strings.Count(strings.TrimRight(entry.HeadComment, "\n"), "\n") + 1
+			}
+			// Entries sharing a line (flow collections) cannot be separated
+			if previous == nil || line <= previous.Line {
+				return
+			}
+			targets[line] = true
+		})
+	}
+	if len(targets) == 0 {
+		return output
+	}
+
+	lines := bytes.Split(output, []byte("\n"))
+	result := make([][]byte, 0, len(lines)+len(targets))
+	for i, line := range lines {
+		if targets[i+1] && i > 0 && len(bytes.TrimSpace(lines[i-1])) > 0 {
+			result = append(result, nil)
+		}
+		result = append(result, line)
+	}
+	return bytes.Join(result, []byte("\n"))
+}
+
+// matchEntries walks the formatted tree alongside the re-parsed output and calls
+// fn for every mapping key and sequence item with its emitted counterpart and
+// the emitted entry before it (nil for the first)
+func matchEntries(formatted, emitted *yaml.Node, fn func(entry, emittedEntry, previous *yaml.Node)) {
+	if formatted == nil || emitted == nil || formatted.Kind != emitted.Kind {
+		return
+	}
+	// Structural divergence means the output is not a plain rendering of the
+	// tree; stop rather than guess
+	if len(formatted.Content) != len(emitted.Content) {
+		return
+	}
+
+	step := 1
+	if formatted.Kind == yaml.MappingNode {
+		step = 2
+	}
+
+	for i := range formatted.Content {
+		if formatted.Kind != yaml.DocumentNode && i%step == 0 {
+			var previous *yaml.Node
+			if i >= step {
+				previous = emitted.Content[i-step]
+			}
+			fn(formatted.Content[i], emitted.Content[i], previous)
+		}
+		matchEntries(formatted.Content[i], emitted.Content[i], fn)
+	}
+}
+
+// firstLine returns the line an entry starts on, including its head comment
+func firstLine(entry *yaml.Node) int {
+	if entry.HeadComment == "" {
+		return entry.Line
+	}
+	return entry.Line - strings.Count(entry.HeadComment, "\n") - 1
+}
+
+// forEachEntry calls fn for every mapping key and sequence item in the tree,
+// with first set for the first entry of its collection
+// The key node of a pair carries its comments, so it stands for the entry
+func forEachEntry(node *yaml.Node, fn func(entry *yaml.Node, first bool)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			fn(node.Content[i], i == 0)
+			forEachEntry(node.Content[i+1], fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			fn(item, i == 0)
+			forEachEntry(item, fn)
+		}
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			forEachEntry(child, fn)
+		}
+	}
+}
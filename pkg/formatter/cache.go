@@ -0,0 +1,126 @@
+package formatter
+
+import (
+	"errors"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrStaleVersion reports a request for a version of a document that is not
+// the one DocumentCache holds, because a newer one replaced it or it was
+// never added
+var ErrStaleVersion = errors.New("document version is not the current one")
+
+// DocumentCache holds the open documents of a long-running server, such as a
+// language server, by URI and version, so that diagnostics, formatting and
+// path queries on the same version of a document parse it once. It is safe
+// for concurrent use.
+type DocumentCache struct {
+	mu   sync.Mutex
+	docs map[string]*cachedDocument
+}
+
+// cachedDocument is one version of a document, its parse and the values
+// computed from it, each computed once
+type cachedDocument struct {
+	version int
+	data    []byte
+
+	parse sync.Once
+	trees []*yaml.Node
+	err   error
+
+	mu     sync.Mutex
+	values map[string]*cachedValue
+}
+
+// cachedValue is a value computed from a version of a document
+type cachedValue struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+// NewDocumentCache creates an empty DocumentCache
+func NewDocumentCache() *DocumentCache {
+	return &DocumentCache{docs: make(map[string]*cachedDocument)}
+}
+
+// Update records the text of a version of the document at uri, dropping the
+// parse and values of the version it replaces; data must not be modified
+// afterwards. A version no newer than the one held is ignored, so requests
+// handled out of order cannot bring back an old text.
+func (c *DocumentCache) Update(uri string, version int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if doc, ok := c.docs[uri]; ok && doc.version >= version {
+		return
+	}
+	c.docs[uri] = &cachedDocument{version: version, data: data, values: make(map[string]*cachedValue)}
+}
+
+// Close forgets the document at uri
+func (c *DocumentCache) Close(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.docs, uri)
+}
+
+// document returns the held version of the document at uri, or
+// ErrStaleVersion when it is not version
+func (c *DocumentCache) document(uri string, version int) (*cachedDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.docs[uri]
+	if !ok || doc.version != version {
+		return nil, ErrStaleVersion
+	}
+	return doc, nil
+}
+
+// Text returns the text of a version of the document at uri
+func (c *DocumentCache) Text(uri string, version int) ([]byte, error) {
+	doc, err := c.document(uri, version)
+	if err != nil {
+		return nil, err
+	}
+	return doc.data, nil
+}
+
+// Parse returns the YAML documents of a version of the document at uri,
+// parsing it on the first call; concurrent callers wait for the same parse
+// The trees are shared and must not be modified: formatting, which reorders
+// them, parses its own copy.
+func (c *DocumentCache) Parse(uri string, version int) ([]*yaml.Node, error) {
+	doc, err := c.document(uri, version)
+	if err != nil {
+		return nil, err
+	}
+	doc.parse.Do(func() {
+		doc.trees, doc.err = ParseDocuments(doc.data)
+	})
+	return doc.trees, doc.err
+}
+
+// Value returns the value stored under key for a version of the document at
+// uri, calling compute with its text on the first call; concurrent callers
+// wait for the same computation. Keys name what is computed, such as
+// "lint:docker-compose", and values are dropped with the version.
+func (c *DocumentCache) Value(uri string, version int, key string, compute func(data []byte) (any, error)) (any, error) {
+	doc, err := c.document(uri, version)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	value, ok := doc.values[key]
+	if !ok {
+		value = &cachedValue{}
+		doc.values[key] = value
+	}
+	doc.mu.Unlock()
+	value.once.Do(func() {
+		value.value, value.err = compute(doc.data)
+	})
+	return value.value, value.err
+}
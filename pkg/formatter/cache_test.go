@@ -0,0 +1,124 @@
+package formatter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestDocumentCacheShared checks that concurrent requests for the same version
+// share one parse and one computation of each value; run it with -race
+func TestDocumentCacheShared(t *testing.T) {
+	c := NewDocumentCache()
+	c.Update("file:///compose.yaml", 1, []byte("services:\n  web:\n    image: nginx\n"))
+
+	var computed atomic.Int32
+	var wg sync.WaitGroup
+	trees := make([]any, 32)
+	values := make([]any, 32)
+	for i := range trees {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			docs, err := c.Parse("file:///compose.yaml", 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			trees[i] = docs[0]
+			values[i], err = c.Value("file:///compose.yaml", 1, "lint", func(data []byte) (any, error) {
+				computed.Add(1)
+				return len(data), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := computed.Load(); n != 1 {
+		t.Errorf("value computed %d times, want once", n)
+	}
+	for i := range trees {
+		if trees[i] != trees[0] || values[i] != values[0] {
+			t.Fatalf("request %d got a tree or value of its own, want the shared ones", i)
+		}
+	}
+}
+
+// TestDocumentCacheUpdate races updates with readers: a reader gets the
+// version it asked for, or ErrStaleVersion once a newer one replaced it,
+// never the text of another version
+func TestDocumentCacheUpdate(t *testing.T) {
+	c := NewDocumentCache()
+	text := func(version int) []byte {
+		return []byte(fmt.Sprintf("version: %d\n", version))
+	}
+	c.Update("file:///traefik.yml", 0, text(0))
+
+	const versions = 50
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for version := 1; version <= versions; version++ {
+			c.Update("file:///traefik.yml", version, text(version))
+		}
+	}()
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for version := 0; version <= versions; version++ {
+				value, err := c.Value("file:///traefik.yml", version, "text", func(data []byte) (any, error) {
+					return string(data), nil
+				})
+				switch {
+				case errors.Is(err, ErrStaleVersion):
+				case err != nil:
+					t.Error(err)
+				case value != string(text(version)):
+					t.Errorf("version %d read %q", version, value)
+				}
+				if _, err := c.Parse("file:///traefik.yml", version); err != nil && !errors.Is(err, ErrStaleVersion) {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := c.Text("file:///traefik.yml", versions); err != nil {
+		t.Errorf("Text() of the last version = %v", err)
+	}
+}
+
+func TestDocumentCacheStale(t *testing.T) {
+	c := NewDocumentCache()
+	c.Update("file:///a.yaml", 1, []byte("a: 1\n"))
+	if _, err := c.Parse("file:///a.yaml", 1); err != nil {
+		t.Fatal(err)
+	}
+	c.Update("file:///a.yaml", 2, []byte("a: 2\n"))
+
+	if _, err := c.Parse("file:///a.yaml", 1); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("Parse() of the replaced version = %v, want ErrStaleVersion", err)
+	}
+	if _, err := c.Value("file:///a.yaml", 1, "k", func([]byte) (any, error) { return nil, nil }); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("Value() of the replaced version = %v, want ErrStaleVersion", err)
+	}
+
+	// An older update, handled late, does not bring the old text back
+	c.Update("file:///a.yaml", 1, []byte("a: 1\n"))
+	if data, err := c.Text("file:///a.yaml", 2); err != nil || string(data) != "a: 2\n" {
+		t.Errorf("Text() after a late update = %q, %v, want version 2", data, err)
+	}
+
+	c.Close("file:///a.yaml")
+	if _, err := c.Text("file:///a.yaml", 2); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("Text() after Close = %v, want ErrStaleVersion", err)
+	}
+}
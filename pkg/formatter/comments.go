@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// alignComments lines up the inline comments of consecutive lines at the same
+// indentation on a common column, one space after the longest line, the way
+// gofmt aligns trailing comments. A line without an inline comment, a blank
+// line or a change of indentation ends the block.
+//
+// Comments are located through the re-parsed output rather than by scanning for
+// "#", which may also appear inside quoted values.
+func alignComments(output []byte) []byte {
+	emitted, err := ParseDocuments(output)
+	if err != nil {
+		return output
+	}
+
+	comments := make(map[int]string)
+	for _, doc := range emitted {
+		collectLineComments(doc, comments)
+	}
+	if len(comments) == 0 {
+		return output
+	}
+
+	lines := bytes.Split(output, []byte("\n"))
+
+	// code returns the part of line i (0-based) before its inline comment, or
+	// ok false when the line has none
+	code := func(i int) ([]byte, bool) {
+		comment, found := comments[i+1]
+		if !found || !bytes.HasSuffix(lines[i], []byte(comment)) {
+			return nil, false
+		}
+		before := bytes.TrimRight(lines[i][:len(lines[i])-len(comment)], " \t")
+		if len(before) == 0 {
+			return nil, false
+		}
+		return before, true
+	}
+
+	for start := 0; start < len(lines); {
+		first, ok := code(start)
+		if !ok {
+			start++
+			continue
+		}
+
+		// Extend the block while lines keep an inline comment and the indentation
+		end, width := start+1, len(first)
+		for end < len(lines) {
+			next, ok := code(end)
+			if !ok || indentation(next) != indentation(first) {
+				break
+			}
+			width = max(width, len(next))
+			end++
+		}
+
+		for i := start; i < end; i++ {
+			before, _ := code(i)
+			comment := comments[i+1]
+			aligned := make([]byte, 0, width+1+len(comment))
+			aligned = append(aligned, before...)
+			aligned = append(aligned, bytes.Repeat([]byte(" "), width+1-len(before))...)
+			aligned = append(aligned, comment...)
+			lines[i] = aligned
+		}
+		start = end
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// collectLineComments records the inline comment of every node under node by
+// the line it is written on
+func collectLineComments(node *yaml.Node, comments map[int]string) {
+	if node.LineComment != "" {
+		comments[node.Line] = node.LineComment
+	}
+	for _, child := range node.Content {
+		collectLineComments(child, comments)
+	}
+}
+
+// indentation returns the column the entry on line starts at; sequence
+// indicators count as indentation, so the keys of a list item line up
+func indentation(line []byte) int {
+	trimmed := bytes.TrimLeft(line, " ")
+	for bytes.HasPrefix(trimmed, []byte("- ")) {
+		trimmed = bytes.TrimLeft(trimmed[1:], " ")
+	}
+	return len(line) - len(trimmed)
+}
@@ -0,0 +1,52 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+)
+
+// MayHaveTopLevelKey reports whether one of keys can be a top-level key of a
+// YAML document in data, from a scan of its lines that is much cheaper than
+// parsing them. It only answers false when none of the keys can be there, so
+// CanHandle methods use it to skip the parse of files that are not theirs and
+// confirm a true answer by parsing. Documents it cannot read line by line,
+// such as flow mappings, quoted or complex keys and merge keys, answer true.
+func MayHaveTopLevelKey(data []byte, keys ...string) bool {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	for len(data) > 0 {
+		var line []byte
+		line, data, _ = bytes.Cut(data, []byte("\n"))
+		line = bytes.TrimRight(line, " \t\r")
+		if len(line) == 0 {
+			continue
+		}
+		switch line[0] {
+		case ' ', '\t', '#', '-':
+			// Nested content, comments, list items, and document markers
+			// unless a value follows them
+			if bytes.HasPrefix(line, []byte("--- ")) && !bytes.HasPrefix(bytes.TrimSpace(line[4:]), []byte("#")) {
+				return true
+			}
+			continue
+		case '%':
+			// Directives
+			continue
+		case '.':
+			if bytes.Equal(line, []byte("...")) {
+				continue
+			}
+		case '{', '[', '"', '\'', '?', '&', '!', '*', '<', '|', '>', '@', '`':
+			return true
+		}
+		key, _, found := bytes.Cut(line, []byte(":"))
+		if !found {
+			continue
+		}
+		for _, k := range keys {
+			if strings.TrimSpace(string(key)) == k {
+				return true
+			}
+		}
+	}
+	return false
+}
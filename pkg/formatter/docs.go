@@ -0,0 +1,48 @@
+package formatter
+
+import "strings"
+
+// Documenter is implemented by formatters that can summarize a file as
+// markdown, for inclusion in runbooks
+type Documenter interface {
+	// Document returns a markdown summary of the given data, or "" when there
+	// is nothing to summarize. The data is expected to be formatted, so the
+	// summary follows the canonical order.
+	Document(data []byte) (string, error)
+}
+
+// MarkdownSection renders a "##" heading and a table with the given header
+// and rows, or "" when there are no rows. Pipes in cells are escaped and empty
+// cells are written as "-".
+func MarkdownSection(title string, header []string, rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## " + title + "\n\n")
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if cell == "" {
+				cell = "-"
+			}
+			cells[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return b.String()
+}
+
+// JoinSections joins markdown sections with blank lines, leaving out the
+// empty ones
+func JoinSections(sections ...string) string {
+	var nonEmpty []string
+	for _, section := range sections {
+		if section != "" {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
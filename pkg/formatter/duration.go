@@ -0,0 +1,65 @@
+package formatter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// durationUnits are the units used when writing a canonical duration, largest first
+var durationUnits = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// NormalizeDuration rewrites a Go-style duration string ("90s", "1h0m0s",
+// "1500ms") into its canonical form ("1m30s", "1h", "1s500ms"): the largest
+// units first, zero components dropped, no fractions. ok is false when the
+// value is not a duration string, including bare numbers, which some tools
+// read as seconds and others as nanoseconds.
+func NormalizeDuration(value string) (normalized string, ok bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" || strings.HasPrefix(trimmed, "-") {
+		return value, false
+	}
+	if _, err := strconv.ParseFloat(trimmed, 64); err == nil {
+		return value, false
+	}
+
+	d, err := time.ParseDuration(trimmed)
+	if err != nil {
+		return value, false
+	}
+	if d == 0 {
+		return "0s", true
+	}
+
+	var b strings.Builder
+	for _, unit := range durationUnits {
+		if d >= unit.size {
+			b.WriteString(strconv.FormatInt(int64(d/unit.size), 10))
+			b.WriteString(unit.suffix)
+			d %= unit.size
+		}
+	}
+	return b.String(), true
+}
+
+// NormalizeDurationNode rewrites a scalar duration value in place, keeping its style
+func NormalizeDurationNode(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	if normalized, ok := NormalizeDuration(node.Value); ok {
+		node.Value = normalized
+	}
+}
@@ -0,0 +1,158 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/awsqed/config-formatter/internal/linediff"
+)
+
+// Position is a place in a text as the Language Server Protocol counts it:
+// a zero-based line and a zero-based offset in UTF-16 code units
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is the text from Start up to, not including, End
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TextEdit replaces the text of Range with NewText, like an LSP TextEdit;
+// the ranges of a list of edits refer to the text before any of them, and do
+// not overlap
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// FormatIncremental formats current, the text of a document just after an
+// edit of the lines of edited, and returns the edits turning current into its
+// formatted form, so an editor can apply them without replacing the whole
+// document, keeping its cursors and scroll position
+// previous is the formatted text before the edit: when current is the same,
+// it is already formatted and there are no edits. Lines that the formatted
+// text repeats, such as two identical settings, are matched so that the
+// edits fall in edited rather than on an equal line elsewhere.
+func FormatIncremental(ctx context.Context, f Formatter, previous, current []byte, edited Range, opts Options) ([]TextEdit, error) {
+	if bytes.Equal(previous, current) {
+		return nil, nil
+	}
+	formatted, err := FormatContext(ctx, f, current, opts)
+	if err != nil {
+		return nil, err
+	}
+	return diffEdits(current, formatted, edited), nil
+}
+
+// Edits returns the edits turning before into after, each covering the
+// smallest span of text that differs within a run of changed lines
+func Edits(before, after []byte) []TextEdit {
+	return diffEdits(before, after, Range{Start: Position{Line: -1}, End: Position{Line: -1}})
+}
+
+// diffEdits is Edits, matching the unchanged lines before and after anchor
+// first so that ambiguous changes are placed in it
+func diffEdits(before, after []byte, anchor Range) []TextEdit {
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	a, b := splitLines(before), splitLines(after)
+
+	// The lines both texts start and end with are unchanged, up to the
+	// anchor, which ambiguous changes are kept in
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		prefix++
+	}
+	if anchor.Start.Line >= 0 && prefix > anchor.Start.Line {
+		prefix = anchor.Start.Line
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+	if anchor.End.Line >= 0 && len(a)-suffix <= anchor.End.Line {
+		suffix = max(len(a)-anchor.End.Line-1, 0)
+	}
+
+	// Offsets of the start of each line of before, and one past the end
+	offsets := make([]int, len(a)+1)
+	for i, line := range a {
+		offsets[i+1] = offsets[i] + len(line)
+	}
+
+	var edits []TextEdit
+	for _, hunk := range linediff.Diff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		start, end := offsets[prefix+hunk.AStart], offsets[prefix+hunk.AEnd]
+		newText := bytes.Join(b[prefix+hunk.BStart:prefix+hunk.BEnd], nil)
+		oldText := before[start:end]
+
+		// Narrow the edit to the text that differs
+		head := commonPrefix(oldText, newText)
+		tail := commonSuffix(oldText[head:], newText[head:])
+		edits = append(edits, TextEdit{
+			Range: Range{
+				Start: position(before, offsets, start+head),
+				End:   position(before, offsets, end-tail),
+			},
+			NewText: string(newText[head : len(newText)-tail]),
+		})
+	}
+	return edits
+}
+
+// splitLines splits text after each newline
+func splitLines(text []byte) [][]byte {
+	lines := bytes.SplitAfter(text, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b
+// that ends on a character boundary
+func commonPrefix(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	for n > 0 && n < len(a) && !utf8.RuneStart(a[n]) {
+		n--
+	}
+	return n
+}
+
+// commonSuffix returns the length of the longest common suffix of a and b
+// that starts on a character boundary
+func commonSuffix(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	for n > 0 && !utf8.RuneStart(a[len(a)-n]) {
+		n--
+	}
+	return n
+}
+
+// position returns the Position of offset in text, whose lines start at
+// offsets
+func position(text []byte, offsets []int, offset int) Position {
+	line := sort.Search(len(offsets), func(i int) bool { return offsets[i] > offset }) - 1
+	if line == len(offsets)-1 && line > 0 && !bytes.HasSuffix(text, []byte("\n")) {
+		// The end of a text without a final newline is on its last line
+		line--
+	}
+	character := 0
+	for _, r := range string(text[offsets[line]:offset]) {
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
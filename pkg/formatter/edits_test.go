@@ -0,0 +1,135 @@
+package formatter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// offset returns the byte offset of an LSP position in text
+func offset(t *testing.T, text string, pos Position) int {
+	t.Helper()
+	start := 0
+	for range pos.Line {
+		i := strings.IndexByte(text[start:], '\n')
+		if i < 0 {
+			t.Fatalf("position %+v is past the end of %q", pos, text)
+		}
+		start += i + 1
+	}
+	units := 0
+	for i, r := range text[start:] {
+		if units == pos.Character {
+			return start + i
+		}
+		if r == '\n' {
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	if units != pos.Character {
+		t.Fatalf("position %+v is not on a character boundary of %q", pos, text)
+	}
+	return len(text)
+}
+
+// applyEdits applies edits, whose ranges refer to text, to text
+func applyEdits(t *testing.T, text string, edits []TextEdit) string {
+	t.Helper()
+	var b strings.Builder
+	last := 0
+	for _, edit := range edits {
+		start, end := offset(t, text, edit.Range.Start), offset(t, text, edit.Range.End)
+		if start < last || end < start {
+			t.Fatalf("edits %+v overlap or are out of order", edits)
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(edit.NewText)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func TestEdits(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		// edits is the number of edits expected
+		edits int
+	}{
+		{"equal", "a: 1\n", "a: 1\n", 0},
+		{"empty before", "", "a: 1\nb: 2\n", 1},
+		{"empty after", "a: 1\nb: 2\n", "", 1},
+		{"both empty", "", "", 0},
+		{"no final newline", "a: 1\nb: 2", "a: 1\nb: 3", 1},
+		{"multi-byte", "name: héllo 😀 x\nnext: ü\n", "name: héllo 😀 y\nnext: ü\n", 1},
+		{"surrogates after the change", "a: 😀😀\nb: 1\n", "a: 🙂😀\nb: 1\n", 1},
+		{"two changes", "a: 1\nb: 2\nc: 3\nd: 4\n", "a: 9\nb: 2\nc: 3\nd: 8\n", 2},
+		{"repeated lines", "x: 1\nx: 1\nx: 1\ny: 2\n", "x: 1\nx: 1\ny: 2\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := Edits([]byte(tt.before), []byte(tt.after))
+			if len(edits) != tt.edits {
+				t.Errorf("Edits() = %+v, want %d edits", edits, tt.edits)
+			}
+			if got := applyEdits(t, tt.before, edits); got != tt.after {
+				t.Errorf("applying %+v gives %q, want %q", edits, got, tt.after)
+			}
+		})
+	}
+}
+
+// TestEditsUTF16 checks that characters are counted in UTF-16 code units and
+// that an edit is narrowed to the characters that differ
+func TestEditsUTF16(t *testing.T) {
+	edits := Edits([]byte("name: héllo 😀 x\n"), []byte("name: héllo 😀 y\n"))
+	want := TextEdit{Range: Range{Start: Position{0, 15}, End: Position{0, 16}}, NewText: "y"}
+	if len(edits) != 1 || edits[0] != want {
+		t.Errorf("Edits() = %+v, want [%+v]", edits, want)
+	}
+}
+
+// fixedFormatter formats every text to its value, counting the calls
+type fixedFormatter struct {
+	formatted string
+	calls     *int
+}
+
+func (f fixedFormatter) Format([]byte, Options) ([]byte, error) {
+	*f.calls++
+	return []byte(f.formatted), nil
+}
+
+func (f fixedFormatter) Name() string { return "fixed" }
+
+func (f fixedFormatter) CanHandle(string, []byte) bool { return true }
+
+// TestFormatIncremental checks that an ambiguous change, here one of three
+// equal lines going away, is placed in the edited range
+func TestFormatIncremental(t *testing.T) {
+	current := "a: 1\nb: 1\nb: 1\nb: 1\nc: 1\n"
+	var calls int
+	f := fixedFormatter{formatted: "a: 1\nb: 1\nb: 1\nc: 1\n", calls: &calls}
+
+	for line := 1; line <= 3; line++ {
+		edited := Range{Start: Position{Line: line}, End: Position{Line: line, Character: 4}}
+		edits, err := FormatIncremental(context.Background(), f, nil, []byte(current), edited, DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := TextEdit{Range: Range{Start: Position{Line: line}, End: Position{Line: line + 1}}}
+		if len(edits) != 1 || edits[0] != want {
+			t.Errorf("editing line %d: FormatIncremental() = %+v, want [%+v]", line, edits, want)
+		}
+	}
+
+	// Text that did not change since it was formatted is not formatted again
+	calls = 0
+	edits, err := FormatIncremental(context.Background(), f, []byte(current), []byte(current), Range{}, DefaultOptions())
+	if err != nil || edits != nil || calls != 0 {
+		t.Errorf("FormatIncremental() of the previous text = %+v, %v after %d calls, want no edits and no call", edits, err, calls)
+	}
+}
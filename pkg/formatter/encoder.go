@@ -0,0 +1,48 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Encoder turns formatted documents into text
+// Modules that need output yaml.v3's encoder cannot produce can set their own
+// on BaseFormatter.Encoder
+type Encoder interface {
+	Encode(docs []*yaml.Node, opts Options) ([]byte, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface
+type EncoderFunc func(docs []*yaml.Node, opts Options) ([]byte, error)
+
+// Encode calls f
+func (f EncoderFunc) Encode(docs []*yaml.Node, opts Options) ([]byte, error) {
+	return f(docs, opts)
+}
+
+// PostProcessor rewrites encoded output before it is returned
+// docs are the formatted documents the output was encoded from
+type PostProcessor func(data []byte, docs []*yaml.Node, opts Options) ([]byte, error)
+
+// YAMLEncoder is the default Encoder, backed by yaml.v3
+type YAMLEncoder struct{}
+
+// Encode marshals the documents with the configured indentation, separated
+// by "---" lines
+func (YAMLEncoder) Encode(docs []*yaml.Node, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(opts.Indent)
+
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError reports input that is not valid YAML (or whatever syntax the
+// formatter reads)
+type ParseError struct {
+	// File is the file being parsed, "" when only the data is known
+	File string
+
+	// Language names the syntax that failed to parse; "" means YAML
+	Language string
+
+	// Line and Col locate the error in the source (1-based); 0 when the
+	// parser does not report a position. yaml.v3 only reports lines.
+	Line int
+	Col  int
+
+	// Message is the parser's description of the problem
+	Message string
+
+	// Hint suggests how to fix the problem; "" when there is no suggestion
+	Hint string
+
+	// Err is the underlying parser error
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ": ")
+	}
+	language := e.Language
+	if language == "" {
+		language = "YAML"
+	}
+	b.WriteString("failed to parse " + language + ": ")
+	switch {
+	case e.Line > 0 && e.Col > 0:
+		fmt.Fprintf(&b, "line %d, column %d: ", e.Line, e.Col)
+	case e.Line > 0:
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	b.WriteString(e.Message)
+	if e.Hint != "" {
+		b.WriteString(" (" + e.Hint + ")")
+	}
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// yamlErrorLine matches the position prefix of yaml.v3 syntax errors
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// newParseError wraps a yaml.v3 error, taking the line out of its message
+func newParseError(err error) *ParseError {
+	parseErr := &ParseError{Message: strings.TrimPrefix(err.Error(), "yaml: "), Err: err}
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		parseErr.Line, _ = strconv.Atoi(match[1])
+		parseErr.Message = strings.TrimPrefix(err.Error(), match[0])
+	}
+	return parseErr
+}
+
+// DetectError reports that no formatter recognized a file
+type DetectError struct {
+	File string
+
+	// Candidates are the names of the formatters that were tried, any of which
+	// can be chosen explicitly
+	Candidates []string
+}
+
+func (e *DetectError) Error() string {
+	return fmt.Sprintf("could not auto-detect config type of %s (available: %s)", e.File, strings.Join(e.Candidates, ", "))
+}
+
+// UnsupportedError reports a formatter type that does not exist
+type UnsupportedError struct {
+	Type string
+
+	// Supported are the names of the available formatters
+	Supported []string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unknown formatter type '%s' (available: %s)", e.Type, strings.Join(e.Supported, ", "))
+}
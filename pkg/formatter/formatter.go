@@ -0,0 +1,237 @@
+// Package formatter is the public API of config-formatter: the Formatter
+// interface, the Registry that detects and looks formatters up, Options, and
+// the error and Issue types that report problems. Within a major version these
+// only change in backward compatible ways. The YAML, INI, TOML and JSON
+// helpers the built-in formatters are written with are exported for other
+// formatters to use; they may gain fields and options in minor releases.
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formatter is the interface that all config formatters must implement
+type Formatter interface {
+	// Format formats the YAML data with consistent indentation and ordering
+	Format(data []byte, opts Options) ([]byte, error)
+
+	// Name returns the name of the formatter
+	Name() string
+
+	// CanHandle returns true if this formatter can handle the given file
+	CanHandle(filename string, data []byte) bool
+}
+
+// BaseFormatter provides common YAML formatting functionality
+type BaseFormatter struct {
+	// Encoder writes the formatted tree; nil means YAMLEncoder
+	Encoder Encoder
+
+	// BlankLinesBetween lists the mappings and sequences, by key path from the
+	// document root, whose entries are separated by a blank line under
+	// BlankLinesSections. An empty path is the top-level mapping.
+	BlankLinesBetween [][]string
+
+	// OrderSensitive lists the key paths, as MatchKeyPath patterns, whose
+	// children mean something in the order they are written (middleware
+	// chains, relabeling rules, scripts). FormatYAML never reorders them, as
+	// if they were in Options.KeepOrder, and LintYAML checks them with the
+	// order-sensitive rules
+	OrderSensitive []string
+
+	// PostProcessors run in order on the encoded output
+	PostProcessors []PostProcessor
+}
+
+// ContextFormatter is implemented by formatters that stop early when their
+// context is cancelled, so editors and daemons can abandon stale requests
+type ContextFormatter interface {
+	Formatter
+
+	// FormatContext is Format, returning ctx.Err() once ctx is done
+	FormatContext(ctx context.Context, data []byte, opts Options) ([]byte, error)
+}
+
+// FormatContext formats data with f, honoring ctx
+// Formatters that do not implement ContextFormatter are run to completion,
+// and their result is dropped if ctx was cancelled in the meantime
+func FormatContext(ctx context.Context, f Formatter, data []byte, opts Options) ([]byte, error) {
+	if cf, ok := f.(ContextFormatter); ok {
+		return cf.FormatContext(ctx, data, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	formatted, err := f.Format(data, opts)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return formatted, err
+}
+
+// FormatYAML is a helper function that provides basic YAML formatting
+// Every document of a multi-document stream is formatted; formatNode is called
+// once per document
+func (bf *BaseFormatter) FormatYAML(data []byte, opts Options, formatNode func(*yaml.Node, bool)) ([]byte, error) {
+	return bf.FormatYAMLContext(context.Background(), data, opts, formatNode)
+}
+
+// FormatYAMLContext is FormatYAML honoring ctx: the context is checked between
+// the parse, format, encode and post-processing stages and between documents,
+// and ctx.Err() is returned once it is done
+func (bf *BaseFormatter) FormatYAMLContext(ctx context.Context, data []byte, opts Options, formatNode func(*yaml.Node, bool)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.ExpandTabs {
+		data = ExpandTabs(data, opts.Indent)
+	}
+	docs, err := ParseDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing but comments or whitespace: there is nothing to format
+	if len(docs) == 0 {
+		return data, nil
+	}
+	for _, doc := range docs {
+		trimComments(doc)
+	}
+
+	// Remember input styles for the audit pass before formatting rewrites them
+	var styles map[*yaml.Node]scalarStyle
+	if opts.OnStyleChange != nil {
+		styles = make(map[*yaml.Node]scalarStyle)
+		for _, doc := range docs {
+			recordStyles(doc, styles)
+		}
+	}
+
+	// Remember which documents had explicit start and end markers
+	var starts, ends []bool
+	if opts.DocumentMarkers == DocumentMarkersPreserve {
+		starts, ends = recordDocumentMarkers(data, docs)
+	}
+
+	// Remember where the input had blank lines
+	blankBefore := make(map[*yaml.Node]bool)
+	groups := make(map[*yaml.Node][]*yaml.Node)
+	if opts.BlankLines == BlankLinesPreserve {
+		for _, doc := range docs {
+			recordBlankLines(doc, data, blankBefore)
+			recordGroupStarts(doc, groups)
+		}
+	}
+
+	// Apply formatting to the node tree, then undo any reordering below the
+	// paths whose order has to be kept and put anchors back above their aliases
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		kept := recordKeptOrder(doc, slices.Concat(opts.KeepOrder, bf.OrderSensitive))
+		formatNode(doc, true)
+		restoreKeptOrder(kept)
+		orderAnchors(doc)
+	}
+
+	// Decide which entries get a blank line above them
+	separated := make(map[*yaml.Node]bool)
+	switch opts.BlankLines {
+	case BlankLinesNone:
+	case BlankLinesPreserve:
+		followGroupStarts(groups, blankBefore)
+		separated = blankBefore
+	default:
+		for _, doc := range docs {
+			sectionEntries(doc, bf.BlankLinesBetween, separated)
+		}
+	}
+
+	// Section banners keep the blank lines around them under every policy
+	banners := make(map[*yaml.Node]bool)
+	for _, doc := range docs {
+		markBanners(doc, separated, banners)
+	}
+
+	// Marshal back to YAML with specified indentation
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	encoder := bf.Encoder
+	if encoder == nil {
+		encoder = YAMLEncoder{}
+	}
+	output, err := encoder.Encode(docs, opts)
+	if err != nil {
+		return nil, err
+	}
+	output = insertBlankLines(output, docs, separated, banners)
+	if opts.AlignComments {
+		output = alignComments(output)
+	}
+
+	for _, process := range bf.PostProcessors {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		output, err = process(output, docs, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	output = applyDocumentMarkers(output, opts.DocumentMarkers, starts, ends)
+
+	// Compare emitted styles against the input
+	if opts.OnStyleChange != nil {
+		if emitted, err := ParseDocuments(output); err == nil && len(emitted) == len(docs) {
+			for i := range docs {
+				auditStyles(docs[i], emitted[i], styles, opts.OnStyleChange)
+			}
+		}
+	}
+
+	// Post-process to fix empty lines (remove trailing spaces)
+	result := cleanEmptyLines(output)
+
+	return result, nil
+}
+
+// ParseDocuments parses every document in a YAML stream
+// Line numbers are relative to the start of the stream; syntax errors are
+// returned as a *ParseError, and documents exceeding DefaultLimits as a
+// *LimitError
+func ParseDocuments(data []byte) ([]*yaml.Node, error) {
+	return ParseDocumentsLimits(data, DefaultLimits)
+}
+
+// cleanEmptyLines removes trailing spaces from empty lines and removes leading empty lines
+func cleanEmptyLines(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+
+	// Remove trailing spaces from empty lines
+	for i, line := range lines {
+		// If line only contains spaces, make it truly empty
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 && len(line) > 0 {
+			lines[i] = []byte{}
+		}
+	}
+
+	// Remove leading empty lines
+	start := 0
+	for start < len(lines) && len(bytes.TrimSpace(lines[start])) == 0 {
+		start++
+	}
+	if start > 0 {
+		lines = lines[start:]
+	}
+
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,77 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// testGolden formats every testdata/<dir>/*.input file and compares the
+// result with the .golden file next to it, then checks that formatting the
+// golden file gives it back unchanged
+func testGolden(t *testing.T, dir string, format func(data []byte) ([]byte, error)) {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join("testdata", dir, "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no inputs in testdata/%s", dir)
+	}
+	for _, input := range inputs {
+		golden := strings.TrimSuffix(input, ".input") + ".golden"
+		t.Run(strings.TrimSuffix(filepath.Base(input), ".input"), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := format(data)
+			if err != nil {
+				t.Fatalf("formatting %s: %v", input, err)
+			}
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("formatting %s gives\n%s\nwant\n%s", input, got, want)
+			}
+
+			again, err := format(want)
+			if err != nil {
+				t.Fatalf("formatting %s: %v", golden, err)
+			}
+			if !bytes.Equal(again, want) {
+				t.Errorf("formatting %s is not idempotent, it gives\n%s", golden, again)
+			}
+		})
+	}
+}
+
+// testMalformed checks that format rejects each input with a ParseError for
+// language
+func testMalformed(t *testing.T, language string, format func(data []byte) ([]byte, error), inputs []string) {
+	t.Helper()
+	for _, input := range inputs {
+		_, err := format([]byte(input))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("formatting %q: got error %v, want a ParseError", input, err)
+			continue
+		}
+		if parseErr.Language != language || parseErr.Line == 0 {
+			t.Errorf("formatting %q: got %#v, want a %s error with a position", input, parseErr, language)
+		}
+	}
+}
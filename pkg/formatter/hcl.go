@@ -0,0 +1,1003 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HCLFile is a parsed HCL (HashiCorp Configuration Language) file, keeping
+// the comments and the expressions as written so that formatting only
+// changes layout and order
+type HCLFile struct {
+	// Head are the comments at the top of the file, separated from what
+	// follows by a blank line
+	Head []string
+
+	Body *HCLBody
+}
+
+// HCLBody is the content of a file, a block or an object written over
+// several lines
+type HCLBody struct {
+	Items []*HCLItem
+
+	// Foot are the comments after the last item
+	Foot []string
+
+	blankBeforeFoot bool
+}
+
+// HCLItem is an attribute, a block, or an item of an object
+type HCLItem struct {
+	// Comments are the comments on the lines above the item, with "" for
+	// the blank lines between them
+	Comments []string
+
+	// Name is the attribute name, the block type, or the object key as
+	// written
+	Name string
+
+	// Labels are the labels of a block as written, quotes included
+	Labels []string
+
+	// Value is the expression of an attribute or object item; nil for a
+	// block
+	Value *HCLExpr
+
+	// Body is the content of a block; nil for an attribute
+	Body *HCLBody
+
+	// LineComment is the comment after the value, or after the opening
+	// brace of a block
+	LineComment string
+
+	// CloseComment is the comment after the closing brace of a block
+	CloseComment string
+
+	// Separate puts a blank line above the item under BlankLinesSections
+	Separate bool
+
+	blankBefore bool
+	oneLine     bool
+	colon       bool
+	comma       bool
+}
+
+// HCLExpr is the value of an attribute
+// Objects written over several lines are parsed into their items, so that
+// they can be aligned and sorted; every other expression is kept as written
+// and only re-indented.
+type HCLExpr struct {
+	// Raw is the expression as written; empty for an object
+	Raw string
+
+	// Object holds the items of an object written over several lines
+	Object *HCLBody
+
+	// OpenComment is the comment after the opening brace of an object
+	OpenComment string
+
+	// levels is, for each line of Raw after the first, its indentation
+	// relative to the first, or -1 for the lines of a heredoc, which are
+	// kept as they are
+	levels []int
+}
+
+// IsBlock reports whether the item is a block
+func (i *HCLItem) IsBlock() bool {
+	return i.Body != nil
+}
+
+// Label returns the n-th label of a block without its quotes, or ""
+func (i *HCLItem) Label(n int) string {
+	if n >= len(i.Labels) {
+		return ""
+	}
+	if label, err := strconv.Unquote(i.Labels[n]); err == nil {
+		return label
+	}
+	return i.Labels[n]
+}
+
+// Key returns the name of an attribute or object item without its quotes
+func (i *HCLItem) Key() string {
+	if key, err := strconv.Unquote(i.Name); err == nil {
+		return key
+	}
+	return i.Name
+}
+
+// Get returns the attribute or object item of the body with the given key,
+// or nil
+func (b *HCLBody) Get(key string) *HCLItem {
+	for _, item := range b.Items {
+		if !item.IsBlock() && item.Key() == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// Blocks returns the blocks of the body of the given type
+func (b *HCLBody) Blocks(kind string) []*HCLItem {
+	var blocks []*HCLItem
+	for _, item := range b.Items {
+		if item.IsBlock() && item.Name == kind {
+			blocks = append(blocks, item)
+		}
+	}
+	return blocks
+}
+
+// Strings returns the string literals, quotes included, of an expression
+// that is a list of them or a single one, and whether it is; lists with
+// comments or template sequences are not
+func (e *HCLExpr) Strings() (values []string, ok bool) {
+	raw := strings.TrimSpace(e.Raw)
+	if e.Object != nil || strings.Contains(raw, "${") || strings.Contains(raw, "%{") {
+		return nil, false
+	}
+	list := strings.HasPrefix(raw, "[")
+	if list {
+		if raw, ok = strings.CutSuffix(raw[1:], "]"); !ok {
+			return nil, false
+		}
+	}
+	for i := 0; i < len(raw); {
+		switch c := raw[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',' && list:
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(raw) && raw[end] != '"' && raw[end] != '\n' {
+				if raw[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(raw) || raw[end] != '"' {
+				return nil, false
+			}
+			values = append(values, raw[i:end+1])
+			i = end + 1
+		default:
+			return nil, false
+		}
+	}
+	if !list && len(values) != 1 {
+		return nil, false
+	}
+	return values, true
+}
+
+// SetStrings replaces the expression with a list of string literals, on one
+// line, or one per line if the expression was written over several
+func (e *HCLExpr) SetStrings(values []string) {
+	if !strings.Contains(e.Raw, "\n") {
+		e.Raw, e.levels = "["+strings.Join(values, ", ")+"]", nil
+		return
+	}
+	e.Raw, e.levels = "[", nil
+	for _, value := range values {
+		e.Raw += "\n" + value + ","
+		e.levels = append(e.levels, 1)
+	}
+	e.Raw += "\n]"
+	e.levels = append(e.levels, 0)
+}
+
+// SortHCLItems orders items by rank; items of the same rank keep their
+// order, and comments move with the item below them
+func SortHCLItems(items []*HCLItem, rank func(item *HCLItem) int) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return rank(items[i]) < rank(items[j])
+	})
+}
+
+// FormatHCL parses data, lets formatFile reorder it and prints it back
+// Items are indented by opts.Indent per level, the equals signs of
+// consecutive attributes are aligned, and expressions are kept as written
+// apart from the indentation of their lines.
+func FormatHCL(data []byte, opts Options, formatFile func(*HCLFile)) ([]byte, error) {
+	return FormatHCLContext(context.Background(), data, opts, formatFile)
+}
+
+// FormatHCLContext is FormatHCL honoring ctx between the parse, format and
+// print stages
+func FormatHCLContext(ctx context.Context, data []byte, opts Options, formatFile func(*HCLFile)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := ParseHCL(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if formatFile != nil {
+		formatFile(file)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return file.Print(opts), nil
+}
+
+// hclParser reads HCL native syntax, tracking positions for error messages
+type hclParser struct {
+	data []byte
+	pos  int
+	line int
+	col  int
+}
+
+// ParseHCL parses an HCL file in native syntax
+// Syntax errors are returned as a *ParseError
+func ParseHCL(data []byte) (file *HCLFile, err error) {
+	p := &hclParser{data: data, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			err = perr
+		}
+	}()
+	file = &HCLFile{}
+	file.Body = p.body(&file.Head, false)
+	return file, nil
+}
+
+// fail aborts parsing with a ParseError at the current position
+func (p *hclParser) fail(format string, args ...any) {
+	panic(&ParseError{
+		Language: "HCL",
+		Line:     p.line,
+		Col:      p.col,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// advance moves past n bytes, keeping the line and column up to date
+func (p *hclParser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.data); i++ {
+		if p.data[p.pos] == '\n' {
+			p.line++
+			p.col = 1
+		} else {
+			p.col++
+		}
+		p.pos++
+	}
+}
+
+// peek returns the byte n bytes ahead of the current position, 0 at the end
+// of the input
+func (p *hclParser) peek(n int) byte {
+	if p.pos+n >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos+n]
+}
+
+// spaces skips spaces and tabs
+func (p *hclParser) spaces() {
+	for c := p.peek(0); c == ' ' || c == '\t'; c = p.peek(0) {
+		p.advance(1)
+	}
+}
+
+// atComment reports whether a comment starts at the current position
+func (p *hclParser) atComment() bool {
+	c := p.peek(0)
+	return c == '#' || c == '/' && (p.peek(1) == '/' || p.peek(1) == '*')
+}
+
+// atNewline reports whether the current position ends the line
+func (p *hclParser) atNewline() bool {
+	return p.peek(0) == '\n' || p.peek(0) == '\r' && p.peek(1) == '\n'
+}
+
+// comment returns the comment starting at the current position, if any;
+// a /* */ comment may span several lines
+func (p *hclParser) comment() string {
+	if !p.atComment() {
+		return ""
+	}
+	if p.peek(1) == '*' {
+		end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+		if end < 0 {
+			p.fail("unterminated comment")
+		}
+		text := string(p.data[p.pos : p.pos+end+4])
+		p.advance(end + 4)
+		return text
+	}
+	end := bytes.IndexByte(p.data[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	text := strings.TrimRight(string(p.data[p.pos:p.pos+end]), " \t\r")
+	p.advance(end)
+	return text
+}
+
+// endOfLine reads the optional comment closing a line and the newline
+func (p *hclParser) endOfLine() string {
+	p.spaces()
+	comment := p.comment()
+	p.spaces()
+	if p.peek(0) == '\r' {
+		p.advance(1)
+	}
+	switch p.peek(0) {
+	case '\n':
+		p.advance(1)
+	case 0:
+	default:
+		p.fail("expected the end of the line, found %q", p.peek(0))
+	}
+	return comment
+}
+
+// body parses items up to the end of the input, or up to a closing brace
+// when closing is set, which is left for the caller
+// Comments go with the item below them. When head is set, the comment groups
+// at the top of the input separated from the first item by a blank line are
+// stored in it.
+func (p *hclParser) body(head *[]string, closing bool) *HCLBody {
+	body := &HCLBody{}
+
+	// pending are the comments since the last item, with "" for the blank
+	// lines between them; blankBefore is set when a blank line comes before
+	// them
+	var pending []string
+	blankBefore := false
+
+	for {
+		p.spaces()
+		switch c := p.peek(0); {
+		case c == 0 || c == '}' && closing:
+			if c == 0 && closing {
+				p.fail("expected '}'")
+			}
+			if head != nil && len(body.Items) == 0 {
+				*head = trimBlankComments(pending)
+			} else {
+				body.Foot = trimBlankComments(pending)
+				body.blankBeforeFoot = blankBefore
+			}
+			return body
+		case p.atNewline():
+			p.endOfLine()
+			if len(pending) == 0 {
+				blankBefore = true
+			} else if pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+		case p.atComment():
+			pending = append(pending, p.comment())
+			p.endOfLine()
+		default:
+			item := p.item()
+			item.Comments = trimBlankComments(pending)
+			item.blankBefore = blankBefore
+			if head != nil && len(body.Items) == 0 {
+				for i := len(pending) - 1; i >= 0; i-- {
+					if pending[i] == "" {
+						*head = trimBlankComments(pending[:i])
+						item.Comments = trimBlankComments(pending[i+1:])
+						item.blankBefore = true
+						break
+					}
+				}
+			}
+			body.Items = append(body.Items, item)
+			pending, blankBefore = nil, false
+		}
+	}
+}
+
+// isHCLIdentifierByte reports whether c may appear in an identifier
+func isHCLIdentifierByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// identifier parses an identifier
+func (p *hclParser) identifier() string {
+	start := p.pos
+	if c := p.peek(0); !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_') {
+		p.fail("expected an attribute or block, found %q", c)
+	}
+	for isHCLIdentifierByte(p.peek(0)) {
+		p.advance(1)
+	}
+	return string(p.data[start:p.pos])
+}
+
+// item parses an attribute or a block, with the rest of its line
+func (p *hclParser) item() *HCLItem {
+	item := &HCLItem{Name: p.identifier()}
+	p.spaces()
+	if p.peek(0) == '=' && p.peek(1) != '=' {
+		p.advance(1)
+		p.spaces()
+		item.Value = p.expr()
+		item.LineComment = p.endOfLine()
+		return item
+	}
+
+	for {
+		switch c := p.peek(0); {
+		case c == '"':
+			item.Labels = append(item.Labels, p.str())
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_':
+			item.Labels = append(item.Labels, p.identifier())
+		case c == '{':
+			p.advance(1)
+			p.spaces()
+			p.blockBody(item)
+			return item
+		default:
+			p.fail("expected '=' or a block after %s", item.Name)
+		}
+		p.spaces()
+	}
+}
+
+// blockBody parses the body of a block after its opening brace, and the
+// rest of the line after the closing one
+func (p *hclParser) blockBody(item *HCLItem) {
+	switch {
+	case p.peek(0) == '}':
+		item.Body = &HCLBody{}
+		item.oneLine = true
+	case p.atNewline() || p.atComment():
+		item.LineComment = p.endOfLine()
+		item.Body = p.body(nil, true)
+	default:
+		// A block written on one line holds a single attribute
+		attribute := &HCLItem{Name: p.identifier()}
+		p.spaces()
+		if p.peek(0) != '=' {
+			p.fail("expected '=' after %s", attribute.Name)
+		}
+		p.advance(1)
+		p.spaces()
+		attribute.Value = p.expr()
+		p.spaces()
+		if p.peek(0) != '}' {
+			p.fail("expected '}' after the attribute of a one-line block")
+		}
+		item.Body = &HCLBody{Items: []*HCLItem{attribute}}
+		item.oneLine = true
+	}
+	p.advance(1)
+	item.CloseComment = p.endOfLine()
+}
+
+// str parses a quoted string, template sequences included, returning it as
+// written
+func (p *hclParser) str() string {
+	start := p.pos
+	p.advance(1)
+	for {
+		switch c := p.peek(0); {
+		case c == 0 || c == '\n':
+			p.fail("unterminated string")
+		case c == '\\':
+			p.advance(2)
+		case c == '"':
+			p.advance(1)
+			return string(p.data[start:p.pos])
+		case (c == '$' || c == '%') && p.peek(1) == c && p.peek(2) == '{':
+			// $${ and %%{ are literal
+			p.advance(3)
+		case (c == '$' || c == '%') && p.peek(1) == '{':
+			p.advance(2)
+			p.templateSequence()
+		default:
+			p.advance(1)
+		}
+	}
+}
+
+// templateSequence skips the inside of a ${ } or %{ } sequence and its
+// closing brace
+func (p *hclParser) templateSequence() {
+	for depth := 1; depth > 0; {
+		switch p.peek(0) {
+		case 0:
+			p.fail("unterminated template sequence")
+		case '"':
+			p.str()
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		p.advance(1)
+	}
+}
+
+// atExprEnd reports whether the current position ends an expression
+func (p *hclParser) atExprEnd() bool {
+	switch p.peek(0) {
+	case 0, ',', '}', ']', ')':
+		return true
+	}
+	return p.atNewline() || p.atComment()
+}
+
+// expr parses the value of an attribute: an object written over several
+// lines, or any other expression kept as written
+func (p *hclParser) expr() *HCLExpr {
+	if p.peek(0) == '{' {
+		if object := p.tryObject(); object != nil {
+			return object
+		}
+	}
+	return p.rawExpr()
+}
+
+// tryObject parses an object written over several lines, followed by the
+// end of the expression; it returns nil, with nothing read, for anything
+// else, such as a for expression or an object that is indexed
+func (p *hclParser) tryObject() (expr *HCLExpr) {
+	saved := *p
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*ParseError); !ok {
+				panic(r)
+			}
+			*p = saved
+			expr = nil
+		}
+	}()
+
+	p.advance(1)
+	p.spaces()
+	if !p.atNewline() && !p.atComment() {
+		*p = saved
+		return nil
+	}
+	expr = &HCLExpr{OpenComment: p.endOfLine(), Object: &HCLBody{}}
+	var pending []string
+	blankBefore := false
+	for {
+		p.spaces()
+		switch {
+		case p.peek(0) == '}':
+			expr.Object.Foot = trimBlankComments(pending)
+			expr.Object.blankBeforeFoot = blankBefore
+			p.advance(1)
+			p.spaces()
+			if !p.atExprEnd() {
+				p.fail("object followed by more of the expression")
+			}
+			return expr
+		case p.atNewline():
+			p.endOfLine()
+			if len(pending) == 0 {
+				blankBefore = true
+			} else if pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+		case p.atComment():
+			pending = append(pending, p.comment())
+			p.endOfLine()
+		default:
+			item := &HCLItem{Comments: trimBlankComments(pending), blankBefore: blankBefore, Name: p.objectKey()}
+			p.spaces()
+			switch {
+			case p.peek(0) == ':':
+				item.colon = true
+			case p.peek(0) != '=' || p.peek(1) == '=':
+				p.fail("expected '=' after object key %s", item.Name)
+			}
+			p.advance(1)
+			p.spaces()
+			item.Value = p.expr()
+			p.spaces()
+			if p.peek(0) == ',' {
+				item.comma = true
+				p.advance(1)
+			}
+			if p.peek(0) != '}' {
+				item.LineComment = p.endOfLine()
+			}
+			expr.Object.Items = append(expr.Object.Items, item)
+			pending, blankBefore = nil, false
+		}
+	}
+}
+
+// objectKey parses the key of an object item: a name, a quoted string or a
+// parenthesized expression
+func (p *hclParser) objectKey() string {
+	switch p.peek(0) {
+	case '"':
+		return p.str()
+	case '(':
+		start := p.pos
+		for depth := 0; ; {
+			switch p.peek(0) {
+			case 0, '\n':
+				p.fail("unterminated object key")
+			case '"':
+				p.str()
+				continue
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			p.advance(1)
+			if depth == 0 {
+				return string(p.data[start:p.pos])
+			}
+		}
+	}
+	start := p.pos
+	for c := p.peek(0); isHCLIdentifierByte(c) || c == '.'; c = p.peek(0) {
+		p.advance(1)
+	}
+	if p.pos == start {
+		p.fail("expected an object key, found %q", p.peek(0))
+	}
+	return string(p.data[start:p.pos])
+}
+
+// heredocStart matches the opening line of a heredoc
+var heredocStart = regexp.MustCompile(`^<<-?([A-Za-z_][A-Za-z0-9_-]*)\r?\n`)
+
+// rawExpr reads an expression up to the end of its line, or over several
+// lines while brackets are open, keeping it as written
+// The indentation of each continuation line is recorded as the number of
+// lines opening the brackets still open at its start, so that an argument
+// list and an object opened on the same line indent their content once; a
+// line starting with closing brackets is indented like the line opening
+// them.
+func (p *hclParser) rawExpr() *HCLExpr {
+	expr := &HCLExpr{}
+	start := p.pos
+
+	// open holds the line each open bracket was opened on; pending is the
+	// index in levels of the line whose level is decided by the first
+	// character on it that does not close a bracket, and closed the line
+	// opening the last bracket closed at its start
+	var open []int
+	pending, closed := -1, -1
+	level := func() int {
+		n := 0
+		for i, line := range open {
+			if (i == 0 || line != open[i-1]) && (closed < 0 || line < closed) {
+				n++
+			}
+		}
+		return n
+	}
+
+	for {
+		c := p.peek(0)
+		if pending >= 0 && c != ' ' && c != '\t' && c != ')' && c != ']' && c != '}' {
+			expr.levels[pending] = level()
+			pending, closed = -1, -1
+		}
+		switch {
+		case c == 0:
+			if len(open) > 0 {
+				p.fail("unclosed bracket in expression")
+			}
+		case p.atNewline():
+			if len(open) == 0 {
+				break
+			}
+			if c == '\r' {
+				p.advance(1)
+			}
+			p.advance(1)
+			expr.levels = append(expr.levels, 0)
+			pending = len(expr.levels) - 1
+			continue
+		case p.atComment():
+			if len(open) == 0 {
+				break
+			}
+			comment := p.comment()
+			for range strings.Count(comment, "\n") {
+				expr.levels = append(expr.levels, -1)
+			}
+			continue
+		case c == '(' || c == '[' || c == '{':
+			open = append(open, p.line)
+			p.advance(1)
+			continue
+		case c == ')' || c == ']' || c == '}' || c == ',':
+			if len(open) == 0 {
+				break
+			}
+			if c != ',' {
+				if pending >= 0 {
+					closed = open[len(open)-1]
+				}
+				open = open[:len(open)-1]
+			}
+			p.advance(1)
+			continue
+		case c == '"':
+			p.str()
+			continue
+		case c == '<' && p.peek(1) == '<':
+			p.heredoc(expr)
+			continue
+		default:
+			p.advance(1)
+			continue
+		}
+		break
+	}
+
+	raw := strings.TrimRight(string(p.data[start:p.pos]), " \t")
+	if raw == "" {
+		p.fail("expected an expression")
+	}
+	expr.Raw = raw
+	return expr
+}
+
+// heredoc reads a heredoc, whose lines are kept as they are
+func (p *hclParser) heredoc(expr *HCLExpr) {
+	match := heredocStart.FindSubmatch(p.data[p.pos:])
+	if match == nil {
+		p.advance(2)
+		return
+	}
+	marker := string(match[1])
+	p.advance(len(match[0]))
+	expr.levels = append(expr.levels, -1)
+	for {
+		if p.pos >= len(p.data) {
+			p.fail("unterminated heredoc, expected %s", marker)
+		}
+		end := bytes.IndexByte(p.data[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.data) - p.pos
+		}
+		line := strings.TrimRight(string(p.data[p.pos:p.pos+end]), "\r")
+		if strings.TrimSpace(line) == marker {
+			p.advance(len(line))
+			return
+		}
+		p.advance(end + 1)
+		expr.levels = append(expr.levels, -1)
+	}
+}
+
+// Print writes the file
+// Under BlankLinesSections blocks are separated from what comes before and
+// after them by a blank line, as are items with Separate set; the blank
+// lines the input had between attributes are kept. BlankLinesPreserve only
+// keeps the input's blank lines and BlankLinesNone writes none.
+func (f *HCLFile) Print(opts Options) []byte {
+	pr := &hclPrinter{opts: opts}
+	pr.comments(f.Head, "")
+	if len(f.Head) > 0 && opts.BlankLines != BlankLinesNone {
+		pr.blank()
+	}
+	pr.items(f.Body.Items, 0)
+	pr.foot(f.Body, 0)
+	return pr.buf.Bytes()
+}
+
+// hclPrinter writes an HCLFile
+type hclPrinter struct {
+	buf  bytes.Buffer
+	opts Options
+}
+
+// pad returns the indentation of level
+func (pr *hclPrinter) pad(level int) string {
+	return strings.Repeat(" ", level*pr.opts.Indent)
+}
+
+// blank writes a blank line, unless at the start of the file or after
+// another blank line or an opening brace
+func (pr *hclPrinter) blank() {
+	if pr.buf.Len() > 0 && !bytes.HasSuffix(pr.buf.Bytes(), []byte("\n\n")) && !bytes.HasSuffix(pr.buf.Bytes(), []byte("{\n")) {
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// separate reports whether a blank line goes between two items
+func (pr *hclPrinter) separate(previous, item *HCLItem) bool {
+	switch pr.opts.BlankLines {
+	case BlankLinesNone:
+		return false
+	case BlankLinesPreserve:
+		return item.blankBefore
+	}
+	return item.blankBefore || item.Separate || item.IsBlock() || previous.IsBlock()
+}
+
+// comments writes comments on lines of their own, prefixed by pad
+func (pr *hclPrinter) comments(comments []string, pad string) {
+	for _, comment := range comments {
+		if comment == "" {
+			if pr.opts.BlankLines != BlankLinesNone {
+				pr.blank()
+			}
+			continue
+		}
+		pr.buf.WriteString(pad + comment + "\n")
+	}
+}
+
+// multiline reports whether an expression is written over several lines
+func (e *HCLExpr) multiline() bool {
+	return e.Object != nil || strings.Contains(e.Raw, "\n")
+}
+
+// items writes attributes and blocks indented by level
+// The equals signs of consecutive attributes are aligned; a comment line, a
+// blank line, a block or a value over several lines ends the run. With
+// AlignComments the line comments of a run share a column too.
+func (pr *hclPrinter) items(items []*HCLItem, level int) {
+	pad := pr.pad(level)
+	startsRun := func(i int) bool {
+		return i == 0 || items[i].IsBlock() || items[i-1].IsBlock() || len(items[i].Comments) > 0 ||
+			pr.separate(items[i-1], items[i]) || items[i-1].Value.multiline()
+	}
+
+	// Render the start of each attribute line, up to its value
+	prefixes := make([]string, len(items))
+	for i := 0; i < len(items); {
+		end := i + 1
+		for end < len(items) && !startsRun(end) {
+			end++
+		}
+		width := 0
+		for _, item := range items[i:end] {
+			if !item.IsBlock() && !item.colon {
+				width = max(width, len(item.Name))
+			}
+		}
+		for j, item := range items[i:end] {
+			switch {
+			case item.IsBlock():
+			case item.colon:
+				prefixes[i+j] = pad + item.Name + ": "
+			default:
+				prefixes[i+j] = pad + item.Name + strings.Repeat(" ", width-len(item.Name)) + " = "
+			}
+		}
+		i = end
+	}
+	lineWidth := func(i int) int {
+		if items[i].IsBlock() || items[i].Value.multiline() || items[i].LineComment == "" {
+			return 0
+		}
+		width := len(prefixes[i]) + len(items[i].Value.Raw)
+		if items[i].comma {
+			width++
+		}
+		return width
+	}
+
+	commentColumn := 0
+	for i, item := range items {
+		if i > 0 && pr.separate(items[i-1], item) {
+			pr.blank()
+		}
+		pr.comments(item.Comments, pad)
+		if item.IsBlock() {
+			pr.block(item, level)
+			continue
+		}
+
+		if pr.opts.AlignComments && startsRun(i) {
+			commentColumn = 0
+			for j := i; j < len(items) && (j == i || !startsRun(j)); j++ {
+				commentColumn = max(commentColumn, lineWidth(j))
+			}
+		}
+		start := pr.buf.Len()
+		pr.buf.WriteString(prefixes[i])
+		pr.value(item.Value, level)
+		if item.comma {
+			pr.buf.WriteByte(',')
+		}
+		if item.LineComment != "" {
+			padding := 1
+			if pr.opts.AlignComments && !item.Value.multiline() {
+				padding = max(commentColumn-(pr.buf.Len()-start), 0) + 1
+			}
+			pr.buf.WriteString(strings.Repeat(" ", padding) + item.LineComment)
+		}
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// block writes a block indented by level
+// Blocks written on one line stay on one line while they hold at most one
+// attribute on one line without comments
+func (pr *hclPrinter) block(item *HCLItem, level int) {
+	pad := pr.pad(level)
+	header := pad + item.Name
+	for _, label := range item.Labels {
+		header += " " + label
+	}
+	closeComment := ""
+	if item.CloseComment != "" {
+		closeComment = " " + item.CloseComment
+	}
+
+	body := item.Body
+	switch {
+	case len(body.Items) == 0 && len(body.Foot) == 0 && item.LineComment == "":
+		pr.buf.WriteString(header + " {}" + closeComment + "\n")
+		return
+	case item.oneLine && len(body.Items) == 1 && len(body.Foot) == 0:
+		attribute := body.Items[0]
+		if !attribute.IsBlock() && !attribute.Value.multiline() && len(attribute.Comments) == 0 && attribute.LineComment == "" {
+			pr.buf.WriteString(header + " { " + attribute.Name + " = " + attribute.Value.Raw + " }" + closeComment + "\n")
+			return
+		}
+	}
+
+	pr.buf.WriteString(header + " {")
+	if item.LineComment != "" {
+		pr.buf.WriteString(" " + item.LineComment)
+	}
+	pr.buf.WriteByte('\n')
+	pr.items(body.Items, level+1)
+	pr.foot(body, level+1)
+	pr.buf.WriteString(pad + "}" + closeComment + "\n")
+}
+
+// foot writes the comments that end a body, indented by level
+func (pr *hclPrinter) foot(body *HCLBody, level int) {
+	if len(body.Foot) > 0 && len(body.Items) > 0 && body.blankBeforeFoot && pr.opts.BlankLines != BlankLinesNone {
+		pr.blank()
+	}
+	pr.comments(body.Foot, pr.pad(level))
+}
+
+// value writes an expression whose first line starts at level
+func (pr *hclPrinter) value(expr *HCLExpr, level int) {
+	if expr.Object != nil {
+		pr.buf.WriteByte('{')
+		if expr.OpenComment != "" {
+			pr.buf.WriteString(" " + expr.OpenComment)
+		}
+		pr.buf.WriteByte('\n')
+		pr.items(expr.Object.Items, level+1)
+		pr.foot(expr.Object, level+1)
+		pr.buf.WriteString(pr.pad(level) + "}")
+		return
+	}
+
+	lines := strings.Split(expr.Raw, "\n")
+	if len(lines)-1 != len(expr.levels) {
+		pr.buf.WriteString(expr.Raw)
+		return
+	}
+	pr.buf.WriteString(strings.TrimRight(lines[0], " \t\r"))
+	for i, line := range lines[1:] {
+		pr.buf.WriteByte('\n')
+		switch trimmed := strings.TrimSpace(line); {
+		case expr.levels[i] < 0:
+			pr.buf.WriteString(line)
+		case trimmed != "":
+			pr.buf.WriteString(pr.pad(level+expr.levels[i]) + trimmed)
+		}
+	}
+}
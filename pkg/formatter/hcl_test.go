@@ -0,0 +1,27 @@
+package formatter
+
+import "testing"
+
+func formatHCL(data []byte) ([]byte, error) {
+	return FormatHCL(data, DefaultOptions(), nil)
+}
+
+// TestFormatHCLGolden checks indentation, the alignment of equals signs,
+// comments, and expressions and heredocs kept as written
+func TestFormatHCLGolden(t *testing.T) {
+	testGolden(t, "hcl", formatHCL)
+}
+
+func TestFormatHCLMalformed(t *testing.T) {
+	testMalformed(t, "HCL", formatHCL, []string{
+		"resource \"a\" \"b\" {\n",
+		"a = \"unterminated\n",
+		"a = <<EOT\nno end\n",
+		"block {\n  a = [1, 2\n}\n",
+		"= 1\n",
+		"a = {\n  b = 1\n",
+		"}\n",
+		"resource \"a\" \"b\" x\n",
+		"/* open\n",
+	})
+}
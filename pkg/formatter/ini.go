@@ -0,0 +1,354 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// INISyntax describes how an INI-like file writes its key/value pairs
+type INISyntax struct {
+	// Separator is written between a key and its value, e.g. " = " or "=";
+	// " " is for configs of "key value" lines, which are split on the first
+	// space instead of the first "="
+	Separator string
+
+	// InlineComments lists the characters that start a comment after a
+	// value when preceded by whitespace; empty if comments are only allowed
+	// on lines of their own
+	InlineComments string
+
+	// AlignValues pads the keys of consecutive entries so their separators,
+	// and so their values, line up; blank lines and lines that are not
+	// entries or comments start a new run
+	AlignValues bool
+
+	// BareKeys reads a line holding a single word and no separator as a key
+	// without a value, such as MySQL's skip-name-resolve, rather than as
+	// another line; it is written back as a word on its own
+	BareKeys bool
+}
+
+// INILineKind is the kind of a line in an INI file
+type INILineKind int
+
+const (
+	// INIBlank is an empty line
+	INIBlank INILineKind = iota
+
+	// INIComment is a comment on a line of its own
+	INIComment
+
+	// INIEntry is a key/value pair
+	INIEntry
+
+	// INIOther is any other line, kept as written
+	INIOther
+)
+
+// INILine is a line of an INI file
+type INILine struct {
+	Kind INILineKind
+
+	// Text is a comment or a line that is not a key/value pair, as written
+	Text string
+
+	Key     string
+	Value   string
+	Comment string
+
+	// Bare is set for a key written without a separator, under
+	// INISyntax.BareKeys
+	Bare bool
+}
+
+// INISection is a [section] with the comments directly above its header
+type INISection struct {
+	Comments []string
+	Name     string
+	Lines    []INILine
+}
+
+// INIFile is a parsed INI file; Preamble holds the lines before the first
+// section
+type INIFile struct {
+	Preamble []INILine
+	Sections []*INISection
+}
+
+// iniSectionHeader matches a [section] line
+var iniSectionHeader = regexp.MustCompile(`^\[([^\]]+)\]$`)
+
+// iniBareKey matches a key written on its own under INISyntax.BareKeys
+var iniBareKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// IsINISectionHeader reports whether a trimmed line is a [section] header
+func IsINISectionHeader(line string) bool {
+	return iniSectionHeader.MatchString(line)
+}
+
+// FormatINI parses data, lets formatFile reorder it and prints it back
+// Spacing around the separator is normalized, sections are separated by one
+// blank line and runs of blank lines are collapsed
+func FormatINI(data []byte, opts Options, syntax INISyntax, formatFile func(*INIFile)) ([]byte, error) {
+	return FormatINIContext(context.Background(), data, opts, syntax, formatFile)
+}
+
+// FormatINIContext is FormatINI, abandoning the work once ctx is done
+func FormatINIContext(ctx context.Context, data []byte, opts Options, syntax INISyntax, formatFile func(*INIFile)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file := ParseINI(data, syntax)
+	if formatFile != nil {
+		formatFile(file)
+	}
+	return file.Print(syntax, opts.AlignComments), nil
+}
+
+// SortSections orders the sections by rank, then by name ignoring case
+// A nil rank sorts by name only
+func (f *INIFile) SortSections(rank func(name string) int) {
+	if rank == nil {
+		rank = func(string) int { return 0 }
+	}
+	sort.SliceStable(f.Sections, func(i, j int) bool {
+		a, b := f.Sections[i].Name, f.Sections[j].Name
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+}
+
+// SortKeys orders the entries of the section by rank; entries of the same
+// rank keep their order, since programs such as systemd read repeated keys
+// in order
+// Comments move with the entry below them, and blank lines and other lines
+// split the section into groups that are sorted on their own
+func (s *INISection) SortKeys(rank func(key string) int) {
+	sortINILines(s.Lines, rank)
+}
+
+// sortINILines sorts each group of lines between blank and other lines
+func sortINILines(lines []INILine, rank func(key string) int) {
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && lines[i].Kind != INIBlank && lines[i].Kind != INIOther {
+			continue
+		}
+		sortINIGroup(lines[start:i], rank)
+		start = i + 1
+	}
+}
+
+// sortINIGroup sorts a group of comment and entry lines in place; comments
+// after the last entry stay at the end
+func sortINIGroup(lines []INILine, rank func(key string) int) {
+	var units [][]INILine
+	unit := []INILine{}
+	for _, l := range lines {
+		unit = append(unit, l)
+		if l.Kind == INIEntry {
+			units = append(units, unit)
+			unit = []INILine{}
+		}
+	}
+	entryRank := func(u []INILine) int { return rank(u[len(u)-1].Key) }
+	sort.SliceStable(units, func(i, j int) bool {
+		return entryRank(units[i]) < entryRank(units[j])
+	})
+
+	sorted := make([]INILine, 0, len(lines))
+	for _, u := range units {
+		sorted = append(sorted, u...)
+	}
+	copy(lines, append(sorted, unit...))
+}
+
+// ParseINI reads an INI file; anything that is not a comment, a header or a
+// key/value pair is kept as written
+func ParseINI(data []byte, syntax INISyntax) *INIFile {
+	f := &INIFile{}
+	lines := &f.Preamble
+
+	for _, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "":
+			*lines = append(*lines, INILine{Kind: INIBlank})
+		case text[0] == ';' || text[0] == '#':
+			*lines = append(*lines, INILine{Kind: INIComment, Text: text})
+		case iniSectionHeader.MatchString(text):
+			s := &INISection{Name: iniSectionHeader.FindStringSubmatch(text)[1]}
+			// Comments touching the header belong to it
+			for len(*lines) > 0 && (*lines)[len(*lines)-1].Kind == INIComment {
+				s.Comments = append([]string{(*lines)[len(*lines)-1].Text}, s.Comments...)
+				*lines = (*lines)[:len(*lines)-1]
+			}
+			*lines = trimBlankLines(*lines)
+			f.Sections = append(f.Sections, s)
+			lines = &s.Lines
+		default:
+			*lines = append(*lines, parseINIEntry(text, syntax))
+		}
+	}
+
+	*lines = trimBlankLines(*lines)
+	return f
+}
+
+// parseINIEntry splits a key/value line, taking off an inline comment
+func parseINIEntry(text string, syntax INISyntax) INILine {
+	var key, value string
+	var ok bool
+	if strings.TrimSpace(syntax.Separator) == "" {
+		key, value, ok = strings.Cut(text, " ")
+		if k, v, tab := strings.Cut(text, "\t"); tab && (!ok || len(k) < len(key)) {
+			key, value, ok = k, v, true
+		}
+		if !ok {
+			// A key without a value
+			return INILine{Kind: INIEntry, Key: text}
+		}
+	} else if key, value, ok = strings.Cut(text, "="); !ok {
+		return parseINIBareKey(text, syntax)
+	}
+
+	entry := INILine{Kind: INIEntry, Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
+	if i := inlineComment(entry.Value, syntax.InlineComments); i >= 0 {
+		entry.Comment = entry.Value[i:]
+		entry.Value = strings.TrimSpace(entry.Value[:i])
+	}
+	return entry
+}
+
+// parseINIBareKey reads a line without a separator: under syntax.BareKeys a
+// key on its own, possibly followed by an inline comment, and otherwise a
+// line kept as written
+func parseINIBareKey(text string, syntax INISyntax) INILine {
+	key, comment := text, ""
+	if i := inlineComment(text, syntax.InlineComments); i >= 0 {
+		key, comment = strings.TrimSpace(text[:i]), text[i:]
+	}
+	if !syntax.BareKeys || !iniBareKey.MatchString(key) {
+		return INILine{Kind: INIOther, Text: text}
+	}
+	return INILine{Kind: INIEntry, Key: key, Comment: comment, Bare: true}
+}
+
+// inlineComment returns where a comment starts in value, or -1
+// A comment character only counts after whitespace and outside double quotes
+func inlineComment(value, chars string) int {
+	if chars == "" {
+		return -1
+	}
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.IndexByte(chars, value[i]) >= 0 && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t'):
+			return i
+		}
+	}
+	return -1
+}
+
+// trimBlankLines drops blank lines from the end of lines
+func trimBlankLines(lines []INILine) []INILine {
+	for len(lines) > 0 && lines[len(lines)-1].Kind == INIBlank {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Print writes the file: one blank line between sections, runs of blank lines
+// collapsed, and "key<separator>value" entries
+// With alignComments the inline comments of consecutive lines share a column
+func (f *INIFile) Print(syntax INISyntax, alignComments bool) []byte {
+	var buf bytes.Buffer
+	writeINILines(&buf, f.Preamble, syntax, alignComments)
+	for _, s := range f.Sections {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		for _, comment := range s.Comments {
+			buf.WriteString(comment + "\n")
+		}
+		buf.WriteString("[" + s.Name + "]\n")
+		writeINILines(&buf, s.Lines, syntax, alignComments)
+	}
+	return buf.Bytes()
+}
+
+// writeINILines writes the lines of a section
+func writeINILines(buf *bytes.Buffer, lines []INILine, syntax INISyntax, alignComments bool) {
+	// Leading blank lines are dropped, inner runs collapsed
+	start := 0
+	for start < len(lines) && lines[start].Kind == INIBlank {
+		start++
+	}
+	lines = lines[start:]
+
+	// Consecutive entries share the comment column, and with
+	// syntax.AlignValues the separator column of their run
+	width, keyWidth := 0, 0
+	entryText := func(l INILine) string {
+		if l.Value != "" && len(l.Key) < keyWidth {
+			l.Key += strings.Repeat(" ", keyWidth-len(l.Key))
+		}
+		return iniEntryText(l, syntax)
+	}
+	for i, l := range lines {
+		if syntax.AlignValues && (i == 0 || lines[i-1].Kind == INIBlank || lines[i-1].Kind == INIOther) {
+			keyWidth = 0
+			for j := i; j < len(lines) && (lines[j].Kind == INIEntry || lines[j].Kind == INIComment); j++ {
+				if lines[j].Kind == INIEntry && lines[j].Value != "" {
+					keyWidth = max(keyWidth, len(lines[j].Key))
+				}
+			}
+		}
+		switch l.Kind {
+		case INIBlank:
+			if lines[i-1].Kind != INIBlank {
+				buf.WriteByte('\n')
+			}
+			continue
+		case INIComment, INIOther:
+			buf.WriteString(l.Text + "\n")
+			continue
+		}
+
+		if alignComments && (i == 0 || lines[i-1].Kind != INIEntry) {
+			width = 0
+			for j := i; j < len(lines) && lines[j].Kind == INIEntry; j++ {
+				if lines[j].Comment != "" {
+					width = max(width, len(entryText(lines[j])))
+				}
+			}
+		}
+		text := entryText(l)
+		buf.WriteString(text)
+		if l.Comment != "" {
+			buf.WriteString(strings.Repeat(" ", max(width-len(text), 0)+1) + l.Comment)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// iniEntryText renders a key and its value
+func iniEntryText(l INILine, syntax INISyntax) string {
+	if l.Bare {
+		return l.Key
+	}
+	if l.Value == "" {
+		if strings.TrimSpace(syntax.Separator) == "" {
+			return l.Key
+		}
+		return l.Key + strings.TrimRight(syntax.Separator, " ")
+	}
+	return l.Key + syntax.Separator + l.Value
+}
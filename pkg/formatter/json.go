@@ -0,0 +1,621 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The JSON backend reads JSON with the extensions of JSONC and JSON5: // and
+// /* */ comments, trailing commas, single-quoted strings, unquoted member
+// names and JSON5 numbers. Comments are kept; member names and strings are
+// written with double quotes and trailing commas are dropped, so the output
+// is JSON (with comments) unless the input used JSON5-only numbers.
+
+// JSONKind is the kind of a JSON value
+type JSONKind int
+
+const (
+	// JSONScalar is a string, number, boolean or null
+	JSONScalar JSONKind = iota
+
+	// JSONObject is an {object}
+	JSONObject
+
+	// JSONArray is an [array]
+	JSONArray
+)
+
+// JSONNode is a JSON value with the comments around it
+type JSONNode struct {
+	Kind JSONKind
+
+	// Raw is the literal of a scalar as written (strings keep their quotes
+	// and escapes)
+	Raw string
+
+	// Entries are the members of an object or the items of an array
+	Entries []*JSONEntry
+
+	// Foot are the comments after the last entry, before the closing bracket
+	Foot []JSONComment
+
+	// Inline is set for an object or array written on one line without
+	// comments, which is printed on one line again
+	Inline bool
+}
+
+// JSONEntry is an object member or array item
+type JSONEntry struct {
+	// Key is the member name in double quotes; "" for array items
+	Key   string
+	Value *JSONNode
+
+	// Head are the comments on the lines above the entry
+	Head []JSONComment
+
+	// Line is the comment following the entry on the same line
+	Line string
+
+	// blankBefore records a blank line above the entry in the input
+	blankBefore bool
+}
+
+// Name returns the member name without its quotes
+func (e *JSONEntry) Name() string {
+	if name, err := strconv.Unquote(e.Key); err == nil {
+		return name
+	}
+	return strings.Trim(e.Key, `"`)
+}
+
+// HasComments reports whether comments are attached to the entry itself
+func (e *JSONEntry) HasComments() bool {
+	return len(e.Head) > 0 || e.Line != ""
+}
+
+// JSONComment is a comment on a line of its own
+type JSONComment struct {
+	Text string
+
+	blankBefore bool
+}
+
+// JSONDocument is a parsed JSON file: the top-level value and the comments
+// above and below it
+type JSONDocument struct {
+	Root *JSONNode
+	Head []JSONComment
+	Foot []JSONComment
+}
+
+// SortJSONEntries orders the members of an object by rank, then by name
+// Members carrying comments keep their place, so comments stay where the
+// author put them, and the others are sorted around them
+func SortJSONEntries(entries []*JSONEntry, rank func(name string) int) {
+	var sorted []*JSONEntry
+	for _, entry := range entries {
+		if !entry.HasComments() {
+			sorted = append(sorted, entry)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if rank(a.Name()) != rank(b.Name()) {
+			return rank(a.Name()) < rank(b.Name())
+		}
+		return a.Name() < b.Name()
+	})
+	for i, entry := range entries {
+		if !entry.HasComments() {
+			entries[i], sorted = sorted[0], sorted[1:]
+		}
+	}
+}
+
+// FormatJSON parses data, calls formatValue for every value from the root
+// down and prints the result with opts.Indent spaces per level
+// formatValue receives the key path of the value (array items are
+// identified by their index) and reorders object members or array items in
+// place; the paths in opts.KeepOrder are put back in their original order
+func FormatJSON(data []byte, opts Options, formatValue func(path []string, node *JSONNode)) ([]byte, error) {
+	return FormatJSONContext(context.Background(), data, opts, formatValue)
+}
+
+// FormatJSONContext is FormatJSON honoring ctx between the parse, format and
+// print stages
+func FormatJSONContext(ctx context.Context, data []byte, opts Options, formatValue func(path []string, node *JSONNode)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc, err := ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if formatValue != nil {
+		walkJSON(doc.Root, nil, opts.KeepOrder, formatValue)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return doc.Print(opts), nil
+}
+
+// walkJSON calls formatValue for node and its descendants
+func walkJSON(node *JSONNode, path []string, keepOrder []string, formatValue func([]string, *JSONNode)) {
+	kept := slices.ContainsFunc(keepOrder, func(pattern string) bool { return MatchKeyPath(pattern, path) })
+	original := slices.Clone(node.Entries)
+	formatValue(path, node)
+	if kept && len(original) == len(node.Entries) && !slices.ContainsFunc(node.Entries, func(entry *JSONEntry) bool {
+		return !slices.Contains(original, entry)
+	}) {
+		copy(node.Entries, original)
+	}
+
+	for i, entry := range node.Entries {
+		key := entry.Name()
+		if node.Kind == JSONArray {
+			key = strconv.Itoa(i)
+		}
+		walkJSON(entry.Value, append(path, key), keepOrder, formatValue)
+	}
+}
+
+// jsonParser reads JSON, tracking positions for error messages
+type jsonParser struct {
+	data []byte
+	pos  int
+	line int
+	col  int
+
+	// lastLine is the line the last token ended on, to tell trailing
+	// comments from comments on their own line
+	lastLine int
+}
+
+// ParseJSON parses a JSON, JSONC or JSON5 document
+// Syntax errors are returned as a *ParseError
+func ParseJSON(data []byte) (doc *JSONDocument, err error) {
+	p := &jsonParser{data: data, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			err = perr
+		}
+	}()
+
+	doc = &JSONDocument{}
+	doc.Head, _ = p.comments()
+	doc.Root = p.value()
+	trailing, line := p.comments()
+	if line != "" {
+		trailing = append([]JSONComment{{Text: line}}, trailing...)
+	}
+	if p.pos < len(p.data) {
+		p.fail("unexpected %q after the top-level value", p.data[p.pos])
+	}
+	doc.Foot = trailing
+	return doc, nil
+}
+
+// fail aborts parsing with a ParseError at the current position
+func (p *jsonParser) fail(format string, args ...any) {
+	panic(&ParseError{
+		Language: "JSON",
+		Line:     p.line,
+		Col:      p.col,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// advance moves past n bytes, keeping the line and column up to date
+func (p *jsonParser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.data); i++ {
+		if p.data[p.pos] == '\n' {
+			p.line++
+			p.col = 1
+		} else {
+			p.col++
+		}
+		p.pos++
+	}
+}
+
+// comments skips whitespace and returns the comments found
+// A comment starting on lastLine trails the previous token and is returned
+// as line; the others are on lines of their own
+func (p *jsonParser) comments() (own []JSONComment, line string) {
+	newlines := 0
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == '\n':
+			newlines++
+			p.advance(1)
+		case c == ' ' || c == '\t' || c == '\r':
+			p.advance(1)
+		case bytes.HasPrefix(p.data[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.data) - p.pos
+			}
+			text := strings.TrimRight(string(p.data[p.pos:p.pos+end]), " \t\r")
+			p.addComment(&own, &line, text, newlines)
+			p.advance(end)
+			newlines = 0
+		case bytes.HasPrefix(p.data[p.pos:], []byte("/*")):
+			end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				p.fail("unterminated comment")
+			}
+			text := string(p.data[p.pos : p.pos+end+4])
+			p.addComment(&own, &line, text, newlines)
+			p.advance(end + 4)
+			newlines = 0
+		default:
+			if newlines > 1 && len(own) == 0 && line == "" {
+				// Remember the blank line for the entry that follows
+				own = append(own, JSONComment{blankBefore: true})
+			}
+			return own, line
+		}
+	}
+	return own, line
+}
+
+// addComment files a comment as trailing or own-line
+func (p *jsonParser) addComment(own *[]JSONComment, line *string, text string, newlines int) {
+	if newlines == 0 && p.line == p.lastLine && len(*own) == 0 {
+		if *line != "" {
+			*line += " "
+		}
+		*line += text
+		return
+	}
+	*own = append(*own, JSONComment{Text: text, blankBefore: newlines > 1})
+}
+
+// value parses any JSON value
+func (p *jsonParser) value() *JSONNode {
+	if p.pos >= len(p.data) {
+		p.fail("unexpected end of input")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.container(JSONObject, '}')
+	case c == '[':
+		return p.container(JSONArray, ']')
+	case c == '"' || c == '\'':
+		return &JSONNode{Raw: p.str()}
+	default:
+		return &JSONNode{Raw: p.literal()}
+	}
+}
+
+// str parses a double- or single-quoted string, returning it in double
+// quotes with its escapes as written
+func (p *jsonParser) str() string {
+	start := p.pos
+	quote := p.data[p.pos]
+	for i := p.pos + 1; i < len(p.data); i++ {
+		switch p.data[i] {
+		case '\\':
+			i++
+		case '\n':
+			p.fail("newline in string")
+		case quote:
+			p.advance(i + 1 - start)
+			p.lastLine = p.line
+			raw := string(p.data[start:p.pos])
+			if quote == '\'' {
+				raw = doubleQuote(raw)
+			}
+			return raw
+		}
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+// doubleQuote rewrites a single-quoted JSON5 string in double quotes
+func doubleQuote(raw string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	content := raw[1 : len(raw)-1]
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\' && i+1 < len(content) && content[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '\\' && i+1 < len(content):
+			b.WriteString(content[i : i+2])
+			i++
+		case c == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// json5Number matches the numbers JSON5 adds to JSON: hexadecimal, leading
+// or trailing decimal points, a plus sign, Infinity and NaN
+var json5Number = regexp.MustCompile(`^[+-]?(Infinity|NaN|0[xX][0-9a-fA-F]+|([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?)$`)
+
+// literal parses a number, true, false or null
+func (p *jsonParser) literal() string {
+	start := p.pos
+	end := p.pos
+	for end < len(p.data) && strings.IndexByte("+-.0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", p.data[end]) >= 0 {
+		end++
+	}
+	if end == start {
+		p.fail("unexpected %q", p.data[p.pos])
+	}
+	raw := string(p.data[start:end])
+	if raw != "true" && raw != "false" && raw != "null" && !isJSONNumber(raw) && !json5Number.MatchString(raw) {
+		p.fail("invalid value %q", raw)
+	}
+	p.advance(end - start)
+	p.lastLine = p.line
+	return raw
+}
+
+// isJSONNumber reports whether s is a JSON number literal
+func isJSONNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// isIdentifierByte reports whether c may appear in an unquoted JSON5 member
+// name; digits are not allowed first
+func isIdentifierByte(c byte, first bool) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || !first && c >= '0' && c <= '9'
+}
+
+// memberName parses a quoted or unquoted member name, returning it in
+// double quotes
+func (p *jsonParser) memberName() string {
+	if c := p.data[p.pos]; c == '"' || c == '\'' {
+		return p.str()
+	}
+	if !isIdentifierByte(p.data[p.pos], true) {
+		p.fail("expected a member name")
+	}
+	end := p.pos
+	for end < len(p.data) && isIdentifierByte(p.data[end], end == p.pos) {
+		end++
+	}
+	name := string(p.data[p.pos:end])
+	p.advance(end - p.pos)
+	p.lastLine = p.line
+	return strconv.Quote(name)
+}
+
+// container parses an object or array; trailing commas are accepted
+func (p *jsonParser) container(kind JSONKind, closing byte) *JSONNode {
+	node := &JSONNode{Kind: kind}
+	startLine := p.line
+	p.advance(1)
+	p.lastLine = p.line
+
+	var pending []JSONComment
+	for {
+		own, line := p.comments()
+		if line != "" {
+			// A comment right after the opening bracket
+			pending = append(pending, JSONComment{Text: line})
+		}
+		pending = append(pending, own...)
+
+		if p.pos >= len(p.data) {
+			p.fail("unexpected end of input, expecting %q", closing)
+		}
+		if p.data[p.pos] == closing {
+			node.Foot = commentsOnly(pending)
+			node.Inline = p.line == startLine && !hasComments(node)
+			p.advance(1)
+			p.lastLine = p.line
+			return node
+		}
+
+		entry := &JSONEntry{}
+		if len(pending) > 0 && pending[0].blankBefore && pending[0].Text == "" {
+			entry.blankBefore = true
+		}
+		entry.Head = commentsOnly(pending)
+		pending = nil
+
+		if kind == JSONObject {
+			entry.Key = p.memberName()
+			own, _ := p.comments()
+			entry.Head = append(entry.Head, commentsOnly(own)...)
+			if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+				p.fail("expected ':' after member name")
+			}
+			p.advance(1)
+			own, _ = p.comments()
+			entry.Head = append(entry.Head, commentsOnly(own)...)
+		}
+		entry.Value = p.value()
+		node.Entries = append(node.Entries, entry)
+
+		// Comments on the rest of the line belong to the entry, whether
+		// they come before or after the comma
+		own, line = p.comments()
+		entry.Line = line
+		pending = own
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.advance(1)
+			p.lastLine = p.line
+			own, line = p.comments()
+			if line != "" && len(pending) == 0 {
+				entry.Line = strings.TrimSpace(entry.Line + " " + line)
+			} else if line != "" {
+				pending = append(pending, JSONComment{Text: line})
+			}
+			pending = append(pending, own...)
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] != closing {
+			p.fail("expected ',' or %q", closing)
+		}
+	}
+}
+
+// hasComments reports whether a container or its entries carry comments
+func hasComments(node *JSONNode) bool {
+	if len(node.Foot) > 0 {
+		return true
+	}
+	for _, entry := range node.Entries {
+		if entry.HasComments() || hasComments(entry.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentsOnly drops the blank line markers that carry no comment text
+func commentsOnly(comments []JSONComment) []JSONComment {
+	var out []JSONComment
+	for _, c := range comments {
+		if c.Text != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Print writes the document with opts.Indent spaces per level
+// Blank lines between entries are kept under BlankLinesPreserve; blank lines
+// between comment groups are always kept
+func (d *JSONDocument) Print(opts Options) []byte {
+	pr := &jsonPrinter{indent: opts.Indent, blankLines: opts.BlankLines == BlankLinesPreserve}
+	pr.comments(d.Head, 0)
+	pr.value(d.Root, 0)
+	pr.buf.WriteByte('\n')
+	pr.comments(d.Foot, 0)
+	return pr.buf.Bytes()
+}
+
+// jsonPrinter writes a JSONNode tree
+type jsonPrinter struct {
+	buf        bytes.Buffer
+	indent     int
+	blankLines bool
+}
+
+// pad writes the indentation for depth
+func (pr *jsonPrinter) pad(depth int) {
+	pr.buf.WriteString(strings.Repeat(" ", depth*pr.indent))
+}
+
+// comments writes own-line comments at depth; blank lines between comment
+// groups are kept
+func (pr *jsonPrinter) comments(comments []JSONComment, depth int) {
+	for i, c := range comments {
+		if c.blankBefore && i > 0 {
+			pr.buf.WriteByte('\n')
+		}
+		pr.pad(depth)
+		pr.buf.WriteString(c.Text)
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// value writes a value starting at the current position
+func (pr *jsonPrinter) value(node *JSONNode, depth int) {
+	if node.Kind == JSONScalar {
+		pr.buf.WriteString(node.Raw)
+		return
+	}
+
+	open, closing := "{", "}"
+	if node.Kind == JSONArray {
+		open, closing = "[", "]"
+	}
+	if len(node.Entries) == 0 && len(node.Foot) == 0 {
+		pr.buf.WriteString(open + closing)
+		return
+	}
+	if node.Inline {
+		pr.buf.WriteString(open)
+		for i, entry := range node.Entries {
+			if i > 0 {
+				pr.buf.WriteString(", ")
+			}
+			if entry.Key != "" {
+				pr.buf.WriteString(entry.Key + ": ")
+			}
+			pr.value(entry.Value, depth)
+		}
+		pr.buf.WriteString(closing)
+		return
+	}
+
+	pr.buf.WriteString(open + "\n")
+	for i, entry := range node.Entries {
+		if i > 0 && (entry.blankBefore && pr.blankLines || len(entry.Head) > 0 && entry.Head[0].blankBefore) {
+			pr.buf.WriteByte('\n')
+		}
+		pr.comments(entry.Head, depth+1)
+		pr.pad(depth + 1)
+		if entry.Key != "" {
+			pr.buf.WriteString(entry.Key + ": ")
+		}
+		pr.value(entry.Value, depth+1)
+		if i < len(node.Entries)-1 {
+			pr.buf.WriteByte(',')
+		}
+		if entry.Line != "" {
+			pr.buf.WriteString(" " + entry.Line)
+		}
+		pr.buf.WriteByte('\n')
+	}
+	if len(node.Foot) > 0 && len(node.Entries) > 0 && node.Foot[0].blankBefore {
+		pr.buf.WriteByte('\n')
+	}
+	pr.comments(node.Foot, depth+1)
+	pr.pad(depth)
+	pr.buf.WriteString(closing)
+}
@@ -0,0 +1,62 @@
+package formatter
+
+import "testing"
+
+func formatJSON(data []byte) ([]byte, error) {
+	return FormatJSON(data, DefaultOptions(), nil)
+}
+
+// TestFormatJSONGolden checks layout, comments, and the JSON5 syntax that
+// is rewritten as JSON
+func TestFormatJSONGolden(t *testing.T) {
+	testGolden(t, "json", formatJSON)
+}
+
+func TestFormatJSONMalformed(t *testing.T) {
+	testMalformed(t, "JSON", formatJSON, []string{
+		`{"a" 1}`,
+		`{"a": 1`,
+		`"unterminated`,
+		`{"a": tru}`,
+		`/* never closed`,
+		`{} {}`,
+		`[1 2]`,
+		``,
+		`{"a": 1,,}`,
+	})
+}
+
+// TestFormatJSONOrder checks that members are sorted by the formatValue
+// callback, commented members keep their place and the paths of
+// Options.KeepOrder are left as written
+func TestFormatJSONOrder(t *testing.T) {
+	input := `{
+  "scripts": {"test": "go test", "build": "go build"},
+  "version": "1.0.0",
+  // what it is
+  "name": "app",
+  "description": "an app"
+}
+`
+	want := `{
+  "description": "an app",
+  "scripts": {"test": "go test", "build": "go build"},
+  // what it is
+  "name": "app",
+  "version": "1.0.0"
+}
+`
+	opts := DefaultOptions()
+	opts.KeepOrder = []string{"scripts"}
+	got, err := FormatJSON([]byte(input), opts, func(path []string, node *JSONNode) {
+		if node.Kind == JSONObject {
+			SortJSONEntries(node.Entries, func(string) int { return 0 })
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatJSON() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package formatter
+
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keptOrder is the original order of the children of a node whose order is
+// kept: mapping keys by name, sequence items by identity
+type keptOrder struct {
+	node  *yaml.Node
+	keys  []string
+	items []*yaml.Node
+}
+
+// MatchKeyPath reports whether a dotted key path pattern matches path
+// "*" matches one key and "**" any number of keys; a pattern without a dot
+// matches that key at any depth, so "relabel_configs" matches every
+// relabel_configs list
+func MatchKeyPath(pattern string, path []string) bool {
+	segments := strings.Split(pattern, ".")
+	if len(segments) == 1 && segments[0] != "**" {
+		segments = []string{"**", segments[0]}
+	}
+	return matchSegments(segments, path)
+}
+
+func matchSegments(segments, path []string) bool {
+	if len(segments) == 0 {
+		return len(path) == 0
+	}
+	if segments[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(segments[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || segments[0] != "*" && segments[0] != path[0] {
+		return false
+	}
+	return matchSegments(segments[1:], path[1:])
+}
+
+// recordKeptOrder remembers the order of the children of every node under
+// root whose path matches one of patterns
+func recordKeptOrder(root *yaml.Node, patterns []string) []keptOrder {
+	var kept []keptOrder
+	Walk(root, func(path []string, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode {
+			return
+		}
+		if !slices.ContainsFunc(patterns, func(pattern string) bool { return MatchKeyPath(pattern, path) }) {
+			return
+		}
+
+		order := keptOrder{node: node}
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				order.keys = append(order.keys, node.Content[i].Value)
+			}
+		} else {
+			order.items = slices.Clone(node.Content)
+		}
+		kept = append(kept, order)
+	})
+	return kept
+}
+
+// restoreKeptOrder puts the children of the recorded nodes back in their
+// original order after a formatter sorted them
+// Keys added by the formatter go after the original ones; a sequence whose
+// items were replaced (by a normalizer rewriting the list) is left alone
+func restoreKeptOrder(kept []keptOrder) {
+	for _, order := range kept {
+		node := order.node
+		if node.Kind == yaml.MappingNode {
+			type pair struct{ key, value *yaml.Node }
+			var pairs []pair
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+			}
+			position := func(p pair) int {
+				if i := slices.Index(order.keys, p.key.Value); i >= 0 {
+					return i
+				}
+				return len(order.keys)
+			}
+			slices.SortStableFunc(pairs, func(a, b pair) int { return position(a) - position(b) })
+			node.Content = node.Content[:0]
+			for _, p := range pairs {
+				node.Content = append(node.Content, p.key, p.value)
+			}
+			continue
+		}
+
+		if node.Kind != yaml.SequenceNode || len(node.Content) != len(order.items) {
+			continue
+		}
+		restored := true
+		for _, item := range node.Content {
+			if !slices.Contains(order.items, item) {
+				restored = false
+				break
+			}
+		}
+		if restored {
+			copy(node.Content, order.items)
+		}
+	}
+}
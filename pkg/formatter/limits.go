@@ -0,0 +1,144 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits bound the YAML documents ParseDocuments accepts, so that crafted
+// input such as a "billion laughs" alias bomb cannot exhaust the memory or
+// time of a long-running process formatting untrusted files. A zero field
+// means no bound.
+type Limits struct {
+	// MaxDepth is the deepest nesting of mappings and sequences
+	MaxDepth int
+
+	// MaxNodes is the number of nodes of a document as written
+	MaxNodes int
+
+	// MaxExpandedNodes is the number of nodes of a document with every alias
+	// replaced by the node it refers to, as a semantic diff reads it
+	MaxExpandedNodes int
+}
+
+// DefaultLimits are the limits of ParseDocuments, far above what any real
+// config file needs
+var DefaultLimits = Limits{
+	MaxDepth:         1000,
+	MaxNodes:         1_000_000,
+	MaxExpandedNodes: 10_000_000,
+}
+
+// LimitError reports a document that exceeds one of its Limits
+type LimitError struct {
+	// Limit names the bound: "depth", "nodes" or "expanded nodes"
+	Limit string
+
+	// Max is the value of the bound
+	Max int
+
+	// Line is where the offending node starts (1-based), 0 if unknown
+	Line int
+}
+
+func (e *LimitError) Error() string {
+	var what string
+	switch e.Limit {
+	case "depth":
+		what = fmt.Sprintf("nesting deeper than %d levels", e.Max)
+	case "expanded nodes":
+		what = fmt.Sprintf("aliases expanding to more than %d nodes", e.Max)
+	default:
+		what = fmt.Sprintf("more than %d nodes", e.Max)
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("input exceeds resource limits: line %d: %s", e.Line, what)
+	}
+	return "input exceeds resource limits: " + what
+}
+
+// ParseDocumentsLimits is ParseDocuments with the given limits; a document
+// exceeding them is reported as a *LimitError
+func ParseDocumentsLimits(data []byte, limits Limits) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			if tabErr := TabError(data); tabErr != nil {
+				tabErr.Err = err
+				return nil, tabErr
+			}
+			return nil, newParseError(err)
+		}
+		if err := checkLimits(&doc, limits); err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+// checkLimits checks a parsed document against limits
+func checkLimits(doc *yaml.Node, limits Limits) error {
+	nodes := 0
+	var walk func(node *yaml.Node, depth int) error
+	walk = func(node *yaml.Node, depth int) error {
+		nodes++
+		if limits.MaxNodes > 0 && nodes > limits.MaxNodes {
+			return &LimitError{Limit: "nodes", Max: limits.MaxNodes, Line: node.Line}
+		}
+		if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			return &LimitError{Limit: "depth", Max: limits.MaxDepth, Line: node.Line}
+		}
+		for _, child := range node.Content {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// The document node itself is not a level of nesting
+	if err := walk(doc, -1); err != nil {
+		return err
+	}
+
+	if limits.MaxExpandedNodes > 0 && expandedSize(doc, limits.MaxExpandedNodes, make(map[*yaml.Node]int)) > limits.MaxExpandedNodes {
+		return &LimitError{Limit: "expanded nodes", Max: limits.MaxExpandedNodes}
+	}
+	return nil
+}
+
+// expandedSize counts the nodes of a tree with aliases expanded, stopping
+// past max; sizes holds the count of each node already seen, -1 while it is
+// being counted, so an alias to a node containing it counts as too large
+func expandedSize(node *yaml.Node, max int, sizes map[*yaml.Node]int) int {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return expandedSize(node.Alias, max, sizes)
+	}
+	if size, ok := sizes[node]; ok {
+		if size < 0 {
+			return max + 1
+		}
+		return size
+	}
+	sizes[node] = -1
+	size := 1
+	for _, child := range node.Content {
+		size += expandedSize(child, max, sizes)
+		if size > max {
+			size = max + 1
+			break
+		}
+	}
+	sizes[node] = size
+	return size
+}
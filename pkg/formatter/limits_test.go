@@ -0,0 +1,67 @@
+package formatter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// laughs returns a "billion laughs" document: levels anchors, each a list of
+// ten aliases to the one before, expanding to 10^levels nodes
+func laughs(levels int) string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 lol\n")
+	for i := 1; i <= levels; i++ {
+		fmt.Fprintf(&b, "l%d: &l%d [%s]\n", i, i, strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*l%d, ", i-1), 10), ", "))
+	}
+	return b.String()
+}
+
+func TestParseDocumentsLimits(t *testing.T) {
+	deep := strings.Repeat("[", 20) + strings.Repeat("]", 20) + "\n"
+	wide := "items:\n" + strings.Repeat("  - item\n", 50)
+
+	tests := []struct {
+		name   string
+		input  string
+		limits Limits
+		// limit is the bound exceeded, "" when the input is within the limits
+		limit string
+	}{
+		{"deep", deep, Limits{MaxDepth: 10}, "depth"},
+		{"deep within", deep, Limits{MaxDepth: 20}, ""},
+		{"wide", wide, Limits{MaxNodes: 40}, "nodes"},
+		{"wide within", wide, Limits{MaxNodes: 200}, ""},
+		{"alias bomb", laughs(4), Limits{MaxExpandedNodes: 5000}, "expanded nodes"},
+		{"alias bomb by default", laughs(9), DefaultLimits, "expanded nodes"},
+		{"anchors", `x-defaults: &defaults
+  restart: always
+  logging:
+    driver: json-file
+services:
+  web:
+    <<: *defaults
+    image: nginx
+  worker:
+    <<: *defaults
+    image: worker
+`, Limits{MaxDepth: 5, MaxNodes: 100, MaxExpandedNodes: 100}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDocumentsLimits([]byte(tt.input), tt.limits)
+			var limitErr *LimitError
+			switch {
+			case tt.limit == "" && err != nil:
+				t.Errorf("ParseDocumentsLimits() = %v, want no error", err)
+			case tt.limit == "":
+			case !errors.As(err, &limitErr):
+				t.Errorf("ParseDocumentsLimits() = %v, want a *LimitError", err)
+			case limitErr.Limit != tt.limit:
+				t.Errorf("exceeded %q (%v), want %q", limitErr.Limit, err, tt.limit)
+			}
+		})
+	}
+}
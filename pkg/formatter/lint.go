@@ -0,0 +1,213 @@
+package formatter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how much a lint issue matters
+type Severity string
+
+const (
+	// SeverityError fails the lint run; it is the severity of rules that
+	// do not set one
+	SeverityError Severity = "error"
+
+	// SeverityWarning is reported without failing the lint run
+	SeverityWarning Severity = "warning"
+
+	// SeverityOff turns a rule off
+	SeverityOff Severity = "off"
+)
+
+// Issue is a single problem reported by a lint rule
+type Issue struct {
+	// Rule is the ID of the rule that reported the issue
+	Rule string
+
+	// Severity is the severity of the rule, after Options.LintSeverity
+	Severity Severity
+
+	// File is the source file, set only by checks that span several files
+	File string
+
+	// Line and Column locate the offending node in the source (1-based)
+	Line   int
+	Column int
+
+	// Message describes the problem
+	Message string
+}
+
+// Rule is a named lint check that runs against a parsed YAML document
+type Rule struct {
+	// ID identifies the rule in reports, e.g. "traefik/basicauth-plaintext"
+	ID string
+
+	// Severity is the default severity of the rule's issues; the zero value
+	// means SeverityError
+	Severity Severity
+
+	// Check inspects the document and returns the issues it found
+	Check func(root *yaml.Node) []Issue
+}
+
+// Linter is implemented by formatters that can report lint issues
+type Linter interface {
+	// Lint returns the issues found in the given data
+	Lint(data []byte) ([]Issue, error)
+}
+
+// OptionsLinter is implemented by linters with rules that opts configure,
+// such as Options.BindMountAllowlist
+type OptionsLinter interface {
+	Linter
+
+	// LintOptions is Lint with the rules configured by opts
+	LintOptions(data []byte, opts Options) ([]Issue, error)
+}
+
+// PolicyChecker is implemented by formatters with policy rules that opts turn
+// on, such as Options.RequireResourceLimits: requirements a project holds its
+// files to, which a check enforces along with the formatting
+type PolicyChecker interface {
+	// CheckPolicy returns the issues of the policy rules configured by opts
+	CheckPolicy(data []byte, opts Options) ([]Issue, error)
+}
+
+// Lint returns the issues l finds in data, configured by opts when l is an
+// OptionsLinter
+// The severities of opts.LintSeverity replace those of the rules, and the
+// issues of rules turned off are dropped.
+func Lint(l Linter, data []byte, opts Options) ([]Issue, error) {
+	var issues []Issue
+	var err error
+	if ol, ok := l.(OptionsLinter); ok {
+		issues, err = ol.LintOptions(data, opts)
+	} else {
+		issues, err = l.Lint(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return applySeverities(issues, opts), nil
+}
+
+// CheckPolicy returns the issues of the policy rules of p in data, with the
+// severities of opts.LintSeverity as Lint applies them
+func CheckPolicy(p PolicyChecker, data []byte, opts Options) ([]Issue, error) {
+	issues, err := p.CheckPolicy(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return applySeverities(issues, opts), nil
+}
+
+// applySeverities sets the severity of each issue from opts.LintSeverity,
+// defaulting to SeverityError, and drops the issues of rules turned off
+func applySeverities(issues []Issue, opts Options) []Issue {
+	kept := issues[:0]
+	for _, issue := range issues {
+		if severity, ok := opts.LintSeverity[issue.Rule]; ok {
+			issue.Severity = severity
+		}
+		if issue.Severity == "" {
+			issue.Severity = SeverityError
+		}
+		if issue.Severity != SeverityOff {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// NewIssue creates an issue positioned at the given node
+func NewIssue(node *yaml.Node, format string, args ...any) Issue {
+	return Issue{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// LintYAML parses the YAML data and runs every rule against each document
+// Issues are returned ordered by their position in the source
+func (bf *BaseFormatter) LintYAML(data []byte, rules []Rule) ([]Issue, error) {
+	docs, err := ParseDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bf.OrderSensitive) > 0 {
+		rules = append(slices.Clip(rules), Rule{ID: "order/repeated-item", Check: bf.checkRepeatedItems})
+	}
+
+	var issues []Issue
+	for _, doc := range docs {
+		for _, rule := range rules {
+			for _, issue := range rule.Check(doc) {
+				issue.Rule = rule.ID
+				issue.Severity = rule.Severity
+				if issue.Severity == "" {
+					issue.Severity = SeverityError
+				}
+				issues = append(issues, issue)
+			}
+		}
+	}
+	// Trailing whitespace is a matter of the source rather than of a document
+	issues = append(issues, trailingWhitespace(data, docs)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+
+	return issues, nil
+}
+
+// IsOrderSensitive reports whether the children of the node at path are
+// order-sensitive, per the formatter's OrderSensitive paths
+func (bf *BaseFormatter) IsOrderSensitive(path []string) bool {
+	return slices.ContainsFunc(bf.OrderSensitive, func(pattern string) bool {
+		return MatchKeyPath(pattern, path)
+	})
+}
+
+// checkRepeatedItems flags an item of an order-sensitive list that is the
+// same as the item right before it: a middleware, relabeling rule or script
+// line applied twice in a row is almost always a copy-paste mistake
+func (bf *BaseFormatter) checkRepeatedItems(root *yaml.Node) []Issue {
+	var issues []Issue
+	Walk(root, func(path []string, node *yaml.Node) {
+		if node.Kind != yaml.SequenceNode || !bf.IsOrderSensitive(path) {
+			return
+		}
+		for i := 1; i < len(node.Content); i++ {
+			if sameNode(node.Content[i-1], node.Content[i]) {
+				issues = append(issues, NewIssue(node.Content[i], "item repeats the one before it in %s, whose order is significant", strings.Join(path, ".")))
+			}
+		}
+	})
+	return issues
+}
+
+// sameNode reports whether two nodes hold the same value, ignoring comments
+// and styles
+func sameNode(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !sameNode(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
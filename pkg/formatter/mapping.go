@@ -0,0 +1,179 @@
+package formatter
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The comments of a mapping are about different things, and go different
+// places when its entries are reordered:
+//
+//   - a comment directly above a key is about that entry, and moves with it
+//   - a trailing comment, on the line of a key or value, moves with its node
+//   - a section banner, split from the key below it by a blank line, or
+//     ending an entry before a blank line, titles the entries below it; it
+//     keeps its place and entries are not sorted across it
+//
+// yaml.v3 keeps the first two on the key and value nodes, so they travel with
+// them. Banners are attached to the first key below them, as the part of its
+// head comment before the last blank line, or to the entry above them, as its
+// foot comment; those are taken off before sorting and put back on the entries
+// that take the same places.
+
+// mappingGroup is a run of entries of a mapping between banners, which is
+// sorted on its own
+type mappingGroup struct {
+	// banner is the section banner above the group
+	banner string
+	// foot is the comment after the group: a banner above the next group, or
+	// the comment closing the mapping
+	foot string
+	// pairs are the keys and values of the entries, in order
+	pairs [][2]*yaml.Node
+}
+
+// splitHeadComment splits the head comment of a key into the section banner
+// above it, if any, and the comment about the key
+func splitHeadComment(head string) (banner, about string) {
+	if strings.HasSuffix(head, "\n") {
+		// The whole comment is separated from the key by a blank line
+		return strings.TrimRight(head, "\n"), ""
+	}
+	if i := strings.LastIndex(head, "\n\n"); i >= 0 {
+		return head[:i], head[i+2:]
+	}
+	return "", head
+}
+
+// joinHeadComment puts a section banner back above the comment about a key,
+// in the form yaml.v3 reads it in
+func joinHeadComment(banner, about string) string {
+	switch {
+	case banner == "":
+		return about
+	case about == "":
+		return banner + "\n"
+	}
+	return banner + "\n\n" + about
+}
+
+// mappingGroups splits the entries of a mapping at its section banners,
+// taking the banners off the keys
+func mappingGroups(node *yaml.Node) []*mappingGroup {
+	var groups []*mappingGroup
+	var group *mappingGroup
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		banner, about := splitHeadComment(key.HeadComment)
+		if group == nil || banner != "" {
+			group = &mappingGroup{banner: banner}
+			groups = append(groups, group)
+			key.HeadComment = about
+		}
+		group.pairs = append(group.pairs, [2]*yaml.Node{key, node.Content[i+1]})
+		if key.FootComment != "" {
+			group.foot, key.FootComment = key.FootComment, ""
+			group = nil
+		}
+	}
+	return groups
+}
+
+// groupStarts returns the first key of each group of entries of a mapping
+func groupStarts(node *yaml.Node) []*yaml.Node {
+	var starts []*yaml.Node
+	ended := true
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if banner, _ := splitHeadComment(key.HeadComment); ended || banner != "" {
+			starts = append(starts, key)
+		}
+		ended = key.FootComment != ""
+	}
+	return starts
+}
+
+// SortMapping orders the entries of a YAML mapping by compare, which is
+// given their keys; entries that compare equal keep their order
+// Comments about an entry move with it, and section banners keep their
+// places: the entries between two banners are sorted among themselves.
+func SortMapping(node *yaml.Node, compare func(a, b string) int) {
+	if node == nil || node.Kind != yaml.MappingNode || len(node.Content) < 4 {
+		return
+	}
+	groups := mappingGroups(node)
+	node.Content = node.Content[:0]
+	for _, group := range groups {
+		slices.SortStableFunc(group.pairs, func(a, b [2]*yaml.Node) int {
+			return compare(a[0].Value, b[0].Value)
+		})
+		first, last := group.pairs[0][0], group.pairs[len(group.pairs)-1][0]
+		first.HeadComment = joinHeadComment(group.banner, first.HeadComment)
+		last.FootComment = group.foot
+		for _, pair := range group.pairs {
+			node.Content = append(node.Content, pair[0], pair[1])
+		}
+	}
+}
+
+// SortMappingByRank orders the entries of a YAML mapping by rank, then by key,
+// moving comments and keeping section banners as SortMapping does
+func SortMappingByRank(node *yaml.Node, rank func(key string) int) {
+	SortMapping(node, func(a, b string) int {
+		return cmp.Or(cmp.Compare(rank(a), rank(b)), strings.Compare(a, b))
+	})
+}
+
+// markBanners adds the keys of the mappings of root that have a section
+// banner above them to separated, for the blank line between the banner and
+// the entry before it, and those whose banner is their whole head comment to
+// banners, for the blank line between the banner and the key. Without them
+// the banner would read as a comment about the key the next time.
+func markBanners(root *yaml.Node, separated, banners map[*yaml.Node]bool) {
+	Walk(root, func(_ []string, node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			banner, about := splitHeadComment(key.HeadComment)
+			if banner == "" {
+				continue
+			}
+			separated[key] = true
+			if about == "" {
+				banners[key] = true
+			}
+		}
+	})
+}
+
+// recordGroupStarts adds the groups of entries of each mapping of root to
+// starts, for followGroupStarts
+func recordGroupStarts(root *yaml.Node, starts map[*yaml.Node][]*yaml.Node) {
+	Walk(root, func(_ []string, node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			starts[node] = groupStarts(node)
+		}
+	})
+}
+
+// followGroupStarts moves the blank lines recorded above the first entry of a
+// group of a mapping, which are above its banner, to the entry that now starts
+// the group, so that sorting keeps them with the banner
+func followGroupStarts(starts map[*yaml.Node][]*yaml.Node, blankBefore map[*yaml.Node]bool) {
+	for node, before := range starts {
+		after := groupStarts(node)
+		if len(after) != len(before) {
+			continue
+		}
+		for i, key := range after {
+			if key != before[i] {
+				blankBefore[key], blankBefore[before[i]] = blankBefore[before[i]], blankBefore[key]
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sortAll sorts every mapping of a document by key
+func sortAll(node *yaml.Node, isRoot bool) {
+	Walk(node, func(_ []string, node *yaml.Node) {
+		SortMapping(node, strings.Compare)
+	})
+}
+
+// TestSortMappingComments checks where each kind of comment goes when a
+// mapping is sorted, and that the output reads back as the same comments,
+// so a second pass changes nothing, under every blank line policy
+func TestSortMappingComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		policy BlankLinePolicy
+		want   string
+	}{
+		{
+			name: "about comments move with their keys",
+			input: `routers:
+  zed: 1
+  # about beta
+  beta: 2
+  alpha: 3 # about alpha
+`,
+			want: `routers:
+  alpha: 3 # about alpha
+  # about beta
+  beta: 2
+  zed: 1
+`,
+		},
+		{
+			name: "banner between blank lines",
+			input: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  beta: 2
+  alpha: 3
+`,
+			want: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  alpha: 3
+  beta: 2
+`,
+		},
+		{
+			name: "banner kept without blank lines elsewhere",
+			input: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  beta: 2
+
+  alpha: 3
+`,
+			policy: BlankLinesNone,
+			want: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  alpha: 3
+  beta: 2
+`,
+		},
+		{
+			name: "banner kept with blank lines preserved",
+			input: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  beta: 2
+  alpha: 3
+`,
+			policy: BlankLinesPreserve,
+			want: `routers:
+  zed: 1
+
+  # ===== internal routers =====
+
+  alpha: 3
+  beta: 2
+`,
+		},
+		{
+			name: "banner above an about comment",
+			input: `services:
+  web:
+    restart: always
+    image: nginx
+
+    # --- storage section ---
+
+    # scratch space
+    tmpfs: /tmp
+    volumes: []
+`,
+			want: `services:
+  web:
+    image: nginx
+    restart: always
+
+    # --- storage section ---
+
+    # scratch space
+    tmpfs: /tmp
+    volumes: []
+`,
+		},
+		{
+			name: "banner on the first key",
+			input: `routers:
+  # ===== public routers =====
+
+  zed: 1
+  beta: 2
+`,
+			want: `routers:
+  # ===== public routers =====
+
+  beta: 2
+  zed: 1
+`,
+		},
+		{
+			name: "foot comment ends its group",
+			input: `routers:
+  zed: 1
+  beta: 2
+  # end of the public routers
+
+  alpha: 3
+  gamma: 4
+`,
+			want: `routers:
+  beta: 2
+  zed: 1
+  # end of the public routers
+
+  alpha: 3
+  gamma: 4
+`,
+		},
+	}
+
+	bf := &BaseFormatter{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.policy != "" {
+				opts.BlankLines = tt.policy
+			}
+			got, err := bf.FormatYAML([]byte(tt.input), opts, sortAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("first pass =\n%s\nwant\n%s", got, tt.want)
+			}
+			again, err := bf.FormatYAML(got, opts, sortAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("second pass =\n%s\nwant the first pass unchanged", again)
+			}
+		})
+	}
+}
+
+func TestSortMappingByRank(t *testing.T) {
+	var doc yaml.Node
+	input := `zed: 1
+# about beta
+beta: 2
+alpha: 3
+
+# Section
+
+name: 4
+image: 5
+`
+	if err := yaml.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatal(err)
+	}
+	rank := func(key string) int {
+		if key == "zed" || key == "name" {
+			return 0
+		}
+		return 1
+	}
+	node := doc.Content[0]
+	SortMappingByRank(node, rank)
+
+	var keys []string
+	for i := 0; i < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	if got, want := strings.Join(keys, " "), "zed alpha beta name image"; got != want {
+		t.Errorf("keys = %s, want %s", got, want)
+	}
+	if got := node.Content[4].HeadComment; got != "# about beta" {
+		t.Errorf("beta head comment = %q, want it moved with the key", got)
+	}
+	if got := node.Content[6].HeadComment; got != "# Section\n" {
+		t.Errorf("name head comment = %q, want the banner kept on the group start", got)
+	}
+}
@@ -0,0 +1,84 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// recordDocumentMarkers reports which documents of a stream start with an
+// explicit "---" and which end with "...", locating each document by the
+// line its content starts on
+func recordDocumentMarkers(data []byte, docs []*yaml.Node) (starts, ends []bool) {
+	firstLines := make([]int, len(docs))
+	for i, doc := range docs {
+		firstLines[i] = doc.Line
+		if len(doc.Content) > 0 {
+			firstLines[i] = doc.Content[0].Line
+		}
+	}
+
+	starts, ends = make([]bool, len(docs)), make([]bool, len(docs))
+	for i, line := range strings.Split(string(data), "\n") {
+		if !isDocumentMarker(line) {
+			continue
+		}
+		number := i + 1
+		for j := range docs {
+			previous, next := 0, int(^uint(0)>>1)
+			if j > 0 {
+				previous = firstLines[j-1]
+			}
+			if j+1 < len(docs) {
+				next = firstLines[j+1]
+			}
+			switch {
+			case strings.HasPrefix(line, "---") && number > previous && number <= firstLines[j]:
+				starts[j] = true
+			case strings.HasPrefix(line, "...") && number > firstLines[j] && number < next:
+				ends[j] = true
+			}
+		}
+	}
+	return starts, ends
+}
+
+// applyDocumentMarkers adds or removes the "---" line starting the first
+// document of output and the "..." lines ending documents as policy asks;
+// with DocumentMarkersPreserve, starts and ends tell which documents had them
+// The "---" lines between documents are always kept, as they separate them.
+func applyDocumentMarkers(output []byte, policy DocumentMarkerPolicy, starts, ends []bool) []byte {
+	if policy != DocumentMarkersAlways && policy != DocumentMarkersPreserve {
+		return output
+	}
+	want := func(marks []bool, i int) bool {
+		return policy == DocumentMarkersAlways || i < len(marks) && marks[i]
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	var b bytes.Buffer
+	doc := 0
+	if len(lines) > 0 && !strings.HasPrefix(lines[0], "---") && want(starts, 0) {
+		b.WriteString("---\n")
+	}
+	for i, line := range lines {
+		if isDocumentMarker(line) && strings.HasPrefix(line, "---") {
+			if i == 0 {
+				if line == "---" && !want(starts, 0) {
+					continue
+				}
+			} else {
+				if want(ends, doc) {
+					b.WriteString("...\n")
+				}
+				doc++
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	if want(ends, doc) {
+		b.WriteString("...\n")
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,169 @@
+package formatter
+
+import "gopkg.in/yaml.v3"
+
+// QuoteStyle selects the quotes normalizers use when a value needs quoting
+type QuoteStyle string
+
+const (
+	// QuoteDouble quotes values with double quotes (the default)
+	QuoteDouble QuoteStyle = "double"
+
+	// QuoteSingle quotes values with single quotes
+	QuoteSingle QuoteStyle = "single"
+)
+
+// NodeStyle returns the yaml.v3 scalar style for the quote style
+func (q QuoteStyle) NodeStyle() yaml.Style {
+	if q == QuoteSingle {
+		return yaml.SingleQuotedStyle
+	}
+	return yaml.DoubleQuotedStyle
+}
+
+// BlankLinePolicy selects where blank lines are written between entries
+type BlankLinePolicy string
+
+const (
+	// BlankLinesSections separates top-level sections (and compose services)
+	// with a blank line; this is the default
+	BlankLinesSections BlankLinePolicy = "sections"
+
+	// BlankLinesNone writes no blank lines, but those setting section
+	// banners apart from the entries around them
+	BlankLinesNone BlankLinePolicy = "none"
+
+	// BlankLinesPreserve keeps blank lines where the input had them and adds
+	// no others
+	BlankLinesPreserve BlankLinePolicy = "preserve"
+)
+
+// DocumentMarkerPolicy selects where the "---" and "..." markers starting
+// and ending YAML documents are written
+type DocumentMarkerPolicy string
+
+const (
+	// DocumentMarkersNever writes "---" only between documents, where it
+	// separates them, and no "..."; this is the default
+	DocumentMarkersNever DocumentMarkerPolicy = "never"
+
+	// DocumentMarkersAlways starts every document with "---" and ends it
+	// with "..."
+	DocumentMarkersAlways DocumentMarkerPolicy = "always"
+
+	// DocumentMarkersPreserve starts the first document with "---" and ends
+	// documents with "..." where the input did
+	DocumentMarkersPreserve DocumentMarkerPolicy = "preserve"
+)
+
+// BuildForm selects how compose service build sections are written
+type BuildForm string
+
+const (
+	// BuildFormKeep leaves build sections as written (the default)
+	BuildFormKeep BuildForm = "keep"
+
+	// BuildFormLong expands build: ./dir into a mapping with a context key
+	BuildFormLong BuildForm = "long"
+
+	// BuildFormShort writes a build mapping holding only a context as the
+	// context path
+	BuildFormShort BuildForm = "short"
+)
+
+// HealthcheckPolicy requires a healthcheck of the compose services whose
+// image matches Image, a path.Match pattern tried on the image with and
+// without its tag
+type HealthcheckPolicy struct {
+	Image string
+
+	// Healthcheck, when set, is the healthcheck mapping formatting inserts
+	// into services without one; otherwise they are only reported
+	Healthcheck *yaml.Node
+}
+
+// Options controls how a formatter rewrites a file
+type Options struct {
+	// Indent is the number of spaces per indentation level
+	Indent int
+
+	// CollapseSingleItemLists writes one-element lists as a plain value for
+	// fields that accept either form (e.g. compose label_file). When false,
+	// such fields are always written as lists.
+	CollapseSingleItemLists bool
+
+	// BuildForm selects the form of compose build sections; the zero value
+	// means BuildFormKeep
+	BuildForm BuildForm
+
+	// SortScrapeConfigs orders the Prometheus scrape_configs list by job_name
+	SortScrapeConfigs bool
+
+	// SortSections orders the sections of INI files and the tables of TOML files
+	// by name
+	SortSections bool
+
+	// QuoteStyle is used by normalizers that quote values (e.g. compose ports)
+	QuoteStyle QuoteStyle
+
+	// PreserveKeyOrder leaves mapping keys in their original order
+	PreserveKeyOrder bool
+
+	// KeepOrder lists key paths whose children are never reordered, such as
+	// "services.*.command" or "relabel_configs"; see MatchKeyPath
+	KeepOrder []string
+
+	// PreserveValues turns off value normalizers (environment lists, ports,
+	// durations, ...), leaving values as written
+	PreserveValues bool
+
+	// BlankLines selects where blank lines go; the zero value means
+	// BlankLinesSections
+	BlankLines BlankLinePolicy
+
+	// DocumentMarkers selects where YAML document markers go; the zero value
+	// means DocumentMarkersNever
+	DocumentMarkers DocumentMarkerPolicy
+
+	// ExpandTabs converts the tabs indenting lines of YAML input to spaces,
+	// a tab reaching the next multiple of Indent, before parsing; without it
+	// tab-indented input is a *ParseError pointing at the first tab
+	ExpandTabs bool
+
+	// AlignComments lines up the inline comments of consecutive lines in a
+	// block on a common column
+	AlignComments bool
+
+	// BindMountAllowlist lists host paths that lint rules accept as writable
+	// bind mounts, each with everything below it
+	BindMountAllowlist []string
+
+	// RequireResourceLimits is a policy rule requiring memory and CPU limits
+	// of every compose service, see PolicyChecker
+	RequireResourceLimits bool
+
+	// HealthcheckPolicy is a policy rule listing the compose images whose
+	// services need a healthcheck, see PolicyChecker; the first policy
+	// matching a service's image applies
+	HealthcheckPolicy []HealthcheckPolicy
+
+	// LintSeverity sets the severity of lint rules by ID, such as
+	// "compose/privileged": SeverityWarning
+	LintSeverity map[string]Severity
+
+	// OnStyleChange, when set, enables the style audit pass: it is called for
+	// every input scalar whose emitted style (plain, quoted, literal, folded)
+	// differs from the style it was written in
+	OnStyleChange func(StyleChange)
+}
+
+// DefaultOptions returns the options used when nothing else is configured
+func DefaultOptions() Options {
+	return Options{
+		Indent:          2,
+		QuoteStyle:      QuoteDouble,
+		BuildForm:       BuildFormKeep,
+		BlankLines:      BlankLinesSections,
+		DocumentMarkers: DocumentMarkersNever,
+	}
+}
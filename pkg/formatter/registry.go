@@ -0,0 +1,46 @@
+package formatter
+
+// Registry is an ordered set of formatters
+// Auto-detection tries them in order, so formatters with looser content checks
+// belong at the end
+type Registry []Formatter
+
+// Names returns the names of the formatters in order
+func (r Registry) Names() []string {
+	names := make([]string, 0, len(r))
+	for _, f := range r {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+// Lookup returns the formatter with the given name, or an *UnsupportedError
+func (r Registry) Lookup(name string) (Formatter, error) {
+	for _, f := range r {
+		if f.Name() == name {
+			return f, nil
+		}
+	}
+	return nil, &UnsupportedError{Type: name, Supported: r.Names()}
+}
+
+// Detect returns the first formatter that handles the file, or a *DetectError
+// filename is passed on as it is, so formatters can match on the directory
+// a file is in as well as on its base name
+func (r Registry) Detect(filename string, data []byte) (Formatter, error) {
+	for _, f := range r {
+		if f.CanHandle(filename, data) {
+			return f, nil
+		}
+	}
+	return nil, &DetectError{File: filename, Candidates: r.Names()}
+}
+
+// FormatFile formats data read from filename with f; a *ParseError names the file
+func FormatFile(f Formatter, filename string, data []byte, opts Options) ([]byte, error) {
+	formatted, err := f.Format(data, opts)
+	if parseErr, ok := err.(*ParseError); ok && parseErr.File == "" {
+		parseErr.File = filename
+	}
+	return formatted, err
+}
@@ -0,0 +1,205 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeKind is the kind of a semantic change
+type ChangeKind string
+
+const (
+	// ChangeAdded is a value only the second file has
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved is a value only the first file has
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeChanged is a value that differs between the files
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference between two documents at one key path
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Path locates the value, e.g. services.web.ports[0]
+	Path string `json:"path"`
+
+	// Old and New are the values in flow-style YAML; Old is empty for an
+	// added value and New for a removed one
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// SemanticDiff compares two YAML (or JSON) files by value: key order,
+// quoting, comments, anchors and layout are ignored, and merge keys ("<<") are
+// expanded. Mappings are compared key
+// by key and sequences item by item; a scalar changes when its value or its
+// type does ("80" and 80 differ). Multi-document files are compared document
+// by document, the path starting with the document number.
+func SemanticDiff(a, b []byte) ([]Change, error) {
+	docsA, err := ParseDocuments(a)
+	if err != nil {
+		return nil, err
+	}
+	docsB, err := ParseDocuments(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	multi := len(docsA) > 1 || len(docsB) > 1
+	for i := 0; i < max(len(docsA), len(docsB)); i++ {
+		var nodeA, nodeB *yaml.Node
+		if i < len(docsA) && len(docsA[i].Content) > 0 {
+			nodeA = docsA[i].Content[0]
+		}
+		if i < len(docsB) && len(docsB[i].Content) > 0 {
+			nodeB = docsB[i].Content[0]
+		}
+		docChanges := compareNodes(nil, "", nodeA, nodeB)
+		if multi {
+			for j := range docChanges {
+				docChanges[j].Path = strings.TrimSpace(fmt.Sprintf("[doc %d] %s", i+1, docChanges[j].Path))
+			}
+		}
+		changes = append(changes, docChanges...)
+	}
+	return changes, nil
+}
+
+// DiffNodes compares two nodes by value as SemanticDiff does, with paths
+// relative to them; a nil node is missing
+func DiffNodes(a, b *yaml.Node) []Change {
+	return compareNodes(nil, "", a, b)
+}
+
+// compareNodes appends the changes between two nodes at path
+func compareNodes(changes []Change, path string, a, b *yaml.Node) []Change {
+	a, b = resolveAlias(a), resolveAlias(b)
+	switch {
+	case a == nil && b == nil:
+		return changes
+	case a == nil:
+		return append(changes, Change{Kind: ChangeAdded, Path: path, New: flowValue(b)})
+	case b == nil:
+		return append(changes, Change{Kind: ChangeRemoved, Path: path, Old: flowValue(a)})
+	case a.Kind != b.Kind:
+		return append(changes, Change{Kind: ChangeChanged, Path: path, Old: flowValue(a), New: flowValue(b)})
+	}
+
+	switch a.Kind {
+	case yaml.MappingNode:
+		// Keys of a first, in its order, then the keys only b has
+		keysA, valuesA := mergedEntries(a)
+		keysB, valuesB := mergedEntries(b)
+		for _, key := range keysA {
+			changes = compareNodes(changes, joinKeyPath(path, key), valuesA[key], valuesB[key])
+		}
+		for _, key := range keysB {
+			if _, ok := valuesA[key]; !ok {
+				changes = compareNodes(changes, joinKeyPath(path, key), nil, valuesB[key])
+			}
+		}
+	case yaml.SequenceNode:
+		for i := 0; i < max(len(a.Content), len(b.Content)); i++ {
+			var itemA, itemB *yaml.Node
+			if i < len(a.Content) {
+				itemA = a.Content[i]
+			}
+			if i < len(b.Content) {
+				itemB = b.Content[i]
+			}
+			changes = compareNodes(changes, path+"["+strconv.Itoa(i)+"]", itemA, itemB)
+		}
+	default:
+		if a.Value != b.Value || a.ShortTag() != b.ShortTag() {
+			changes = append(changes, Change{Kind: ChangeChanged, Path: path, Old: flowValue(a), New: flowValue(b)})
+		}
+	}
+	return changes
+}
+
+// mergedEntries returns the keys of a mapping, in order, and their values,
+// with the mappings merged in by "<<" keys expanded; keys set in the mapping
+// itself win, as in a YAML loader
+func mergedEntries(node *yaml.Node) ([]string, map[string]*yaml.Node) {
+	var keys []string
+	values := make(map[string]*yaml.Node)
+	var merged []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == "<<" && key.ShortTag() == "!!merge" {
+			value = resolveAlias(value)
+			if value.Kind == yaml.SequenceNode {
+				merged = append(merged, value.Content...)
+			} else {
+				merged = append(merged, value)
+			}
+			continue
+		}
+		keys = append(keys, key.Value)
+		values[key.Value] = value
+	}
+	for _, source := range merged {
+		source = resolveAlias(source)
+		if source.Kind != yaml.MappingNode {
+			continue
+		}
+		sourceKeys, sourceValues := mergedEntries(source)
+		for _, key := range sourceKeys {
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+				values[key] = sourceValues[key]
+			}
+		}
+	}
+	return keys, values
+}
+
+// resolveAlias returns the node an alias points to
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// joinKeyPath appends a key to a dotted path; keys that would make the path
+// ambiguous are quoted in brackets
+func joinKeyPath(path, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[] ") {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// flowValue renders a node as one line of YAML, without comments or anchors
+func flowValue(node *yaml.Node) string {
+	data, err := yaml.Marshal(flowCopy(node))
+	if err != nil {
+		return node.Value
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// flowCopy copies a node in flow style, resolving aliases and dropping
+// comments and anchors
+func flowCopy(node *yaml.Node) *yaml.Node {
+	node = resolveAlias(node)
+	c := &yaml.Node{Kind: node.Kind, Tag: node.Tag, Value: node.Value, Style: node.Style &^ (yaml.LiteralStyle | yaml.FoldedStyle)}
+	if node.Kind != yaml.ScalarNode {
+		c.Style |= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		c.Content = append(c.Content, flowCopy(child))
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SequenceOptions controls how SortSequence orders and deduplicates a list
+type SequenceOptions struct {
+	// Field sorts a list of mappings by the scalar stored under this key,
+	// such as name or job_name
+	Field string
+
+	// Key returns the scalar an item sorts by, for keys Field cannot name;
+	// it takes precedence over Field. Without either, the items themselves
+	// must be scalars and sort by value.
+	Key func(item *yaml.Node) *yaml.Node
+
+	// Compare orders two sort keys; nil compares them as strings
+	Compare func(a, b string) int
+
+	// Unique drops the items whose sort key an earlier item already has;
+	// items with comments are kept
+	Unique bool
+
+	// MoveComments sorts lists with comments, which move with the item they
+	// are attached to; by default such lists keep their order
+	MoveComments bool
+}
+
+// SortSequence sorts the items of a YAML sequence by their sort key, keeping
+// the order of items with equal keys, and reports whether it did. A list is
+// left alone when it is not a sequence, when an item has no scalar sort key,
+// or when it has comments and opts.MoveComments is not set.
+//
+// Modules use it for lists that are sets, such as host names or linter names
+// (SequenceOptions{}), lists of mappings named by a key
+// (SequenceOptions{Field: "name"}), and lists keyed by something else, such as
+// the first element of each item (SequenceOptions{Key: ...}).
+func SortSequence(node *yaml.Node, opts SequenceOptions) bool {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return false
+	}
+	key := opts.Key
+	switch {
+	case key != nil:
+	case opts.Field != "":
+		key = func(item *yaml.Node) *yaml.Node { return MappingValue(item, opts.Field) }
+	default:
+		key = func(item *yaml.Node) *yaml.Node { return item }
+	}
+	compare := opts.Compare
+	if compare == nil {
+		compare = strings.Compare
+	}
+
+	keys := make(map[*yaml.Node]string, len(node.Content))
+	for _, item := range node.Content {
+		k := key(item)
+		if k == nil || k.Kind != yaml.ScalarNode {
+			return false
+		}
+		if !opts.MoveComments && itemHasComments(item) {
+			return false
+		}
+		keys[item] = k.Value
+	}
+
+	if opts.Unique {
+		seen := make(map[string]bool, len(node.Content))
+		kept := node.Content[:0:0]
+		for _, item := range node.Content {
+			if seen[keys[item]] && !itemHasComments(item) {
+				continue
+			}
+			seen[keys[item]] = true
+			kept = append(kept, item)
+		}
+		node.Content = kept
+	}
+
+	slices.SortStableFunc(node.Content, func(a, b *yaml.Node) int {
+		return compare(keys[a], keys[b])
+	})
+	return true
+}
+
+// itemHasComments reports whether a list item has comments of its own
+func itemHasComments(item *yaml.Node) bool {
+	return item.HeadComment != "" || item.LineComment != "" || item.FootComment != ""
+}
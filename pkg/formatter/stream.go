@@ -0,0 +1,150 @@
+package formatter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Copy formats the stream read from src with f and writes it to dst one
+// document at a time, so only the documents being formatted are held in
+// memory. YAML streams are split at "---" and "..." lines, as anchors cannot
+// reach across them; empty and comment-only documents stay with their
+// neighbors, so the output is the same as Format's. Formatters not built on
+// FormatYAML, such as JSON and TOML, read a single document and get the
+// whole stream. A *ParseError locates the error in the whole stream.
+func Copy(dst io.Writer, src io.Reader, f Formatter, opts Options) error {
+	if _, ok := f.(yamlFormatter); !ok {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return err
+		}
+		formatted, err := f.Format(data, opts)
+		if err != nil {
+			return err
+		}
+		_, err = dst.Write(formatted)
+		return err
+	}
+
+	reader := bufio.NewReader(src)
+
+	// group holds the documents not yet written, starting at line groupLine.
+	// boundary is the offset of the marker ending its last document, when
+	// that document holds more than comments; content tells whether the text
+	// after the last marker does.
+	var group bytes.Buffer
+	groupLine := 1
+	boundary := -1
+	content, ended := false, false
+	written := false
+
+	flush := func(n int) error {
+		formatted, err := f.Format(group.Bytes()[:n], opts)
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && parseErr.Line > 0 {
+			parseErr.Line += groupLine - 1
+		}
+		if err != nil {
+			return err
+		}
+		// The first document of a group may start with its own marker
+		if written && !bytes.HasPrefix(formatted, []byte("---")) {
+			if _, err := io.WriteString(dst, "---\n"); err != nil {
+				return err
+			}
+		}
+		written = true
+		if _, err := dst.Write(formatted); err != nil {
+			return err
+		}
+		groupLine += bytes.Count(group.Bytes()[:n], []byte("\n"))
+		rest := append([]byte(nil), group.Bytes()[n:]...)
+		group.Reset()
+		group.Write(rest)
+		return nil
+	}
+
+	// startContent writes out the documents before the boundary once the
+	// document right after it turns out to hold more than comments. Empty
+	// and comment-only documents are encoded depending on their neighbors,
+	// so they keep the documents around them in one group.
+	startContent := func() error {
+		content = true
+		if boundary >= 0 {
+			if err := flush(boundary); err != nil {
+				return err
+			}
+		}
+		boundary = -1
+		return nil
+	}
+
+	for {
+		text, readErr := reader.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		if text != "" {
+			trimmed := strings.TrimSpace(text)
+			if isDocumentMarker(text) {
+				switch {
+				case content && strings.HasPrefix(text, "..."):
+					// An end marker belongs to the document it ends
+					boundary = group.Len() + len(text)
+					ended = true
+				case content:
+					boundary = group.Len()
+				case ended && strings.HasPrefix(text, "---"):
+					// The start of the document after an end marker
+					ended = false
+				default:
+					boundary = -1
+				}
+				content = false
+				// A marker can be followed by a value, such as a block scalar
+				if after := strings.TrimSpace(trimmed[3:]); after != "" && !strings.HasPrefix(after, "#") {
+					if err := startContent(); err != nil {
+						return err
+					}
+				}
+			} else if trimmed != "" {
+				ended = false
+				if !strings.HasPrefix(trimmed, "#") && !content {
+					if err := startContent(); err != nil {
+						return err
+					}
+				}
+			}
+			group.WriteString(text)
+		}
+		if readErr != nil {
+			if group.Len() == 0 {
+				return nil
+			}
+			return flush(group.Len())
+		}
+	}
+}
+
+// yamlFormatter is implemented by the formatters embedding BaseFormatter
+type yamlFormatter interface {
+	FormatYAMLContext(ctx context.Context, data []byte, opts Options, formatNode func(*yaml.Node, bool)) ([]byte, error)
+}
+
+// isDocumentMarker reports whether a line starts or ends a document: "---" or
+// "..." at the start of the line, alone or followed by a space
+func isDocumentMarker(line string) bool {
+	line = strings.TrimRight(line, "\r\n")
+	for _, marker := range []string{"---", "..."} {
+		if line == marker || strings.HasPrefix(line, marker+" ") || strings.HasPrefix(line, marker+"\t") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package formatter
+
+import "bytes"
+
+// tabIndentation returns the line and column (1-based) of the first tab in
+// the indentation of a line of data, or 0, 0 when no line is indented with
+// tabs; blank lines do not count
+func tabIndentation(data []byte) (line, col int) {
+	for i, text := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue
+		}
+		indent := text[:len(text)-len(bytes.TrimLeft(text, " \t"))]
+		if tab := bytes.IndexByte(indent, '\t'); tab >= 0 {
+			return i + 1, tab + 1
+		}
+	}
+	return 0, 0
+}
+
+// TabError reports the first tab in the indentation of data, which YAML does
+// not allow and yaml.v3 reports in terms that rarely point at it, as a
+// *ParseError suggesting the fix; it returns nil when data has no tab-indented
+// line
+func TabError(data []byte) *ParseError {
+	line, col := tabIndentation(data)
+	if line == 0 {
+		return nil
+	}
+	return &ParseError{
+		Line:    line,
+		Col:     col,
+		Message: "found a tab character in the indentation",
+		Hint:    "YAML indents with spaces only: replace the tabs with spaces, or set expand_tabs (-expand-tabs) to convert them",
+	}
+}
+
+// ExpandTabs replaces the tabs in the indentation of the lines of data that
+// start with a tab by spaces, with tab stops every width columns
+// Lines starting with a space are left alone, as their tabs may be the
+// content of a block scalar.
+func ExpandTabs(data []byte, width int) []byte {
+	if width <= 0 || !bytes.Contains(data, []byte("\t")) {
+		return data
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, text := range lines {
+		if len(text) == 0 || text[0] != '\t' {
+			continue
+		}
+		rest := bytes.TrimLeft(text, " \t")
+		column := 0
+		for _, c := range text[:len(text)-len(rest)] {
+			if c == '\t' {
+				column += width - column%width
+			} else {
+				column++
+			}
+		}
+		lines[i] = append(bytes.Repeat([]byte(" "), column), rest...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
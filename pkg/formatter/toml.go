@@ -0,0 +1,868 @@
+package formatter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TOMLDocument is a parsed TOML file, keeping the comments and the values as
+// written so that formatting only changes layout and order
+type TOMLDocument struct {
+	// Head are the comments at the top of the file, separated from what
+	// follows by a blank line
+	Head []string
+
+	// Root holds the key/value pairs before the first table header; its
+	// Key is empty
+	Root *TOMLTable
+
+	// Tables are the [table] and [[array of tables]] sections in file order
+	Tables []*TOMLTable
+
+	// IndentTables indents each table by opts.Indent per level of nesting of
+	// its key, with its entries one level further, as containerd writes its
+	// config
+	IndentTables bool
+}
+
+// TOMLTable is a table header with its key/value pairs
+type TOMLTable struct {
+	// Comments are the comments on the lines above the header
+	Comments []string
+
+	// Key is the header's dotted key, one segment per element as written
+	// (quoted segments keep their quotes)
+	Key []string
+
+	// Array is set for an [[array of tables]] element
+	Array bool
+
+	// LineComment is the comment after the header on the same line
+	LineComment string
+
+	Entries []*TOMLEntry
+
+	// Foot are the comments after the last entry that are separated from the
+	// next header by a blank line, such as commented-out settings
+	Foot []string
+
+	blankBefore     bool
+	blankBeforeFoot bool
+}
+
+// TOMLEntry is a key/value pair, or an item of an array when Key is nil
+type TOMLEntry struct {
+	// Comments are the comments on the lines above the entry
+	Comments []string
+
+	// Key is the dotted key, one segment per element as written
+	Key []string
+
+	Value *TOMLValue
+
+	// LineComment is the comment after the value on the same line
+	LineComment string
+
+	blankBefore bool
+}
+
+// TOMLKind is the kind of a TOML value
+type TOMLKind int
+
+const (
+	// TOMLScalar is a string, number, boolean or date, kept as written
+	TOMLScalar TOMLKind = iota
+
+	// TOMLArray is an [array]
+	TOMLArray
+
+	// TOMLInlineTable is an { inline = "table" }
+	TOMLInlineTable
+)
+
+// TOMLValue is a TOML value
+type TOMLValue struct {
+	Kind TOMLKind
+
+	// Raw is a scalar exactly as written, including quotes and escapes
+	Raw string
+
+	// Entries are the items of an array or the pairs of an inline table
+	Entries []*TOMLEntry
+
+	// Foot are the comments after the last item of an array
+	Foot []string
+
+	// Multiline is set for an array written over several lines or holding
+	// comments, which is printed one item per line
+	Multiline bool
+}
+
+// Name returns the table's dotted key as written; "" for the root table
+func (t *TOMLTable) Name() string {
+	return strings.Join(t.Key, ".")
+}
+
+// Path returns the table's key segments without their quotes
+func (t *TOMLTable) Path() []string {
+	return unquoteTOMLKey(t.Key)
+}
+
+// Name returns the entry's dotted key as written
+func (e *TOMLEntry) Name() string {
+	return strings.Join(e.Key, ".")
+}
+
+// Path returns the entry's key segments without their quotes
+func (e *TOMLEntry) Path() []string {
+	return unquoteTOMLKey(e.Key)
+}
+
+// Get returns the entry of the table with the given dotted key, or nil
+func (t *TOMLTable) Get(key string) *TOMLEntry {
+	for _, entry := range t.Entries {
+		if strings.Join(entry.Path(), ".") == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+// unquoteTOMLKey takes the quotes off quoted key segments
+func unquoteTOMLKey(key []string) []string {
+	path := make([]string, len(key))
+	for i, segment := range key {
+		switch {
+		case strings.HasPrefix(segment, `"`):
+			if unquoted, err := strconv.Unquote(segment); err == nil {
+				segment = unquoted
+			} else {
+				segment = strings.Trim(segment, `"`)
+			}
+		case strings.HasPrefix(segment, "'"):
+			segment = strings.Trim(segment, "'")
+		}
+		path[i] = segment
+	}
+	return path
+}
+
+// SortTOMLEntries orders entries by rank; entries of the same rank keep their
+// order, and comments move with the entry below them
+func SortTOMLEntries(entries []*TOMLEntry, rank func(key string) int) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return rank(entries[i].Name()) < rank(entries[j].Name())
+	})
+}
+
+// SortTables orders the tables by key
+func (d *TOMLDocument) SortTables() {
+	d.SortTablesFunc(compareKeys)
+}
+
+// SortTablesFunc orders the tables by key with compare, which returns a
+// negative number when key path a sorts before b
+// An [[array of tables]] element keeps its place among the elements of the
+// same array, and the sub-tables following an element stay with it, since
+// they belong to that element
+func (d *TOMLDocument) SortTablesFunc(compare func(a, b []string) int) {
+	var units [][]*TOMLTable
+	var arrays [][]string
+	for _, table := range d.Tables {
+		path := table.Path()
+		attached := false
+		for _, array := range arrays {
+			if len(path) > len(array) && slicesHavePrefix(path, array) {
+				attached = true
+				break
+			}
+		}
+		if attached && len(units) > 0 {
+			units[len(units)-1] = append(units[len(units)-1], table)
+		} else {
+			units = append(units, []*TOMLTable{table})
+			arrays = nil
+		}
+		if table.Array {
+			arrays = append(arrays, path)
+		}
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		return compare(units[i][0].Path(), units[j][0].Path()) < 0
+	})
+
+	d.Tables = d.Tables[:0]
+	for _, unit := range units {
+		d.Tables = append(d.Tables, unit...)
+	}
+}
+
+// slicesHavePrefix reports whether path starts with prefix
+func slicesHavePrefix(path, prefix []string) bool {
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// compareKeys compares key paths segment by segment; a parent sorts before
+// its children
+func compareKeys(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// FormatTOML parses data, lets formatDocument reorder it and prints it back
+// Keys are written at the start of the line, unless formatDocument sets
+// IndentTables, and multi-line arrays are indented by opts.Indent; values are
+// kept as written
+func FormatTOML(data []byte, opts Options, formatDocument func(*TOMLDocument)) ([]byte, error) {
+	return FormatTOMLContext(context.Background(), data, opts, formatDocument)
+}
+
+// FormatTOMLContext is FormatTOML honoring ctx between the parse, format and
+// print stages
+func FormatTOMLContext(ctx context.Context, data []byte, opts Options, formatDocument func(*TOMLDocument)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	doc, err := ParseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if formatDocument != nil {
+		formatDocument(doc)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return doc.Print(opts), nil
+}
+
+// tomlParser reads TOML, tracking positions for error messages
+type tomlParser struct {
+	data []byte
+	pos  int
+	line int
+	col  int
+}
+
+// ParseTOML parses a TOML document
+// Syntax errors are returned as a *ParseError
+func ParseTOML(data []byte) (doc *TOMLDocument, err error) {
+	p := &tomlParser{data: data, line: 1, col: 1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			err = perr
+		}
+	}()
+	return p.document(), nil
+}
+
+// fail aborts parsing with a ParseError at the current position
+func (p *tomlParser) fail(format string, args ...any) {
+	panic(&ParseError{
+		Language: "TOML",
+		Line:     p.line,
+		Col:      p.col,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// advance moves past n bytes, keeping the line and column up to date
+func (p *tomlParser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.data); i++ {
+		if p.data[p.pos] == '\n' {
+			p.line++
+			p.col = 1
+		} else {
+			p.col++
+		}
+		p.pos++
+	}
+}
+
+// peek returns the current byte, 0 at the end of the input
+func (p *tomlParser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// spaces skips spaces and tabs
+func (p *tomlParser) spaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.advance(1)
+	}
+}
+
+// comment returns the comment starting at the current position, if any
+func (p *tomlParser) comment() string {
+	if p.peek() != '#' {
+		return ""
+	}
+	end := bytes.IndexByte(p.data[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	text := strings.TrimRight(string(p.data[p.pos:p.pos+end]), " \t\r")
+	p.advance(end)
+	return text
+}
+
+// endOfLine reads the optional comment closing a line and the newline
+func (p *tomlParser) endOfLine() string {
+	p.spaces()
+	comment := p.comment()
+	if p.peek() == '\r' {
+		p.advance(1)
+	}
+	switch p.peek() {
+	case '\n':
+		p.advance(1)
+	case 0:
+	default:
+		p.fail("expected the end of the line, found %q", p.peek())
+	}
+	return comment
+}
+
+// document parses the whole file
+// Comments go with the header or entry below them. At the top of the file a
+// group separated by a blank line is the head; before a header, the groups
+// separated from it by a blank line end the previous table
+func (p *tomlParser) document() *TOMLDocument {
+	doc := &TOMLDocument{Root: &TOMLTable{}}
+	current := doc.Root
+
+	// pending are the comments since the last entry or header, with "" for
+	// the blank lines between them; blankBefore is set when a blank line
+	// comes before them
+	var pending []string
+	blankBefore, started := false, false
+
+	// split separates the comment groups ending with a blank line from the
+	// group touching what follows
+	split := func() (before, touching []string) {
+		for i := len(pending) - 1; i >= 0; i-- {
+			if pending[i] == "" {
+				return trimBlankComments(pending[:i]), pending[i+1:]
+			}
+		}
+		return nil, pending
+	}
+
+	for {
+		p.spaces()
+		switch c := p.peek(); {
+		case c == 0:
+			if !started {
+				doc.Head = trimBlankComments(pending)
+			} else if foot := trimBlankComments(pending); len(foot) > 0 {
+				current.Foot = foot
+				current.blankBeforeFoot = blankBefore
+			}
+			return doc
+		case c == '\n' || c == '\r':
+			p.endOfLine()
+			if len(pending) == 0 {
+				blankBefore = true
+			} else if pending[len(pending)-1] != "" {
+				pending = append(pending, "")
+			}
+		case c == '#':
+			pending = append(pending, p.comment())
+			p.endOfLine()
+		case c == '[':
+			before, touching := split()
+			table := p.header()
+			switch {
+			case !started:
+				doc.Head = before
+			case len(before) > 0:
+				current.Foot = before
+				current.blankBeforeFoot = blankBefore
+			}
+			table.Comments = touching
+			table.blankBefore = blankBefore || len(before) > 0
+			table.LineComment = p.endOfLine()
+			doc.Tables = append(doc.Tables, table)
+			current = table
+			pending, blankBefore, started = nil, false, true
+		default:
+			entry := &TOMLEntry{Comments: pending, blankBefore: blankBefore}
+			if !started {
+				var touching []string
+				doc.Head, touching = split()
+				entry.Comments = touching
+				entry.blankBefore = blankBefore || len(doc.Head) > 0
+			}
+			entry.Key = p.key()
+			p.spaces()
+			if p.peek() != '=' {
+				p.fail("expected '=' after key %s", strings.Join(entry.Key, "."))
+			}
+			p.advance(1)
+			p.spaces()
+			entry.Value = p.value()
+			entry.LineComment = p.endOfLine()
+			current.Entries = append(current.Entries, entry)
+			pending, blankBefore, started = nil, false, true
+		}
+	}
+}
+
+// trimBlankComments drops the blank line markers at the ends of comments
+func trimBlankComments(comments []string) []string {
+	for len(comments) > 0 && comments[0] == "" {
+		comments = comments[1:]
+	}
+	for len(comments) > 0 && comments[len(comments)-1] == "" {
+		comments = comments[:len(comments)-1]
+	}
+	return comments
+}
+
+// header parses a [table] or [[array of tables]] header
+func (p *tomlParser) header() *TOMLTable {
+	table := &TOMLTable{}
+	p.advance(1)
+	if p.peek() == '[' {
+		table.Array = true
+		p.advance(1)
+	}
+	p.spaces()
+	table.Key = p.key()
+	p.spaces()
+	closing := "]"
+	if table.Array {
+		closing = "]]"
+	}
+	if !bytes.HasPrefix(p.data[p.pos:], []byte(closing)) {
+		p.fail("expected %q after table name", closing)
+	}
+	p.advance(len(closing))
+	return table
+}
+
+// key parses a dotted key, returning its segments as written
+func (p *tomlParser) key() []string {
+	var segments []string
+	for {
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			raw := p.str()
+			if strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, "'''") {
+				p.fail("multi-line strings cannot be keys")
+			}
+			segments = append(segments, raw)
+		case isBareKeyByte(c):
+			start := p.pos
+			for isBareKeyByte(p.peek()) {
+				p.advance(1)
+			}
+			segments = append(segments, string(p.data[start:p.pos]))
+		default:
+			p.fail("expected a key, found %q", c)
+		}
+		p.spaces()
+		if p.peek() != '.' {
+			return segments
+		}
+		p.advance(1)
+		p.spaces()
+	}
+}
+
+// isBareKeyByte reports whether c may appear in an unquoted key
+func isBareKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses any TOML value
+func (p *tomlParser) value() *TOMLValue {
+	switch c := p.peek(); c {
+	case 0, '\n', '\r', '#':
+		p.fail("expected a value")
+	case '"', '\'':
+		return &TOMLValue{Raw: p.str()}
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+	return &TOMLValue{Raw: p.scalar()}
+}
+
+// str parses a basic, literal or multi-line string, returning it as written
+func (p *tomlParser) str() string {
+	start := p.pos
+	quote := p.data[p.pos]
+	delimiter := string([]byte{quote, quote, quote})
+	escapes := quote == '"'
+
+	if bytes.HasPrefix(p.data[p.pos:], []byte(delimiter)) {
+		for i := p.pos + 3; i < len(p.data); i++ {
+			switch {
+			case escapes && p.data[i] == '\\':
+				i++
+			case bytes.HasPrefix(p.data[i:], []byte(delimiter)):
+				// Up to two quotes right before the delimiter are content
+				end := i + 3
+				for n := 0; n < 2 && end < len(p.data) && p.data[end] == quote; n++ {
+					end++
+				}
+				p.advance(end - start)
+				return string(p.data[start:p.pos])
+			}
+		}
+		p.fail("unterminated multi-line string")
+	}
+
+	for i := p.pos + 1; i < len(p.data); i++ {
+		switch p.data[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case '\n':
+			p.fail("newline in string")
+		case quote:
+			p.advance(i + 1 - start)
+			return string(p.data[start:p.pos])
+		}
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+// tomlScalar matches the values that are not strings: booleans, numbers and
+// dates and times
+var tomlScalar = regexp.MustCompile(`^(true|false|[+-]?(inf|nan)|[+-]?[0-9][0-9A-Za-z_.:+-]*( [0-9][0-9A-Za-z_.:+-]*)?)$`)
+
+// tomlDate matches the date part of a date-time written with a space instead
+// of a T
+var tomlDate = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+
+// scalar parses a boolean, number or date, returning it as written
+func (p *tomlParser) scalar() string {
+	end := p.pos
+	for end < len(p.data) && strings.IndexByte(" \t\r\n,]}#", p.data[end]) < 0 {
+		end++
+	}
+	// "1979-05-27 07:32:00Z" is one value
+	if tomlDate.Match(p.data[p.pos:end]) && end+1 < len(p.data) && p.data[end] == ' ' && p.data[end+1] >= '0' && p.data[end+1] <= '9' {
+		end++
+		for end < len(p.data) && strings.IndexByte(" \t\r\n,]}#", p.data[end]) < 0 {
+			end++
+		}
+	}
+	raw := string(p.data[p.pos:end])
+	if !tomlScalar.MatchString(raw) {
+		p.fail("invalid value %q (strings must be quoted)", raw)
+	}
+	p.advance(end - p.pos)
+	return raw
+}
+
+// arrayComments skips whitespace and newlines inside an array, collecting
+// the comments; line is the comment on the line the previous item ended on
+func (p *tomlParser) arrayComments(value *TOMLValue) (own []string, line string) {
+	newline := false
+	for {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.advance(1)
+		case c == '\n':
+			newline = true
+			value.Multiline = true
+			p.advance(1)
+		case c == '#':
+			value.Multiline = true
+			if !newline && len(own) == 0 && line == "" {
+				line = p.comment()
+			} else {
+				own = append(own, p.comment())
+			}
+		default:
+			return own, line
+		}
+	}
+}
+
+// array parses an array; comments and a trailing comma are allowed
+func (p *tomlParser) array() *TOMLValue {
+	value := &TOMLValue{Kind: TOMLArray}
+	p.advance(1)
+
+	var pending []string
+	for {
+		own, line := p.arrayComments(value)
+		if line != "" {
+			pending = append(pending, line)
+		}
+		pending = append(pending, own...)
+
+		switch p.peek() {
+		case 0:
+			p.fail("unterminated array")
+		case ']':
+			value.Foot = pending
+			p.advance(1)
+			return value
+		}
+
+		item := &TOMLEntry{Comments: pending}
+		item.Value = p.value()
+		value.Entries = append(value.Entries, item)
+
+		own, line = p.arrayComments(value)
+		item.LineComment = line
+		pending = own
+		if p.peek() == ',' {
+			p.advance(1)
+			own, line = p.arrayComments(value)
+			if line != "" && len(pending) == 0 && item.LineComment == "" {
+				item.LineComment = line
+			} else if line != "" {
+				pending = append(pending, line)
+			}
+			pending = append(pending, own...)
+			continue
+		}
+		if p.peek() != ']' {
+			p.fail("expected ',' or ']' in array")
+		}
+	}
+}
+
+// inlineTable parses an inline table, which has to fit on one line
+func (p *tomlParser) inlineTable() *TOMLValue {
+	value := &TOMLValue{Kind: TOMLInlineTable}
+	p.advance(1)
+	p.spaces()
+	if p.peek() == '}' {
+		p.advance(1)
+		return value
+	}
+	for {
+		p.spaces()
+		entry := &TOMLEntry{Key: p.key()}
+		p.spaces()
+		if p.peek() != '=' {
+			p.fail("expected '=' after key %s", strings.Join(entry.Key, "."))
+		}
+		p.advance(1)
+		p.spaces()
+		entry.Value = p.value()
+		value.Entries = append(value.Entries, entry)
+		p.spaces()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case '}':
+			p.advance(1)
+			return value
+		case '\n', '\r':
+			p.fail("newline in inline table")
+		default:
+			p.fail("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// Print writes the document
+// Under BlankLinesSections every table header gets a blank line above it and
+// blank lines within tables are dropped; BlankLinesPreserve keeps them where
+// the input had them and BlankLinesNone writes none
+func (d *TOMLDocument) Print(opts Options) []byte {
+	pr := &tomlPrinter{opts: opts}
+	pr.comments(d.Head, "")
+	if len(d.Head) > 0 && opts.BlankLines != BlankLinesNone {
+		pr.blank()
+	}
+
+	pr.entries(d.Root.Entries, 0)
+	pr.foot(d.Root, 0)
+	for _, table := range d.Tables {
+		if pr.separate(table.blankBefore, true) {
+			pr.blank()
+		}
+		level := 0
+		if d.IndentTables {
+			level = len(table.Key) - 1
+		}
+		pad := strings.Repeat(" ", level*opts.Indent)
+		pr.comments(table.Comments, pad)
+		pr.buf.WriteString(pad)
+		if table.Array {
+			pr.buf.WriteString("[[" + table.Name() + "]]")
+		} else {
+			pr.buf.WriteString("[" + table.Name() + "]")
+		}
+		if table.LineComment != "" {
+			pr.buf.WriteString(" " + table.LineComment)
+		}
+		pr.buf.WriteByte('\n')
+		if d.IndentTables {
+			level++
+		}
+		pr.entries(table.Entries, level)
+		pr.foot(table, level)
+	}
+	return pr.buf.Bytes()
+}
+
+// tomlPrinter writes a TOMLDocument
+type tomlPrinter struct {
+	buf  bytes.Buffer
+	opts Options
+}
+
+// blank writes a blank line, unless at the start of the file or after
+// another blank line
+func (pr *tomlPrinter) blank() {
+	if pr.buf.Len() > 0 && !bytes.HasSuffix(pr.buf.Bytes(), []byte("\n\n")) {
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// separate reports whether a blank line goes before an entry or table that
+// had one above it in the input; section is set for a table header
+func (pr *tomlPrinter) separate(blankBefore, section bool) bool {
+	switch pr.opts.BlankLines {
+	case BlankLinesNone:
+		return false
+	case BlankLinesPreserve:
+		return blankBefore
+	}
+	return section
+}
+
+// comments writes comments on lines of their own, prefixed by pad; the blank
+// lines between them are only kept under BlankLinesPreserve
+func (pr *tomlPrinter) comments(comments []string, pad string) {
+	for _, comment := range comments {
+		if comment == "" {
+			if pr.opts.BlankLines == BlankLinesPreserve {
+				pr.blank()
+			}
+			continue
+		}
+		pr.buf.WriteString(pad + comment + "\n")
+	}
+}
+
+// entries writes the key/value pairs of a table, indented by level
+// With AlignComments the line comments of consecutive one-line entries share
+// a column
+func (pr *tomlPrinter) entries(entries []*TOMLEntry, level int) {
+	pad := strings.Repeat(" ", level*pr.opts.Indent)
+	rendered := make([]string, len(entries))
+	for i, entry := range entries {
+		rendered[i] = pad + entry.Name() + " = " + pr.value(entry.Value, level)
+	}
+	oneLine := func(i int) bool { return !strings.Contains(rendered[i], "\n") }
+
+	// A comment above an entry, a blank line or a multi-line value starts a
+	// new block of aligned comments
+	startsBlock := func(i int) bool {
+		return i == 0 || len(entries[i].Comments) > 0 || pr.separate(entries[i].blankBefore, false) || !oneLine(i-1) || !oneLine(i)
+	}
+
+	width := 0
+	for i, entry := range entries {
+		if i > 0 && pr.separate(entry.blankBefore, false) {
+			pr.blank()
+		}
+		pr.comments(entry.Comments, pad)
+
+		if pr.opts.AlignComments && startsBlock(i) {
+			width = 0
+			for j := i; j < len(entries) && (j == i || !startsBlock(j)); j++ {
+				if entries[j].LineComment != "" && oneLine(j) {
+					width = max(width, len(rendered[j]))
+				}
+			}
+		}
+		pr.buf.WriteString(rendered[i])
+		if entry.LineComment != "" {
+			padding := 1
+			if pr.opts.AlignComments && oneLine(i) {
+				padding = max(width-len(rendered[i]), 0) + 1
+			}
+			pr.buf.WriteString(strings.Repeat(" ", padding) + entry.LineComment)
+		}
+		pr.buf.WriteByte('\n')
+	}
+}
+
+// foot writes the comments that end a table, indented by level
+func (pr *tomlPrinter) foot(table *TOMLTable, level int) {
+	if len(table.Foot) > 0 && pr.separate(table.blankBeforeFoot, false) {
+		pr.blank()
+	}
+	pr.comments(table.Foot, strings.Repeat(" ", level*pr.opts.Indent))
+}
+
+// value renders a value; depth is the nesting of multi-line arrays
+func (pr *tomlPrinter) value(value *TOMLValue, depth int) string {
+	switch value.Kind {
+	case TOMLInlineTable:
+		if len(value.Entries) == 0 {
+			return "{}"
+		}
+		parts := make([]string, len(value.Entries))
+		for i, entry := range value.Entries {
+			parts[i] = entry.Name() + " = " + pr.value(entry.Value, depth)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case TOMLArray:
+		if !value.Multiline {
+			parts := make([]string, len(value.Entries))
+			for i, item := range value.Entries {
+				parts[i] = pr.value(item.Value, depth)
+			}
+			return "[" + strings.Join(parts, ", ") + "]"
+		}
+		pad := strings.Repeat(" ", (depth+1)*pr.opts.Indent)
+		var b strings.Builder
+		b.WriteString("[\n")
+		for _, item := range value.Entries {
+			for _, comment := range trimBlankComments(item.Comments) {
+				b.WriteString(pad + comment + "\n")
+			}
+			b.WriteString(pad + pr.value(item.Value, depth+1) + ",")
+			if item.LineComment != "" {
+				b.WriteString(" " + item.LineComment)
+			}
+			b.WriteByte('\n')
+		}
+		for _, comment := range value.Foot {
+			b.WriteString(pad + comment + "\n")
+		}
+		b.WriteString(strings.Repeat(" ", depth*pr.opts.Indent) + "]")
+		return b.String()
+	}
+	return value.Raw
+}
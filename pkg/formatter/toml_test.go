@@ -0,0 +1,65 @@
+package formatter
+
+import "testing"
+
+func formatTOML(data []byte) ([]byte, error) {
+	return FormatTOML(data, DefaultOptions(), nil)
+}
+
+// TestFormatTOMLGolden checks layout, comments, and values kept as written
+func TestFormatTOMLGolden(t *testing.T) {
+	testGolden(t, "toml", formatTOML)
+}
+
+func TestFormatTOMLMalformed(t *testing.T) {
+	testMalformed(t, "TOML", formatTOML, []string{
+		"a = \"unterminated\n",
+		"key value\n",
+		"a = [1, 2\n",
+		"[table\n",
+		"[[array]\n",
+		"a = { b = 1\n",
+		"a = \"\"\"never closed\n",
+		"= 1\n",
+	})
+}
+
+// TestSortTOMLTables checks that sorting tables keeps array of tables
+// elements in their order and the root entries first
+func TestSortTOMLTables(t *testing.T) {
+	input := `name = "root"
+
+[zeta]
+a = 1
+
+[[alpha]]
+n = 2
+
+[[alpha]]
+n = 1
+
+[beta]
+b = 1
+`
+	want := `name = "root"
+
+[[alpha]]
+n = 2
+
+[[alpha]]
+n = 1
+
+[beta]
+b = 1
+
+[zeta]
+a = 1
+`
+	got, err := FormatTOML([]byte(input), DefaultOptions(), (*TOMLDocument).SortTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("FormatTOML() =\n%s\nwant\n%s", got, want)
+	}
+}
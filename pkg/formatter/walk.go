@@ -0,0 +1,57 @@
+package formatter
+
+import (
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Walk visits node and all of its descendants depth-first, calling fn with the
+// path of keys leading to each node. Sequence items use their index as the path
+// element, and document nodes are transparent.
+//
+// fn is called before the children of a node are visited, so it may rewrite
+// node.Content. The path slice is reused between calls and must be copied if
+// it needs to be retained.
+func Walk(node *yaml.Node, fn func(path []string, node *yaml.Node)) {
+	walk(node, nil, fn)
+}
+
+func walk(node *yaml.Node, path []string, fn func(path []string, node *yaml.Node)) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			walk(child, path, fn)
+		}
+		return
+	}
+
+	fn(path, node)
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walk(node.Content[i+1], append(path, node.Content[i].Value), fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walk(child, append(path, strconv.Itoa(i)), fn)
+		}
+	}
+}
+
+// MappingValue returns the value stored under key in a mapping node, or nil
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// trimComments drops the whitespace ending the lines of the comments of node
+// and its descendants, which the encoder would write as it is
+func trimComments(node *yaml.Node) {
+	node.HeadComment = trimLines(node.HeadComment)
+	node.LineComment = trimLines(node.LineComment)
+	node.FootComment = trimLines(node.FootComment)
+	for _, child := range node.Content {
+		trimComments(child)
+	}
+}
+
+// trimLines drops the spaces and tabs ending each line of s
+func trimLines(s string) string {
+	if !strings.ContainsAny(s, " \t") {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trailingWhitespace reports the lines of data that end in spaces or tabs,
+// which formatting removes; the lines of block scalars are left out, as
+// their whitespace is part of the value
+func trailingWhitespace(data []byte, docs []*yaml.Node) []Issue {
+	content := make(map[int]bool)
+	for _, doc := range docs {
+		Walk(doc, func(_ []string, node *yaml.Node) {
+			if node.Kind != yaml.ScalarNode || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				return
+			}
+			// The value starts on the line after the indicator
+			lines := strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+			for line := node.Line + 1; line <= node.Line+lines; line++ {
+				content[line] = true
+			}
+		})
+	}
+
+	var issues []Issue
+	for i, text := range bytes.Split(data, []byte("\n")) {
+		text = bytes.TrimSuffix(text, []byte("\r"))
+		trimmed := bytes.TrimRight(text, " \t")
+		if len(trimmed) == len(text) || content[i+1] {
+			continue
+		}
+		issues = append(issues, Issue{
+			Rule:     "style/trailing-whitespace",
+			Severity: SeverityWarning,
+			Line:     i + 1,
+			Column:   utf8.RuneCount(trimmed) + 1,
+			Message:  "trailing whitespace (formatting removes it)",
+		})
+	}
+	return issues
+}
@@ -0,0 +1,59 @@
+package formatter
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTrailingWhitespace(t *testing.T) {
+	input := "services:   \n" +
+		"  # note   \n" +
+		"  web:\n" +
+		"    command: |\n" +
+		"      echo hi  \n" +
+		"    image: nginx # pinned\t\n"
+
+	bf := &BaseFormatter{}
+	got, err := bf.FormatYAML([]byte(input), DefaultOptions(), func(*yaml.Node, bool) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "services:\n" +
+		"  # note\n" +
+		"  web:\n" +
+		"    command: \"echo hi  \\n\"\n" +
+		"    image: nginx # pinned\n"
+	if string(got) != want {
+		t.Errorf("FormatYAML() =\n%q\nwant\n%q", got, want)
+	}
+
+	// The block scalar line keeps its spaces, as they are part of the value
+	issues, err := bf.LintYAML([]byte(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, issue := range issues {
+		if issue.Rule != "style/trailing-whitespace" || issue.Severity != SeverityWarning {
+			t.Errorf("issue %+v, want a style/trailing-whitespace warning", issue)
+		}
+		lines = append(lines, issue.Line)
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 2 || lines[2] != 6 {
+		t.Errorf("issues on lines %v, want 1, 2 and 6", lines)
+	}
+	if len(issues) > 0 && issues[0].Column != 10 {
+		t.Errorf("first issue at column %d, want 10", issues[0].Column)
+	}
+}
+
+func TestTabError(t *testing.T) {
+	if err := TabError([]byte("services:\n  web:\n    image: nginx\n")); err != nil {
+		t.Errorf("TabError() = %v for space-indented input, want nil", err)
+	}
+	err := TabError([]byte("services:\n\n\tweb:\n  \t\timage: nginx\n"))
+	if err == nil || err.Line != 3 || err.Col != 1 || err.Hint == "" {
+		t.Errorf("TabError() = %+v, want line 3, column 1 with a hint", err)
+	}
+}
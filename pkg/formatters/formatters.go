@@ -0,0 +1,15 @@
+// Package formatters gives library users the formatters shipped with
+// config-formatter; their implementations are internal and may change
+// between releases, their names and output conventions follow the CLI
+package formatters
+
+import (
+	"github.com/awsqed/config-formatter/internal/modules"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// All returns every built-in formatter in auto-detection order, as the CLI
+// uses them
+func All() formatter.Registry {
+	return modules.All()
+}
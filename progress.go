@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// progressWidth is the number of cells in the progress bar
+const progressWidth = 30
+
+// progressBar draws a one-line "[####    ] 3/40 path" bar on a terminal,
+// redrawn in place with a carriage return
+type progressBar struct {
+	out     *os.File
+	total   int
+	enabled bool
+
+	// drawn is the length of the line currently on screen, 0 when cleared
+	drawn int
+}
+
+// newProgressBar returns a bar for total files; a disabled bar draws nothing
+func newProgressBar(out *os.File, total int, enabled bool) *progressBar {
+	return &progressBar{out: out, total: total, enabled: enabled}
+}
+
+// useProgressBar decides whether a progress bar is drawn on f: only on a
+// terminal, and never in CI (where the CI variable is set) or when TERM is
+// "dumb", since the carriage returns would clutter the log
+func useProgressBar(f *os.File) bool {
+	if os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// update shows that done files are finished and current is being processed
+func (p *progressBar) update(done int, current string) {
+	if !p.enabled || p.total == 0 {
+		return
+	}
+
+	filled := done * progressWidth / p.total
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", progressWidth-filled)
+	line := fmt.Sprintf("[%s] %d/%d ", bar, done, p.total)
+
+	// A line wider than the terminal wraps, and the carriage return would then
+	// only redraw its last row, so long paths are cut from the left
+	room := terminalWidth() - 1 - utf8.RuneCountInString(line)
+	if runes := []rune(current); len(runes) > room && room > 1 {
+		current = "…" + string(runes[len(runes)-room+1:])
+	}
+	line += current
+
+	// Blank out whatever is left of a longer previous line
+	padding := ""
+	if length := utf8.RuneCountInString(line); length < p.drawn {
+		padding = strings.Repeat(" ", p.drawn-length)
+	}
+	fmt.Fprint(p.out, "\r"+line+padding)
+	p.drawn = utf8.RuneCountInString(line)
+}
+
+// clear removes the bar so other output can be printed on a clean line
+func (p *progressBar) clear() {
+	if !p.enabled || p.drawn == 0 {
+		return
+	}
+	fmt.Fprint(p.out, "\r"+strings.Repeat(" ", p.drawn)+"\r")
+	p.drawn = 0
+}
+
+// finish removes the bar once every file is processed
+func (p *progressBar) finish() {
+	p.clear()
+}
+
+// terminalWidth returns the terminal width from COLUMNS, or 80
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/config"
+)
+
+// skippedDirs are never descended into when formatting a directory
+var skippedDirs = []string{".git", "node_modules"}
+
+// findConfigFiles returns the .yml and .yaml files under dir in lexical order,
+// leaving out config-formatter's own config files. Files in other syntaxes are
+// included when a formatter recognizes them by name (devcontainer.json).
+func findConfigFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && slices.Contains(skippedDirs, entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if slices.Contains(config.FileNames, entry.Name()) {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext == ".yml" || ext == ".yaml" {
+			files = append(files, path)
+		} else if _, err := formatters.Detect(path, nil); err == nil {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// runDirectory formats, checks or lints every supported file under dir and
+// prints a summary. Files no formatter detects are skipped.
+func (r *runner) runDirectory(dir string, showProgress bool) int {
+	files, err := findConfigFiles(dir)
+	if err != nil {
+		printError("Error reading directory: %v", err)
+		return 1
+	}
+
+	// Color follows the settings for the directory itself; a broken config
+	// file stops the run before any file is touched
+	settings, err := r.settings(dir)
+	if err != nil {
+		return 1
+	}
+	setupColor(*settings.Color)
+
+	// Per-file output is buffered so it can be printed between progress bar
+	// updates; success messages for single files are left out of the summary
+	summary := r.status
+	r.status = io.Discard
+	r.recursive = true
+
+	jobs := *settings.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	outputs := r.startFiles(files, jobs)
+
+	bar := newProgressBar(os.Stderr, len(files), showProgress && useProgressBar(os.Stderr))
+
+	counts := make(map[fileResult]int)
+	exitCode := 0
+	for i, path := range files {
+		bar.update(i, path)
+
+		out := <-outputs[i]
+		counts[out.result]++
+		if r.failed(out.result) {
+			exitCode = 1
+		}
+		if r.manifest != nil {
+			r.manifest.Files = append(r.manifest.Files, out.manifest.Files...)
+		}
+
+		if out.stdout.Len() > 0 || out.stderr.Len() > 0 {
+			bar.clear()
+			r.stdout.Write(out.stdout.Bytes())
+			r.stderr.Write(out.stderr.Bytes())
+		}
+	}
+	bar.finish()
+
+	processed := len(files) - counts[resultSkipped]
+	var message string
+	switch {
+	case r.lint:
+		message = fmt.Sprintf("Linted %d files, %d with issues", processed, counts[resultChanged])
+	case r.check:
+		message = fmt.Sprintf("Checked %d files, %d not formatted or against policy", processed, counts[resultChanged])
+	case r.diff:
+		message = fmt.Sprintf("Compared %d files, %d would change", processed, counts[resultChanged])
+	default:
+		message = fmt.Sprintf("Formatted %d files, %d changed", processed, counts[resultChanged])
+	}
+	if counts[resultError] > 0 {
+		message += fmt.Sprintf(", %d failed", counts[resultError])
+	}
+	if counts[resultSkipped] > 0 {
+		message += fmt.Sprintf(" (%d not recognized, skipped)", counts[resultSkipped])
+	}
+
+	// Below the threshold unformatted files are a warning; errors still fail
+	if r.maxUnformatted != nil && counts[resultChanged] > 0 && (r.check || r.lint) {
+		if counts[resultError] == 0 && r.maxUnformatted.allows(counts[resultChanged], processed) {
+			fmt.Fprintln(os.Stderr, stderrColor.yellow(message+fmt.Sprintf(", within -max-unformatted %s", r.maxUnformatted)))
+			return 0
+		}
+		message += fmt.Sprintf(", more than -max-unformatted %s", r.maxUnformatted)
+	}
+
+	if exitCode != 0 {
+		fmt.Fprintln(os.Stderr, stderrColor.red(message))
+	} else {
+		fmt.Fprintln(summary, stdoutColor.green(message))
+	}
+	return exitCode
+}
+
+// fileOutput is what processing one file of a directory run produced, held
+// until the files before it are reported
+type fileOutput struct {
+	result         fileResult
+	stdout, stderr bytes.Buffer
+
+	// manifest records the file for -manifest
+	manifest *manifest
+}
+
+// startFiles processes files with up to jobs files at a time and returns a
+// channel per file delivering its output. Settings are resolved in the order
+// of the files, as the config files found are cached on r; a config file that
+// fails to load is reported in full with the first file it applies to.
+func (r *runner) startFiles(files []string, jobs int) []chan *fileOutput {
+	outputs := make([]chan *fileOutput, len(files))
+	for i := range outputs {
+		outputs[i] = make(chan *fileOutput, 1)
+	}
+
+	go func() {
+		running := make(chan struct{}, jobs)
+		reported := make(map[string]bool)
+		for i, path := range files {
+			settings, err := r.resolveSettings(path)
+			first := err != nil && !reported[err.Error()]
+			if first {
+				reported[err.Error()] = true
+			}
+			running <- struct{}{}
+			go func() {
+				defer func() { <-running }()
+				outputs[i] <- r.processPath(path, settings, err, first)
+			}()
+		}
+	}()
+	return outputs
+}
+
+// processPath reads one file of a directory run and formats it in place,
+// with its own copy of r writing to the buffers of its output
+// settingsErr is the error resolving the settings of the file failed with,
+// printed in full when reportSettingsErr is set
+func (r *runner) processPath(path string, settings config.Settings, settingsErr error, reportSettingsErr bool) *fileOutput {
+	out := &fileOutput{result: resultError}
+	w := *r
+	w.stdout, w.stderr = &out.stdout, &out.stderr
+	if r.manifest != nil {
+		w.manifest = &manifest{}
+		out.manifest = w.manifest
+	}
+
+	if settingsErr != nil {
+		if reportSettingsErr {
+			printConfigError(w.stderr, settingsErr)
+		}
+		w.errorf(path, "Error: skipped, as its config file could not be loaded")
+		return out
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		w.errorf(path, "Error reading file: %v", err)
+		return out
+	}
+
+	output := ""
+	if r.inPlace {
+		output = path
+	}
+	out.result = w.formatFile(path, data, settings, output)
+	return out
+}
+
+// threshold is the number of unformatted files, or the percentage of the
+// processed files, a directory check tolerates
+type threshold struct {
+	count   int
+	percent float64
+
+	// relative is set for a percentage
+	relative bool
+}
+
+// parseThreshold parses a count ("10") or a percentage ("5%")
+func parseThreshold(s string) (*threshold, error) {
+	if number, ok := strings.CutSuffix(s, "%"); ok {
+		percent, err := strconv.ParseFloat(number, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("%q is not a percentage between 0%% and 100%%", s)
+		}
+		return &threshold{percent: percent, relative: true}, nil
+	}
+	count, err := strconv.Atoi(s)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("%q is not a file count or a percentage", s)
+	}
+	return &threshold{count: count}, nil
+}
+
+// allows reports whether n unformatted files out of total are tolerated
+func (t *threshold) allows(n, total int) bool {
+	if t.relative {
+		return float64(n)*100 <= t.percent*float64(total)
+	}
+	return n <= t.count
+}
+
+func (t *threshold) String() string {
+	if t.relative {
+		return strconv.FormatFloat(t.percent, 'f', -1, 64) + "%"
+	}
+	return strconv.Itoa(t.count)
+}
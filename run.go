@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/awsqed/config-formatter/internal/config"
+	"github.com/awsqed/config-formatter/internal/lockedfile"
+	"github.com/awsqed/config-formatter/internal/modules/dockercompose"
+	"github.com/awsqed/config-formatter/pkg/formatter"
+)
+
+// fileResult is the outcome of processing one file
+type fileResult int
+
+const (
+	// resultOK means the file was already formatted or lint clean
+	resultOK fileResult = iota
+
+	// resultChanged means formatting changed the file, or lint found issues
+	resultChanged
+
+	// resultSkipped means no formatter handles the file (directory runs only)
+	resultSkipped
+
+	// resultError means the file could not be processed
+	resultError
+)
+
+// runner formats files with the settings given on the command line
+type runner struct {
+	configFile   string
+	envSettings  config.Settings
+	flagSettings config.Settings
+
+	check       bool
+	diff        bool
+	lint        bool
+	debugStyles bool
+	inPlace     bool
+
+	// resolveExtends merges the extends of compose services in before
+	// formatting
+	resolveExtends bool
+
+	// manifest, when set, records every formatted file for -manifest
+	manifest *manifest
+
+	// maxUnformatted, when set, lets a directory check or lint pass with a
+	// warning while no more files than this are unformatted
+	maxUnformatted *threshold
+
+	// recursive is set while formatting a directory: files no formatter
+	// detects are skipped, messages name the file and unchanged files are not
+	// rewritten
+	recursive bool
+
+	// stdout receives formatted documents and diffs, stderr errors and issues,
+	// and status the success messages
+	stdout io.Writer
+	stderr io.Writer
+	status io.Writer
+
+	// configs caches the config file found for each directory, or the error
+	// loading it failed with
+	configs map[string]foundConfig
+}
+
+// foundConfig is the config file found for a directory, nil when there is
+// none, or the error loading it
+type foundConfig struct {
+	config *config.Config
+	err    error
+}
+
+// failed reports whether a result makes the command exit with an error
+// Changes only count as failures when checking or linting
+func (r *runner) failed(result fileResult) bool {
+	return result == resultError || (result == resultChanged && (r.check || r.lint))
+}
+
+// settings resolves the settings for a file:
+// flags > environment > project config > preset > defaults
+// Errors are reported before returning
+func (r *runner) settings(name string) (config.Settings, error) {
+	settings, err := r.resolveSettings(name)
+	if err != nil {
+		printConfigError(os.Stderr, err)
+	}
+	return settings, err
+}
+
+// resolveSettings is settings without reporting errors
+func (r *runner) resolveSettings(name string) (config.Settings, error) {
+	layers := []config.Layer{{Source: "default", Settings: config.Defaults()}}
+
+	dir := name
+	if info, err := os.Stat(name); err != nil || !info.IsDir() {
+		dir = filepath.Dir(name)
+	}
+	found, cached := r.configs[dir]
+	if !cached {
+		found.config, found.err = findConfig(name, r.configFile)
+		if r.configs == nil {
+			r.configs = make(map[string]foundConfig)
+		}
+		r.configs[dir] = found
+	}
+	if found.err != nil {
+		return config.Settings{}, found.err
+	}
+	if found.config != nil {
+		layers = append(layers, found.config.Layers(name)...)
+	}
+
+	layers = append(layers,
+		config.Layer{Source: config.EnvSource, Settings: r.envSettings},
+		config.Layer{Source: "flags", Settings: r.flagSettings})
+	layers, err := config.WithPreset(layers)
+	if err != nil {
+		return config.Settings{}, err
+	}
+	settings := config.Merged(layers)
+	if *settings.Offline {
+		disableNetwork()
+	}
+	return settings, nil
+}
+
+// errorf reports an error about a file, naming it when several files are processed
+func (r *runner) errorf(name, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if r.recursive {
+		message = name + ": " + message
+	}
+	fmt.Fprintln(r.stderr, stderrColor.red(message))
+}
+
+// issuesURL is where panics are to be reported
+const issuesURL = "https://github.com/awsqed/config-formatter/issues"
+
+// maxStackLines bounds the stack trace printed for a panic
+const maxStackLines = 20
+
+// formatFile formats, checks or lints one file according to the mode flags
+// output is where the formatted file is written; "" prints it to stdout
+// A panic while processing the file is reported as the failure of that file,
+// so a directory run goes on with the other files
+func (r *runner) formatFile(name string, data []byte, settings config.Settings, output string) (result fileResult) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.errorf(name, "Internal error: %v", v)
+			fmt.Fprintf(r.stderr, "This is a bug, please report it at %s with the file that caused it:\n", issuesURL)
+			fmt.Fprint(r.stderr, panicStack(debug.Stack()))
+			result = resultError
+		}
+	}()
+	return r.processFile(name, data, settings, output)
+}
+
+// panicStack returns the frames of a stack trace taken while recovering
+// from a panic, starting at the function that panicked and cut to
+// maxStackLines lines
+func panicStack(stack []byte) string {
+	lines := strings.Split(strings.TrimRight(string(stack), "\n"), "\n")
+	for i, line := range lines {
+		// Each frame is a function line followed by a file line
+		if strings.HasPrefix(line, "panic(") && i+2 < len(lines) {
+			lines = lines[i+2:]
+			break
+		}
+	}
+	if len(lines) > maxStackLines {
+		lines = append(lines[:maxStackLines], "\t...")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// processFile is formatFile without the panic recovery
+func (r *runner) processFile(name string, data []byte, settings config.Settings, output string) fileResult {
+	opts := formatter.DefaultOptions()
+	settings.Apply(&opts)
+
+	// Select the appropriate formatter: the configured type, or auto-detection
+	// based on file content and name. Detection sees tab-indented input as
+	// formatting will, with the tabs expanded when it expands them.
+	var selectedFormatter formatter.Formatter
+	var err error
+	if settings.Type != nil && *settings.Type != "" {
+		selectedFormatter, err = formatters.Lookup(*settings.Type)
+	} else if opts.ExpandTabs {
+		selectedFormatter, err = formatters.Detect(name, formatter.ExpandTabs(data, opts.Indent))
+	} else {
+		selectedFormatter, err = formatters.Detect(name, data)
+	}
+
+	var unsupported *formatter.UnsupportedError
+	var undetected *formatter.DetectError
+	switch {
+	case errors.As(err, &unsupported):
+		r.errorf(name, "Error: unknown formatter type '%s'", unsupported.Type)
+		r.printFormatters(unsupported.Supported)
+		return resultError
+	case errors.As(err, &undetected):
+		// Other YAML files in a directory are not ours to format
+		if r.recursive {
+			return resultSkipped
+		}
+		// A YAML file indented with tabs can be neither read nor recognized;
+		// the tabs are the error to fix
+		if tabErr := formatter.TabError(data); tabErr != nil && isYAML(formatter.ExpandTabs(data, opts.Indent)) {
+			r.errorf(name, "Error: %v", tabErr)
+			return resultError
+		}
+		r.errorf(name, "Error: could not auto-detect config type")
+		fmt.Fprintln(r.stderr, "Please specify formatter type with -type flag")
+		r.printFormatters(undetected.Candidates)
+		return resultError
+	}
+
+	// Lint mode
+	if r.lint {
+		linter, ok := selectedFormatter.(formatter.Linter)
+		if !ok {
+			fmt.Fprintf(r.status, "No lint rules for %s files\n", selectedFormatter.Name())
+			return resultOK
+		}
+		if opts.ExpandTabs {
+			data = formatter.ExpandTabs(data, opts.Indent)
+		}
+		issues, err := formatter.Lint(linter, data, opts)
+		if err != nil {
+			r.errorf(name, "Error linting file: %v", err)
+			return resultError
+		}
+		// Warnings are reported without failing the file
+		if r.printIssues(name, issues) > 0 {
+			return resultChanged
+		}
+		if len(issues) > 0 {
+			fmt.Fprintln(r.status, stdoutColor.green(fmt.Sprintf("No lint errors found, %d warnings (detected as %s)", len(issues), selectedFormatter.Name())))
+			return resultOK
+		}
+		fmt.Fprintln(r.status, stdoutColor.green(fmt.Sprintf("No lint issues found (detected as %s)", selectedFormatter.Name())))
+		return resultOK
+	}
+
+	input := data
+	if r.resolveExtends {
+		if selectedFormatter.Name() != "docker-compose" {
+			r.errorf(name, "Error: -resolve-extends only applies to compose files (detected as %s)", selectedFormatter.Name())
+			return resultError
+		}
+		if data, err = dockercompose.ResolveExtends(data, filepath.Dir(name)); err != nil {
+			r.errorf(name, "Error resolving extends: %v", err)
+			return resultError
+		}
+	}
+
+	// Format the config file
+	if r.debugStyles {
+		opts.OnStyleChange = func(change formatter.StyleChange) {
+			fmt.Fprintf(r.stderr, "%s style changed from %s to %s: %q\n", location(stderrColor, name, change.Line, change.Column), change.From, change.To, change.Value)
+		}
+	}
+
+	// A plain check compares the output with the file as it is written, one
+	// document at a time, so the formatted file is never held as a whole
+	if r.check && !r.diff && opts.OnStyleChange == nil && r.manifest == nil {
+		return max(r.checkFile(name, data, selectedFormatter, opts), r.checkPolicy(name, data, selectedFormatter, opts))
+	}
+
+	formatted, err := selectedFormatter.Format(data, opts)
+	if err != nil {
+		r.errorf(name, "Error formatting file: %v", err)
+		return resultError
+	}
+	changed := !bytes.Equal(data, formatted)
+	if r.manifest != nil {
+		r.manifest.add(name, selectedFormatter.Name(), input, formatted, settings)
+	}
+
+	// Check mode
+	if r.check {
+		policy := r.checkPolicy(name, data, selectedFormatter, opts)
+		if changed {
+			if r.diff {
+				fmt.Fprint(r.stdout, unifiedDiff(name, data, formatted, stdoutColor))
+			}
+			r.errorf(name, "File is not formatted (detected as %s)", selectedFormatter.Name())
+			return max(resultChanged, policy)
+		}
+		fmt.Fprintln(r.status, stdoutColor.green(fmt.Sprintf("File is formatted (detected as %s)", selectedFormatter.Name())))
+		return policy
+	}
+
+	result := resultOK
+	if changed {
+		result = resultChanged
+	}
+
+	// Diff mode shows the changes without writing anything
+	if r.diff {
+		if changed {
+			fmt.Fprint(r.stdout, unifiedDiff(name, data, formatted, stdoutColor))
+		}
+		return result
+	}
+
+	// Write output
+	if output == "" {
+		r.stdout.Write(formatted)
+		return result
+	}
+	if r.recursive && !changed {
+		return result
+	}
+	// Writes are made under a lock, and a file written in place is left alone
+	// when another process changed it since it was read
+	if r.inPlace {
+		err = lockedfile.Replace(output, data, formatted)
+	} else {
+		err = lockedfile.WriteFile(output, formatted, 0644)
+	}
+	if err != nil {
+		r.errorf(name, "Error writing file: %v", err)
+		return resultError
+	}
+	fmt.Fprintln(r.status, stdoutColor.green(fmt.Sprintf("Formatted file written to: %s (using %s formatter)", output, selectedFormatter.Name())))
+	return result
+}
+
+// isYAML reports whether data reads as YAML
+func isYAML(data []byte) bool {
+	_, err := formatter.ParseDocuments(data)
+	return err == nil
+}
+
+// writeManifest writes the -manifest file, if one was asked for, and returns
+// the exit code of the run
+func (r *runner) writeManifest(path string, code int) int {
+	if r.manifest == nil {
+		return code
+	}
+	if err := r.manifest.write(path); err != nil {
+		printError("Error writing manifest: %v", err)
+		return 1
+	}
+	return code
+}
+
+// checkPolicy reports the issues of the policy rules the settings of a file
+// turn on, which a check enforces along with the formatting; the file fails
+// when one of them is an error
+func (r *runner) checkPolicy(name string, data []byte, selectedFormatter formatter.Formatter, opts formatter.Options) fileResult {
+	checker, ok := selectedFormatter.(formatter.PolicyChecker)
+	if !ok {
+		return resultOK
+	}
+	issues, err := formatter.CheckPolicy(checker, data, opts)
+	if err != nil {
+		r.errorf(name, "Error checking policy: %v", err)
+		return resultError
+	}
+	if r.printIssues(name, issues) > 0 {
+		return resultChanged
+	}
+	return resultOK
+}
+
+// printIssues prints lint issues, marking warnings, and returns the number
+// of errors among them
+func (r *runner) printIssues(name string, issues []formatter.Issue) int {
+	errorCount := 0
+	for _, issue := range issues {
+		message := issue.Message
+		if issue.Severity == formatter.SeverityWarning {
+			message = stderrColor.yellow("warning:") + " " + message
+		} else {
+			errorCount++
+		}
+		fmt.Fprintf(r.stderr, "%s %s %s\n", location(stderrColor, name, issue.Line, issue.Column), message, stderrColor.dim("["+issue.Rule+"]"))
+	}
+	return errorCount
+}
+
+// errNotFormatted stops a check at the first difference
+var errNotFormatted = errors.New("file is not formatted")
+
+// checkFile checks that a file is formatted, streaming the formatted output
+// into a comparison with the file and stopping at the first difference
+func (r *runner) checkFile(name string, data []byte, selectedFormatter formatter.Formatter, opts formatter.Options) fileResult {
+	compare := &compareWriter{want: data}
+	err := formatter.Copy(compare, bytes.NewReader(data), selectedFormatter, opts)
+	switch {
+	case errors.Is(err, errNotFormatted) || err == nil && compare.offset != len(data):
+		r.errorf(name, "File is not formatted (detected as %s)", selectedFormatter.Name())
+		return resultChanged
+	case err != nil:
+		r.errorf(name, "Error formatting file: %v", err)
+		return resultError
+	}
+	fmt.Fprintln(r.status, stdoutColor.green(fmt.Sprintf("File is formatted (detected as %s)", selectedFormatter.Name())))
+	return resultOK
+}
+
+// compareWriter compares what is written to it with want, returning
+// errNotFormatted as soon as they differ
+type compareWriter struct {
+	want   []byte
+	offset int
+}
+
+func (w *compareWriter) Write(p []byte) (int, error) {
+	if !bytes.HasPrefix(w.want[w.offset:], p) {
+		return 0, errNotFormatted
+	}
+	w.offset += len(p)
+	return len(p), nil
+}
+
+// printFormatters lists the formatter names after a detection or lookup error
+func (r *runner) printFormatters(names []string) {
+	fmt.Fprintln(r.stderr, "Available formatters:")
+	for _, name := range names {
+		fmt.Fprintf(r.stderr, "  - %s\n", name)
+	}
+}